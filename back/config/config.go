@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gin-template/logging"
 	"os"
 )
@@ -13,11 +15,31 @@ const (
 	Production  Environment = "prod"
 )
 
+// The algorithms signing the JWT tokens
+const (
+	// HS256 signs the tokens with the shared secret
+	HS256 = "HS256"
+	// RS256 signs the tokens with rotated RSA keys
+	RS256 = "RS256"
+	// EdDSA signs the tokens with rotated Ed25519 keys
+	EdDSA = "EdDSA"
+)
+
+// DefaultJwtSecret is the secret of the default configuration, it is refused in production
+const DefaultJwtSecret = "secret"
+
 type JwtConfig struct {
-	// Secret is the secret key used to sign the JWT token
+	// Algorithm is the algorithm signing the tokens, HS256 when empty
+	Algorithm string `json:"algorithm" example:"EdDSA" default:"EdDSA" enums:"HS256,RS256,EdDSA"`
+	// Secret is the secret key used to sign the JWT token with HS256
 	Secret string `json:"secret" example:"secret"`
-	// Expiration is the duration of the token in hours
-	Expiration int `json:"expiration" example:"24" default:"24"`
+	// RotationInterval is the duration in hours a key of RS256 or EdDSA signs the tokens,
+	// it keeps verifying them until the tokens it signed expire
+	RotationInterval int `json:"rotation_interval" example:"720" default:"720"`
+	// Expiration is the duration of the refresh tokens in hours
+	Expiration int `json:"expiration" example:"168" default:"168"`
+	// AccessExpiration is the duration of the access tokens in minutes
+	AccessExpiration int `json:"access_expiration" example:"15" default:"15"`
 }
 
 type DatabaseConfig struct {
@@ -40,17 +62,252 @@ type ServerConfig struct {
 	Port int `json:"port"`
 }
 
+type TrashConfig struct {
+	// Retention is the number of days deleted items are kept before being purged, 0 disables the purge
+	Retention int `json:"retention" example:"30" default:"30"`
+	// PurgeInterval is the interval between two purges in hours
+	PurgeInterval int `json:"purge_interval" example:"24" default:"24"`
+}
+
+type WebhookConfig struct {
+	// Interval is the interval between two delivery runs in seconds, 0 disables the deliveries
+	Interval int `json:"interval" example:"30" default:"30"`
+	// Timeout is the timeout of a delivery request in seconds
+	Timeout int `json:"timeout" example:"10" default:"10"`
+	// MaxAttempts is the number of attempts before a delivery is dead
+	MaxAttempts int `json:"max_attempts" example:"8" default:"8"`
+	// Backoff is the delay before the first retry in seconds, doubled at each retry
+	Backoff int `json:"backoff" example:"60" default:"60"`
+	// MaxBackoff is the maximum delay between two retries in seconds
+	MaxBackoff int `json:"max_backoff" example:"21600" default:"21600"`
+}
+
+type MailConfig struct {
+	// Host is the host of the SMTP server, the emails are only logged when empty
+	Host string `json:"host"`
+	// Port is the port of the SMTP server
+	Port int `json:"port" example:"587" default:"587"`
+	// Username is the user of the SMTP server
+	Username string `json:"username"`
+	// Password is the password of the SMTP server
+	Password string `json:"password"`
+	// From is the sender address of the emails
+	From string `json:"from" example:"noreply@epicarte.fr"`
+	// Language is the language of the emails, fr or en
+	Language string `json:"language" example:"fr" default:"fr"`
+	// AppURL is the URL of the web application the links of the emails point to
+	AppURL string `json:"app_url" example:"http://localhost:3000"`
+	// DigestHour is the hour of the day the absence digests are sent, -1 disables the absence and alert emails
+	DigestHour int `json:"digest_hour" example:"18" default:"18"`
+}
+
+type RegistrationConfig struct {
+	// AllowedDomains is the list of email domains allowed to register
+	AllowedDomains []string `json:"allowed_domains" example:"epitech.eu"`
+	// Roster is true if the emails of the students of the classes are allowed to register
+	Roster bool `json:"roster" example:"true" default:"true"`
+	// VerificationValidity is the duration of the email verification links in hours
+	VerificationValidity int `json:"verification_validity" example:"48" default:"48"`
+	// InvitationValidity is the duration of the staff invitation links in hours
+	InvitationValidity int `json:"invitation_validity" example:"72" default:"72"`
+}
+
+type PasswordConfig struct {
+	// MinLength is the minimum number of characters of a password, it cannot be lower than password.HardMinLength
+	MinLength int `json:"min_length" example:"8" default:"8"`
+	// RequireUpper is true if a password must contain an uppercase letter
+	RequireUpper bool `json:"require_upper" example:"true" default:"true"`
+	// RequireLower is true if a password must contain a lowercase letter
+	RequireLower bool `json:"require_lower" example:"true" default:"true"`
+	// RequireDigit is true if a password must contain a digit
+	RequireDigit bool `json:"require_digit" example:"true" default:"true"`
+	// RequireSymbol is true if a password must contain a character which is neither a letter nor a digit
+	RequireSymbol bool `json:"require_symbol" example:"false" default:"false"`
+	// History is the number of previous passwords of an account a new password must differ from, 0 disables the check
+	History int `json:"history" example:"5" default:"5"`
+	// BreachedList is the directory of the breached password list split in SHA-1 prefix files, the check is disabled when empty
+	BreachedList string `json:"breached_list" example:"/var/lib/epicarte/pwned"`
+}
+
+type MFAConfig struct {
+	// Issuer is the name displayed by the authenticator apps
+	Issuer string `json:"issuer" example:"Epicarte" default:"Epicarte"`
+	// Skew is the number of time steps of 30 seconds a code is accepted before and after the current one
+	Skew int `json:"skew" example:"1" default:"1"`
+}
+
+type OIDCConfig struct {
+	// Issuer is the URL of the OpenID Connect provider, the single sign-on is disabled when empty
+	Issuer string `json:"issuer" example:"https://sso.university.fr"`
+	// ClientID is the id of the application registered at the provider
+	ClientID string `json:"client_id"`
+	// ClientSecret is the secret of the application, empty for a public client
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is the URL of the web application the provider redirects to with the code
+	RedirectURL string `json:"redirect_url" example:"http://localhost:3000/oidc/callback"`
+	// Scopes are the requested scopes, openid, email and profile by default
+	Scopes []string `json:"scopes" example:"openid,email,profile"`
+	// AutoProvision is true if an account is created for an unknown identity
+	AutoProvision bool `json:"auto_provision" example:"true"`
+	// RoleClaim is the claim of the ID token matched against the role mappings
+	RoleClaim string `json:"role_claim" example:"groups"`
+	// RoleMappings maps the values of the role claim to roles, the first matching mapping wins
+	RoleMappings []RoleMapping `json:"role_mappings"`
+	// SyncRole is true if the role mappings are applied at each login, overriding the role assigned locally,
+	// they are only applied to the provisioned accounts otherwise
+	SyncRole bool `json:"sync_role" example:"false"`
+	// DefaultRole is the role of the provisioned accounts matching no mapping
+	DefaultRole string `json:"default_role" example:"student" default:"student"`
+}
+
+type LDAPConfig struct {
+	// URL is the ldap:// or ldaps:// URL of the directory, the directory authentication is disabled when empty
+	URL string `json:"url" example:"ldaps://ad.school.fr"`
+	// BindDN is the DN of the account searching the users, the search is anonymous when empty
+	BindDN string `json:"bind_dn" example:"cn=reader,dc=school,dc=fr"`
+	// BindPassword is the password of the account searching the users
+	BindPassword string `json:"bind_password"`
+	// BaseDN is the DN under which the users are searched
+	BaseDN string `json:"base_dn" example:"ou=people,dc=school,dc=fr"`
+	// Filter is the filter finding a user, {username} is replaced by the escaped login
+	Filter string `json:"filter" example:"(&(objectClass=person)(sAMAccountName={username}))" default:"(uid={username})"`
+	// EmailAttribute is the attribute holding the email of a user
+	EmailAttribute string `json:"email_attribute" example:"mail" default:"mail"`
+	// FirstNameAttribute is the attribute holding the first name of a user
+	FirstNameAttribute string `json:"first_name_attribute" example:"givenName" default:"givenName"`
+	// LastNameAttribute is the attribute holding the last name of a user
+	LastNameAttribute string `json:"last_name_attribute" example:"sn" default:"sn"`
+	// GroupAttribute is the attribute holding the groups of a user, matched against the role mappings
+	GroupAttribute string `json:"group_attribute" example:"memberOf" default:"memberOf"`
+	// RoleMappings maps the groups to roles, the first matching mapping wins
+	RoleMappings []RoleMapping `json:"role_mappings"`
+	// SyncRole is true if the role mappings are applied at each login, overriding the role assigned locally,
+	// they are only applied to the provisioned accounts otherwise
+	SyncRole bool `json:"sync_role" example:"false"`
+	// AutoProvision is true if an account is created for a user of the directory without account
+	AutoProvision bool `json:"auto_provision" example:"true"`
+	// DefaultRole is the role of the provisioned accounts matching no mapping
+	DefaultRole string `json:"default_role" example:"student" default:"student"`
+	// Timeout is the timeout of the directory operations in seconds
+	Timeout int `json:"timeout" example:"10" default:"10"`
+}
+
+type RoleMapping struct {
+	// Value is the value of the claim or the group
+	Value string `json:"value" example:"staff"`
+	// Role is the role given to the users having the value
+	Role string `json:"role" example:"admin"`
+}
+
+type LoginConfig struct {
+	// MaxFailures is the number of consecutive failed logins locking an account, 0 disables the lockout
+	MaxFailures int `json:"max_failures" example:"5" default:"5"`
+	// LockoutDelay is the duration of the first lockout in seconds, doubled at each further failure
+	LockoutDelay int `json:"lockout_delay" example:"60" default:"60"`
+	// MaxLockoutDelay is the maximum duration of a lockout in seconds
+	MaxLockoutDelay int `json:"max_lockout_delay" example:"3600" default:"3600"`
+	// HistorySize is the number of logins shown in the history of an account
+	HistorySize int `json:"history_size" example:"20" default:"20"`
+	// HistoryRetention is the number of days the logins are kept, 0 keeps them forever
+	HistoryRetention int `json:"history_retention" example:"90" default:"90"`
+}
+
+type RateLimitRule struct {
+	// Burst is the number of requests allowed at once, the rule is disabled when zero
+	Burst int `json:"burst" example:"10"`
+	// Period is the duration in seconds to allow the whole burst again
+	Period int `json:"period" example:"60"`
+}
+
+type RateLimitGroupConfig struct {
+	// IP limits the requests of a client address
+	IP RateLimitRule `json:"ip"`
+	// Account limits the requests of an authenticated user, or targeting the account of a login
+	Account RateLimitRule `json:"account"`
+	// Session limits the requests targeting a session
+	Session RateLimitRule `json:"session"`
+}
+
+type RateLimitConfig struct {
+	// Store is memory, limiting each replica, or postgres, limiting across the replicas
+	Store string `json:"store" example:"postgres" default:"memory"`
+	// Groups are the limits of the route groups: login, register and check_in, the default ones when left out
+	Groups map[string]RateLimitGroupConfig `json:"groups"`
+}
+
 type Config struct {
 	// Env is the environment of the application
 	Env Environment `json:"env"`
 	// Version is the version of the application
 	Version string `json:"version"`
+	// Tenant is the name of the institution served by the application, recorded in the audit log
+	Tenant string `json:"tenant"`
 	// Jwt is the configuration of the JWT
 	Jwt JwtConfig `json:"jwt"`
 	// Db is the configuration of the database
 	Db DatabaseConfig `json:"database"`
 	// Server is the configuration of the server
 	Server ServerConfig `json:"server"`
+	// Trash is the configuration of the deleted items retention
+	Trash TrashConfig `json:"trash"`
+	// Webhook is the configuration of the webhook deliveries
+	Webhook WebhookConfig `json:"webhook"`
+	// Mail is the configuration of the emails
+	Mail MailConfig `json:"mail"`
+	// Registration is the configuration of the self-registration
+	Registration RegistrationConfig `json:"registration"`
+	// Password is the configuration of the password policy
+	Password PasswordConfig `json:"password"`
+	// MFA is the configuration of the two-factor authentication
+	MFA MFAConfig `json:"mfa"`
+	// OIDC is the configuration of the OpenID Connect single sign-on
+	OIDC OIDCConfig `json:"oidc"`
+	// LDAP is the configuration of the directory authentication
+	LDAP LDAPConfig `json:"ldap"`
+	// RateLimit is the configuration of the rate limiting
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	// Login is the configuration of the lockout and the history of the logins
+	Login LoginConfig `json:"login"`
+}
+
+// defaultPasswordConfig returns the password policy used when the configuration does not set it
+func defaultPasswordConfig() PasswordConfig {
+	return PasswordConfig{
+		MinLength:    8,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+		History:      5,
+	}
+}
+
+// defaultLoginConfig returns the lockout and the login history used when the configuration does not set them
+func defaultLoginConfig() LoginConfig {
+	return LoginConfig{
+		MaxFailures:      5,
+		LockoutDelay:     60,
+		MaxLockoutDelay:  3600,
+		HistorySize:      20,
+		HistoryRetention: 90,
+	}
+}
+
+// defaultRateLimitGroups returns the limits of the route groups used when the configuration does not set them
+func defaultRateLimitGroups() map[string]RateLimitGroupConfig {
+	return map[string]RateLimitGroupConfig{
+		"login": {
+			IP:      RateLimitRule{Burst: 20, Period: 60},
+			Account: RateLimitRule{Burst: 10, Period: 600},
+		},
+		"register": {
+			IP: RateLimitRule{Burst: 5, Period: 3600},
+		},
+		"check_in": {
+			IP:      RateLimitRule{Burst: 30, Period: 60},
+			Account: RateLimitRule{Burst: 10, Period: 60},
+			Session: RateLimitRule{Burst: 300, Period: 60},
+		},
+	}
 }
 
 // NewConfig returns a new configuration from a file path that is by default config.json
@@ -69,9 +326,13 @@ func NewConfig() Config {
 		return Config{
 			Env:     Development,
 			Version: "1.0.0",
+			Tenant:  "default",
 			Jwt: JwtConfig{
-				Secret:     "secret",
-				Expiration: 24,
+				Algorithm:        EdDSA,
+				Secret:           DefaultJwtSecret,
+				RotationInterval: 720,
+				Expiration:       168,
+				AccessExpiration: 15,
 			},
 			Db: DatabaseConfig{
 				Host:         "localhost",
@@ -84,16 +345,86 @@ func NewConfig() Config {
 				Host: "0.0.0.0",
 				Port: 8080,
 			},
+			Trash: TrashConfig{
+				Retention:     30,
+				PurgeInterval: 24,
+			},
+			Webhook: WebhookConfig{
+				Interval:    30,
+				Timeout:     10,
+				MaxAttempts: 8,
+				Backoff:     60,
+				MaxBackoff:  21600,
+			},
+			Mail: MailConfig{
+				Port:       587,
+				From:       "noreply@epicarte.fr",
+				Language:   "fr",
+				AppURL:     "http://localhost:3000",
+				DigestHour: 18,
+			},
+			Registration: RegistrationConfig{
+				Roster:               true,
+				VerificationValidity: 48,
+				InvitationValidity:   72,
+			},
+			Password: defaultPasswordConfig(),
+			MFA: MFAConfig{
+				Issuer: "Epicarte",
+				Skew:   1,
+			},
+			OIDC: OIDCConfig{
+				DefaultRole: "student",
+			},
+			LDAP: LDAPConfig{
+				Filter:             "(uid={username})",
+				EmailAttribute:     "mail",
+				FirstNameAttribute: "givenName",
+				LastNameAttribute:  "sn",
+				GroupAttribute:     "memberOf",
+				DefaultRole:        "student",
+				Timeout:            10,
+			},
+			RateLimit: RateLimitConfig{
+				Store:  "memory",
+				Groups: defaultRateLimitGroups(),
+			},
+			Login: defaultLoginConfig(),
 		}
 	}
 	defer open.Close()
 
 	decoder := json.NewDecoder(open)
-	configuration := Config{}
+	// the sections guarding the accounts start from their defaults, so that leaving them out does not disable them
+	configuration := Config{
+		Password:  defaultPasswordConfig(),
+		Login:     defaultLoginConfig(),
+		RateLimit: RateLimitConfig{Store: "memory"},
+	}
 	err = decoder.Decode(&configuration)
 	if err != nil {
 		logging.Error.Fatal(err)
 	}
+	if configuration.RateLimit.Groups == nil {
+		configuration.RateLimit.Groups = defaultRateLimitGroups()
+	}
 
 	return configuration
 }
+
+// Validate checks that the configuration can be used in its environment
+func (c Config) Validate() error {
+	switch c.Jwt.Algorithm {
+	case "", HS256:
+		if c.Env == Production && (c.Jwt.Secret == "" || c.Jwt.Secret == DefaultJwtSecret) {
+			return errors.New("the default JWT secret cannot be used in production, set jwt.secret or use an asymmetric jwt.algorithm")
+		}
+	case RS256, EdDSA:
+		if c.Jwt.RotationInterval <= 0 {
+			return errors.New("jwt.rotation_interval must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm '%s'", c.Jwt.Algorithm)
+	}
+	return nil
+}
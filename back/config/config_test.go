@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadConfig loads a configuration file having the given content
+func loadConfig(t *testing.T, content string) Config {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_PATH", path)
+	return NewConfig()
+}
+
+// TestNewConfig_PasswordDefaults tests that a file without password section keeps the default policy
+func TestNewConfig_PasswordDefaults(t *testing.T) {
+	conf := loadConfig(t, `{"env": "prod"}`)
+	if conf.Password != defaultPasswordConfig() {
+		t.Errorf("Password policy should be the default one, got %+v", conf.Password)
+	}
+
+	conf = loadConfig(t, `{"password": {"min_length": 12, "require_upper": false}}`)
+	expected := defaultPasswordConfig()
+	expected.MinLength = 12
+	expected.RequireUpper = false
+	if conf.Password != expected {
+		t.Errorf("Password policy should only override the given fields, got %+v", conf.Password)
+	}
+}
+
+// TestNewConfig_LoginDefaults tests that a file without login section keeps the lockout enabled
+func TestNewConfig_LoginDefaults(t *testing.T) {
+	conf := loadConfig(t, `{"env": "prod", "password": {"min_length": 10}}`)
+	if conf.Login != defaultLoginConfig() {
+		t.Errorf("Login configuration should be the default one, got %+v", conf.Login)
+	}
+	if conf.Login.MaxFailures == 0 {
+		t.Error("Lockout should be enabled by default")
+	}
+
+	conf = loadConfig(t, `{"login": {"max_failures": 0}}`)
+	if conf.Login.MaxFailures != 0 || conf.Login.LockoutDelay != 60 {
+		t.Errorf("Lockout should be disabled explicitly only, got %+v", conf.Login)
+	}
+}
+
+// TestNewConfig_RateLimitDefaults tests that a file without rate_limit section keeps the default limits
+func TestNewConfig_RateLimitDefaults(t *testing.T) {
+	conf := loadConfig(t, `{"env": "prod"}`)
+	if conf.RateLimit.Store != "memory" {
+		t.Errorf("Store should be memory by default, got %s", conf.RateLimit.Store)
+	}
+	for group := range defaultRateLimitGroups() {
+		if conf.RateLimit.Groups[group].IP.Burst == 0 {
+			t.Errorf("Group %s should be limited by default", group)
+		}
+	}
+
+	conf = loadConfig(t, `{"rate_limit": {"store": "postgres", "groups": {"login": {"ip": {"burst": 3, "period": 60}}}}}`)
+	if conf.RateLimit.Store != "postgres" || len(conf.RateLimit.Groups) != 1 || conf.RateLimit.Groups["login"].IP.Burst != 3 {
+		t.Errorf("Configured groups should replace the default ones, got %+v", conf.RateLimit)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"gin-template/config"
 	"gin-template/logging"
-	"gin-template/pkg/model"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -13,18 +12,18 @@ import (
 	"time"
 )
 
-// NewDatabase returns a new database connection
+// Open returns a new database connection
 // The database connection is made with the configuration given in parameter
 // The database connection is made with the postgres driver
 // The database connection is made with the gorm library
-func NewDatabase(config config.DatabaseConfig) *gorm.DB {
+func Open(config config.DatabaseConfig) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=UTC",
 		config.Host,
 		config.Username,
 		config.Password,
 		config.DatabaseName,
 		config.Port)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
 		SkipDefaultTransaction: true,
 		Logger: logger.New(
 			log.New(os.Stdout, "\r\n", log.LstdFlags), // io writer
@@ -36,25 +35,17 @@ func NewDatabase(config config.DatabaseConfig) *gorm.DB {
 			},
 		),
 	})
+}
+
+// NewDatabase returns a new database connection
+// The schema is not migrated, the server refuses to start while migrations are pending
+func NewDatabase(config config.DatabaseConfig) *gorm.DB {
+	db, err := Open(config)
 	if err != nil {
 		logging.Error.Fatal(err)
 	}
-
-	// Create enum types
-	db.Exec("CREATE TYPE role AS ENUM ('superadmin', 'student', 'admin');")
-
-	// Migrate the schema
-	err = db.AutoMigrate(
-		model.User{},
-		model.Account{},
-		model.Token{},
-		model.Class{},
-		model.Session{},
-		model.Student{},
-	)
-	if err != nil {
+	if err := CheckSchema(db); err != nil {
 		logging.Error.Fatal(err)
 	}
-
 	return db
 }
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"gin-template/pkg/model"
+	"gin-template/utils/migration"
+	"gorm.io/gorm"
+)
+
+// migrationFiles are the versioned migrations of the schema, embedded in the binary
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// NewMigrator returns the migrator of the embedded migrations
+func NewMigrator(db *gorm.DB) (*migration.Migrator, error) {
+	migrations, err := migration.Load(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	m := migration.New(db, migrations)
+	m.Baseline = baselineAutoMigrated
+	return m, nil
+}
+
+// MigrateUp applies the pending migrations and creates the builtin roles which do not exist yet
+func MigrateUp(db *gorm.DB) ([]migration.Migration, error) {
+	m, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.Up()
+	if err != nil {
+		return nil, err
+	}
+	return applied, model.NewRoleModel(db).Seed()
+}
+
+// CheckSchema returns an error when migrations are pending, the migrations of a newer version are tolerated
+// so that the replicas of the previous version keep running during a deployment
+func CheckSchema(db *gorm.DB) error {
+	m, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("the database schema is not migrated, %d migrations are pending from %s, run the 'migrate up' command", len(pending), pending[0])
+	}
+	return nil
+}
+
+// baselineAutoMigrated brings a database created by AutoMigrate, before the versioned migrations, to the initial migration
+// The models must match the initial migration, once a later migration changes them, such a database must first be
+// migrated by a version preceding the change
+func baselineAutoMigrated(tx *gorm.DB) (bool, error) {
+	if !tx.Migrator().HasTable(&model.Account{}) {
+		return false, nil
+	}
+
+	// Roles are now rows of the roles table, convert the former role enum type
+	for _, statement := range []string{
+		"ALTER TABLE IF EXISTS users_t ALTER COLUMN role DROP DEFAULT;",
+		"ALTER TABLE IF EXISTS users_t ALTER COLUMN role TYPE varchar(40) USING role::text;",
+		"DROP TYPE IF EXISTS role;",
+	} {
+		if err := tx.Exec(statement).Error; err != nil {
+			return false, err
+		}
+	}
+
+	// The accounts created before the email verification are considered verified
+	backfillVerified := !tx.Migrator().HasColumn(&model.Account{}, "VerifiedAt")
+
+	err := tx.AutoMigrate(
+		model.Role{},
+		model.User{},
+		model.Account{},
+		model.Token{},
+		model.PasswordReset{},
+		model.PasswordHistory{},
+		model.MFA{},
+		model.RecoveryCode{},
+		model.Identity{},
+		model.Term{},
+		model.Holiday{},
+		model.Class{},
+		model.TimetableSlot{},
+		model.Session{},
+		model.Student{},
+		model.Attendance{},
+		model.Justification{},
+		model.GuardianInvitation{},
+		model.AuditLog{},
+		model.AbsenceNotification{},
+		model.AlertRule{},
+		model.Alert{},
+		model.Webhook{},
+		model.WebhookDelivery{},
+		model.RateLimitBucket{},
+		model.APIKey{},
+		model.SigningKey{},
+		model.StaffInvitation{},
+		model.LoginAttempt{},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if backfillVerified {
+		if err := tx.Exec("UPDATE accounts SET verified_at = created_at WHERE verified_at IS NULL;").Error; err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
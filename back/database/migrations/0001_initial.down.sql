@@ -0,0 +1,32 @@
+DROP TABLE IF EXISTS "login_attempts";
+DROP TABLE IF EXISTS "staff_invitations";
+DROP TABLE IF EXISTS "signing_keys";
+DROP TABLE IF EXISTS "api_keys";
+DROP TABLE IF EXISTS "rate_limit_buckets";
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhooks";
+DROP TABLE IF EXISTS "alerts";
+DROP TABLE IF EXISTS "alert_rules";
+DROP TABLE IF EXISTS "absence_notifications";
+DROP TABLE IF EXISTS "audit_logs";
+DROP TABLE IF EXISTS "guardian_invitations";
+DROP TABLE IF EXISTS "justifications";
+DROP TABLE IF EXISTS "attendances";
+DROP TABLE IF EXISTS "guardian_students";
+DROP TABLE IF EXISTS "students";
+DROP TABLE IF EXISTS "session_students";
+DROP TABLE IF EXISTS "sessions";
+DROP TABLE IF EXISTS "timetable_slots";
+DROP TABLE IF EXISTS "class_teachers";
+DROP TABLE IF EXISTS "classes";
+DROP TABLE IF EXISTS "holidays";
+DROP TABLE IF EXISTS "terms";
+DROP TABLE IF EXISTS "identities";
+DROP TABLE IF EXISTS "recovery_codes";
+DROP TABLE IF EXISTS "mfa";
+DROP TABLE IF EXISTS "password_history";
+DROP TABLE IF EXISTS "password_resets";
+DROP TABLE IF EXISTS "tokens";
+DROP TABLE IF EXISTS "users_t";
+DROP TABLE IF EXISTS "accounts";
+DROP TABLE IF EXISTS "roles";
@@ -0,0 +1,454 @@
+-- Schema of every table up to the introduction of the versioned migrations, as created until then by the
+-- AutoMigrate of the models. The later changes of the schema are made by the next numbered migrations.
+
+CREATE TABLE "roles" (
+    "name" varchar(40),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "description" varchar(255),
+    "permissions" text NOT NULL,
+    "require_mfa" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("name")
+);
+
+CREATE TABLE "accounts" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "email" text NOT NULL,
+    "username" varchar(80) NOT NULL,
+    "password" text NOT NULL,
+    "verified_at" timestamptz,
+    "service" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_idx_username" ON "accounts" ("username");
+CREATE UNIQUE INDEX "unique_idx_email" ON "accounts" ("email");
+CREATE INDEX "idx_accounts_deleted_at" ON "accounts" ("deleted_at");
+
+CREATE TABLE "users_t" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "account_id" bigint,
+    "first_name" text,
+    "last_name" text,
+    "role" varchar(40) NOT NULL DEFAULT 'student',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_accounts_user" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_users_t_role" ON "users_t" ("role");
+CREATE INDEX "idx_users_t_deleted_at" ON "users_t" ("deleted_at");
+
+CREATE TABLE "tokens" (
+    "id" bigserial,
+    "token_id" text NOT NULL,
+    "created_at" timestamptz,
+    "family" varchar(36) NOT NULL DEFAULT '',
+    "user_id" bigint NOT NULL DEFAULT 0,
+    "user_agent" varchar(255),
+    "ip" varchar(45),
+    "logged_in_at" timestamptz,
+    "last_used_at" timestamptz,
+    "expires_at" timestamptz,
+    "rotated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_tokens_deleted_at" ON "tokens" ("deleted_at");
+CREATE INDEX "idx_tokens_user_id" ON "tokens" ("user_id");
+CREATE INDEX "idx_tokens_family" ON "tokens" ("family");
+CREATE UNIQUE INDEX "unique_idx_token" ON "tokens" ("token_id");
+
+CREATE TABLE "password_resets" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "account_id" bigint NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_password_resets_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX "unique_idx_password_reset_token" ON "password_resets" ("token_hash");
+CREATE INDEX "idx_password_resets_account_id" ON "password_resets" ("account_id");
+
+CREATE TABLE "password_history" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "account_id" bigint NOT NULL,
+    "hash" text NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_password_history_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_password_history_account_id" ON "password_history" ("account_id");
+
+CREATE TABLE "mfa" (
+    "account_id" bigserial,
+    "created_at" timestamptz,
+    "secret" varchar(64) NOT NULL,
+    "enabled_at" timestamptz,
+    "last_used_step" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("account_id"),
+    CONSTRAINT "fk_mfa_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE "recovery_codes" (
+    "id" bigserial,
+    "account_id" bigint NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "used_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_recovery_codes_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_recovery_codes_account_id" ON "recovery_codes" ("account_id");
+
+CREATE TABLE "identities" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "account_id" bigint NOT NULL,
+    "issuer" varchar(255) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "last_login_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_identities_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX "unique_idx_identity" ON "identities" ("issuer","subject");
+CREATE INDEX "idx_identities_account_id" ON "identities" ("account_id");
+
+CREATE TABLE "terms" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(80) NOT NULL,
+    "start_date" timestamptz NOT NULL,
+    "end_date" timestamptz NOT NULL,
+    "archived" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "unique_idx_term_name" ON "terms" ("name");
+CREATE INDEX "idx_terms_deleted_at" ON "terms" ("deleted_at");
+
+CREATE TABLE "holidays" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(120) NOT NULL,
+    "start_date" timestamptz NOT NULL,
+    "end_date" timestamptz NOT NULL,
+    "term_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_terms_holidays" FOREIGN KEY ("term_id") REFERENCES "terms"("id")
+);
+CREATE INDEX "idx_holidays_deleted_at" ON "holidays" ("deleted_at");
+
+CREATE TABLE "classes" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" text NOT NULL,
+    "year" text NOT NULL,
+    "term_id" bigint,
+    "notify_absences" boolean NOT NULL DEFAULT true,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_terms_classes" FOREIGN KEY ("term_id") REFERENCES "terms"("id")
+);
+CREATE INDEX "idx_classes_deleted_at" ON "classes" ("deleted_at");
+
+CREATE TABLE "class_teachers" (
+    "class_id" bigint,
+    "user_id" bigint,
+    PRIMARY KEY ("class_id","user_id"),
+    CONSTRAINT "fk_class_teachers_class" FOREIGN KEY ("class_id") REFERENCES "classes"("id"),
+    CONSTRAINT "fk_class_teachers_user" FOREIGN KEY ("user_id") REFERENCES "users_t"("id")
+);
+
+CREATE TABLE "timetable_slots" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "weekday" bigint NOT NULL,
+    "start_time" varchar(5) NOT NULL,
+    "end_time" varchar(5) NOT NULL,
+    "room" varchar(80),
+    "class_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_classes_timetable" FOREIGN KEY ("class_id") REFERENCES "classes"("id")
+);
+CREATE INDEX "idx_timetable_slots_deleted_at" ON "timetable_slots" ("deleted_at");
+
+CREATE TABLE "sessions" (
+    "id" uuid,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "password" varchar(255) NOT NULL,
+    "is_closed" boolean NOT NULL DEFAULT false,
+    "class_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_classes_sessions" FOREIGN KEY ("class_id") REFERENCES "classes"("id")
+);
+CREATE INDEX "idx_sessions_deleted_at" ON "sessions" ("deleted_at");
+
+CREATE TABLE "session_students" (
+    "session_id" uuid,
+    "user_id" bigint,
+    PRIMARY KEY ("session_id","user_id"),
+    CONSTRAINT "fk_session_students_session" FOREIGN KEY ("session_id") REFERENCES "sessions"("id"),
+    CONSTRAINT "fk_session_students_user" FOREIGN KEY ("user_id") REFERENCES "users_t"("id")
+);
+
+CREATE TABLE "students" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "email" text UNIQUE,
+    "first_name" varchar(120) NOT NULL,
+    "last_name" varchar(120) NOT NULL,
+    "class_id" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_classes_students" FOREIGN KEY ("class_id") REFERENCES "classes"("id")
+);
+CREATE INDEX "idx_students_deleted_at" ON "students" ("deleted_at");
+
+CREATE TABLE "guardian_students" (
+    "student_id" bigint,
+    "user_id" bigint,
+    PRIMARY KEY ("student_id","user_id"),
+    CONSTRAINT "fk_guardian_students_student" FOREIGN KEY ("student_id") REFERENCES "students"("id"),
+    CONSTRAINT "fk_guardian_students_user" FOREIGN KEY ("user_id") REFERENCES "users_t"("id")
+);
+
+CREATE TABLE "attendances" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "session_id" uuid NOT NULL,
+    "student_id" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "manual" boolean NOT NULL DEFAULT false,
+    "justified" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_attendances_session" FOREIGN KEY ("session_id") REFERENCES "sessions"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_attendances_student" FOREIGN KEY ("student_id") REFERENCES "students"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_attendances_student_id" ON "attendances" ("student_id");
+CREATE UNIQUE INDEX "unique_idx_attendance" ON "attendances" ("session_id","student_id");
+
+CREATE TABLE "justifications" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "attendance_id" bigint NOT NULL,
+    "reason" text NOT NULL,
+    "submitted_by" bigint NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "reviewed_by" bigint,
+    "reviewed_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_justifications_attendance" FOREIGN KEY ("attendance_id") REFERENCES "attendances"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_justifications_status" ON "justifications" ("status");
+CREATE INDEX "idx_justifications_attendance_id" ON "justifications" ("attendance_id");
+
+CREATE TABLE "guardian_invitations" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "student_id" bigint NOT NULL,
+    "email" text NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "created_by" bigint NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "accepted_at" timestamptz,
+    "accepted_by" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_guardian_invitations_student" FOREIGN KEY ("student_id") REFERENCES "students"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX "unique_idx_guardian_invitation_code" ON "guardian_invitations" ("code_hash");
+CREATE INDEX "idx_guardian_invitations_student_id" ON "guardian_invitations" ("student_id");
+
+CREATE TABLE "audit_logs" (
+    "id" bigserial,
+    "created_at" timestamptz NOT NULL,
+    "actor_id" bigint,
+    "tenant" varchar(80),
+    "action" varchar(40) NOT NULL,
+    "entity" varchar(80) NOT NULL,
+    "entity_id" varchar(120),
+    "before" jsonb,
+    "after" jsonb,
+    "request_id" varchar(64),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_audit_logs_request_id" ON "audit_logs" ("request_id");
+CREATE INDEX "idx_audit_entity" ON "audit_logs" ("entity","entity_id");
+CREATE INDEX "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+CREATE INDEX "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+
+CREATE TABLE "absence_notifications" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "student_id" bigint NOT NULL,
+    "session_id" uuid NOT NULL,
+    "sent_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_absence_notifications_student" FOREIGN KEY ("student_id") REFERENCES "students"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_absence_notifications_session" FOREIGN KEY ("session_id") REFERENCES "sessions"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_absence_notifications_sent_at" ON "absence_notifications" ("sent_at");
+CREATE INDEX "idx_absence_notifications_student_id" ON "absence_notifications" ("student_id");
+CREATE INDEX "idx_absence_notifications_created_at" ON "absence_notifications" ("created_at");
+
+CREATE TABLE "alert_rules" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "class_id" bigint,
+    "kind" varchar(40) NOT NULL,
+    "threshold" decimal NOT NULL,
+    "window_days" bigint NOT NULL DEFAULT 0,
+    "notify" boolean NOT NULL DEFAULT false,
+    "active" boolean NOT NULL DEFAULT true,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_alert_rules_class" FOREIGN KEY ("class_id") REFERENCES "classes"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_alert_rules_class_id" ON "alert_rules" ("class_id");
+CREATE INDEX "idx_alert_rules_deleted_at" ON "alert_rules" ("deleted_at");
+
+CREATE TABLE "alerts" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "rule_id" bigint NOT NULL,
+    "class_id" bigint NOT NULL,
+    "student_id" bigint NOT NULL,
+    "value" decimal,
+    "acknowledged_at" timestamptz,
+    "acknowledged_by" bigint,
+    "notified_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_alerts_class" FOREIGN KEY ("class_id") REFERENCES "classes"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_alerts_student" FOREIGN KEY ("student_id") REFERENCES "students"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_alerts_rule" FOREIGN KEY ("rule_id") REFERENCES "alert_rules"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_alerts_rule_id" ON "alerts" ("rule_id");
+CREATE INDEX "idx_alerts_notified_at" ON "alerts" ("notified_at");
+CREATE INDEX "idx_alerts_student_id" ON "alerts" ("student_id");
+CREATE INDEX "idx_alerts_class_id" ON "alerts" ("class_id");
+
+CREATE TABLE "webhooks" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "url" varchar(2048) NOT NULL,
+    "events" text NOT NULL,
+    "secret" varchar(255) NOT NULL,
+    "active" boolean NOT NULL DEFAULT true,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_webhooks_deleted_at" ON "webhooks" ("deleted_at");
+
+CREATE TABLE "webhook_deliveries" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "webhook_id" bigint NOT NULL,
+    "event" varchar(40) NOT NULL,
+    "payload" text NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz NOT NULL,
+    "response_code" bigint,
+    "last_error" text,
+    "delivered_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_webhook_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX "idx_webhook_deliveries_status" ON "webhook_deliveries" ("status");
+CREATE INDEX "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
+
+CREATE TABLE "rate_limit_buckets" (
+    "key" varchar(255),
+    "tokens" decimal NOT NULL,
+    "refilled_at" timestamptz NOT NULL,
+    PRIMARY KEY ("key")
+);
+CREATE INDEX "idx_rate_limit_buckets_refilled_at" ON "rate_limit_buckets" ("refilled_at");
+
+CREATE TABLE "api_keys" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "name" varchar(80) NOT NULL,
+    "user_id" bigint NOT NULL,
+    "prefix" varchar(16) NOT NULL,
+    "hash" varchar(64) NOT NULL,
+    "permissions" text NOT NULL,
+    "class_id" bigint,
+    "tenant" varchar(80),
+    "expires_at" timestamptz,
+    "last_used_at" timestamptz,
+    "last_used_ip" varchar(45),
+    "created_by" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_api_keys_user" FOREIGN KEY ("user_id") REFERENCES "users_t"("id") ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT "fk_api_keys_class" FOREIGN KEY ("class_id") REFERENCES "classes"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE UNIQUE INDEX "idx_api_keys_prefix" ON "api_keys" ("prefix");
+CREATE INDEX "idx_api_keys_user_id" ON "api_keys" ("user_id");
+CREATE INDEX "idx_api_keys_class_id" ON "api_keys" ("class_id");
+
+CREATE TABLE "signing_keys" (
+    "id" varchar(36),
+    "created_at" timestamptz,
+    "algorithm" varchar(16) NOT NULL,
+    "private_key" text NOT NULL,
+    "active_at" timestamptz NOT NULL,
+    "retires_at" timestamptz NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_signing_keys_expires_at" ON "signing_keys" ("expires_at");
+CREATE INDEX "idx_signing_keys_active_at" ON "signing_keys" ("active_at");
+
+CREATE TABLE "staff_invitations" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "email" text NOT NULL,
+    "username" text NOT NULL,
+    "first_name" text NOT NULL,
+    "last_name" text NOT NULL,
+    "role" varchar(40) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "created_by" bigint NOT NULL,
+    "accepted_at" timestamptz,
+    "revoked_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_staff_invitations_email" ON "staff_invitations" ("email");
+CREATE UNIQUE INDEX "unique_idx_staff_invitation_token" ON "staff_invitations" ("token_hash");
+
+CREATE TABLE "login_attempts" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "account_id" bigint NOT NULL,
+    "method" varchar(20) NOT NULL,
+    "ip" varchar(45),
+    "user_agent" varchar(255),
+    "success" boolean NOT NULL,
+    "new_client" boolean NOT NULL DEFAULT false,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_login_attempts_account" FOREIGN KEY ("account_id") REFERENCES "accounts"("id") ON DELETE CASCADE ON UPDATE CASCADE
+);
+CREATE INDEX "idx_login_attempts_created_at" ON "login_attempts" ("created_at");
+CREATE INDEX "idx_login_attempts_account" ON "login_attempts" ("account_id","created_at");
@@ -0,0 +1,2 @@
+ALTER TABLE "users_t" DROP COLUMN IF EXISTS "erased_at";
+ALTER TABLE "students" DROP COLUMN IF EXISTS "erased_at";
@@ -0,0 +1,4 @@
+-- Date the personal data of a student or a user was erased, the rows are kept for the attendance statistics
+
+ALTER TABLE "students" ADD COLUMN IF NOT EXISTS "erased_at" timestamptz;
+ALTER TABLE "users_t" ADD COLUMN IF NOT EXISTS "erased_at" timestamptz;
@@ -5,7 +5,7 @@ import (
 	"gin-template/config"
 	"gin-template/docs"
 	"gin-template/logging"
-	"gin-template/pkg/protocol/http"
+	"gin-template/pkg/protocol/cli"
 	"os"
 )
 
@@ -31,8 +31,17 @@ func setDoc(c config.Config) {
 // @securityDefinitions.apikey  Bearer
 // @in                          header
 // @name                        Authorization
+
+// @securityDefinitions.apikey  ApiKey
+// @in                          header
+// @name                        X-API-Key
 func main() {
 	conf := config.NewConfig()
 	setDoc(conf)
-	logging.Error.Fatal(http.RunServer(conf))
+
+	// Run the subcommand, the server is started without one
+	env := cli.Env{Conf: conf, Stdin: os.Stdin, Stdout: os.Stdout}
+	if err := cli.Run(env, os.Args[1:]); err != nil {
+		logging.Error.Fatal(err)
+	}
 }
@@ -0,0 +1,282 @@
+package alert
+
+import (
+	"fmt"
+	"gin-template/pkg/common/webhook"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"time"
+)
+
+// toAlertRule converts an alert rule model to a dto.AlertRule
+func toAlertRule(r model.AlertRule) dto.AlertRule {
+	return dto.AlertRule{
+		ID:         r.ID,
+		ClassID:    r.ClassID,
+		Kind:       r.Kind,
+		Threshold:  r.Threshold,
+		WindowDays: r.WindowDays,
+		Notify:     r.Notify,
+		Active:     r.Active,
+	}
+}
+
+// toStudent converts a student model to a dto.Student
+func toStudent(s *model.Student) dto.Student {
+	if s == nil {
+		return dto.Student{}
+	}
+	return dto.Student{
+		ID:        s.ID,
+		Email:     s.Email,
+		FirstName: s.FirstName,
+		LastName:  s.LastName,
+	}
+}
+
+// GetAlertRules gets the alert rules
+func GetAlertRules(tx *gorm.DB, req dto.AlertRuleQueryParams) (*dto.AlertRuleList, error) {
+	ruleModel := model.NewAlertRuleModel(tx)
+	rules, err := ruleModel.FindAll(req.ClassID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	ruleDtos := make([]dto.AlertRule, 0)
+	for _, r := range rules {
+		ruleDtos = append(ruleDtos, toAlertRule(r))
+	}
+
+	return &dto.AlertRuleList{Rules: ruleDtos}, nil
+}
+
+// validateThreshold checks that an attendance rate threshold is a percentage
+func validateThreshold(kind enum.AlertRuleKind, threshold float64) error {
+	if kind == enum.ATTENDANCE_RATE && threshold > 100 {
+		return error2.BadRequestError("the attendance rate threshold is a percentage and cannot exceed 100", nil)
+	}
+	return nil
+}
+
+// CreateAlertRule creates a new alert rule for a class or the whole institution
+func CreateAlertRule(tx *gorm.DB, req dto.CreateAlertRule) (*dto.AlertRule, error) {
+	if err := validateThreshold(req.Kind, req.Threshold); err != nil {
+		return nil, err
+	}
+
+	if req.ClassID != nil {
+		if _, err := model.NewClassModel(tx).GetWithTerm(*req.ClassID); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	}
+
+	ruleModel := model.NewAlertRuleModel(tx)
+	r := model.AlertRule{
+		ClassID:    req.ClassID,
+		Kind:       req.Kind,
+		Threshold:  req.Threshold,
+		WindowDays: req.WindowDays,
+		Notify:     req.Notify,
+		Active:     true,
+	}
+	if err := ruleModel.Create(&r); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	rule := toAlertRule(r)
+	return &rule, nil
+}
+
+// UpdateAlertRule updates an alert rule
+func UpdateAlertRule(tx *gorm.DB, req dto.UpdateAlertRule) error {
+	ruleModel := model.NewAlertRuleModel(tx)
+	r, err := ruleModel.GetByID(req.Id)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if req.Threshold != 0 {
+		r.Threshold = req.Threshold
+	}
+	if req.WindowDays != nil {
+		r.WindowDays = *req.WindowDays
+	}
+	if req.Notify != nil {
+		r.Notify = *req.Notify
+	}
+	if req.Active != nil {
+		r.Active = *req.Active
+	}
+
+	if err := validateThreshold(r.Kind, r.Threshold); err != nil {
+		return err
+	}
+
+	if err := ruleModel.Update(r).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// DeleteAlertRule deletes an alert rule
+func DeleteAlertRule(tx *gorm.DB, ruleID uint64) error {
+	ruleModel := model.NewAlertRuleModel(tx)
+	res := ruleModel.Delete(ruleID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("alert rule '%d' not found", ruleID))
+	}
+
+	return nil
+}
+
+// GetClassAlerts gets the alerts raised for the students of a class
+func GetClassAlerts(tx *gorm.DB, classID uint64, req dto.AlertQueryParams) (*dto.AlertList, error) {
+	if _, err := model.NewClassModel(tx).GetWithTerm(classID); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	alertModel := model.NewAlertModel(tx)
+	alerts, err := alertModel.FindByClass(classID, req.Open)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	alertDtos := make([]dto.Alert, 0)
+	for _, a := range alerts {
+		alert := dto.Alert{
+			ID:             a.ID,
+			Student:        toStudent(a.Student),
+			Value:          a.Value,
+			CreatedAt:      a.CreatedAt,
+			AcknowledgedAt: a.AcknowledgedAt,
+			AcknowledgedBy: a.AcknowledgedBy,
+		}
+		if a.Rule != nil {
+			alert.Rule = toAlertRule(*a.Rule)
+		}
+		alertDtos = append(alertDtos, alert)
+	}
+
+	return &dto.AlertList{Alerts: alertDtos}, nil
+}
+
+// AcknowledgeAlert marks an open alert of a class as handled by a user
+func AcknowledgeAlert(tx *gorm.DB, classID, alertID, userID uint64) error {
+	alertModel := model.NewAlertModel(tx)
+	res := alertModel.Acknowledge(classID, alertID, userID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("open alert '%d' not found for class '%d'", alertID, classID))
+	}
+
+	return nil
+}
+
+// windowStart returns the date the attendance is evaluated from for a rule
+func windowStart(rule model.AlertRule, class *model.Class, now time.Time) time.Time {
+	if rule.WindowDays > 0 {
+		return now.AddDate(0, 0, -rule.WindowDays)
+	}
+	if class.Term != nil {
+		return class.Term.StartDate
+	}
+	return time.Time{}
+}
+
+// check returns the value of the rule metric for a student and true if it triggers the rule
+func check(rule model.AlertRule, stats model.AttendanceStats) (float64, bool) {
+	switch rule.Kind {
+	case enum.ABSENCE_COUNT:
+		value := float64(stats.UnjustifiedAbsences)
+		return value, value > rule.Threshold
+	case enum.ATTENDANCE_RATE:
+		value := stats.Rate()
+		return value, stats.Total > 0 && value < rule.Threshold
+	default:
+		return 0, false
+	}
+}
+
+// Evaluate evaluates the active rules applying to a class and raises an alert for each student at risk.
+// A rule raises a single alert per student until it is acknowledged.
+func Evaluate(tx *gorm.DB, classID uint64) error {
+	ruleModel := model.NewAlertRuleModel(tx)
+	rules, err := ruleModel.FindActiveForClass(classID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	cl, err := model.NewClassModel(tx).GetWithTerm(classID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	attendanceModel := model.NewAttendanceModel(tx)
+	alertModel := model.NewAlertModel(tx)
+	studentModel := model.NewStudentModel(tx)
+	now := time.Now()
+	statsBySince := make(map[time.Time][]model.AttendanceStats)
+
+	for _, rule := range rules {
+		since := windowStart(rule, cl, now)
+		stats, ok := statsBySince[since]
+		if !ok {
+			if stats, err = attendanceModel.StatsByStudent(classID, since); err != nil {
+				return error2.FromDatabaseError(err)
+			}
+			statsBySince[since] = stats
+		}
+
+		for _, s := range stats {
+			value, triggered := check(rule, s)
+			if !triggered {
+				continue
+			}
+
+			open, err := alertModel.HasOpen(rule.ID, s.StudentID)
+			if err != nil {
+				return error2.FromDatabaseError(err)
+			}
+			if open {
+				continue
+			}
+
+			a := model.Alert{RuleID: rule.ID, ClassID: classID, StudentID: s.StudentID, Value: value}
+			if err := alertModel.Create(&a); err != nil {
+				return error2.FromDatabaseError(err)
+			}
+
+			student := model.Student{ID: s.StudentID}
+			if err := studentModel.GetByClassID(classID, &student).Error; err != nil {
+				return error2.FromDatabaseError(err)
+			}
+
+			if err := webhook.Dispatch(tx, enum.STUDENT_AT_RISK, dto.StudentAtRiskEvent{
+				AlertID: a.ID,
+				ClassID: classID,
+				Rule:    toAlertRule(rule),
+				Student: toStudent(&student),
+				Value:   value,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
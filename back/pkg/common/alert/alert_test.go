@@ -0,0 +1,135 @@
+package alert
+
+import (
+	"fmt"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+// TestCheck tests the thresholds of the rules
+func TestCheck(t *testing.T) {
+	absences := model.AlertRule{Kind: enum.ABSENCE_COUNT, Threshold: 3}
+	rate := model.AlertRule{Kind: enum.ATTENDANCE_RATE, Threshold: 80}
+
+	tests := []struct {
+		name      string
+		rule      model.AlertRule
+		stats     model.AttendanceStats
+		triggered bool
+	}{
+		{"absences at the threshold", absences, model.AttendanceStats{Total: 10, Present: 7, UnjustifiedAbsences: 3}, false},
+		{"absences over the threshold", absences, model.AttendanceStats{Total: 10, Present: 6, UnjustifiedAbsences: 4}, true},
+		{"justified absences", absences, model.AttendanceStats{Total: 10, Present: 2, UnjustifiedAbsences: 0}, false},
+		{"rate at the threshold", rate, model.AttendanceStats{Total: 10, Present: 8}, false},
+		{"rate under the threshold", rate, model.AttendanceStats{Total: 10, Present: 7}, true},
+		{"no attendance", rate, model.AttendanceStats{}, false},
+	}
+
+	for _, test := range tests {
+		if _, triggered := check(test.rule, test.stats); triggered != test.triggered {
+			t.Errorf("%s: triggered should be %t", test.name, test.triggered)
+		}
+	}
+}
+
+// setupTestDatabase sets up the tables of the alerts and the attendance in the test database
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Migrator().DropTable(&model.WebhookDelivery{}, &model.Webhook{}, &model.Alert{}, &model.AlertRule{},
+		&model.Attendance{}, "session_students", &model.Session{}, "guardian_students", &model.Student{},
+		"class_teachers", &model.Class{}, &model.Term{}, &model.User{}, &model.Account{})
+	if err := db.AutoMigrate(&model.Account{}, &model.User{}, &model.Term{}, &model.Class{}, &model.Student{},
+		&model.Session{}, &model.Attendance{}, &model.AlertRule{}, &model.Alert{}, &model.Webhook{},
+		&model.WebhookDelivery{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// countAlerts returns the number of alerts and of open alerts of a student
+func countAlerts(db *gorm.DB, studentID uint64) (total, open int64) {
+	db.Model(&model.Alert{}).Where("student_id = ?", studentID).Count(&total)
+	db.Model(&model.Alert{}).Where("student_id = ? AND acknowledged_at IS NULL", studentID).Count(&open)
+	return total, open
+}
+
+// TestEvaluate tests that a student over the threshold raises a single open alert per rule,
+// and raises a new one once the alert is acknowledged
+func TestEvaluate(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	class := model.Class{Name: "alert", Year: "2023"}
+	db.Create(&class)
+	absent := model.Student{Email: "student.absent@gmail.com", FirstName: "John", LastName: "Doe", ClassID: class.ID}
+	present := model.Student{Email: "student.present@gmail.com", FirstName: "Jane", LastName: "Doe", ClassID: class.ID}
+	db.Create(&absent)
+	db.Create(&present)
+	for i := 0; i < 3; i++ {
+		session := model.Session{Password: "password", ClassID: class.ID}
+		db.Create(&session)
+		db.Create(&model.Attendance{SessionID: session.ID, StudentID: absent.ID, Status: enum.ABSENT})
+		db.Create(&model.Attendance{SessionID: session.ID, StudentID: present.ID, Status: enum.PRESENT})
+	}
+	rule := model.AlertRule{ClassID: &class.ID, Kind: enum.ABSENCE_COUNT, Threshold: 2, Active: true}
+	db.Create(&rule)
+
+	if err := Evaluate(db, class.ID); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := countAlerts(db, absent.ID); total != 1 {
+		t.Errorf("Student over the threshold should raise an alert, got %d", total)
+	}
+	if total, _ := countAlerts(db, present.ID); total != 0 {
+		t.Errorf("Student under the threshold should not raise an alert, got %d", total)
+	}
+
+	// The open alert is not raised again
+	if err := Evaluate(db, class.ID); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := countAlerts(db, absent.ID); total != 1 {
+		t.Errorf("Open alert should not be raised again, got %d alerts", total)
+	}
+
+	var a model.Alert
+	db.Where("student_id = ?", absent.ID).First(&a)
+	if err := AcknowledgeAlert(db, class.ID+1, a.ID, 1); err == nil {
+		t.Error("Alert should not be acknowledged from another class")
+	}
+	if err := AcknowledgeAlert(db, class.ID, a.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := AcknowledgeAlert(db, class.ID, a.ID, 1); err == nil {
+		t.Error("Alert should not be acknowledged twice")
+	}
+
+	// The student still at risk raises a new alert once the previous one is closed
+	if err := Evaluate(db, class.ID); err != nil {
+		t.Fatal(err)
+	}
+	if total, open := countAlerts(db, absent.ID); total != 2 || open != 1 {
+		t.Errorf("Closed alert should be followed by a new one, got %d alerts, %d open", total, open)
+	}
+
+	// An inactive rule raises nothing
+	db.Model(&rule).Update("active", false)
+	db.Model(&model.Alert{}).Where("acknowledged_at IS NULL").Update("acknowledged_at", time.Now())
+	if err := Evaluate(db, class.ID); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := countAlerts(db, absent.ID); total != 2 {
+		t.Errorf("Inactive rule should not raise alerts, got %d", total)
+	}
+}
@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+)
+
+// GetAuditLogs gets the audit entries matching the filters
+func GetAuditLogs(tx *gorm.DB, req dto.AuditLogQueryParams) (*dto.AuditLogList, error) {
+	auditModel := model.NewAuditLogModel(tx)
+	var entries []model.AuditLog
+	if err := auditModel.FindAll(&entries, req).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	entryDtos := make([]dto.AuditLog, 0)
+	for _, e := range entries {
+		entryDtos = append(entryDtos, dto.AuditLog{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			ActorID:   e.ActorID,
+			Tenant:    e.Tenant,
+			Action:    e.Action,
+			Entity:    e.Entity,
+			EntityID:  e.EntityID,
+			Before:    e.Before,
+			After:     e.After,
+			RequestID: e.RequestID,
+		})
+	}
+
+	return &dto.AuditLogList{Entries: entryDtos}, nil
+}
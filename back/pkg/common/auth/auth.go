@@ -1,60 +1,109 @@
 package auth
 
 import (
+	"errors"
 	"gin-template/config"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/model"
 	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
 	"gin-template/utils/jwt"
+	"gin-template/utils/mail"
+	uuid "github.com/satori/go.uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"time"
 )
 
-// Login login a user from dto.Login struct and return a token
-func Login(db *gorm.DB, req dto.Login, jwtConfig config.JwtConfig) (*dto.AuthResponse, error) {
-	userModel := model.AccountModel{Tx: db}
-	// find a by email or username
-	a := model.Account{Username: req.Username, Email: req.Email}
-	if err := userModel.FindByEmailOrUsername(&a).Error; err != nil {
+// Login login a user from dto.Login struct with the authenticator and return a token,
+// or a token waiting for the second factor when it is enabled.
+// A wrong password is recorded against the account, which is locked after too many failures.
+func Login(db *gorm.DB, req dto.Login, client dto.Client, authenticator Authenticator, conf config.Config, keys *jwt.KeySet) (*dto.LoginResponse, error) {
+	// a locked account is refused before its password is checked
+	accountModel := model.AccountModel{Tx: db}
+	known := model.Account{Username: req.Username, Email: req.Email}
+	err := accountModel.FindByEmailOrUsername(&known).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, error2.FromDatabaseError(err)
 	}
+	if err == nil {
+		if err := checkLockout(db, known.ID, conf.Login, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 
-	// check password is correct
-	err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(req.Password))
+	a, err := authenticator.Authenticate(db, req)
+	if errors.Is(err, errInvalidCredentials) && a != nil {
+		return nil, recordFailedLogin(db, a, client, loginMethodPassword, errInvalidCredentials, conf.Login)
+	}
 	if err != nil {
-		return nil, error2.BadRequestError("password is incorrect", nil)
+		return nil, err
 	}
 
-	// generate token
-	token, err := jwt.GenerateTokens(a.ID, a.User.Role, jwtConfig)
-	if err != nil {
-		return nil, error2.InternalServerError("", err)
+	// the directory may link the login to another account than the one of the username
+	if a.ID != known.ID {
+		if err := checkLockout(db, a.ID, conf.Login, time.Now()); err != nil {
+			return nil, err
+		}
 	}
 
-	// save token id to database
-	tokenModel := model.TokenModel{Tx: db}
-	if err = tokenModel.CreateToken(&model.Token{TokenID: token.TokenID}); err != nil {
+	return completeLogin(db, a, client, loginMethodPassword, conf.Jwt, keys)
+}
+
+// completeLogin issues the tokens of an authenticated account and records the login,
+// or a token waiting for the second factor when it is enabled
+func completeLogin(db *gorm.DB, a *model.Account, client dto.Client, method string, jwtConfig config.JwtConfig, keys *jwt.KeySet) (*dto.LoginResponse, error) {
+	// the login waits for the second factor when it is enabled
+	enabled, err := model.NewMFAModel(db).IsEnabled(a.ID)
+	if err != nil {
 		return nil, error2.FromDatabaseError(err)
 	}
+	if enabled {
+		token, expiresAt, err := jwt.GenerateMFAPendingToken(a.User.ID, a.User.Role, keys)
+		if err != nil {
+			return nil, error2.InternalServerError("", err)
+		}
+		return &dto.LoginResponse{MFARequired: true, MFAToken: token, MFAExpiresIn: &expiresAt}, nil
+	}
 
-	// return token
-	return &dto.AuthResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		ExpiresIn:    token.ExpiresIn,
-	}, nil
+	required, err := roleRequiresMFA(db, &a.User)
+	if err != nil {
+		return nil, err
+	}
+
+	// generate the tokens of a new token family
+	tokens, err := issueTokens(db, a.User.ID, a.User.Role, nil, client, false, jwtConfig, keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordLogin(db, a, client, method); err != nil {
+		return nil, err
+	}
+	return &dto.LoginResponse{AuthResponse: tokens, MFAEnrollmentRequired: required}, nil
 }
 
-// Logout logout a user from token id and return an error if there is one
-func Logout(db *gorm.DB, tokenId string) error {
-	// delete token from database
-	tokenModel := model.TokenModel{Tx: db}
-	return tokenModel.DeleteToken(model.Token{TokenID: tokenId})
+// Logout logout a user by revoking the token family of its login and return an error if there is one
+func Logout(db *gorm.DB, family string) error {
+	tokenModel := model.NewTokenModel(db)
+	if err := tokenModel.RevokeFamily(family); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
 }
 
-// Register register a user from dto.Register and return a token
-func Register(db *gorm.DB, req dto.Register, jwtConfig config.JwtConfig) (*dto.AuthResponse, error) {
+// Register register a user from dto.Register and return a token,
+// the account stays unverified until the link sent by email is opened
+func Register(db *gorm.DB, req dto.Register, client dto.Client, conf config.Config, keys *jwt.KeySet, sender mail.Sender) (*dto.AuthResponse, error) {
+	// Check the email is allowed to register
+	if err := ensureRegistrationAllowed(db, conf.Registration, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Check the password follows the policy
+	if err := checkPassword(db, conf.Password, "Password", req.Password, &model.Account{Username: req.Username, Email: req.Email}); err != nil {
+		return nil, err
+	}
+
 	// Generate password hash
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -76,99 +125,195 @@ func Register(db *gorm.DB, req dto.Register, jwtConfig config.JwtConfig) (*dto.A
 	if err = accountModel.Create(&a); err != nil {
 		return nil, error2.FromDatabaseError(err)
 	}
-
-	// Generate token
-	token, err := jwt.GenerateTokens(a.ID, a.User.Role, jwtConfig)
-	if err != nil {
-		return nil, error2.InternalServerError("", err)
+	if err = recordPassword(db, conf.Password, &a); err != nil {
+		return nil, err
 	}
 
-	// Save token id to database
-	tokenModel := model.TokenModel{Tx: db}
-	if err = tokenModel.CreateToken(&model.Token{TokenID: token.TokenID}); err != nil {
-		return nil, error2.FromDatabaseError(err)
+	// Send the verification link
+	if err = sendVerification(sender, conf, keys, a); err != nil {
+		return nil, err
 	}
 
-	// Return token
-	return &dto.AuthResponse{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		ExpiresIn:    token.ExpiresIn,
-	}, nil
+	// Generate the tokens of a new token family
+	return issueTokens(db, a.User.ID, a.User.Role, nil, client, false, conf.Jwt, keys)
 }
 
-// ChangePassword change a user password from request dto.ChangePassword and return a token
-func ChangePassword(db *gorm.DB, req dto.ChangePassword) error {
-	// find u by id
-	userModel := model.AccountModel{Tx: db}
-	u := model.Account{User: model.User{ID: req.UserId}}
-
-	if err := userModel.FindByEmailOrUsername(&u).Error; err != nil {
-		return error2.FromDatabaseError(err)
+// ChangePassword change a user password from request dto.ChangePassword,
+// the new password must follow the password policy
+func ChangePassword(db *gorm.DB, conf config.PasswordConfig, req dto.ChangePassword) error {
+	// find the account of the user
+	u, err := findUser(db, req.UserId)
+	if err != nil {
+		return err
 	}
+	a := u.Account
 
 	// check old password is correct
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.OldPassword))
+	err = bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(req.OldPassword))
 	if err != nil {
 		return error2.BadRequestError("password is incorrect", nil)
 	}
 
-	// generate new password hash
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err := checkPassword(db, conf, "NewPassword", req.NewPassword, a); err != nil {
+		return err
+	}
+
+	return setPassword(db, conf, a, req.NewPassword)
+}
+
+// ErrRefreshTokenReused is returned when a refresh token is exchanged twice,
+// the token family is then revoked and the revocation must be committed
+var ErrRefreshTokenReused = error2.UnauthorizedError("refresh token was already used, the session is revoked")
+
+// issueTokens generates the tokens of a new token family, or rotated from the previous refresh token,
+// and saves the refresh token, mfa records whether the login was confirmed with a second factor
+func issueTokens(db *gorm.DB, userId uint64, role enum.Role, previous *model.Token, client dto.Client, mfa bool, jwtConfig config.JwtConfig, keys *jwt.KeySet) (*dto.AuthResponse, error) {
+	now := time.Now()
+	t := model.Token{UserID: userId, UserAgent: client.UserAgent, IP: client.IP, LoggedInAt: now, LastUsedAt: now}
+
+	family := uuid.NewV4().String()
+	if previous != nil {
+		t.LoggedInAt = previous.LoggedInAt
+		family = previous.Family
+	}
+	token, err := jwt.GenerateFamilyTokens(userId, role, family, mfa, jwtConfig, keys)
 	if err != nil {
-		return error2.InternalServerError("", nil)
+		return nil, error2.InternalServerError("", err)
 	}
 
-	// update password
-	u.Password = string(hash)
-	if err = userModel.Update(&u); err != nil {
-		return error2.FromDatabaseError(err)
+	tokenModel := model.NewTokenModel(db)
+	t.TokenID = token.TokenID
+	t.Family = token.Family
+	t.ExpiresAt = token.RefreshExpiresIn
+	if err = tokenModel.CreateToken(&t); err != nil {
+		return nil, error2.FromDatabaseError(err)
 	}
 
-	return nil
+	return &dto.AuthResponse{
+		AccessToken:      token.AccessToken,
+		RefreshToken:     token.RefreshToken,
+		ExpiresIn:        token.ExpiresIn,
+		RefreshExpiresIn: token.RefreshExpiresIn,
+	}, nil
 }
 
-// RefreshToken refresh a user token from refresh token and return a token
-func RefreshToken(db *gorm.DB, jwtConfig config.JwtConfig, token string) (*dto.AuthResponse, error) {
+// RefreshToken exchanges a refresh token for a new pair of tokens of the same family with the current role of the user,
+// replaying an exchanged refresh token revokes the whole family
+func RefreshToken(db *gorm.DB, jwtConfig config.JwtConfig, keys *jwt.KeySet, token string, client dto.Client) (*dto.AuthResponse, error) {
 	// parse token
-	claims, err := jwt.ParseToken(token, jwtConfig.Secret)
+	claims, err := jwt.ParseTypedToken(token, keys, jwt.RefreshToken)
 	if err != nil {
-		return nil, error2.InternalServerError("", err)
+		return nil, error2.UnauthorizedError(err.Error())
 	}
 
-	// generate new token
-	newToken, err := jwt.GenerateTokens(claims.UserId, claims.Role, jwtConfig)
+	tokenModel := model.NewTokenModel(db)
+	t, err := tokenModel.FindByTokenID(claims.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.UnauthorizedError("refresh token is revoked")
+	}
 	if err != nil {
-		return nil, error2.InternalServerError("", err)
+		return nil, error2.FromDatabaseError(err)
+	}
+	if t.DeletedAt.Valid {
+		return nil, error2.UnauthorizedError("refresh token is revoked")
 	}
 
-	// save token id to database
-	tokenModel := model.TokenModel{Tx: db}
-	if err = tokenModel.CreateToken(&model.Token{TokenID: newToken.TokenID}); err != nil {
+	// The new tokens carry the current role of the user, not the one of the refresh token
+	u := model.User{}
+	if err := model.NewUserModel(db).FindByUserID(claims.UserId, &u).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.UnauthorizedError("user no longer exists")
+	} else if err != nil {
 		return nil, error2.FromDatabaseError(err)
 	}
 
-	return &dto.AuthResponse{
-		AccessToken:  newToken.AccessToken,
-		RefreshToken: newToken.RefreshToken,
-		ExpiresIn:    newToken.ExpiresIn,
-	}, nil
+	rotated, err := tokenModel.Rotate(t, time.Now())
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if !rotated {
+		// The token was stolen or replayed, none of the tokens of the family can be trusted
+		if err := tokenModel.RevokeFamily(t.Family); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return issueTokens(db, claims.UserId, u.Role, t, client, claims.MFA, jwtConfig, keys)
 }
 
-// GetAccount get a user from user id and return a dto.Account
-func GetAccount(db *gorm.DB, userId uint64) (*dto.User, error) {
+// GetAccount get a user from user id and return a dto.Account with its latest logins
+func GetAccount(db *gorm.DB, userId uint64, historySize int) (*dto.User, error) {
 	accountModel := model.AccountModel{Tx: db}
 	a := model.Account{User: model.User{ID: userId}}
 	if err := accountModel.Find(&a).Error; err != nil {
 		return nil, error2.FromDatabaseError(err)
 	}
 
+	mfaEnabled, err := model.NewMFAModel(db).IsEnabled(a.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	history, err := getLoginHistory(db, a.ID, historySize)
+	if err != nil {
+		return nil, err
+	}
+
 	return &dto.User{
-		ID:        a.User.ID,
-		FirstName: a.User.FirstName,
-		LastName:  a.User.LastName,
-		Email:     a.Email,
-		Username:  a.Username,
-		Role:      a.User.Role,
+		ID:           a.User.ID,
+		FirstName:    a.User.FirstName,
+		LastName:     a.User.LastName,
+		Email:        a.Email,
+		Username:     a.Username,
+		Role:         a.User.Role,
+		Verified:     a.VerifiedAt != nil,
+		MFAEnabled:   mfaEnabled,
+		LoginHistory: history,
 	}, nil
 }
+
+// GetLoginSessions gets the active sessions of a user, the current one is flagged
+func GetLoginSessions(db *gorm.DB, userId uint64, currentFamily string) (*dto.LoginSessionList, error) {
+	tokenModel := model.NewTokenModel(db)
+	tokens, err := tokenModel.FindActiveByUser(userId, time.Now())
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	sessions := make([]dto.LoginSession, 0)
+	for _, t := range tokens {
+		sessions = append(sessions, dto.LoginSession{
+			ID:         t.Family,
+			UserAgent:  t.UserAgent,
+			IP:         t.IP,
+			LoggedInAt: t.LoggedInAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			Current:    t.Family == currentFamily,
+		})
+	}
+
+	return &dto.LoginSessionList{Sessions: sessions}, nil
+}
+
+// RevokeLoginSession revokes an active session of a user
+func RevokeLoginSession(db *gorm.DB, userId uint64, family string) error {
+	tokenModel := model.NewTokenModel(db)
+	if _, err := tokenModel.GetActiveFamily(userId, family, time.Now()); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := tokenModel.RevokeFamily(family); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
+
+// RevokeLoginSessions revokes all the sessions of a user but the kept one, none is kept when empty
+func RevokeLoginSessions(db *gorm.DB, userId uint64, keptFamily string) error {
+	tokenModel := model.NewTokenModel(db)
+	if err := tokenModel.RevokeUserFamilies(userId, keptFamily).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"gin-template/config"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// errInvalidCredentials is returned by an authenticator when the password of a login is wrong,
+// along with the account of the login when it is known so that the failure is recorded
+var errInvalidCredentials = error2.BadRequestError("password is incorrect", nil)
+
+// errNotAuthenticated is returned by an authenticator which does not handle a login, the next one is tried
+var errNotAuthenticated = errors.New("login is not handled by the authenticator")
+
+// Authenticator checks the credentials of a login and returns the authenticated account
+type Authenticator interface {
+	// Authenticate returns errNotAuthenticated when the login is not handled by the authenticator,
+	// and errInvalidCredentials with the account of the login, when known, if the password is wrong
+	Authenticate(db *gorm.DB, req dto.Login) (*model.Account, error)
+}
+
+// Chain is an authenticator trying its authenticators in turn until one handles the login
+type Chain []Authenticator
+
+// NewChain creates the chain of the configured authenticators, falling back to the local accounts
+func NewChain(conf config.Config) Chain {
+	chain := Chain{}
+	if conf.LDAP.URL != "" {
+		chain = append(chain, NewLDAPAuthenticator(conf.LDAP))
+	}
+	return append(chain, LocalAuthenticator{})
+}
+
+// Authenticate authenticates a login with the first authenticator handling it
+func (c Chain) Authenticate(db *gorm.DB, req dto.Login) (*model.Account, error) {
+	for _, authenticator := range c {
+		a, err := authenticator.Authenticate(db, req)
+		if errors.Is(err, errNotAuthenticated) {
+			continue
+		}
+		return a, err
+	}
+	return nil, errInvalidCredentials
+}
+
+// LocalAuthenticator authenticates the logins with the bcrypt passwords of the accounts
+type LocalAuthenticator struct{}
+
+// Authenticate checks the password of the account having the email or the username of a login
+func (LocalAuthenticator) Authenticate(db *gorm.DB, req dto.Login) (*model.Account, error) {
+	accountModel := model.AccountModel{Tx: db}
+	// find a by email or username
+	a := model.Account{Username: req.Username, Email: req.Email}
+	if err := accountModel.FindByEmailOrUsername(&a).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	// service accounts have no password, they authenticate with their API keys
+	if a.Service {
+		return nil, errInvalidCredentials
+	}
+
+	// check password is correct
+	if err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(req.Password)); err != nil {
+		return &a, errInvalidCredentials
+	}
+
+	return &a, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/model"
+	"gin-template/utils/jwt"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// keyPublication is how long a new key is published before it signs the tokens,
+// so that every instance and the verifiers of the key set know it by then
+const keyPublication = 10 * time.Minute
+
+// KeyRefreshInterval is the interval between two rotations of the keys, shorter than keyPublication
+const KeyRefreshInterval = time.Minute
+
+// LoadKeys returns the key set of the configured algorithm, the keys of RS256 and EdDSA
+// are stored in the database and must then be rotated every KeyRefreshInterval by RotateKeys
+func LoadKeys(db *gorm.DB, conf config.JwtConfig) (*jwt.KeySet, error) {
+	if conf.Algorithm == "" || conf.Algorithm == config.HS256 {
+		return jwt.NewSecretKeySet(conf.Secret), nil
+	}
+
+	keys := jwt.NewKeySet()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return RotateKeys(tx, conf, keys, time.Now())
+	})
+	return keys, err
+}
+
+// RotateKeys generates the next key when the current one retires soon, deletes the keys
+// which no longer verify any token and loads the others in the key set.
+// A key verifies the tokens until the longest-lived ones it signed, the refresh tokens, have expired.
+func RotateKeys(tx *gorm.DB, conf config.JwtConfig, keys *jwt.KeySet, now time.Time) error {
+	keyModel := model.NewSigningKeyModel(tx)
+	if err := keyModel.DeleteExpired(now); err != nil {
+		return err
+	}
+	stored, err := keyModel.FindValid(conf.Algorithm, now)
+	if err != nil {
+		return err
+	}
+
+	// publish the next key before the latest one retires, it signs the tokens as soon as the latest retires
+	if len(stored) == 0 || stored[0].RetiresAt.Sub(now) <= keyPublication {
+		activeAt := now
+		if len(stored) > 0 && stored[0].RetiresAt.After(now) {
+			activeAt = stored[0].RetiresAt
+		}
+		next, err := newSigningKey(conf, activeAt)
+		if err != nil {
+			return err
+		}
+		if err := keyModel.Create(next); err != nil {
+			return err
+		}
+		stored = append([]model.SigningKey{*next}, stored...)
+	}
+
+	var current *jwt.Key
+	others := make([]*jwt.Key, 0, len(stored))
+	for _, k := range stored {
+		key, err := jwt.UnmarshalKey(k.ID, k.Algorithm, k.PrivateKey)
+		if err != nil {
+			return err
+		}
+		if current == nil && !k.ActiveAt.After(now) {
+			current = key
+		} else {
+			others = append(others, key)
+		}
+	}
+	keys.Set(current, others)
+	return nil
+}
+
+// ForceKeyRotation retires the keys signing the tokens once a new key is published, the returned date.
+// The tokens they signed are still verified until they expire.
+func ForceKeyRotation(tx *gorm.DB, conf config.JwtConfig, now time.Time) (time.Time, error) {
+	if conf.Algorithm != config.RS256 && conf.Algorithm != config.EdDSA {
+		return time.Time{}, fmt.Errorf("keys are only rotated with the %s and %s algorithms", config.RS256, config.EdDSA)
+	}
+
+	activeAt := now.Add(keyPublication)
+	if err := model.NewSigningKeyModel(tx).Retire(conf.Algorithm, activeAt); err != nil {
+		return time.Time{}, err
+	}
+	return activeAt, RotateKeys(tx, conf, jwt.NewKeySet(), now)
+}
+
+// newSigningKey generates a key signing the tokens from a date for the rotation interval
+func newSigningKey(conf config.JwtConfig, activeAt time.Time) (*model.SigningKey, error) {
+	id := uuid.NewV4().String()
+	key, err := jwt.GenerateKey(id, conf.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := jwt.MarshalKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	retiresAt := activeAt.Add(time.Duration(conf.RotationInterval) * time.Hour)
+	return &model.SigningKey{
+		ID:         id,
+		Algorithm:  conf.Algorithm,
+		PrivateKey: encoded,
+		ActiveAt:   activeAt,
+		RetiresAt:  retiresAt,
+		ExpiresAt:  retiresAt.Add(time.Duration(conf.Expiration) * time.Hour),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"gin-template/config"
+	"gin-template/logging"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"github.com/go-ldap/ldap/v3"
+	"gorm.io/gorm"
+	"net"
+	"strings"
+	"time"
+)
+
+// LDAPAuthenticator authenticates the logins by binding to a directory with the DN of the user,
+// the logins of the users unknown to the directory, or while it is unreachable, are not handled
+type LDAPAuthenticator struct {
+	conf config.LDAPConfig
+}
+
+// NewLDAPAuthenticator creates a directory authenticator
+func NewLDAPAuthenticator(conf config.LDAPConfig) LDAPAuthenticator {
+	return LDAPAuthenticator{conf: conf}
+}
+
+// Authenticate searches the user of a login in the directory, binds with its password
+// and returns the account linked to the entry
+func (l LDAPAuthenticator) Authenticate(db *gorm.DB, req dto.Login) (*model.Account, error) {
+	login := req.Username
+	if login == "" {
+		login = req.Email
+	}
+
+	timeout := time.Duration(l.conf.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if !strings.HasPrefix(l.conf.URL, "ldap://") && !strings.HasPrefix(l.conf.URL, "ldaps://") {
+		logging.Error.Printf("ldap: unsupported URL '%s'", l.conf.URL)
+		return nil, errNotAuthenticated
+	}
+	conn, err := ldap.DialURL(l.conf.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		logging.Error.Printf("ldap: %v", err)
+		return nil, errNotAuthenticated
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if l.conf.BindDN != "" {
+		if err := conn.Bind(l.conf.BindDN, l.conf.BindPassword); err != nil {
+			logging.Error.Printf("ldap: bind of the search account: %v", err)
+			return nil, errNotAuthenticated
+		}
+	}
+
+	filter := strings.ReplaceAll(l.conf.Filter, "{username}", ldap.EscapeFilter(login))
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.conf.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(timeout/time.Second), false, filter,
+		[]string{l.conf.EmailAttribute, l.conf.FirstNameAttribute, l.conf.LastNameAttribute, l.conf.GroupAttribute},
+		nil,
+	))
+	switch {
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject):
+		return nil, errNotAuthenticated
+	case ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || err == nil && len(result.Entries) > 1:
+		logging.Error.Printf("ldap: the filter matches several entries for a login")
+		return nil, errNotAuthenticated
+	case err != nil:
+		logging.Error.Printf("ldap: search: %v", err)
+		return nil, errNotAuthenticated
+	case len(result.Entries) == 0:
+		return nil, errNotAuthenticated
+	}
+	entry := result.Entries[0]
+
+	// an empty password is refused since the servers treat it as an anonymous bind
+	if req.Password == "" {
+		return l.invalidCredentials(db, entry.DN)
+	}
+	if err := conn.Bind(entry.DN, req.Password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return l.invalidCredentials(db, entry.DN)
+		}
+		logging.Error.Printf("ldap: bind: %v", err)
+		return nil, error2.ServiceUnavailableError("directory is unavailable")
+	}
+
+	identity := externalIdentity{
+		Issuer:        l.conf.URL,
+		Subject:       strings.ToLower(entry.DN),
+		Email:         entry.GetEqualFoldAttributeValue(l.conf.EmailAttribute),
+		EmailVerified: true,
+		FirstName:     entry.GetEqualFoldAttributeValue(l.conf.FirstNameAttribute),
+		LastName:      entry.GetEqualFoldAttributeValue(l.conf.LastNameAttribute),
+		Groups:        entry.GetEqualFoldAttributeValues(l.conf.GroupAttribute),
+	}
+	if req.Username != "" {
+		identity.PreferredUsername = req.Username
+	}
+
+	return accountOfIdentity(db, provisioning{
+		AutoProvision: l.conf.AutoProvision,
+		RoleMappings:  l.conf.RoleMappings,
+		SyncRole:      l.conf.SyncRole,
+		DefaultRole:   l.conf.DefaultRole,
+	}, identity)
+}
+
+// invalidCredentials returns the account already linked to an entry, the failed login is recorded against it
+func (l LDAPAuthenticator) invalidCredentials(db *gorm.DB, dn string) (*model.Account, error) {
+	identity, err := model.NewIdentityModel(db).FindBySubject(l.conf.URL, strings.ToLower(dn))
+	if err != nil {
+		return nil, errInvalidCredentials
+	}
+	return identity.Account, errInvalidCredentials
+}
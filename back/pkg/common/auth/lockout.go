@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/common/webhook"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Methods of the logins recorded in the history
+const (
+	loginMethodPassword = "password"
+	loginMethodMFA      = "mfa"
+	loginMethodOIDC     = "oidc"
+)
+
+// LoginError is returned when a login is refused because of wrong credentials or of the lockout of the account
+type LoginError struct {
+	*error2.MyError
+	// Recorded is true if the failure was recorded, it must be committed to count towards the lockout
+	Recorded bool
+	// RetryAfter is the remaining duration of the lockout of the account, zero when it is not locked
+	RetryAfter time.Duration
+}
+
+// lockoutDelay returns the duration of the lockout after the given number of failures beyond the maximum,
+// doubling from base up to max
+func lockoutDelay(failures int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// lockedUntil returns the end of the lockout of an account, the zero time when it never was locked
+func lockedUntil(db *gorm.DB, accountID uint64, conf config.LoginConfig) (time.Time, error) {
+	if conf.MaxFailures <= 0 {
+		return time.Time{}, nil
+	}
+
+	failures, last, err := model.NewLoginAttemptModel(db).FailuresSinceSuccess(accountID)
+	if err != nil {
+		return time.Time{}, error2.FromDatabaseError(err)
+	}
+	if failures < conf.MaxFailures || last == nil {
+		return time.Time{}, nil
+	}
+
+	delay := lockoutDelay(
+		failures-conf.MaxFailures+1,
+		time.Duration(conf.LockoutDelay)*time.Second,
+		time.Duration(conf.MaxLockoutDelay)*time.Second,
+	)
+	return last.Add(delay), nil
+}
+
+// checkLockout refuses the login of an account locked after too many failed logins
+func checkLockout(db *gorm.DB, accountID uint64, conf config.LoginConfig, now time.Time) error {
+	until, err := lockedUntil(db, accountID, conf)
+	if err != nil {
+		return err
+	}
+	if !until.After(now) {
+		return nil
+	}
+
+	retryAfter := until.Sub(now).Round(time.Second)
+	return &LoginError{
+		MyError: error2.TooManyRequestsError(fmt.Sprintf(
+			"account is locked after too many failed logins, retry in %s", retryAfter,
+		)),
+		RetryAfter: retryAfter,
+	}
+}
+
+// recordFailedLogin records a failed login of an account and returns the error of the login,
+// telling when the failure locks the account
+func recordFailedLogin(db *gorm.DB, a *model.Account, client dto.Client, method string, cause *error2.MyError, conf config.LoginConfig) error {
+	attempt := model.LoginAttempt{
+		AccountID: a.ID,
+		Method:    method,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if err := model.NewLoginAttemptModel(db).Create(&attempt); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	loginErr := &LoginError{MyError: cause, Recorded: true}
+	until, err := lockedUntil(db, a.ID, conf)
+	if err != nil {
+		return err
+	}
+	if until.After(attempt.CreatedAt) {
+		loginErr.RetryAfter = until.Sub(attempt.CreatedAt).Round(time.Second)
+		loginErr.MyError = error2.NewError(cause.Code, cause.ShortMessage, fmt.Sprintf(
+			"%s, the account is locked for %s", cause.Message, loginErr.RetryAfter,
+		), cause.Fields)
+	}
+	return loginErr
+}
+
+// recordLogin records a successful login of an account, a login from a client never seen for an account
+// which already logged in is flagged and dispatched to the webhooks so that the staff is notified
+func recordLogin(db *gorm.DB, a *model.Account, client dto.Client, method string) error {
+	attemptModel := model.NewLoginAttemptModel(db)
+	loggedIn, err := attemptModel.HasSucceeded(a.ID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	seen, err := attemptModel.HasSucceededFrom(a.ID, client.IP, client.UserAgent)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	attempt := model.LoginAttempt{
+		AccountID: a.ID,
+		Method:    method,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Success:   true,
+		NewClient: loggedIn && !seen,
+	}
+	if err := attemptModel.Create(&attempt); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if !attempt.NewClient {
+		return nil
+	}
+	return webhook.Dispatch(db, enum.LOGIN_NEW_CLIENT, dto.LoginNewClientEvent{
+		UserID:    a.User.ID,
+		Username:  a.Username,
+		Role:      a.User.Role,
+		Method:    method,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	})
+}
+
+// getLoginHistory gets the latest logins of an account, the newest first
+func getLoginHistory(db *gorm.DB, accountID uint64, size int) ([]dto.LoginAttempt, error) {
+	attempts, err := model.NewLoginAttemptModel(db).FindByAccount(accountID, size)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	history := make([]dto.LoginAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		history = append(history, dto.LoginAttempt{
+			CreatedAt: attempt.CreatedAt,
+			Method:    attempt.Method,
+			IP:        attempt.IP,
+			UserAgent: attempt.UserAgent,
+			Success:   attempt.Success,
+			NewClient: attempt.NewClient,
+		})
+	}
+	return history, nil
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gin-template/config"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/jwt"
+	"gin-template/utils/totp"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is the number of recovery codes generated at once
+const recoveryCodeCount = 10
+
+// errInvalidMFACode is returned when a code is neither a valid TOTP code nor an unused recovery code
+var errInvalidMFACode = error2.BadRequestError("two-factor code is invalid", nil)
+
+// normalizeRecoveryCode removes the separators and the case of a recovery code
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// newRecoveryCodes generates recovery codes formatted as xxxxx-xxxxx and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// findUser finds a user along with its account
+func findUser(db *gorm.DB, userId uint64) (*model.User, error) {
+	u := model.User{}
+	if err := model.NewUserModel(db).FindByUserID(userId, &u).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if u.Account == nil {
+		return nil, error2.NotFoundError("account not found")
+	}
+	return &u, nil
+}
+
+// getEnabledMFA gets the confirmed second factor of an account
+func getEnabledMFA(db *gorm.DB, accountID uint64) (*model.MFA, error) {
+	mfa, err := model.NewMFAModel(db).Get(accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && mfa.EnabledAt == nil) {
+		return nil, error2.BadRequestError("two-factor authentication is not enabled", nil)
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	return mfa, nil
+}
+
+// checkTOTP checks a code of the authenticator app, a code is accepted once
+func checkTOTP(db *gorm.DB, mfa *model.MFA, code string, skew int) error {
+	step, ok := totp.Validate(mfa.Secret, code, time.Now(), int64(skew))
+	if !ok {
+		return errInvalidMFACode
+	}
+
+	used, err := model.NewMFAModel(db).UseStep(mfa.AccountID, step)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// checkMFACode checks a code of the authenticator app or, when it is not one, a recovery code
+func checkMFACode(db *gorm.DB, mfa *model.MFA, code string, skew int) error {
+	if len(code) == totp.Digits {
+		return checkTOTP(db, mfa, code, skew)
+	}
+
+	used, err := model.NewMFAModel(db).UseRecoveryCode(mfa.AccountID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes generates new recovery codes for an account, the previous ones are discarded
+func replaceRecoveryCodes(db *gorm.DB, accountID uint64) (*dto.RecoveryCodes, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+
+	if err := model.NewMFAModel(db).ReplaceRecoveryCodes(accountID, hashes); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	return &dto.RecoveryCodes{Codes: codes}, nil
+}
+
+// roleRequiresMFA returns true if the role of a user requires a second factor
+func roleRequiresMFA(db *gorm.DB, u *model.User) (bool, error) {
+	role, err := model.NewRoleModel(db).GetByName(u.Role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, error2.FromDatabaseError(err)
+	}
+	return role.RequireMFA, nil
+}
+
+// EnrollMFA starts the enrollment of a second factor, it is enabled once a first code is confirmed
+func EnrollMFA(db *gorm.DB, mfaConfig config.MFAConfig, userId uint64) (*dto.MFAEnrollment, error) {
+	u, err := findUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaModel := model.NewMFAModel(db)
+	enabled, err := mfaModel.IsEnabled(u.AccountID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if enabled {
+		return nil, error2.BadRequestError("two-factor authentication is already enabled", nil)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+	if err := mfaModel.Start(&model.MFA{AccountID: u.AccountID, CreatedAt: time.Now(), Secret: secret}); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaConfig.Issuer, u.Account.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA enables the enrolled second factor with a first code and returns the recovery codes
+func ConfirmMFA(db *gorm.DB, mfaConfig config.MFAConfig, userId uint64, code string) (*dto.RecoveryCodes, error) {
+	u, err := findUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaModel := model.NewMFAModel(db)
+	mfa, err := mfaModel.Get(u.AccountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.BadRequestError("two-factor enrollment is not started", nil)
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if mfa.EnabledAt != nil {
+		return nil, error2.BadRequestError("two-factor authentication is already enabled", nil)
+	}
+
+	if err := checkTOTP(db, mfa, code, mfaConfig.Skew); err != nil {
+		return nil, err
+	}
+	if err := mfaModel.Enable(u.AccountID, time.Now()).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return replaceRecoveryCodes(db, u.AccountID)
+}
+
+// VerifyMFA confirms a login waiting for the second factor and returns the tokens,
+// a wrong code is recorded against the account like a wrong password
+func VerifyMFA(db *gorm.DB, conf config.Config, keys *jwt.KeySet, req dto.VerifyMFA, client dto.Client) (*dto.AuthResponse, error) {
+	claims, err := jwt.ParseTypedToken(req.MFAToken, keys, jwt.MFAPendingToken)
+	if err != nil {
+		return nil, error2.UnauthorizedError(err.Error())
+	}
+
+	u, err := findUser(db, claims.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLockout(db, u.AccountID, conf.Login, time.Now()); err != nil {
+		return nil, err
+	}
+	mfa, err := getEnabledMFA(db, u.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	a := *u.Account
+	a.User = *u
+	if err := checkMFACode(db, mfa, req.Code, conf.MFA.Skew); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			return nil, recordFailedLogin(db, &a, client, loginMethodMFA, errInvalidMFACode, conf.Login)
+		}
+		return nil, err
+	}
+
+	tokens, err := issueTokens(db, u.ID, u.Role, nil, client, true, conf.Jwt, keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordLogin(db, &a, client, loginMethodMFA); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after checking a code
+func RegenerateRecoveryCodes(db *gorm.DB, mfaConfig config.MFAConfig, userId uint64, code string) (*dto.RecoveryCodes, error) {
+	u, err := findUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := getEnabledMFA(db, u.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMFACode(db, mfa, code, mfaConfig.Skew); err != nil {
+		return nil, err
+	}
+
+	return replaceRecoveryCodes(db, u.AccountID)
+}
+
+// DisableMFA removes the second factor of a user after checking a code,
+// it is refused when the role of the user requires it
+func DisableMFA(db *gorm.DB, mfaConfig config.MFAConfig, userId uint64, code string) error {
+	u, err := findUser(db, userId)
+	if err != nil {
+		return err
+	}
+
+	required, err := roleRequiresMFA(db, u)
+	if err != nil {
+		return err
+	}
+	if required {
+		return error2.ForbiddenError("your role requires two-factor authentication")
+	}
+
+	mfa, err := getEnabledMFA(db, u.AccountID)
+	if err != nil {
+		return err
+	}
+	if err := checkMFACode(db, mfa, code, mfaConfig.Skew); err != nil {
+		return err
+	}
+
+	if err := model.NewMFAModel(db).Delete(u.AccountID); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
+
+// ResetMFA removes the second factor of a user who lost it and logs out all its sessions,
+// the user enrolls again at the next login
+func ResetMFA(db *gorm.DB, userId uint64) error {
+	u, err := findUser(db, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := model.NewMFAModel(db).Delete(u.AccountID); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if err := model.NewTokenModel(db).RevokeUserFamilies(u.ID, "").Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
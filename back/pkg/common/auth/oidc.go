@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"gin-template/config"
+	"gin-template/logging"
+	"gin-template/pkg/dto"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/jwt"
+	"gin-template/utils/oidc"
+	"gorm.io/gorm"
+)
+
+// errSSODisabled is returned when no OpenID Connect provider is configured
+var errSSODisabled = error2.NotFoundError("single sign-on is not configured")
+
+// OIDCAuthorize starts a single sign-on, the client redirects the user to the returned URL
+// and keeps the flow token until the provider redirects back with the code
+func OIDCAuthorize(ctx context.Context, provider *oidc.Provider, conf config.Config, keys *jwt.KeySet) (*dto.OIDCAuthorization, error) {
+	if conf.OIDC.Issuer == "" {
+		return nil, errSSODisabled
+	}
+
+	flow := jwt.OIDCFlow{}
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		random, err := oidc.RandomString()
+		if err != nil {
+			return nil, error2.InternalServerError("", err)
+		}
+		*value = random
+	}
+
+	authorizationURL, err := provider.AuthorizationURL(ctx, flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		logging.Error.Printf("oidc: %v", err)
+		return nil, error2.ServiceUnavailableError("identity provider is unavailable")
+	}
+
+	token, expiresAt, err := jwt.GenerateOIDCFlowToken(flow, keys)
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+
+	return &dto.OIDCAuthorization{AuthorizationURL: authorizationURL, FlowToken: token, ExpiresIn: expiresAt}, nil
+}
+
+// OIDCCallback completes a single sign-on with the code returned by the provider,
+// the identity is mapped to an account and the tokens are issued as for a login
+func OIDCCallback(ctx context.Context, db *gorm.DB, provider *oidc.Provider, conf config.Config, keys *jwt.KeySet, req dto.OIDCCallback, client dto.Client) (*dto.LoginResponse, error) {
+	if conf.OIDC.Issuer == "" {
+		return nil, errSSODisabled
+	}
+
+	flow, err := jwt.ParseOIDCFlowToken(req.FlowToken, keys)
+	if err != nil {
+		return nil, error2.UnauthorizedError(err.Error())
+	}
+	if subtle.ConstantTimeCompare([]byte(flow.State), []byte(req.State)) != 1 {
+		return nil, error2.UnauthorizedError("state does not match")
+	}
+
+	idToken, err := provider.Exchange(ctx, req.Code, flow.Verifier)
+	if err != nil {
+		logging.Error.Printf("oidc: %v", err)
+		return nil, error2.UnauthorizedError("authorization code was refused by the identity provider")
+	}
+	claims, err := provider.Verify(ctx, idToken, flow.Nonce)
+	if err != nil {
+		logging.Error.Printf("oidc: %v", err)
+		return nil, error2.UnauthorizedError("ID token is invalid")
+	}
+
+	identity := externalIdentity{
+		Issuer:            conf.OIDC.Issuer,
+		Subject:           claims.String("sub"),
+		Email:             claims.String("email"),
+		EmailVerified:     claims.EmailVerified(),
+		PreferredUsername: claims.String("preferred_username"),
+		FirstName:         claims.String("given_name"),
+		LastName:          claims.String("family_name"),
+	}
+	if conf.OIDC.RoleClaim != "" {
+		identity.Groups = claims.Strings(conf.OIDC.RoleClaim)
+	}
+
+	a, err := accountOfIdentity(db, provisioning{
+		AutoProvision: conf.OIDC.AutoProvision,
+		RoleMappings:  conf.OIDC.RoleMappings,
+		SyncRole:      conf.OIDC.SyncRole,
+		DefaultRole:   conf.OIDC.DefaultRole,
+	}, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeLogin(db, a, client, loginMethodOIDC, conf.Jwt, keys)
+}
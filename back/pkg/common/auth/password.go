@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"gin-template/config"
+	"gin-template/logging"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/password"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// checkPassword checks a new password of an account against the password policy, the breached passwords
+// and the previous passwords of the account, an account not created yet has an id of 0.
+// The violations are reported on the field of the request holding the password.
+func checkPassword(db *gorm.DB, conf config.PasswordConfig, field string, newPassword string, a *model.Account) error {
+	policy := password.Policy{
+		MinLength: conf.MinLength,
+		Upper:     conf.RequireUpper,
+		Lower:     conf.RequireLower,
+		Digit:     conf.RequireDigit,
+		Symbol:    conf.RequireSymbol,
+	}
+	violations := policy.Violations(newPassword, password.PersonalValues(a.Username, a.Email)...)
+
+	if conf.BreachedList != "" {
+		// the list is a safety net, a broken copy must not prevent the users from choosing a password
+		breached, err := password.BreachedList{Dir: conf.BreachedList}.Contains(newPassword)
+		if err != nil {
+			logging.Error.Printf("breached password list: %v", err)
+		}
+		if breached {
+			violations = append(violations, "appears in a list of breached passwords")
+		}
+	}
+
+	if len(violations) == 0 && a.ID != 0 && conf.History > 0 {
+		reused, err := isPreviousPassword(db, a, newPassword, conf.History)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, "must differ from your previous passwords")
+		}
+	}
+
+	if len(violations) > 0 {
+		return error2.BadRequestError("password does not follow the password policy", map[string]string{
+			field: strings.Join(violations, ", "),
+		})
+	}
+	return nil
+}
+
+// isPreviousPassword returns true if a password is the current one of an account or one of its previous ones
+func isPreviousPassword(db *gorm.DB, a *model.Account, newPassword string, history int) (bool, error) {
+	hashes := []string{a.Password}
+	previous, err := model.NewPasswordHistoryModel(db).FindLatest(a.ID, history)
+	if err != nil {
+		return false, error2.FromDatabaseError(err)
+	}
+	for _, p := range previous {
+		hashes = append(hashes, p.Hash)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(newPassword)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setPassword hashes a new password of an account, saves it and records it in the password history
+func setPassword(db *gorm.DB, conf config.PasswordConfig, a *model.Account, newPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	a.Password = string(hash)
+	accountModel := model.AccountModel{Tx: db}
+	if err := accountModel.Update(a); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return recordPassword(db, conf, a)
+}
+
+// recordPassword records the current password of an account in its password history
+func recordPassword(db *gorm.DB, conf config.PasswordConfig, a *model.Account) error {
+	if conf.History <= 0 {
+		return nil
+	}
+	if err := model.NewPasswordHistoryModel(db).Add(a.ID, a.Password, conf.History); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/common/role"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// externalIdentity is a user authenticated by an identity provider or a directory
+type externalIdentity struct {
+	// Issuer identifies the provider or the directory
+	Issuer string
+	// Subject is the id of the user at the provider
+	Subject string
+	// Email is the email of the user
+	Email string
+	// EmailVerified is true if the provider states that the email is verified
+	EmailVerified bool
+	// PreferredUsername is the username the user goes by, if any
+	PreferredUsername string
+	// FirstName is the first name of the user
+	FirstName string
+	// LastName is the last name of the user
+	LastName string
+	// Groups are the values matched against the role mappings
+	Groups []string
+}
+
+// provisioning is how the accounts of the users of a provider are created and given their role
+type provisioning struct {
+	// AutoProvision is true if an account is created for an unknown user
+	AutoProvision bool
+	// RoleMappings maps the groups to roles
+	RoleMappings []config.RoleMapping
+	// SyncRole is true if the role mappings are applied at each login, not only at the provisioning
+	SyncRole bool
+	// DefaultRole is the role of the provisioned accounts matching no mapping
+	DefaultRole string
+}
+
+// mappedRole returns the role of the first mapping matching a group
+func (p provisioning) mappedRole(groups []string) (enum.Role, bool) {
+	for _, mapping := range p.RoleMappings {
+		for _, group := range groups {
+			if strings.EqualFold(group, mapping.Value) {
+				return enum.Role(mapping.Role), true
+			}
+		}
+	}
+	return "", false
+}
+
+// accountOfIdentity finds the account linked to an identity, links the account having its email
+// or provisions a new one. The role mappings are applied to the existing accounts only if the role is synced.
+func accountOfIdentity(db *gorm.DB, p provisioning, id externalIdentity) (*model.Account, error) {
+	now := time.Now()
+	identityModel := model.NewIdentityModel(db)
+	identity, err := identityModel.FindBySubject(id.Issuer, id.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	var a *model.Account
+	if err == nil && identity.Account != nil {
+		a = identity.Account
+		if err := identityModel.Touch(identity.ID, now); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	} else {
+		if id.Email == "" || !id.EmailVerified {
+			return nil, error2.ForbiddenError("the identity provider did not share a verified email")
+		}
+
+		if a, err = findOrProvisionAccount(db, p, id); err != nil {
+			return nil, err
+		}
+		if err := identityModel.Create(&model.Identity{
+			AccountID:   a.ID,
+			Issuer:      id.Issuer,
+			Subject:     id.Subject,
+			LastLoginAt: now,
+		}); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	}
+
+	// The synced role follows the mappings at each login, overriding the role assigned locally,
+	// the users matching none keep theirs
+	if !p.SyncRole {
+		return a, nil
+	}
+	if r, ok := p.mappedRole(id.Groups); ok && r != a.User.Role {
+		if err := role.EnsureRole(db, r); err != nil {
+			return nil, err
+		}
+		if err := model.NewUserModel(db).SetRole(a.User.ID, r); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+		a.User.Role = r
+	}
+
+	return a, nil
+}
+
+// findOrProvisionAccount finds the account having the email of an identity,
+// or creates it when the provisioning is enabled. A service account is never linked.
+func findOrProvisionAccount(db *gorm.DB, p provisioning, id externalIdentity) (*model.Account, error) {
+	accountModel := model.AccountModel{Tx: db}
+	a := model.Account{}
+	err := accountModel.FindByEmail(id.Email, &a).Error
+	if err == nil {
+		// The email of a service account is derived from its username, it is not owned by the identity
+		if a.Service {
+			return nil, error2.ForbiddenError("the identity cannot be linked to a service account")
+		}
+		// The provider verified the email
+		if a.VerifiedAt == nil {
+			if err := accountModel.Verify(a.ID, time.Now()).Error; err != nil {
+				return nil, error2.FromDatabaseError(err)
+			}
+		}
+		return &a, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if !p.AutoProvision {
+		return nil, error2.ForbiddenError("no account matches the identity")
+	}
+
+	r, ok := p.mappedRole(id.Groups)
+	if !ok {
+		r = enum.Role(p.DefaultRole)
+	}
+	if r == "" {
+		r = enum.STUDENT
+	}
+	if err := role.EnsureRole(db, r); err != nil {
+		return nil, err
+	}
+
+	username, err := availableUsername(accountModel, id.PreferredUsername, id.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account logs in with the provider only until a password is set with a reset link
+	random, err := newToken()
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+
+	now := time.Now()
+	a = model.Account{
+		Email:      id.Email,
+		Username:   username,
+		Password:   string(hash),
+		VerifiedAt: &now,
+		User: model.User{
+			FirstName: id.FirstName,
+			LastName:  id.LastName,
+			Role:      r,
+		},
+	}
+	if err := accountModel.Create(&a); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &a, nil
+}
+
+// availableUsername derives a free alphanumeric username from the preferred username or the email of an identity
+func availableUsername(accountModel model.AccountModel, preferred, email string) (string, error) {
+	base := preferred
+	if base == "" {
+		base = strings.SplitN(email, "@", 2)[0]
+	}
+	base = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, base)
+	if len(base) > 16 {
+		base = base[:16]
+	}
+	for len(base) < 3 {
+		base += "0"
+	}
+
+	username := base
+	for i := 1; ; i++ {
+		exists, err := accountModel.UsernameExists(username)
+		if err != nil {
+			return "", error2.FromDatabaseError(err)
+		}
+		if !exists {
+			return username, nil
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/logging"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/mail"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// resetValidity is the duration a password reset token can be used
+const resetValidity = time.Hour
+
+// fallbackLanguage is the language used when the configured one has no template
+const fallbackLanguage = "fr"
+
+// passwordReset is the email containing the password reset link
+var passwordReset = mail.Template{
+	"fr": {
+		Subject: "Réinitialisation de votre mot de passe",
+		Body: `Bonjour {{.FirstName}},
+
+Une réinitialisation du mot de passe de votre compte a été demandée.
+Pour choisir un nouveau mot de passe, ouvrez le lien suivant dans l'heure :
+
+{{.Link}}
+
+Si vous n'êtes pas à l'origine de cette demande, ignorez ce message.
+`,
+	},
+	"en": {
+		Subject: "Reset your password",
+		Body: `Hello {{.FirstName}},
+
+A reset of the password of your account was requested.
+To choose a new password, open the following link within the hour:
+
+{{.Link}}
+
+If you did not request it, please ignore this message.
+`,
+	},
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken returns a random hex encoded token of 32 bytes
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ForgotPassword sends a password reset link to the email of an account,
+// an unknown email is not reported so that the registered emails cannot be guessed
+func ForgotPassword(db *gorm.DB, sender mail.Sender, mailConfig config.MailConfig, req dto.ForgotPassword) error {
+	accountModel := model.AccountModel{Tx: db}
+	a := model.Account{}
+	if err := accountModel.FindByEmail(req.Email, &a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logging.Info.Printf("password reset requested for unknown email")
+			return nil
+		}
+		return error2.FromDatabaseError(err)
+	}
+	if a.Service {
+		logging.Info.Printf("password reset requested for a service account")
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	resetModel := model.NewPasswordResetModel(db)
+	reset := model.PasswordReset{
+		AccountID: a.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(resetValidity),
+	}
+	if err := resetModel.Create(&reset); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	msg, err := passwordReset.Render(mailConfig.Language, fallbackLanguage, []string{a.Email}, struct {
+		FirstName string
+		Link      string
+	}{
+		FirstName: a.User.FirstName,
+		Link:      fmt.Sprintf("%s/reset-password?token=%s", strings.TrimSuffix(mailConfig.AppURL, "/"), token),
+	})
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+	if err := sender.Send(msg); err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password following the password policy with a reset token received by email,
+// the token can be used once and all the sessions of the user are revoked
+func ResetPassword(db *gorm.DB, conf config.PasswordConfig, req dto.ResetPassword) error {
+	invalid := error2.BadRequestError("reset token is invalid or expired", nil)
+
+	resetModel := model.NewPasswordResetModel(db)
+	reset, err := resetModel.GetByTokenHash(hashToken(strings.ToLower(req.Token)))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalid
+	}
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	now := time.Now()
+	if reset.UsedAt != nil || now.After(reset.ExpiresAt) {
+		return invalid
+	}
+	used, err := resetModel.Use(reset, now)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !used {
+		return invalid
+	}
+
+	accountModel := model.AccountModel{Tx: db}
+	a := model.Account{ID: reset.AccountID}
+	if err := accountModel.Find(&a).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := checkPassword(db, conf, "NewPassword", req.NewPassword, &a); err != nil {
+		return err
+	}
+	if err := setPassword(db, conf, &a, req.NewPassword); err != nil {
+		return err
+	}
+
+	if err := model.NewTokenModel(db).RevokeUserFamilies(a.User.ID, "").Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/common/role"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/mail"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// invitationEmail is the email containing the invitation link of a staff member
+var invitationEmail = mail.Template{
+	"fr": {
+		Subject: "Invitation à rejoindre Epicarte",
+		Body: `Bonjour {{.FirstName}},
+
+Un compte {{.Role}} a été créé pour vous avec l'identifiant {{.Username}}.
+Pour l'activer et choisir votre mot de passe, ouvrez le lien suivant avant le {{.ExpiresAt}} :
+
+{{.Link}}
+
+Si vous n'attendiez pas cette invitation, ignorez ce message.
+`,
+	},
+	"en": {
+		Subject: "Invitation to join Epicarte",
+		Body: `Hello {{.FirstName}},
+
+A {{.Role}} account was created for you with the username {{.Username}}.
+To activate it and choose your password, open the following link before {{.ExpiresAt}}:
+
+{{.Link}}
+
+If you were not expecting this invitation, please ignore this message.
+`,
+	},
+}
+
+// errInvalidInvitation is returned for a link which is unknown, expired, revoked or already used
+var errInvalidInvitation = error2.BadRequestError("invitation link is invalid or expired", nil)
+
+// toStaffInvitation converts an invitation model to a dto.StaffInvitation
+func toStaffInvitation(i model.StaffInvitation) dto.StaffInvitation {
+	return dto.StaffInvitation{
+		ID:        i.ID,
+		Email:     i.Email,
+		Username:  i.Username,
+		FirstName: i.FirstName,
+		LastName:  i.LastName,
+		Role:      i.Role,
+		CreatedBy: i.CreatedBy,
+		CreatedAt: i.CreatedAt,
+		ExpiresAt: i.ExpiresAt,
+	}
+}
+
+// sendInvitation generates a new link of an invitation and sends it by email, the previous link is invalidated
+func sendInvitation(db *gorm.DB, sender mail.Sender, conf config.Config, i *model.StaffInvitation) error {
+	token, err := newToken()
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	invitationModel := model.NewStaffInvitationModel(db)
+	expiresAt := time.Now().Add(time.Duration(conf.Registration.InvitationValidity) * time.Hour)
+	if i.ID == 0 {
+		i.TokenHash = hashToken(token)
+		i.ExpiresAt = expiresAt
+		err = invitationModel.Create(i)
+	} else {
+		err = invitationModel.Renew(i, hashToken(token), expiresAt)
+	}
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	msg, err := invitationEmail.Render(conf.Mail.Language, fallbackLanguage, []string{i.Email}, struct {
+		FirstName string
+		Username  string
+		Role      enum.Role
+		ExpiresAt string
+		Link      string
+	}{
+		FirstName: i.FirstName,
+		Username:  i.Username,
+		Role:      i.Role,
+		ExpiresAt: i.ExpiresAt.Format("02/01/2006 15:04"),
+		Link:      fmt.Sprintf("%s/accept-invitation?token=%s", strings.TrimSuffix(conf.Mail.AppURL, "/"), url.QueryEscape(token)),
+	})
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+	if err := sender.Send(msg); err != nil {
+		return error2.InternalServerError("", err)
+	}
+	return nil
+}
+
+// GetInvitations gets the invitations neither accepted nor revoked
+func GetInvitations(db *gorm.DB) (*dto.StaffInvitationList, error) {
+	invitations, err := model.NewStaffInvitationModel(db).FindPending()
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	invitationDtos := make([]dto.StaffInvitation, 0, len(invitations))
+	for _, i := range invitations {
+		invitationDtos = append(invitationDtos, toStaffInvitation(i))
+	}
+	return &dto.StaffInvitationList{Invitations: invitationDtos}, nil
+}
+
+// Invite sends an invitation to a staff member, the account is created with the role once the invitee sets their password.
+// The inviter can only grant a role whose permissions it holds.
+func Invite(db *gorm.DB, sender mail.Sender, conf config.Config, req dto.CreateUser, inviterID uint64, inviterPermissions model.Permissions) (*dto.StaffInvitation, error) {
+	r, err := model.NewRoleModel(db).GetByName(req.Role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.BadRequestError(fmt.Sprintf("role '%s' does not exist", req.Role), nil)
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if !inviterPermissions.Grants(r.Permissions) {
+		return nil, error2.ForbiddenError(fmt.Sprintf("you cannot grant the role '%s'", req.Role))
+	}
+
+	// Check the invitee has neither an account nor a pending invitation
+	accountModel := model.AccountModel{Tx: db}
+	exists, err := accountModel.UsernameExists(req.Username)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	err = accountModel.FindByEmail(req.Email, &model.Account{}).Error
+	if err == nil || exists {
+		return nil, error2.BadRequestError("an account already exists with this email or username", nil)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.FromDatabaseError(err)
+	}
+	pending, err := model.NewStaffInvitationModel(db).HasPending(req.Email, req.Username, time.Now())
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if pending {
+		return nil, error2.BadRequestError("an invitation is already pending for this email or username", nil)
+	}
+
+	i := model.StaffInvitation{
+		Email:     req.Email,
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      req.Role,
+		CreatedBy: inviterID,
+	}
+	if err := sendInvitation(db, sender, conf, &i); err != nil {
+		return nil, err
+	}
+
+	invitation := toStaffInvitation(i)
+	return &invitation, nil
+}
+
+// ResendInvitation sends a new link of a pending invitation, valid for a new period
+func ResendInvitation(db *gorm.DB, sender mail.Sender, conf config.Config, id uint64) (*dto.StaffInvitation, error) {
+	i, err := model.NewStaffInvitationModel(db).GetPending(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.NotFoundError("invitation not found")
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	if err := sendInvitation(db, sender, conf, i); err != nil {
+		return nil, err
+	}
+
+	invitation := toStaffInvitation(*i)
+	return &invitation, nil
+}
+
+// RevokeInvitation revokes a pending invitation, its link cannot be used anymore
+func RevokeInvitation(db *gorm.DB, id uint64) error {
+	revoked, err := model.NewStaffInvitationModel(db).Revoke(id, time.Now())
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !revoked {
+		return error2.NotFoundError("invitation not found")
+	}
+	return nil
+}
+
+// AcceptInvitation creates the account of an invitation with the password chosen by the invitee,
+// the email is verified by the link
+func AcceptInvitation(db *gorm.DB, conf config.PasswordConfig, req dto.AcceptStaffInvitation) error {
+	invitationModel := model.NewStaffInvitationModel(db)
+	i, err := invitationModel.GetByTokenHash(hashToken(req.Token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errInvalidInvitation
+	}
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	now := time.Now()
+	if i.AcceptedAt != nil || i.RevokedAt != nil || !now.Before(i.ExpiresAt) {
+		return errInvalidInvitation
+	}
+
+	accepted, err := invitationModel.Accept(i, now)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !accepted {
+		return errInvalidInvitation
+	}
+
+	_, err = CreateStaffAccount(db, conf, dto.CreateUser{
+		Username:  i.Username,
+		Email:     i.Email,
+		FirstName: i.FirstName,
+		LastName:  i.LastName,
+		Role:      i.Role,
+	}, req.Password)
+	return err
+}
+
+// CreateStaffAccount creates the verified account of a staff member with a role and a password following the policy
+func CreateStaffAccount(db *gorm.DB, conf config.PasswordConfig, req dto.CreateUser, password string) (*model.Account, error) {
+	now := time.Now()
+	a := model.Account{
+		Email:      req.Email,
+		Username:   req.Username,
+		VerifiedAt: &now,
+		User: model.User{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Role:      req.Role,
+		},
+	}
+	if err := checkPassword(db, conf, "Password", password, &a); err != nil {
+		return nil, err
+	}
+	if err := role.EnsureRole(db, req.Role); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+	a.Password = string(hash)
+	accountModel := model.AccountModel{Tx: db}
+	if err := accountModel.Create(&a); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if err := recordPassword(db, conf, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/jwt"
+	"gin-template/utils/mail"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// emailVerification is the email containing the email verification link
+var emailVerification = mail.Template{
+	"fr": {
+		Subject: "Vérification de votre adresse email",
+		Body: `Bonjour {{.FirstName}},
+
+Pour activer votre compte, confirmez votre adresse email en ouvrant le lien suivant :
+
+{{.Link}}
+
+Si vous n'avez pas créé de compte, ignorez ce message.
+`,
+	},
+	"en": {
+		Subject: "Verify your email address",
+		Body: `Hello {{.FirstName}},
+
+To activate your account, confirm your email address by opening the following link:
+
+{{.Link}}
+
+If you did not create an account, please ignore this message.
+`,
+	},
+}
+
+// ensureRegistrationAllowed checks that the email belongs to an allowed domain, to a student of a class
+// or was sent a guardian or staff invitation still pending
+func ensureRegistrationAllowed(db *gorm.DB, conf config.RegistrationConfig, email string) error {
+	domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+	for _, allowed := range conf.AllowedDomains {
+		if strings.ToLower(allowed) == domain {
+			return nil
+		}
+	}
+
+	if conf.Roster {
+		studentModel := model.NewStudentModel(db)
+		err := studentModel.FindByEmail(email, &model.Student{}).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.FromDatabaseError(err)
+		}
+	}
+
+	// An invited guardian needs an account to accept the invitation
+	now := time.Now()
+	invited, err := model.NewGuardianModel(db).HasPendingInvitation(email, now)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !invited {
+		invited, err = model.NewStaffInvitationModel(db).HasPending(email, "", now)
+		if err != nil {
+			return error2.FromDatabaseError(err)
+		}
+	}
+	if invited {
+		return nil
+	}
+
+	return error2.ForbiddenError("this email is not allowed to register, please contact your school")
+}
+
+// sendVerification sends the email verification link of an account
+func sendVerification(sender mail.Sender, conf config.Config, keys *jwt.KeySet, a model.Account) error {
+	token, err := jwt.GenerateVerificationToken(a.Email, conf.Registration.VerificationValidity, keys)
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	msg, err := emailVerification.Render(conf.Mail.Language, fallbackLanguage, []string{a.Email}, struct {
+		FirstName string
+		Link      string
+	}{
+		FirstName: a.User.FirstName,
+		Link:      fmt.Sprintf("%s/verify-email?token=%s", strings.TrimSuffix(conf.Mail.AppURL, "/"), url.QueryEscape(token)),
+	})
+	if err != nil {
+		return error2.InternalServerError("", err)
+	}
+	if err := sender.Send(msg); err != nil {
+		return error2.InternalServerError("", err)
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new email verification link to the account of a user
+func ResendVerification(db *gorm.DB, sender mail.Sender, conf config.Config, keys *jwt.KeySet, userId uint64) error {
+	userModel := model.NewUserModel(db)
+	u := model.User{}
+	if err := userModel.FindByUserID(userId, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if u.Account.VerifiedAt != nil {
+		return error2.BadRequestError("email is already verified", nil)
+	}
+
+	a := *u.Account
+	a.User = u
+	return sendVerification(sender, conf, keys, a)
+}
+
+// VerifyEmail verifies the email of an account with the token of a verification link
+func VerifyEmail(db *gorm.DB, keys *jwt.KeySet, token string) error {
+	claims, err := jwt.ParseTypedToken(token, keys, jwt.VerifyEmailToken)
+	if err != nil {
+		return error2.BadRequestError("verification link is invalid or expired", nil)
+	}
+
+	accountModel := model.AccountModel{Tx: db}
+	a := model.Account{}
+	if err := accountModel.FindByEmail(claims.Subject, &a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.BadRequestError("verification link is invalid or expired", nil)
+		}
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := accountModel.Verify(a.ID, time.Now()).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
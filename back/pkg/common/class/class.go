@@ -1,12 +1,45 @@
 package class
 
 import (
+	"fmt"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
 	"gorm.io/gorm"
+	"time"
 )
 
+// EnsureWritable returns an error if the class does not exist or belongs to an archived term
+func EnsureWritable(tx *gorm.DB, classId uint64) error {
+	classModel := model.NewClassModel(tx)
+	archived, err := classModel.IsArchived(classId)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if archived {
+		return error2.ForbiddenError("class belongs to an archived term and is read-only")
+	}
+
+	return nil
+}
+
+// getWritableTerm gets a term a class can be attached to
+func getWritableTerm(tx *gorm.DB, termId uint64) (*model.Term, error) {
+	termModel := model.NewTermModel(tx)
+	t, err := termModel.GetByID(termId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	if t.Archived {
+		return nil, error2.BadRequestError("cannot attach a class to an archived term", nil)
+	}
+
+	return t, nil
+}
+
 // GetClassByID gets a class by ID
 func GetClassByID(tx *gorm.DB, classId uint64) (*dto.Class, error) {
 	classModel := model.NewClassModel(tx)
@@ -25,13 +58,38 @@ func GetClassByID(tx *gorm.DB, classId uint64) (*dto.Class, error) {
 		})
 	}
 
+	teachers := make([]dto.User, 0)
+	for _, teacher := range class.Teachers {
+		teachers = append(teachers, dto.User{
+			ID:        teacher.ID,
+			FirstName: teacher.FirstName,
+			LastName:  teacher.LastName,
+			Role:      teacher.Role,
+		})
+	}
+
+	timetable := make([]dto.TimetableSlot, 0)
+	for _, slot := range class.Timetable {
+		timetable = append(timetable, dto.TimetableSlot{
+			ID:        slot.ID,
+			Weekday:   int(slot.Weekday),
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			Room:      slot.Room,
+		})
+	}
+
 	return &dto.Class{
 		TinyClass: dto.TinyClass{
-			ID:   class.ID,
-			Name: class.Name,
-			Year: class.Year,
+			ID:     class.ID,
+			Name:   class.Name,
+			Year:   class.Year,
+			TermID: class.TermID,
 		},
-		Students: students,
+		NotifyAbsences: class.NotifyAbsences,
+		Students:       students,
+		Teachers:       teachers,
+		Timetable:      timetable,
 	}, nil
 }
 
@@ -46,9 +104,10 @@ func GetAllClasses(tx *gorm.DB) (*dto.ClassList, error) {
 	classDtos := make([]dto.TinyClass, 0)
 	for _, class := range classes {
 		classDtos = append(classDtos, dto.TinyClass{
-			ID:   class.ID,
-			Name: class.Name,
-			Year: class.Year,
+			ID:     class.ID,
+			Name:   class.Name,
+			Year:   class.Year,
+			TermID: class.TermID,
 		})
 	}
 
@@ -59,28 +118,51 @@ func GetAllClasses(tx *gorm.DB) (*dto.ClassList, error) {
 func CreateClass(tx *gorm.DB, class dto.CreateClass) (*dto.TinyClass, error) {
 	classModel := model.NewClassModel(tx)
 	cl := model.Class{
-		Name: class.Name,
-		Year: class.Year,
+		Name:   class.Name,
+		Year:   class.Year,
+		TermID: class.TermID,
 	}
+	if class.TermID != nil {
+		t, err := getWritableTerm(tx, *class.TermID)
+		if err != nil {
+			return nil, err
+		}
+		if cl.Year == "" {
+			cl.Year = t.Name
+		}
+	}
+
 	err := classModel.Create(&cl)
 	if err != nil {
 		return nil, error2.FromDatabaseError(err)
 	}
 
 	return &dto.TinyClass{
-		ID:   cl.ID,
-		Name: cl.Name,
-		Year: cl.Year,
+		ID:     cl.ID,
+		Name:   cl.Name,
+		Year:   cl.Year,
+		TermID: cl.TermID,
 	}, nil
 }
 
 // UpdateClass updates a class
 func UpdateClass(tx *gorm.DB, class dto.UpdateClass) error {
+	if err := EnsureWritable(tx, class.Id); err != nil {
+		return err
+	}
+
+	if class.TermID != nil {
+		if _, err := getWritableTerm(tx, *class.TermID); err != nil {
+			return err
+		}
+	}
+
 	classModel := model.NewClassModel(tx)
 	cl := model.Class{
-		ID:   class.Id,
-		Name: class.Name,
-		Year: class.Year,
+		ID:     class.Id,
+		Name:   class.Name,
+		Year:   class.Year,
+		TermID: class.TermID,
 	}
 	err := classModel.Update(&cl)
 	if err != nil {
@@ -92,6 +174,10 @@ func UpdateClass(tx *gorm.DB, class dto.UpdateClass) error {
 
 // DeleteClass deletes a class
 func DeleteClass(tx *gorm.DB, classId uint64) error {
+	if err := EnsureWritable(tx, classId); err != nil {
+		return err
+	}
+
 	classModel := model.NewClassModel(tx)
 	err := classModel.Delete(classId)
 	if err != nil {
@@ -103,6 +189,10 @@ func DeleteClass(tx *gorm.DB, classId uint64) error {
 
 // AddStudentToClass adds a student to a class
 func AddStudentToClass(tx *gorm.DB, student dto.AddStudentToClass) (*dto.Student, error) {
+	if err := EnsureWritable(tx, student.ClassId); err != nil {
+		return nil, err
+	}
+
 	studentModel := model.NewClassModel(tx)
 	st := model.Student{
 		Email:     student.Student.Email,
@@ -122,3 +212,172 @@ func AddStudentToClass(tx *gorm.DB, student dto.AddStudentToClass) (*dto.Student
 		LastName:  st.LastName,
 	}, nil
 }
+
+// SetClassNotifications enables or disables the absence notifications of a class
+func SetClassNotifications(tx *gorm.DB, req dto.SetClassNotifications) error {
+	if err := EnsureWritable(tx, req.ClassId); err != nil {
+		return err
+	}
+
+	classModel := model.NewClassModel(tx)
+	if err := classModel.SetNotifyAbsences(req.ClassId, *req.Enabled).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// AddTeacherToClass adds a staff member as teacher of a class
+func AddTeacherToClass(tx *gorm.DB, req dto.ClassTeacher) error {
+	if err := EnsureWritable(tx, req.ClassId); err != nil {
+		return err
+	}
+
+	userModel := model.NewUserModel(tx)
+	u := model.User{}
+	if err := userModel.FindByUserID(req.UserId, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	canTeach, err := model.NewRoleModel(tx).HasPermission(u.Role, enum.CLASS_TEACH)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !canTeach {
+		return error2.BadRequestError("only staff members can teach a class", nil)
+	}
+
+	classModel := model.NewClassModel(tx)
+	if err := classModel.AddTeacher(&model.Class{ID: req.ClassId}, &u); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RemoveTeacherFromClass removes a teacher from a class
+func RemoveTeacherFromClass(tx *gorm.DB, req dto.ClassTeacher) error {
+	if err := EnsureWritable(tx, req.ClassId); err != nil {
+		return err
+	}
+
+	classModel := model.NewClassModel(tx)
+	if err := classModel.RemoveTeacher(&model.Class{ID: req.ClassId}, &model.User{ID: req.UserId}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// SetClassTimetable replaces the weekly timetable of a class
+func SetClassTimetable(tx *gorm.DB, req dto.SetClassTimetable) error {
+	if err := EnsureWritable(tx, req.ClassId); err != nil {
+		return err
+	}
+
+	slots := make([]model.TimetableSlot, 0)
+	for _, slot := range req.Slots {
+		if slot.EndTime <= slot.StartTime {
+			return error2.BadRequestError("slot end time must be after its start time", nil)
+		}
+		slots = append(slots, model.TimetableSlot{
+			Weekday:   time.Weekday(slot.Weekday),
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			Room:      slot.Room,
+		})
+	}
+
+	timetableModel := model.NewTimetableModel(tx)
+	if err := timetableModel.Replace(req.ClassId, slots); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RolloverClass clones the structure of a class (name, teachers and timetable) into another term
+// and promotes the selected students to the new class.
+// The class may belong to an archived term since it is only read, but its students cannot be moved out of it.
+func RolloverClass(tx *gorm.DB, req dto.RolloverClass) (*dto.TinyClass, error) {
+	classModel := model.NewClassModel(tx)
+	src, err := classModel.GetByID(req.ClassId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	if src.TermID != nil && *src.TermID == req.TermID {
+		return nil, error2.BadRequestError("class already belongs to this term", nil)
+	}
+
+	t, err := getWritableTerm(tx, req.TermID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only students of the cloned class can be promoted
+	students := make(map[uint64]bool)
+	for _, st := range src.Students {
+		students[st.ID] = true
+	}
+	for _, id := range req.StudentIDs {
+		if !students[id] {
+			return nil, error2.BadRequestError(fmt.Sprintf("student '%d' is not in class '%d'", id, src.ID), nil)
+		}
+	}
+
+	// Promoting the students moves them out of the class, which would rewrite an archived term
+	if len(req.StudentIDs) > 0 {
+		archived, err := classModel.IsArchived(src.ID)
+		if err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+		if archived {
+			return nil, error2.BadRequestError("students cannot be promoted out of a class of an archived term, roll the class over before archiving its term or without students", nil)
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = src.Name
+	}
+
+	timetable := make([]model.TimetableSlot, 0)
+	for _, slot := range src.Timetable {
+		timetable = append(timetable, model.TimetableSlot{
+			Weekday:   slot.Weekday,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			Room:      slot.Room,
+		})
+	}
+
+	cl := model.Class{
+		Name:      name,
+		Year:      t.Name,
+		TermID:    &t.ID,
+		Timetable: timetable,
+	}
+	if err = classModel.Create(&cl); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	for _, teacher := range src.Teachers {
+		if err = classModel.AddTeacher(&cl, teacher); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	}
+
+	if len(req.StudentIDs) > 0 {
+		if err = classModel.MoveStudents(src.ID, cl.ID, req.StudentIDs).Error; err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	}
+
+	return &dto.TinyClass{
+		ID:     cl.ID,
+		Name:   cl.Name,
+		Year:   cl.Year,
+		TermID: cl.TermID,
+	}, nil
+}
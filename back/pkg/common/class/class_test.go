@@ -0,0 +1,196 @@
+package class
+
+import (
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// setupTestDatabase sets up the tables of the classes and their terms in the test database
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Migrator().DropTable(&model.Session{}, "guardian_students", &model.Student{}, &model.TimetableSlot{},
+		"class_teachers", &model.Class{}, &model.Holiday{}, &model.Term{}, &model.User{}, &model.Account{})
+	if err := db.AutoMigrate(&model.Account{}, &model.User{}, &model.Term{}, &model.Holiday{}, &model.Class{},
+		&model.TimetableSlot{}, &model.Student{}, &model.Session{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createTerm creates a term, archived or not
+func createTerm(t *testing.T, db *gorm.DB, name string, archived bool) *model.Term {
+	term := model.Term{
+		Name:      name,
+		StartDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+		Archived:  archived,
+	}
+	if err := db.Create(&term).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &term
+}
+
+// createClass creates a class of a term with a teacher, a slot and two students
+func createClass(t *testing.T, db *gorm.DB, term *model.Term) (*model.Class, []model.Student) {
+	a := model.Account{
+		Email:    "teacher.rollover@gmail.com",
+		Username: "teacherrollover",
+		Password: "password",
+		User:     model.User{FirstName: "first", LastName: "last", Role: enum.ADMIN},
+	}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	class := model.Class{
+		Name:      "rollover",
+		Year:      term.Name,
+		TermID:    &term.ID,
+		Timetable: []model.TimetableSlot{{Weekday: time.Monday, StartTime: "08:00", EndTime: "10:00", Room: "A1"}},
+	}
+	classModel := model.NewClassModel(db)
+	if err := classModel.Create(&class); err != nil {
+		t.Fatal(err)
+	}
+	if err := classModel.AddTeacher(&class, &a.User); err != nil {
+		t.Fatal(err)
+	}
+
+	students := []model.Student{
+		{Email: "student.promoted@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID},
+		{Email: "student.repeating@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID},
+	}
+	if err := db.Create(&students).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &class, students
+}
+
+// studentsOf returns the ids of the students of a class
+func studentsOf(t *testing.T, db *gorm.DB, classID uint64) []uint64 {
+	var ids []uint64
+	if err := db.Model(&model.Student{}).Where("class_id = ?", classID).Order("id").Pluck("id", &ids).Error; err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+// TestRolloverClass tests that the structure of a class is cloned into the next term with the promoted students
+func TestRolloverClass(t *testing.T) {
+	db := setupTestDatabase(t)
+	current := createTerm(t, db, "2022-2023", false)
+	next := createTerm(t, db, "2023-2024", false)
+	src, students := createClass(t, db, current)
+
+	cl, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: next.ID, StudentIDs: []uint64{students[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.Name != src.Name || cl.Year != next.Name || cl.TermID == nil || *cl.TermID != next.ID {
+		t.Errorf("Class should be cloned into the next term, got %+v", cl)
+	}
+
+	created, err := model.NewClassModel(db).GetByID(cl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Teachers) != 1 || len(created.Timetable) != 1 || created.Timetable[0].Room != "A1" {
+		t.Error("Teachers and timetable should be cloned")
+	}
+
+	if ids := studentsOf(t, db, cl.ID); len(ids) != 1 || ids[0] != students[0].ID {
+		t.Errorf("Promoted student should be moved to the new class, got %v", ids)
+	}
+	if ids := studentsOf(t, db, src.ID); len(ids) != 1 || ids[0] != students[1].ID {
+		t.Errorf("Student not promoted should stay in the class, got %v", ids)
+	}
+}
+
+// TestRolloverClass_WithoutPromotion tests that a class is cloned without students and without changing its own
+func TestRolloverClass_WithoutPromotion(t *testing.T) {
+	db := setupTestDatabase(t)
+	current := createTerm(t, db, "2022-2023", false)
+	next := createTerm(t, db, "2023-2024", false)
+	src, _ := createClass(t, db, current)
+
+	cl, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: next.ID, Name: "renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cl.Name != "renamed" {
+		t.Errorf("Class should be renamed, got %s", cl.Name)
+	}
+	if ids := studentsOf(t, db, cl.ID); len(ids) != 0 {
+		t.Errorf("New class should have no students, got %v", ids)
+	}
+	if ids := studentsOf(t, db, src.ID); len(ids) != 2 {
+		t.Errorf("Students should stay in the class, got %v", ids)
+	}
+
+	// A class cannot be cloned into its own term
+	if _, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: current.ID}); err == nil {
+		t.Error("Class should not be cloned into its own term")
+	}
+}
+
+// TestRolloverClass_ArchivedTerm tests that a class of an archived term is cloned,
+// but its students are not promoted and no class is cloned into an archived term
+func TestRolloverClass_ArchivedTerm(t *testing.T) {
+	db := setupTestDatabase(t)
+	archived := createTerm(t, db, "2022-2023", true)
+	next := createTerm(t, db, "2023-2024", false)
+	src, students := createClass(t, db, archived)
+
+	_, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: next.ID, StudentIDs: []uint64{students[0].ID}})
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusBadRequest {
+		t.Errorf("Students of an archived term should not be promoted, got %v", err)
+	}
+	if ids := studentsOf(t, db, src.ID); len(ids) != 2 {
+		t.Errorf("Students of an archived term should stay in their class, got %v", ids)
+	}
+
+	if _, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: next.ID}); err != nil {
+		t.Error("Class of an archived term should be cloned without students", err)
+	}
+
+	other := createTerm(t, db, "2021-2022", true)
+	if _, err := RolloverClass(db, dto.RolloverClass{ClassId: src.ID, TermID: other.ID}); err == nil {
+		t.Error("Class should not be cloned into an archived term")
+	}
+}
+
+// TestEnsureWritable tests that the classes of an archived term are read-only
+func TestEnsureWritable(t *testing.T) {
+	db := setupTestDatabase(t)
+	term := createTerm(t, db, "2022-2023", false)
+	current, _ := createClass(t, db, term)
+
+	if err := EnsureWritable(db, current.ID); err != nil {
+		t.Error("Class of a current term should be writable", err)
+	}
+
+	if err := model.NewTermModel(db).Archive(term).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := EnsureWritable(db, current.ID)
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusForbidden {
+		t.Errorf("Class of an archived term should be read-only, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package guardian
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// invitationValidity is the duration a guardian invitation can be accepted for
+const invitationValidity = 7 * 24 * time.Hour
+
+// hashCode returns the hex encoded SHA-256 hash of an invitation code
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// getClassStudent gets a student of a class
+func getClassStudent(tx *gorm.DB, classID, studentID uint64) (*model.Student, error) {
+	studentModel := model.NewStudentModel(tx)
+	s := model.Student{ID: studentID}
+	if err := studentModel.GetByClassID(classID, &s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, error2.NotFoundError(fmt.Sprintf("student '%d' not found for class '%d'", studentID, classID))
+		}
+		return nil, error2.FromDatabaseError(err)
+	}
+	return &s, nil
+}
+
+// EnsureGuardianOf returns an error if the user is not guardian of the student
+func EnsureGuardianOf(tx *gorm.DB, userID, studentID uint64) error {
+	guardianModel := model.NewGuardianModel(tx)
+	ok, err := guardianModel.IsGuardianOf(userID, studentID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if !ok {
+		return error2.ForbiddenError("you are not a guardian of this student")
+	}
+
+	return nil
+}
+
+// AddGuardianToStudent makes a guardian user guardian of a student of a class
+func AddGuardianToStudent(tx *gorm.DB, req dto.StudentGuardian) error {
+	s, err := getClassStudent(tx, req.ClassId, req.StudentId)
+	if err != nil {
+		return err
+	}
+
+	userModel := model.NewUserModel(tx)
+	u := model.User{}
+	if err := userModel.FindByUserID(req.UserId, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if u.Role != enum.GUARDIAN {
+		return error2.BadRequestError("only guardian users can be guardians of a student", nil)
+	}
+
+	guardianModel := model.NewGuardianModel(tx)
+	if err := guardianModel.AddGuardian(s, &u); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RemoveGuardianFromStudent removes a user from the guardians of a student of a class
+func RemoveGuardianFromStudent(tx *gorm.DB, req dto.StudentGuardian) error {
+	s, err := getClassStudent(tx, req.ClassId, req.StudentId)
+	if err != nil {
+		return err
+	}
+
+	guardianModel := model.NewGuardianModel(tx)
+	if err := guardianModel.RemoveGuardian(s, &model.User{ID: req.UserId}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// CreateGuardianInvitation creates an invitation to become guardian of a student of a class,
+// the code is only returned here and must be shared with the guardian
+func CreateGuardianInvitation(tx *gorm.DB, req dto.CreateGuardianInvitation, creatorID uint64) (*dto.GuardianInvitation, error) {
+	if _, err := getClassStudent(tx, req.ClassId, req.StudentId); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+	code := hex.EncodeToString(buf)
+
+	guardianModel := model.NewGuardianModel(tx)
+	invitation := model.GuardianInvitation{
+		StudentID: req.StudentId,
+		Email:     strings.ToLower(req.Email),
+		CodeHash:  hashCode(code),
+		CreatedBy: creatorID,
+		ExpiresAt: time.Now().Add(invitationValidity),
+	}
+	if err := guardianModel.CreateInvitation(&invitation); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.GuardianInvitation{
+		Code:      code,
+		Email:     invitation.Email,
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+// AcceptGuardianInvitation makes the user guardian of the invited student.
+// The account email must be the invited one, a student account becomes a guardian account.
+func AcceptGuardianInvitation(tx *gorm.DB, req dto.AcceptGuardianInvitation, userID uint64) error {
+	guardianModel := model.NewGuardianModel(tx)
+	invitation, err := guardianModel.GetInvitationByCodeHash(hashCode(strings.ToLower(req.Code)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.NotFoundError("invitation not found")
+		}
+		return error2.FromDatabaseError(err)
+	}
+
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return error2.BadRequestError("the invitation is expired or already accepted", nil)
+	}
+
+	userModel := model.NewUserModel(tx)
+	u := model.User{}
+	if err := userModel.FindByUserID(userID, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if u.Account == nil || !strings.EqualFold(u.Account.Email, invitation.Email) {
+		return error2.ForbiddenError("the invitation was sent to another email")
+	}
+
+	switch u.Role {
+	case enum.GUARDIAN:
+	case enum.STUDENT:
+		u.Role = enum.GUARDIAN
+		if err := userModel.SetRole(u.ID, enum.GUARDIAN); err != nil {
+			return error2.FromDatabaseError(err)
+		}
+		// the role is carried by the tokens, the sessions of the student account are logged out
+		if err := model.NewTokenModel(tx).RevokeUserFamilies(u.ID, "").Error; err != nil {
+			return error2.FromDatabaseError(err)
+		}
+	default:
+		return error2.ForbiddenError("staff accounts cannot be guardians")
+	}
+
+	if res := guardianModel.AcceptInvitation(invitation, userID); res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	} else if res.RowsAffected == 0 {
+		return error2.BadRequestError("the invitation is expired or already accepted", nil)
+	}
+
+	if err := guardianModel.AddGuardian(&model.Student{ID: invitation.StudentID}, &u); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// GetChildren gets the students a user is guardian of
+func GetChildren(tx *gorm.DB, userID uint64) (*dto.ChildList, error) {
+	guardianModel := model.NewGuardianModel(tx)
+	students, err := guardianModel.FindChildren(userID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	children := make([]dto.Child, 0)
+	for _, s := range students {
+		child := dto.Child{
+			Student: dto.Student{
+				ID:        s.ID,
+				Email:     s.Email,
+				FirstName: s.FirstName,
+				LastName:  s.LastName,
+			},
+		}
+		if s.Class != nil {
+			child.Class = dto.TinyClass{
+				ID:     s.Class.ID,
+				Name:   s.Class.Name,
+				Year:   s.Class.Year,
+				TermID: s.Class.TermID,
+			}
+		}
+		children = append(children, child)
+	}
+
+	return &dto.ChildList{Children: children}, nil
+}
+
+// GetChildAttendance gets the attendance of a student the user is guardian of
+func GetChildAttendance(tx *gorm.DB, userID, studentID uint64) (*dto.ChildAttendanceList, error) {
+	if err := EnsureGuardianOf(tx, userID, studentID); err != nil {
+		return nil, err
+	}
+
+	attendanceModel := model.NewAttendanceModel(tx)
+	attendances, err := attendanceModel.FindByStudent(studentID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	attendanceDtos := make([]dto.ChildAttendance, 0)
+	for _, a := range attendances {
+		attendance := dto.ChildAttendance{
+			SessionID: a.SessionID,
+			Status:    a.Status,
+			Justified: a.Justified,
+		}
+		if a.Session != nil {
+			attendance.SessionDate = a.Session.CreatedAt
+		}
+		attendanceDtos = append(attendanceDtos, attendance)
+	}
+
+	return &dto.ChildAttendanceList{Attendances: attendanceDtos}, nil
+}
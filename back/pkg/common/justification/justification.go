@@ -0,0 +1,140 @@
+package justification
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/pkg/common/class"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+// toJustification converts a justification model with its attendance and session to a dto.Justification
+func toJustification(j model.Justification) dto.Justification {
+	justification := dto.Justification{
+		ID:         j.ID,
+		Reason:     j.Reason,
+		Status:     j.Status,
+		CreatedAt:  j.CreatedAt,
+		ReviewedAt: j.ReviewedAt,
+	}
+	if j.Attendance != nil {
+		justification.SessionID = j.Attendance.SessionID
+		if j.Attendance.Session != nil {
+			justification.SessionDate = j.Attendance.Session.CreatedAt
+		}
+		if s := j.Attendance.Student; s != nil {
+			justification.Student = &dto.Student{
+				ID:        s.ID,
+				Email:     s.Email,
+				FirstName: s.FirstName,
+				LastName:  s.LastName,
+			}
+		}
+	}
+	return justification
+}
+
+// GetStudentJustifications gets the justifications of the absences of a student
+func GetStudentJustifications(tx *gorm.DB, studentID uint64) (*dto.JustificationList, error) {
+	justificationModel := model.NewJustificationModel(tx)
+	justifications, err := justificationModel.FindByStudent(studentID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	justificationDtos := make([]dto.Justification, 0)
+	for _, j := range justifications {
+		justificationDtos = append(justificationDtos, toJustification(j))
+	}
+
+	return &dto.JustificationList{Justifications: justificationDtos}, nil
+}
+
+// SubmitJustification submits a justification for an absence of a student, on behalf of a user
+func SubmitJustification(tx *gorm.DB, req dto.CreateJustification, userID uint64) (*dto.Justification, error) {
+	sessionID := uuid.Must(uuid.FromString(req.SessionID))
+
+	attendanceModel := model.NewAttendanceModel(tx)
+	absence, err := attendanceModel.GetAbsence(sessionID, req.StudentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, error2.NotFoundError(fmt.Sprintf("student '%d' has no absence to session '%s'", req.StudentId, sessionID))
+		}
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	justificationModel := model.NewJustificationModel(tx)
+	active, err := justificationModel.HasActive(absence.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	if active {
+		return nil, error2.BadRequestError("the absence already has a pending or accepted justification", nil)
+	}
+
+	j := model.Justification{
+		AttendanceID: absence.ID,
+		Attendance:   absence,
+		Reason:       req.Reason,
+		SubmittedBy:  userID,
+		Status:       enum.JUSTIFICATION_PENDING,
+	}
+	if err := justificationModel.Create(&j); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	justification := toJustification(j)
+	return &justification, nil
+}
+
+// GetClassJustifications gets the justifications of the absences to the sessions of a class
+func GetClassJustifications(tx *gorm.DB, classID uint64, req dto.JustificationQueryParams) (*dto.JustificationList, error) {
+	justificationModel := model.NewJustificationModel(tx)
+	justifications, err := justificationModel.FindByClass(classID, req.Status)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	justificationDtos := make([]dto.Justification, 0)
+	for _, j := range justifications {
+		justificationDtos = append(justificationDtos, toJustification(j))
+	}
+
+	return &dto.JustificationList{Justifications: justificationDtos}, nil
+}
+
+// ReviewJustification accepts or rejects a pending justification, an accepted justification justifies the absence
+func ReviewJustification(tx *gorm.DB, req dto.ReviewJustification, reviewerID uint64) error {
+	if err := class.EnsureWritable(tx, req.ClassId); err != nil {
+		return err
+	}
+
+	justificationModel := model.NewJustificationModel(tx)
+	j, err := justificationModel.GetByClassID(req.ClassId, req.JustificationId)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	res := justificationModel.Review(j, req.Status, reviewerID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.BadRequestError("the justification was already reviewed", nil)
+	}
+
+	if req.Status == enum.JUSTIFICATION_ACCEPTED {
+		attendanceModel := model.NewAttendanceModel(tx)
+		if err := attendanceModel.SetJustified(j.AttendanceID, true); err != nil {
+			return error2.FromDatabaseError(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package notification
+
+import (
+	"errors"
+	"gin-template/config"
+	"gin-template/logging"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/mail"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// fallbackLanguage is the language used when the configured one has no template
+const fallbackLanguage = "fr"
+
+// dateFormats is the format of the session dates in each language
+var dateFormats = map[string]string{
+	"fr": "02/01/2006 à 15h04",
+	"en": "2006-01-02 at 3:04 PM",
+}
+
+// absenceDigest is the email listing the absences of a student since the last digest
+var absenceDigest = mail.Template{
+	"fr": {
+		Subject: "Absences de {{.FirstName}} {{.LastName}}",
+		Body: `Bonjour,
+
+{{.FirstName}} {{.LastName}} a été noté(e) absent(e) aux sessions suivantes :
+{{range .Absences}}
+- {{.Class}}, le {{.Date}}{{end}}
+
+Si cette absence est justifiée, merci de contacter l'établissement.
+`,
+	},
+	"en": {
+		Subject: "Absences of {{.FirstName}} {{.LastName}}",
+		Body: `Hello,
+
+{{.FirstName}} {{.LastName}} was marked absent from the following sessions:
+{{range .Absences}}
+- {{.Class}}, on {{.Date}}{{end}}
+
+If this absence is justified, please contact the school.
+`,
+	},
+}
+
+// studentAtRisk is the email sent to the teachers of a class when a student is at risk
+var studentAtRisk = mail.Template{
+	"fr": {
+		Subject: "Alerte d'assiduité : {{.FirstName}} {{.LastName}} ({{.Class}})",
+		Body: `Bonjour,
+
+{{.FirstName}} {{.LastName}} de la classe {{.Class}} a déclenché une alerte d'assiduité :
+{{if eq .Kind "absence_count"}}{{.Value}} absences non justifiées, au-delà du seuil de {{.Threshold}}{{else}}un taux de présence de {{printf "%.1f" .Value}} %, sous le seuil de {{.Threshold}} %{{end}}.
+`,
+	},
+	"en": {
+		Subject: "Attendance alert: {{.FirstName}} {{.LastName}} ({{.Class}})",
+		Body: `Hello,
+
+{{.FirstName}} {{.LastName}} of the class {{.Class}} raised an attendance alert:
+{{if eq .Kind "absence_count"}}{{.Value}} unjustified absences, above the threshold of {{.Threshold}}{{else}}an attendance rate of {{printf "%.1f" .Value}}%, below the threshold of {{.Threshold}}%{{end}}.
+`,
+	},
+}
+
+type atRisk struct {
+	FirstName string
+	LastName  string
+	Class     string
+	Kind      string
+	Value     float64
+	Threshold float64
+}
+
+type absence struct {
+	Class string
+	Date  string
+}
+
+type digest struct {
+	FirstName string
+	LastName  string
+	Absences  []absence
+}
+
+// QueueAbsences queues a notification for each absent student if the class notifies the absences
+func QueueAbsences(tx *gorm.DB, classID uint64, sessionID uuid.UUID, absents []model.Attendance) error {
+	if len(absents) == 0 {
+		return nil
+	}
+
+	classModel := model.NewClassModel(tx)
+	enabled, err := classModel.NotifiesAbsences(classID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	notifications := make([]model.AbsenceNotification, 0, len(absents))
+	for _, a := range absents {
+		notifications = append(notifications, model.AbsenceNotification{
+			StudentID: a.StudentID,
+			SessionID: sessionID,
+		})
+	}
+
+	notificationModel := model.NewAbsenceNotificationModel(tx)
+	if err := notificationModel.Create(notifications); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// DigestCutoff returns the last time the digests were due, the given hour of today if passed, of yesterday otherwise
+func DigestCutoff(now time.Time, hour int) time.Time {
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if cutoff.After(now) {
+		cutoff = cutoff.AddDate(0, 0, -1)
+	}
+	return cutoff
+}
+
+// recipients returns the addresses notified of the absences of a student, the student and its guardians,
+// leaving out the missing ones like the email of an erased student
+func recipients(student *model.Student) []string {
+	to := make([]string, 0, len(student.Guardians)+1)
+	if student.Email != "" {
+		to = append(to, student.Email)
+	}
+	for _, g := range student.Guardians {
+		if g.Account != nil && g.Account.Email != "" {
+			to = append(to, g.Account.Email)
+		}
+	}
+	return to
+}
+
+// sendDigest sends the pending absences of a student in a single email and marks them sent,
+// nothing is sent when another replica is sending them
+func sendDigest(tx *gorm.DB, sender mail.Sender, conf config.MailConfig, studentID uint64, cutoff, now time.Time) error {
+	notificationModel := model.NewAbsenceNotificationModel(tx)
+	notifications, err := notificationModel.FindPending(studentID, cutoff)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+
+	ids := make([]uint64, 0, len(notifications))
+	for _, n := range notifications {
+		ids = append(ids, n.ID)
+	}
+
+	// The student was deleted since the absence, there is no one to notify anymore
+	student := notifications[0].Student
+	if student == nil {
+		return notificationModel.MarkSent(ids, now)
+	}
+
+	dateFormat, ok := dateFormats[conf.Language]
+	if !ok {
+		dateFormat = dateFormats[fallbackLanguage]
+	}
+	d := digest{FirstName: student.FirstName, LastName: student.LastName}
+	for _, n := range notifications {
+		if n.Session == nil || n.Session.Class == nil {
+			continue
+		}
+		d.Absences = append(d.Absences, absence{
+			Class: n.Session.Class.Name,
+			Date:  n.Session.CreatedAt.Format(dateFormat),
+		})
+	}
+
+	// Without address there is no one to notify, the absences are dropped instead of being retried forever
+	to := recipients(student)
+	if len(d.Absences) > 0 && len(to) > 0 {
+		msg, err := absenceDigest.Render(conf.Language, fallbackLanguage, to, d)
+		if err != nil {
+			return err
+		}
+		if err := sender.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return notificationModel.MarkSent(ids, now)
+}
+
+// SendDigests sends to each student a single email listing the absences recorded before the last digest time.
+// Each digest is sent and marked in its own transaction, so that a failure only keeps its absences pending for the next run.
+func SendDigests(db *gorm.DB, sender mail.Sender, conf config.MailConfig, now time.Time) error {
+	cutoff := DigestCutoff(now, conf.DigestHour)
+	students, err := model.NewAbsenceNotificationModel(db).FindPendingStudents(cutoff)
+	if err != nil {
+		return err
+	}
+
+	for _, studentID := range students {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return sendDigest(tx, sender, conf, studentID, cutoff, now)
+		})
+		if err != nil {
+			logging.Error.Printf("absence digest to student %d failed: %v", studentID, err)
+		}
+	}
+
+	return nil
+}
+
+// sendAlert sends an alert to the teachers of its class and marks it notified,
+// nothing is sent when another replica is sending it
+func sendAlert(tx *gorm.DB, sender mail.Sender, conf config.MailConfig, alertID uint64, now time.Time) error {
+	alertModel := model.NewAlertModel(tx)
+	a, err := alertModel.LockUnnotified(alertID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	to := make([]string, 0)
+	if a.Class != nil {
+		for _, t := range a.Class.Teachers {
+			if t.Account != nil && t.Account.Email != "" {
+				to = append(to, t.Account.Email)
+			}
+		}
+	}
+
+	// Without student or teacher there is no one to notify, the alert stays visible in the class
+	if a.Student != nil && len(to) > 0 {
+		msg, err := studentAtRisk.Render(conf.Language, fallbackLanguage, to, atRisk{
+			FirstName: a.Student.FirstName,
+			LastName:  a.Student.LastName,
+			Class:     a.Class.Name,
+			Kind:      a.Rule.Kind.String(),
+			Value:     a.Value,
+			Threshold: a.Rule.Threshold,
+		})
+		if err != nil {
+			return err
+		}
+		if err := sender.Send(msg); err != nil {
+			return err
+		}
+	}
+
+	return alertModel.MarkNotified([]uint64{a.ID}, now)
+}
+
+// SendAlerts sends the alerts of the notifying rules to the teachers of the classes.
+// Each alert is sent and marked in its own transaction, so that a failure only keeps it pending for the next run.
+func SendAlerts(db *gorm.DB, sender mail.Sender, conf config.MailConfig, now time.Time) error {
+	alerts, err := model.NewAlertModel(db).FindUnnotified()
+	if err != nil {
+		return err
+	}
+
+	for _, alertID := range alerts {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return sendAlert(tx, sender, conf, alertID, now)
+		})
+		if err != nil {
+			logging.Error.Printf("alert %d to the teachers failed: %v", alertID, err)
+		}
+	}
+
+	return nil
+}
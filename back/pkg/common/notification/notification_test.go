@@ -0,0 +1,174 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	"gin-template/utils/mail"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTestDatabase sets up the tables of the notifications in the test database
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Migrator().DropTable(&model.Alert{}, &model.AlertRule{}, &model.AbsenceNotification{}, &model.Session{},
+		"guardian_students", "class_teachers", &model.Student{}, &model.Class{}, &model.User{}, &model.Account{})
+	if err := db.AutoMigrate(&model.Account{}, &model.User{}, &model.Class{}, &model.Student{}, &model.Session{},
+		&model.AbsenceNotification{}, &model.AlertRule{}, &model.Alert{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createAccount creates an account with its user
+func createAccount(t *testing.T, db *gorm.DB, email string, role enum.Role) *model.User {
+	a := model.Account{
+		Email:    email,
+		Username: strings.Split(email, "@")[0],
+		Password: "password",
+		User:     model.User{FirstName: "first", LastName: "last", Role: role},
+	}
+	if err := db.Create(&a).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &a.User
+}
+
+// createAbsence queues the notification of an absence of a student recorded at the given date
+func createAbsence(t *testing.T, db *gorm.DB, student model.Student, session model.Session, at time.Time) {
+	n := model.AbsenceNotification{StudentID: student.ID, SessionID: session.ID, CreatedAt: at}
+	if err := db.Omit("Student", "Session").Create(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSendDigests tests that the absences of a student before the cutoff are sent in one email to the student
+// and its guardians and marked sent, while a student without address is dropped
+func TestSendDigests(t *testing.T) {
+	db := setupTestDatabase(t)
+	conf := config.MailConfig{Language: "en", DigestHour: 18}
+	now := time.Date(2023, 1, 10, 19, 0, 0, 0, time.Local)
+
+	class := model.Class{Name: "digest", Year: "2023"}
+	db.Create(&class)
+	student := model.Student{Email: "student.digest@gmail.com", FirstName: "John", LastName: "Doe", ClassID: class.ID}
+	db.Create(&student)
+	guardian := createAccount(t, db, "guardian.digest@gmail.com", enum.GUARDIAN)
+	model.NewGuardianModel(db).AddGuardian(&student, guardian)
+	anonymous := model.Student{FirstName: "Erased", LastName: "student", ClassID: class.ID}
+	db.Create(&anonymous)
+
+	session := model.Session{Password: "password", ClassID: class.ID}
+	db.Create(&session)
+	createAbsence(t, db, student, session, now.Add(-8*time.Hour))
+	createAbsence(t, db, student, session, now.Add(-7*time.Hour))
+	createAbsence(t, db, student, session, now.Add(-10*time.Minute))
+	createAbsence(t, db, anonymous, session, now.Add(-8*time.Hour))
+
+	sender := &mail.FakeSender{}
+	if err := SendDigests(db, sender, conf, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.Sent) != 1 {
+		t.Fatalf("A single digest should be sent, got %d", len(sender.Sent))
+	}
+	msg := sender.Sent[0]
+	if len(msg.To) != 2 || msg.To[0] != student.Email || msg.To[1] != "guardian.digest@gmail.com" {
+		t.Errorf("Digest should be sent to the student and its guardian, got %v", msg.To)
+	}
+	if strings.Count(msg.Body, "- digest, on") != 2 {
+		t.Errorf("Digest should list the 2 absences before the cutoff, got %s", msg.Body)
+	}
+
+	notificationModel := model.NewAbsenceNotificationModel(db)
+	if pending, _ := notificationModel.FindPendingStudents(now); len(pending) != 0 {
+		t.Errorf("Absences before the cutoff should be marked sent, got pending students %v", pending)
+	}
+	if pending, _ := notificationModel.FindPendingStudents(now.Add(24 * time.Hour)); len(pending) != 1 || pending[0] != student.ID {
+		t.Errorf("Absence after the cutoff should stay pending, got pending students %v", pending)
+	}
+
+	if err := SendDigests(db, sender, conf, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.Sent) != 1 {
+		t.Error("Sent absences should not be sent again")
+	}
+}
+
+// TestSendDigests_Failure tests that the absences of a failed digest stay pending
+func TestSendDigests_Failure(t *testing.T) {
+	db := setupTestDatabase(t)
+	conf := config.MailConfig{Language: "en", DigestHour: 18}
+	now := time.Date(2023, 1, 10, 19, 0, 0, 0, time.Local)
+
+	class := model.Class{Name: "digest", Year: "2023"}
+	db.Create(&class)
+	student := model.Student{Email: "student.failure@gmail.com", FirstName: "John", LastName: "Doe", ClassID: class.ID}
+	db.Create(&student)
+	session := model.Session{Password: "password", ClassID: class.ID}
+	db.Create(&session)
+	createAbsence(t, db, student, session, now.Add(-8*time.Hour))
+
+	if err := SendDigests(db, &mail.FakeSender{Err: errors.New("smtp down")}, conf, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := model.NewAbsenceNotificationModel(db).FindPendingStudents(now); len(pending) != 1 {
+		t.Error("Absences of a failed digest should stay pending")
+	}
+}
+
+// TestSendAlerts tests that an alert is sent once to the teachers of its class having an email
+func TestSendAlerts(t *testing.T) {
+	db := setupTestDatabase(t)
+	conf := config.MailConfig{Language: "en", DigestHour: 18}
+
+	class := model.Class{Name: "alert", Year: "2023"}
+	db.Create(&class)
+	teacher := createAccount(t, db, "teacher.alert@gmail.com", enum.ADMIN)
+	model.NewClassModel(db).AddTeacher(&class, teacher)
+	other := model.Class{Name: "other", Year: "2023"}
+	db.Create(&other)
+	model.NewClassModel(db).AddTeacher(&other, createAccount(t, db, "teacher.other@gmail.com", enum.ADMIN))
+
+	student := model.Student{Email: "student.alert@gmail.com", FirstName: "John", LastName: "Doe", ClassID: class.ID}
+	db.Create(&student)
+	rule := model.AlertRule{Kind: enum.ABSENCE_COUNT, Threshold: 3, Notify: true, Active: true}
+	db.Create(&rule)
+	alert := model.Alert{RuleID: rule.ID, ClassID: class.ID, StudentID: student.ID, Value: 4}
+	db.Omit("Rule", "Class", "Student").Create(&alert)
+
+	sender := &mail.FakeSender{}
+	if err := SendAlerts(db, sender, conf, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.Sent) != 1 {
+		t.Fatalf("A single alert should be sent, got %d", len(sender.Sent))
+	}
+	if to := sender.Sent[0].To; len(to) != 1 || to[0] != "teacher.alert@gmail.com" {
+		t.Errorf("Alert should only be sent to the teachers of the class, got %v", to)
+	}
+
+	if err := SendAlerts(db, sender, conf, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(sender.Sent) != 1 {
+		t.Error("Notified alert should not be sent again")
+	}
+}
@@ -0,0 +1,399 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/utils/audit"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"io"
+	"time"
+)
+
+// deletedAt returns the deletion date of a soft deleted row, nil if it is not deleted
+func deletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Time
+}
+
+// toJustifications converts justifications loaded with their sessions
+func toJustifications(justifications []model.Justification, withStudent bool) []dto.Justification {
+	justificationDtos := make([]dto.Justification, 0, len(justifications))
+	for _, j := range justifications {
+		d := dto.Justification{
+			ID:         j.ID,
+			Reason:     j.Reason,
+			Status:     j.Status,
+			CreatedAt:  j.CreatedAt,
+			ReviewedAt: j.ReviewedAt,
+		}
+		if j.Attendance != nil {
+			d.SessionID = j.Attendance.SessionID
+			if j.Attendance.Session != nil {
+				d.SessionDate = j.Attendance.Session.CreatedAt
+			}
+			if withStudent && j.Attendance.Student != nil {
+				d.Student = &dto.Student{
+					ID:        j.Attendance.Student.ID,
+					Email:     j.Attendance.Student.Email,
+					FirstName: j.Attendance.Student.FirstName,
+					LastName:  j.Attendance.Student.LastName,
+				}
+			}
+		}
+		justificationDtos = append(justificationDtos, d)
+	}
+	return justificationDtos
+}
+
+// exportStudent exports a roster entry with its guardians, attendance and justifications,
+// and adds the entities holding its data to refs
+func exportStudent(db *gorm.DB, s model.Student, refs model.EntityRefs) (*dto.ExportedStudent, error) {
+	attendances, err := model.NewAttendanceModel(db).FindByStudent(s.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	justifications, err := model.NewJustificationModel(db).FindByStudent(s.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	e := dto.ExportedStudent{
+		Student: dto.Student{
+			ID:        s.ID,
+			Email:     s.Email,
+			FirstName: s.FirstName,
+			LastName:  s.LastName,
+		},
+		CreatedAt:      s.CreatedAt,
+		DeletedAt:      deletedAt(s.DeletedAt),
+		ErasedAt:       s.ErasedAt,
+		Guardians:      make([]dto.User, 0),
+		Attendances:    make([]dto.ChildAttendance, 0, len(attendances)),
+		Justifications: toJustifications(justifications, false),
+	}
+	if s.Class != nil {
+		e.Class = dto.TinyClass{ID: s.Class.ID, Name: s.Class.Name, Year: s.Class.Year, TermID: s.Class.TermID}
+	}
+	for _, g := range s.Guardians {
+		e.Guardians = append(e.Guardians, dto.User{ID: g.ID, FirstName: g.FirstName, LastName: g.LastName, Role: g.Role})
+	}
+
+	refs.Add("students", s.ID)
+	for _, a := range attendances {
+		d := dto.ChildAttendance{SessionID: a.SessionID, Status: a.Status, Justified: a.Justified}
+		if a.Session != nil {
+			d.SessionDate = a.Session.CreatedAt
+		}
+		e.Attendances = append(e.Attendances, d)
+		refs.Add("attendances", a.ID)
+	}
+	for _, j := range justifications {
+		refs.Add("justifications", j.ID)
+	}
+	return &e, nil
+}
+
+// exportAuditLogs gets the audit entries about the entities and the ones of the actions made by the actor, if any
+func exportAuditLogs(db *gorm.DB, refs model.EntityRefs, actorID *uint64) ([]dto.AuditLog, error) {
+	entries, err := model.NewAuditLogModel(db).FindBySubject(refs, actorID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	entryDtos := make([]dto.AuditLog, 0, len(entries))
+	for _, e := range entries {
+		entryDtos = append(entryDtos, dto.AuditLog{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			ActorID:   e.ActorID,
+			Tenant:    e.Tenant,
+			Action:    e.Action,
+			Entity:    e.Entity,
+			EntityID:  e.EntityID,
+			Before:    e.Before,
+			After:     e.After,
+			RequestID: e.RequestID,
+		})
+	}
+	return entryDtos, nil
+}
+
+// getUser gets a user, including a deleted one, with its account
+func getUser(db *gorm.DB, userId uint64) (*model.User, error) {
+	u, err := model.NewUserModel(db).GetWithDeleted(userId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if u.Account == nil {
+		return nil, error2.NotFoundError("the user has no account")
+	}
+	return u, nil
+}
+
+// ExportUser exports everything stored about a user: its account, logins, roster entries having its email,
+// the justifications it submitted and the audit entries about it or its actions
+func ExportUser(db *gorm.DB, userId uint64) (*dto.PersonalData, error) {
+	u, err := getUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+	a := u.Account
+	refs := model.EntityRefs{}
+	refs.Add("users_t", u.ID)
+	refs.Add("accounts", a.ID)
+
+	mfaEnabled, err := model.NewMFAModel(db).IsEnabled(a.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	account := dto.ExportedAccount{
+		User: dto.User{
+			ID:         u.ID,
+			Username:   a.Username,
+			Email:      a.Email,
+			FirstName:  u.FirstName,
+			LastName:   u.LastName,
+			Role:       u.Role,
+			Verified:   a.VerifiedAt != nil,
+			MFAEnabled: mfaEnabled,
+		},
+		Service:    a.Service,
+		CreatedAt:  a.CreatedAt,
+		VerifiedAt: a.VerifiedAt,
+		DeletedAt:  deletedAt(u.DeletedAt),
+		ErasedAt:   u.ErasedAt,
+		Identities: make([]dto.ExportedIdentity, 0),
+		Sessions:   make([]dto.LoginSession, 0),
+		Classes:    make([]dto.TinyClass, 0),
+		Children:   make([]dto.Student, 0),
+	}
+
+	identities, err := model.NewIdentityModel(db).FindByAccount(a.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, i := range identities {
+		account.Identities = append(account.Identities, dto.ExportedIdentity{
+			Issuer:      i.Issuer,
+			Subject:     i.Subject,
+			CreatedAt:   i.CreatedAt,
+			LastLoginAt: i.LastLoginAt,
+		})
+		refs.Add("identities", i.ID)
+	}
+
+	tokens, err := model.NewTokenModel(db).FindActiveByUser(u.ID, time.Now())
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, t := range tokens {
+		account.Sessions = append(account.Sessions, dto.LoginSession{
+			ID:         t.Family,
+			UserAgent:  t.UserAgent,
+			IP:         t.IP,
+			LoggedInAt: t.LoggedInAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+		})
+	}
+
+	classes, err := model.NewClassModel(db).FindByTeacher(u.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, cl := range classes {
+		account.Classes = append(account.Classes, dto.TinyClass{ID: cl.ID, Name: cl.Name, Year: cl.Year, TermID: cl.TermID})
+	}
+
+	children, err := model.NewGuardianModel(db).FindChildren(u.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, s := range children {
+		account.Children = append(account.Children, dto.Student{ID: s.ID, FirstName: s.FirstName, LastName: s.LastName})
+	}
+
+	attempts, err := model.NewLoginAttemptModel(db).FindByAccount(a.ID, -1)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	history := make([]dto.LoginAttempt, 0, len(attempts))
+	for _, attempt := range attempts {
+		history = append(history, dto.LoginAttempt{
+			CreatedAt: attempt.CreatedAt,
+			Method:    attempt.Method,
+			IP:        attempt.IP,
+			UserAgent: attempt.UserAgent,
+			Success:   attempt.Success,
+			NewClient: attempt.NewClient,
+		})
+	}
+
+	students, err := model.NewStudentModel(db).FindAllByEmail(a.Email)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	studentDtos := make([]dto.ExportedStudent, 0, len(students))
+	for _, s := range students {
+		e, err := exportStudent(db, s, refs)
+		if err != nil {
+			return nil, err
+		}
+		studentDtos = append(studentDtos, *e)
+	}
+
+	submitted, err := model.NewJustificationModel(db).FindBySubmitter(u.ID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, j := range submitted {
+		refs.Add("justifications", j.ID)
+	}
+
+	auditLogs, err := exportAuditLogs(db, refs, &u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PersonalData{
+		ExportedAt:              time.Now(),
+		Account:                 &account,
+		LoginHistory:            history,
+		Students:                studentDtos,
+		SubmittedJustifications: toJustifications(submitted, true),
+		AuditLogs:               auditLogs,
+	}, nil
+}
+
+// ExportStudent exports everything stored about a roster entry: the student, its attendance, justifications
+// and the audit entries about them
+func ExportStudent(db *gorm.DB, studentId uint64) (*dto.PersonalData, error) {
+	s, err := model.NewStudentModel(db).GetWithDeleted(studentId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	refs := model.EntityRefs{}
+	e, err := exportStudent(db, *s, refs)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs, err := exportAuditLogs(db, refs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PersonalData{
+		ExportedAt:              time.Now(),
+		LoginHistory:            make([]dto.LoginAttempt, 0),
+		Students:                []dto.ExportedStudent{*e},
+		SubmittedJustifications: make([]dto.Justification, 0),
+		AuditLogs:               auditLogs,
+	}, nil
+}
+
+// WriteZip writes an export as a ZIP archive holding a JSON file per section
+func WriteZip(w io.Writer, data *dto.PersonalData) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"account.json", data.Account},
+		{"login_history.json", data.LoginHistory},
+		{"students.json", data.Students},
+		{"submitted_justifications.json", data.SubmittedJustifications},
+		{"audit_logs.json", data.AuditLogs},
+	}
+	for _, file := range files {
+		if file.name == "account.json" && data.Account == nil {
+			continue
+		}
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// EraseUser erases the personal data of a user and of the roster entries having its email,
+// the audit entries about them are kept without the values they recorded
+func EraseUser(db *gorm.DB, userId, eraserId uint64) (*dto.Erasure, error) {
+	if userId == eraserId {
+		return nil, error2.ForbiddenError("you cannot erase your own account")
+	}
+	u, err := getUser(db, userId)
+	if err != nil {
+		return nil, err
+	}
+	if u.ErasedAt != nil {
+		return nil, error2.BadRequestError("the user is already erased", nil)
+	}
+
+	tx := audit.WithAction(db, "erase")
+	now := time.Now()
+	students, err := model.NewStudentModel(tx).FindAllByEmail(u.Account.Email)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	refs, err := model.NewUserModel(tx).Erase(u, now)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	for _, s := range students {
+		studentRefs, err := model.NewStudentModel(tx).Erase(s.ID, now)
+		if err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+		refs.Merge(studentRefs)
+	}
+	if err := model.NewAuditLogModel(tx).Scrub(refs); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	// Recorded after the scrub, it only holds the ids of the erased entities
+	if err := audit.Record(tx, "erase", "users_t", fmt.Sprint(u.ID), model.AuditChanges{"erased": refs}); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.Erasure{ErasedAt: now, Users: 1, Students: len(students)}, nil
+}
+
+// EraseStudent erases the personal data of a roster entry, its attendance is kept for the statistics of its class
+// and the audit entries about it are kept without the values they recorded
+func EraseStudent(db *gorm.DB, studentId uint64) (*dto.Erasure, error) {
+	s, err := model.NewStudentModel(db).GetWithDeleted(studentId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if s.ErasedAt != nil {
+		return nil, error2.BadRequestError("the student is already erased", nil)
+	}
+
+	tx := audit.WithAction(db, "erase")
+	now := time.Now()
+	refs, err := model.NewStudentModel(tx).Erase(s.ID, now)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	if err := model.NewAuditLogModel(tx).Scrub(refs); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	// Recorded after the scrub, it only holds the ids of the erased entities
+	if err := audit.Record(tx, "erase", "students", fmt.Sprint(s.ID), model.AuditChanges{"erased": refs}); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.Erasure{ErasedAt: now, Students: 1}, nil
+}
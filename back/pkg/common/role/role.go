@@ -0,0 +1,172 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+)
+
+// toRole converts a role model to a dto.Role
+func toRole(r model.Role) dto.Role {
+	return dto.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: r.Permissions,
+		RequireMFA:  r.RequireMFA,
+		Builtin:     r.Name.IsBuiltin(),
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+// validatePermissions checks that every permission is a named permission
+func validatePermissions(permissions []enum.Permission) error {
+	for _, p := range permissions {
+		if !p.IsValid() {
+			return error2.BadRequestError(fmt.Sprintf("unknown permission '%s'", p), nil)
+		}
+	}
+	return nil
+}
+
+// EnsureRole checks that a role exists
+func EnsureRole(tx *gorm.DB, name enum.Role) error {
+	_, err := model.NewRoleModel(tx).GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return error2.BadRequestError(fmt.Sprintf("role '%s' does not exist", name), nil)
+	}
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
+
+// GetRoles gets all roles with their permissions
+func GetRoles(tx *gorm.DB) (*dto.RoleList, error) {
+	roles, err := model.NewRoleModel(tx).FindAll()
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	roleDtos := make([]dto.Role, 0)
+	for _, r := range roles {
+		roleDtos = append(roleDtos, toRole(r))
+	}
+
+	return &dto.RoleList{Roles: roleDtos}, nil
+}
+
+// GetPermissions gets the permissions a role can be granted
+func GetPermissions() *dto.PermissionList {
+	return &dto.PermissionList{Permissions: append(enum.Permissions(), enum.ALL_PERMISSIONS)}
+}
+
+// ensureGrantable checks that the user managing a role holds every permission it grants
+func ensureGrantable(granterPermissions model.Permissions, permissions model.Permissions) error {
+	for _, p := range permissions {
+		if !granterPermissions.Grants(model.Permissions{p}) {
+			return error2.ForbiddenError(fmt.Sprintf("you cannot grant the permission '%s'", p))
+		}
+	}
+	return nil
+}
+
+// CreateRole creates a new role, the creator can only grant the permissions it holds
+func CreateRole(tx *gorm.DB, req dto.CreateRole, creatorPermissions model.Permissions) (*dto.Role, error) {
+	if err := validatePermissions(req.Permissions); err != nil {
+		return nil, err
+	}
+	if err := ensureGrantable(creatorPermissions, req.Permissions); err != nil {
+		return nil, err
+	}
+
+	roleModel := model.NewRoleModel(tx)
+	if _, err := roleModel.GetByName(req.Name); err == nil {
+		return nil, error2.BadRequestError(fmt.Sprintf("role '%s' already exists", req.Name), nil)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	r := model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+		RequireMFA:  req.RequireMFA,
+	}
+	if err := roleModel.Create(&r); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	role := toRole(r)
+	return &role, nil
+}
+
+// UpdateRole updates the description, the permissions and the second factor requirement of a role,
+// only the second factor requirement of the superadmin role can be edited so that the institution is never locked out.
+// The updater must hold every permission of the role, the ones it has and the ones it is given.
+func UpdateRole(tx *gorm.DB, req dto.UpdateRole, updaterPermissions model.Permissions) error {
+	if req.Name == enum.SUPERADMIN && (req.Description != "" || len(req.Permissions) > 0) {
+		return error2.ForbiddenError("only the two-factor requirement of the superadmin role can be edited")
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return err
+	}
+
+	roleModel := model.NewRoleModel(tx)
+	r, err := roleModel.GetByName(req.Name)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !updaterPermissions.Grants(r.Permissions) {
+		return error2.ForbiddenError(fmt.Sprintf("you cannot update the role '%s'", req.Name))
+	}
+	if err := ensureGrantable(updaterPermissions, req.Permissions); err != nil {
+		return err
+	}
+
+	if req.Description != "" {
+		r.Description = req.Description
+	}
+	if len(req.Permissions) > 0 {
+		r.Permissions = req.Permissions
+	}
+	if req.RequireMFA != nil {
+		r.RequireMFA = *req.RequireMFA
+	}
+
+	if err := roleModel.Update(r).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// DeleteRole deletes a role which is neither builtin nor given to a user
+func DeleteRole(tx *gorm.DB, name enum.Role) error {
+	if name.IsBuiltin() {
+		return error2.ForbiddenError(fmt.Sprintf("the builtin role '%s' can not be deleted", name))
+	}
+
+	roleModel := model.NewRoleModel(tx)
+	count, err := roleModel.CountUsers(name)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if count > 0 {
+		return error2.BadRequestError(fmt.Sprintf("role '%s' is given to %d users", name, count), nil)
+	}
+
+	res := roleModel.Delete(name)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("role '%s' not found", name))
+	}
+
+	return nil
+}
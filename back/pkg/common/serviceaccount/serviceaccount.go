@@ -0,0 +1,192 @@
+package serviceaccount
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/pkg/common/role"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/utils/apikey"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"time"
+)
+
+// emailDomain is the reserved domain of the placeholder emails of the service accounts, which receive no email
+const emailDomain = "service.invalid"
+
+// toServiceAccount converts an account model to a dto.ServiceAccount
+func toServiceAccount(a model.Account) dto.ServiceAccount {
+	return dto.ServiceAccount{
+		ID:        a.User.ID,
+		Username:  a.Username,
+		Name:      a.User.FirstName,
+		Role:      a.User.Role,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+// toAPIKey converts an API key model to a dto.APIKey
+func toAPIKey(k model.APIKey) dto.APIKey {
+	return dto.APIKey{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Permissions: k.Permissions,
+		ClassID:     k.ClassID,
+		Tenant:      k.Tenant,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		LastUsedIP:  k.LastUsedIP,
+		CreatedAt:   k.CreatedAt,
+		CreatedBy:   k.CreatedBy,
+	}
+}
+
+// findServiceAccount finds the user of a service account
+func findServiceAccount(tx *gorm.DB, userID uint64) (*model.User, error) {
+	u := model.User{}
+	err := model.NewUserModel(tx).FindByUserID(userID, &u).Error
+	if err == nil && (u.Account == nil || !u.Account.Service) {
+		err = gorm.ErrRecordNotFound
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, error2.NotFoundError("service account not found")
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	return &u, nil
+}
+
+// GetServiceAccounts gets the service accounts
+func GetServiceAccounts(tx *gorm.DB) (*dto.ServiceAccountList, error) {
+	accountModel := model.AccountModel{Tx: tx}
+	accounts, err := accountModel.FindServiceAccounts()
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	serviceAccounts := make([]dto.ServiceAccount, 0, len(accounts))
+	for _, a := range accounts {
+		serviceAccounts = append(serviceAccounts, toServiceAccount(a))
+	}
+	return &dto.ServiceAccountList{ServiceAccounts: serviceAccounts}, nil
+}
+
+// CreateServiceAccount creates a service account, it has no password and authenticates with its API keys only
+func CreateServiceAccount(tx *gorm.DB, req dto.CreateServiceAccount) (*dto.ServiceAccount, error) {
+	if err := role.EnsureRole(tx, req.Role); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	a := model.Account{
+		Email:      fmt.Sprintf("%s@%s", req.Username, emailDomain),
+		Username:   req.Username,
+		VerifiedAt: &now,
+		Service:    true,
+		User: model.User{
+			FirstName: req.Name,
+			Role:      req.Role,
+		},
+	}
+	accountModel := model.AccountModel{Tx: tx}
+	if err := accountModel.Create(&a); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	serviceAccount := toServiceAccount(a)
+	return &serviceAccount, nil
+}
+
+// DeleteServiceAccount deletes a service account, its API keys are refused from then on
+func DeleteServiceAccount(tx *gorm.DB, userID uint64) error {
+	u, err := findServiceAccount(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	accountModel := model.AccountModel{Tx: tx}
+	if err := accountModel.Delete(model.Account{ID: u.AccountID, User: *u}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	return nil
+}
+
+// GetAPIKeys gets the API keys of a service account
+func GetAPIKeys(tx *gorm.DB, userID uint64) (*dto.APIKeyList, error) {
+	if _, err := findServiceAccount(tx, userID); err != nil {
+		return nil, err
+	}
+
+	keys, err := model.NewAPIKeyModel(tx).FindByUser(userID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	keyDtos := make([]dto.APIKey, 0, len(keys))
+	for _, k := range keys {
+		keyDtos = append(keyDtos, toAPIKey(k))
+	}
+	return &dto.APIKeyList{Keys: keyDtos}, nil
+}
+
+// CreateAPIKey creates an API key of a service account, the key is only returned here and stored hashed.
+// The creator can only grant the permissions it holds.
+func CreateAPIKey(tx *gorm.DB, req dto.CreateAPIKey, creatorID uint64, creatorPermissions model.Permissions) (*dto.CreatedAPIKey, error) {
+	if _, err := findServiceAccount(tx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	for _, p := range req.Permissions {
+		if !p.IsValid() {
+			return nil, error2.BadRequestError(fmt.Sprintf("unknown permission '%s'", p), nil)
+		}
+		if !creatorPermissions.Grants(model.Permissions{p}) {
+			return nil, error2.ForbiddenError(fmt.Sprintf("you cannot grant the permission '%s'", p))
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, error2.BadRequestError("expiration date must be in the future", nil)
+	}
+	if req.ClassID != nil {
+		if _, err := model.NewClassModel(tx).GetByID(*req.ClassID); err != nil {
+			return nil, error2.FromDatabaseError(err)
+		}
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return nil, error2.InternalServerError("", err)
+	}
+
+	k := model.APIKey{
+		Name:        req.Name,
+		UserID:      req.UserID,
+		Prefix:      prefix,
+		Hash:        hash,
+		Permissions: req.Permissions,
+		ClassID:     req.ClassID,
+		Tenant:      req.Tenant,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   creatorID,
+	}
+	if err := model.NewAPIKeyModel(tx).Create(&k); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.CreatedAPIKey{APIKey: toAPIKey(k), Key: key}, nil
+}
+
+// RevokeAPIKey deletes an API key of a service account
+func RevokeAPIKey(tx *gorm.DB, userID, keyID uint64) error {
+	deleted, err := model.NewAPIKeyModel(tx).Delete(userID, keyID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if !deleted {
+		return error2.NotFoundError("API key not found")
+	}
+	return nil
+}
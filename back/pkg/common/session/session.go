@@ -1,9 +1,16 @@
 package session
 
 import (
+	"errors"
 	"fmt"
+	"gin-template/pkg/common/alert"
+	"gin-template/pkg/common/class"
+	"gin-template/pkg/common/notification"
+	"gin-template/pkg/common/webhook"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	"gin-template/utils/audit"
 	error2 "gin-template/utils/error"
 	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
@@ -56,6 +63,10 @@ func GetSessionByID(tx *gorm.DB, sessionID uuid.UUID) (*dto.Session, error) {
 
 // CreateSession creates a new session for a class
 func CreateSession(tx *gorm.DB, session dto.CreateSession) (*dto.TinySession, error) {
+	if err := class.EnsureWritable(tx, session.ClassID); err != nil {
+		return nil, err
+	}
+
 	sessionModel := model.NewSessionModel(tx)
 
 	s := model.Session{
@@ -77,26 +88,89 @@ func CreateSession(tx *gorm.DB, session dto.CreateSession) (*dto.TinySession, er
 
 // CloseSession closes a session
 func CloseSession(tx *gorm.DB, classID uint64, sessionID uuid.UUID) error {
+	if err := class.EnsureWritable(tx, classID); err != nil {
+		return err
+	}
+
 	sessionModel := model.NewSessionModel(tx)
 
+	current := model.Session{ID: sessionID}
+	if err := sessionModel.GetByClassID(classID, &current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.NotFoundError(fmt.Sprintf("session '%s' not found for class '%d'", sessionID, classID))
+		}
+		return error2.FromDatabaseError(err)
+	}
+
+	// Closing a closed session again must not notify the absences twice
+	if current.IsClosed {
+		return nil
+	}
+
 	s := model.Session{ID: sessionID, ClassID: classID, IsClosed: true}
-	tx = sessionModel.Close(&s)
-	if tx.Error != nil {
-		return error2.FromDatabaseError(tx.Error)
+	if err := sessionModel.Close(&s).Error; err != nil {
+		return error2.FromDatabaseError(err)
 	}
 
-	if tx.RowsAffected == 0 {
-		return error2.NotFoundError(fmt.Sprintf("session '%s' not found for class '%d'", sessionID, classID))
+	attendanceModel := model.NewAttendanceModel(tx)
+	absents, err := attendanceModel.MarkAbsent(sessionID, classID)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	present, err := attendanceModel.CountByStatus(sessionID, enum.PRESENT)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := webhook.Dispatch(tx, enum.SESSION_CLOSED, dto.SessionClosedEvent{
+		SessionID: sessionID,
+		ClassID:   classID,
+		Present:   int(present),
+		Absent:    len(absents),
+	}); err != nil {
+		return err
+	}
+
+	if err := notification.QueueAbsences(tx, classID, sessionID, absents); err != nil {
+		return err
+	}
+
+	if err := alert.Evaluate(tx, classID); err != nil {
+		return err
+	}
+
+	for _, a := range absents {
+		if err := webhook.Dispatch(tx, enum.STUDENT_ABSENT, dto.StudentAbsentEvent{
+			SessionID: sessionID,
+			ClassID:   classID,
+			Student: dto.Student{
+				ID:        a.Student.ID,
+				Email:     a.Student.Email,
+				FirstName: a.Student.FirstName,
+				LastName:  a.Student.LastName,
+			},
+		}); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// DeleteSession deletes a session
+// DeleteSession deletes a session, unless its class belongs to an archived term
 func DeleteSession(tx *gorm.DB, sessionID uuid.UUID) error {
 	sessionModel := model.NewSessionModel(tx)
 
 	s := model.Session{ID: sessionID}
+	if err := sessionModel.GetByID(&s).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := class.EnsureWritable(tx, s.ClassID); err != nil {
+		return err
+	}
+
 	if err := sessionModel.Delete(&s); err != nil {
 		return error2.FromDatabaseError(err)
 	}
@@ -106,6 +180,10 @@ func DeleteSession(tx *gorm.DB, sessionID uuid.UUID) error {
 
 // DeleteSessionsByClassID deletes all sessions of a class
 func DeleteSessionsByClassID(tx *gorm.DB, classID uint64) error {
+	if err := class.EnsureWritable(tx, classID); err != nil {
+		return err
+	}
+
 	sessionModel := model.NewSessionModel(tx)
 
 	if err := sessionModel.DeleteAll(classID); err != nil {
@@ -115,30 +193,100 @@ func DeleteSessionsByClassID(tx *gorm.DB, classID uint64) error {
 	return nil
 }
 
-// AddStudentToSession adds a student to a session
+// getClassSessionAndStudent checks that both the session and the student belong to the class
+func getClassSessionAndStudent(tx *gorm.DB, sessionID uuid.UUID, studentID, classID uint64) error {
+	sessionModel := model.NewSessionModel(tx)
+	if err := sessionModel.GetByClassID(classID, &model.Session{ID: sessionID}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.NotFoundError(fmt.Sprintf("session '%s' not found for class '%d'", sessionID, classID))
+		}
+		return error2.FromDatabaseError(err)
+	}
+
+	studentModel := model.NewStudentModel(tx)
+	if err := studentModel.GetByClassID(classID, &model.Student{ID: studentID}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.NotFoundError(fmt.Sprintf("student '%d' not found for class '%d'", studentID, classID))
+		}
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// GetSessionAttendance gets the attendance of the students of a session
+func GetSessionAttendance(tx *gorm.DB, classID uint64, sessionID uuid.UUID) (*dto.AttendanceList, error) {
+	sessionModel := model.NewSessionModel(tx)
+	if err := sessionModel.GetByClassID(classID, &model.Session{ID: sessionID}).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	attendanceModel := model.NewAttendanceModel(tx)
+	attendances, err := attendanceModel.FindBySession(sessionID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	attendanceDtos := make([]dto.Attendance, 0)
+	for _, a := range attendances {
+		attendanceDtos = append(attendanceDtos, dto.Attendance{
+			Student: dto.Student{
+				ID:        a.Student.ID,
+				Email:     a.Student.Email,
+				FirstName: a.Student.FirstName,
+				LastName:  a.Student.LastName,
+			},
+			Status:    a.Status,
+			Manual:    a.Manual,
+			UpdatedAt: a.UpdatedAt,
+		})
+	}
+
+	return &dto.AttendanceList{Attendances: attendanceDtos}, nil
+}
+
+// AddStudentToSession manually marks a student of the class as present to a session
 func AddStudentToSession(tx *gorm.DB, sessionID uuid.UUID, studentID, classID uint64) error {
-	//userModel := model.NewUserModel(tx)
-	//
-	//if err := userModel.AddUserToSession(&model.User{ID: studentID, ClassID: &classID}, &model.Session{
-	//	ID:      sessionID,
-	//	ClassID: classID,
-	//}); err != nil {
-	//	return error2.FromDatabaseError(err)
-	//}
+	if err := class.EnsureWritable(tx, classID); err != nil {
+		return err
+	}
+
+	if err := getClassSessionAndStudent(tx, sessionID, studentID, classID); err != nil {
+		return err
+	}
+
+	attendanceModel := model.NewAttendanceModel(audit.WithAction(tx, "attendance"))
+	if err := attendanceModel.Upsert(&model.Attendance{
+		SessionID: sessionID,
+		StudentID: studentID,
+		Status:    enum.PRESENT,
+		Manual:    true,
+	}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
 
 	return nil
 }
 
-// RemoveStudentFromSession removes a student from a session
+// RemoveStudentFromSession manually removes the attendance of a student to a session
 func RemoveStudentFromSession(tx *gorm.DB, sessionID uuid.UUID, studentID, classID uint64) error {
-	//userModel := model.NewUserModel(tx)
-	//
-	//if err := userModel.RemoveUserFromSession(&model.User{ID: studentID, ClassID: &classID}, &model.Session{
-	//	ID:      sessionID,
-	//	ClassID: classID,
-	//}); err != nil {
-	//	return error2.FromDatabaseError(err)
-	//}
+	if err := class.EnsureWritable(tx, classID); err != nil {
+		return err
+	}
+
+	if err := getClassSessionAndStudent(tx, sessionID, studentID, classID); err != nil {
+		return err
+	}
+
+	attendanceModel := model.NewAttendanceModel(audit.WithAction(tx, "attendance"))
+	res := attendanceModel.Delete(sessionID, studentID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("student '%d' has no attendance to session '%s'", studentID, sessionID))
+	}
 
 	return nil
 }
@@ -163,3 +311,45 @@ func VerifySession(tx *gorm.DB, sessionID uuid.UUID, password string) (*model.Se
 
 	return &s, nil
 }
+
+// CheckIn marks the student of a user as present to an open session of its class,
+// the student is the one with the email of the account which must be verified
+func CheckIn(tx *gorm.DB, sessionID uuid.UUID, password string, userID uint64) error {
+	userModel := model.NewUserModel(tx)
+	u := model.User{}
+	if err := userModel.FindByUserID(userID, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if u.Account == nil || u.Account.VerifiedAt == nil {
+		return error2.ForbiddenError("verify your email before checking in")
+	}
+
+	s, err := VerifySession(tx, sessionID, password)
+	if err != nil {
+		return err
+	}
+
+	studentModel := model.NewStudentModel(tx)
+	student := model.Student{}
+	if err := studentModel.FindByEmail(u.Account.Email, &student).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return error2.ForbiddenError("you are not a student of the class of this session")
+		}
+		return error2.FromDatabaseError(err)
+	}
+	if student.ClassID != s.ClassID {
+		return error2.ForbiddenError("you are not a student of the class of this session")
+	}
+
+	attendanceModel := model.NewAttendanceModel(audit.WithAction(tx, "check-in"))
+	if err := attendanceModel.Upsert(&model.Attendance{
+		SessionID: sessionID,
+		StudentID: student.ID,
+		Status:    enum.PRESENT,
+	}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// setupTestDatabase sets up the tables of the sessions and their classes in the test database
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Migrator().DropTable(&model.Session{}, "guardian_students", &model.Student{}, "class_teachers", &model.Class{},
+		&model.Holiday{}, &model.Term{}, &model.User{}, &model.Account{})
+	if err := db.AutoMigrate(&model.Account{}, &model.User{}, &model.Term{}, &model.Holiday{}, &model.Class{},
+		&model.Student{}, &model.Session{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestArchivedTermSessions tests that the sessions of a class of an archived term cannot be edited
+func TestArchivedTermSessions(t *testing.T) {
+	db := setupTestDatabase(t)
+
+	term := model.Term{
+		Name:      "2022-2023",
+		StartDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+	db.Create(&term)
+	class := model.Class{Name: "archived", Year: term.Name, TermID: &term.ID}
+	db.Create(&class)
+	student := model.Student{Email: "student.archived@gmail.com", FirstName: "John", LastName: "Doe", ClassID: class.ID}
+	db.Create(&student)
+
+	s, err := CreateSession(db, dto.CreateSession{ClassID: class.ID, Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := model.NewTermModel(db).Archive(&term).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for name, err := range map[string]error{
+		"created": func() error {
+			_, err := CreateSession(db, dto.CreateSession{ClassID: class.ID, Password: "password"})
+			return err
+		}(),
+		"closed":          CloseSession(db, class.ID, s.ID),
+		"deleted":         DeleteSession(db, s.ID),
+		"deleted in bulk": DeleteSessionsByClassID(db, class.ID),
+		"given a student": AddStudentToSession(db, s.ID, student.ID, class.ID),
+		"left by student": RemoveStudentFromSession(db, s.ID, student.ID, class.ID),
+	} {
+		if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusForbidden {
+			t.Errorf("Session of an archived term should not be %s, got %v", name, err)
+		}
+	}
+
+	if _, err := GetSessionByID(db, s.ID); err != nil {
+		t.Error("Session of an archived term should still be read", err)
+	}
+}
@@ -0,0 +1,188 @@
+package term
+
+import (
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gorm.io/gorm"
+	"time"
+)
+
+// toTinyTerm converts a term model to a dto.TinyTerm
+func toTinyTerm(t model.Term) dto.TinyTerm {
+	return dto.TinyTerm{
+		ID:        t.ID,
+		Name:      t.Name,
+		StartDate: t.StartDate,
+		EndDate:   t.EndDate,
+		Archived:  t.Archived,
+	}
+}
+
+// GetTermByID gets a term by ID with its holidays
+func GetTermByID(tx *gorm.DB, termId uint64) (*dto.Term, error) {
+	termModel := model.NewTermModel(tx)
+	t, err := termModel.GetByID(termId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	holidays := make([]dto.Holiday, 0)
+	for _, h := range t.Holidays {
+		holidays = append(holidays, dto.Holiday{
+			ID:        h.ID,
+			Name:      h.Name,
+			StartDate: h.StartDate,
+			EndDate:   h.EndDate,
+		})
+	}
+
+	return &dto.Term{
+		TinyTerm: toTinyTerm(*t),
+		Holidays: holidays,
+	}, nil
+}
+
+// GetAllTerms gets all terms
+func GetAllTerms(tx *gorm.DB) (*dto.TermList, error) {
+	termModel := model.NewTermModel(tx)
+	terms, err := termModel.FindAll()
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	termDtos := make([]dto.TinyTerm, 0)
+	for _, t := range terms {
+		termDtos = append(termDtos, toTinyTerm(t))
+	}
+
+	return &dto.TermList{Terms: termDtos}, nil
+}
+
+// CreateTerm creates a new term with its holidays
+func CreateTerm(tx *gorm.DB, req dto.CreateTerm) (*dto.TinyTerm, error) {
+	holidays := make([]model.Holiday, 0)
+	for _, h := range req.Holidays {
+		if h.StartDate.Before(req.StartDate) || h.EndDate.After(req.EndDate) {
+			return nil, error2.BadRequestError(fmt.Sprintf("holiday '%s' is outside of the term", h.Name), nil)
+		}
+		holidays = append(holidays, model.Holiday{
+			Name:      h.Name,
+			StartDate: h.StartDate,
+			EndDate:   h.EndDate,
+		})
+	}
+
+	termModel := model.NewTermModel(tx)
+	t := model.Term{
+		Name:      req.Name,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Holidays:  holidays,
+	}
+	if err := termModel.Create(&t); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	res := toTinyTerm(t)
+	return &res, nil
+}
+
+// UpdateTerm updates a term that is not archived, its holidays must stay inside its dates
+func UpdateTerm(tx *gorm.DB, req dto.UpdateTerm) error {
+	termModel := model.NewTermModel(tx)
+	t, err := termModel.GetByID(req.Id)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if t.Archived {
+		return error2.ForbiddenError("term is archived and is read-only")
+	}
+
+	start, end := t.StartDate, t.EndDate
+	if !req.StartDate.IsZero() {
+		start = req.StartDate
+	}
+	if !req.EndDate.IsZero() {
+		end = req.EndDate
+	}
+	if !end.After(start) {
+		return error2.BadRequestError("end date must be after start date", nil)
+	}
+
+	// The holidays already added must stay inside the new dates
+	for _, h := range t.Holidays {
+		if h.StartDate.Before(start) || h.EndDate.After(end) {
+			return error2.BadRequestError(fmt.Sprintf("holiday '%s' would be outside of the term", h.Name), nil)
+		}
+	}
+
+	if err = termModel.Update(&model.Term{
+		ID:        req.Id,
+		Name:      req.Name,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// ArchiveTerm archives a term once it is over, making its classes read-only
+func ArchiveTerm(tx *gorm.DB, termId uint64) error {
+	termModel := model.NewTermModel(tx)
+	t, err := termModel.GetByID(termId)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if t.Archived {
+		return error2.BadRequestError("term is already archived", nil)
+	}
+
+	if t.EndDate.After(time.Now()) {
+		return error2.BadRequestError("only past terms can be archived", nil)
+	}
+
+	if err = termModel.Archive(t).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// AddHolidayToTerm adds a holiday to a term that is not archived
+func AddHolidayToTerm(tx *gorm.DB, req dto.AddHolidayToTerm) (*dto.Holiday, error) {
+	termModel := model.NewTermModel(tx)
+	t, err := termModel.GetByID(req.TermId)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	if t.Archived {
+		return nil, error2.ForbiddenError("term is archived and is read-only")
+	}
+
+	if req.Holiday.StartDate.Before(t.StartDate) || req.Holiday.EndDate.After(t.EndDate) {
+		return nil, error2.BadRequestError("holiday is outside of the term", nil)
+	}
+
+	h := model.Holiday{
+		Name:      req.Holiday.Name,
+		StartDate: req.Holiday.StartDate,
+		EndDate:   req.Holiday.EndDate,
+	}
+	if err = termModel.AddHoliday(t, &h); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	return &dto.Holiday{
+		ID:        h.ID,
+		Name:      h.Name,
+		StartDate: h.StartDate,
+		EndDate:   h.EndDate,
+	}, nil
+}
@@ -0,0 +1,110 @@
+package term
+
+import (
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	error2 "gin-template/utils/error"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// setupTestDatabase sets up the tables of the terms in the test database
+func setupTestDatabase(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Migrator().DropTable(&model.Holiday{}, &model.Term{})
+	if err := db.AutoMigrate(&model.Term{}, &model.Holiday{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// date returns the midnight of a day
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// createTerm creates a term with a holiday in december
+func createTerm(t *testing.T, db *gorm.DB) *dto.TinyTerm {
+	term, err := CreateTerm(db, dto.CreateTerm{
+		Name:      "2022-2023",
+		StartDate: date(2022, 9, 1),
+		EndDate:   date(2023, 7, 1),
+		Holidays:  []dto.Holiday{{Name: "christmas", StartDate: date(2022, 12, 17), EndDate: date(2023, 1, 2)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return term
+}
+
+// TestUpdateTerm_Holidays tests that the dates of a term cannot leave its holidays out
+func TestUpdateTerm_Holidays(t *testing.T) {
+	db := setupTestDatabase(t)
+	term := createTerm(t, db)
+
+	err := UpdateTerm(db, dto.UpdateTerm{Id: term.ID, EndDate: date(2022, 12, 20)})
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusBadRequest {
+		t.Errorf("Term should not end during its holiday, got %v", err)
+	}
+	err = UpdateTerm(db, dto.UpdateTerm{Id: term.ID, StartDate: date(2023, 1, 1)})
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusBadRequest {
+		t.Errorf("Term should not start after its holiday, got %v", err)
+	}
+
+	if err := UpdateTerm(db, dto.UpdateTerm{Id: term.ID, StartDate: date(2022, 9, 5), EndDate: date(2023, 6, 30)}); err != nil {
+		t.Error("Term keeping its holiday should be updated", err)
+	}
+	updated, _ := GetTermByID(db, term.ID)
+	if !updated.StartDate.Equal(date(2022, 9, 5)) || !updated.EndDate.Equal(date(2023, 6, 30)) {
+		t.Errorf("Term dates are not updated, got %v %v", updated.StartDate, updated.EndDate)
+	}
+}
+
+// TestArchiveTerm tests that only past terms are archived and that archived terms are read-only
+func TestArchiveTerm(t *testing.T) {
+	db := setupTestDatabase(t)
+	term := createTerm(t, db)
+
+	current, err := CreateTerm(db, dto.CreateTerm{
+		Name:      "current",
+		StartDate: time.Now().AddDate(0, -1, 0),
+		EndDate:   time.Now().AddDate(0, 6, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ArchiveTerm(db, current.ID); err == nil {
+		t.Error("Term not over should not be archived")
+	}
+
+	if err := ArchiveTerm(db, term.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ArchiveTerm(db, term.ID); err == nil {
+		t.Error("Term should not be archived twice")
+	}
+
+	err = UpdateTerm(db, dto.UpdateTerm{Id: term.ID, Name: "renamed"})
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusForbidden {
+		t.Errorf("Archived term should not be updated, got %v", err)
+	}
+	_, err = AddHolidayToTerm(db, dto.AddHolidayToTerm{
+		TermId:  term.ID,
+		Holiday: dto.Holiday{Name: "winter", StartDate: date(2023, 2, 11), EndDate: date(2023, 2, 26)},
+	})
+	if e, ok := err.(*error2.MyError); !ok || e.Code != http.StatusForbidden {
+		t.Errorf("Holiday should not be added to an archived term, got %v", err)
+	}
+}
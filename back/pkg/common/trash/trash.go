@@ -0,0 +1,238 @@
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/utils/audit"
+	error2 "gin-template/utils/error"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// GetDeletedClasses gets the soft deleted classes
+func GetDeletedClasses(tx *gorm.DB, req dto.TrashQueryParams) (*dto.DeletedClassList, error) {
+	classModel := model.NewClassModel(tx)
+	var classes []model.Class
+	if err := classModel.FindDeleted(&classes, req.Page, req.PageSize).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	classDtos := make([]dto.DeletedClass, 0)
+	for _, cl := range classes {
+		classDtos = append(classDtos, dto.DeletedClass{
+			TinyClass: dto.TinyClass{
+				ID:     cl.ID,
+				Name:   cl.Name,
+				Year:   cl.Year,
+				TermID: cl.TermID,
+			},
+			DeletedAt: cl.DeletedAt.Time,
+		})
+	}
+
+	return &dto.DeletedClassList{Classes: classDtos}, nil
+}
+
+// GetDeletedSessions gets the soft deleted sessions
+func GetDeletedSessions(tx *gorm.DB, req dto.TrashQueryParams) (*dto.DeletedSessionList, error) {
+	sessionModel := model.NewSessionModel(tx)
+	var sessions []model.Session
+	if err := sessionModel.FindDeleted(&sessions, req.ClassID, req.Page, req.PageSize).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	sessionDtos := make([]dto.DeletedSession, 0)
+	for _, s := range sessions {
+		sessionDtos = append(sessionDtos, dto.DeletedSession{
+			TinySession: dto.TinySession{
+				ID:        s.ID,
+				IsClosed:  s.IsClosed,
+				CreatedAt: s.CreatedAt,
+				UpdatedAt: s.UpdatedAt,
+			},
+			ClassID:   s.ClassID,
+			DeletedAt: s.DeletedAt.Time,
+		})
+	}
+
+	return &dto.DeletedSessionList{Sessions: sessionDtos}, nil
+}
+
+// GetDeletedStudents gets the soft deleted students
+func GetDeletedStudents(tx *gorm.DB, req dto.TrashQueryParams) (*dto.DeletedStudentList, error) {
+	studentModel := model.NewStudentModel(tx)
+	var students []model.Student
+	if err := studentModel.FindDeleted(&students, req.ClassID, req.Page, req.PageSize).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	studentDtos := make([]dto.DeletedStudent, 0)
+	for _, st := range students {
+		studentDtos = append(studentDtos, dto.DeletedStudent{
+			Student: dto.Student{
+				ID:        st.ID,
+				Email:     st.Email,
+				FirstName: st.FirstName,
+				LastName:  st.LastName,
+			},
+			ClassID:   st.ClassID,
+			DeletedAt: st.DeletedAt.Time,
+		})
+	}
+
+	return &dto.DeletedStudentList{Students: studentDtos}, nil
+}
+
+// GetDeletedUsers gets the soft deleted users
+func GetDeletedUsers(tx *gorm.DB, req dto.TrashQueryParams) (*dto.DeletedUserList, error) {
+	userModel := model.NewUserModel(tx)
+	var users []model.User
+	if err := userModel.FindDeleted(&users, req.Page, req.PageSize).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	userDtos := make([]dto.DeletedUser, 0)
+	for _, u := range users {
+		d := dto.DeletedUser{
+			User: dto.User{
+				ID:        u.ID,
+				FirstName: u.FirstName,
+				LastName:  u.LastName,
+				Role:      u.Role,
+			},
+			DeletedAt: u.DeletedAt.Time,
+		}
+		if u.Account != nil {
+			d.Email = u.Account.Email
+			d.Username = u.Account.Username
+		}
+		userDtos = append(userDtos, d)
+	}
+
+	return &dto.DeletedUserList{Users: userDtos}, nil
+}
+
+// ensureClassExists returns an error if the class of a restored item is still deleted
+func ensureClassExists(tx *gorm.DB, classID uint64) error {
+	err := tx.Where("id = ?", classID).First(&model.Class{}).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return error2.BadRequestError(fmt.Sprintf("class '%d' is deleted, restore it first", classID), nil)
+	}
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RestoreClass restores a deleted class with the students, sessions and timetable deleted with it
+func RestoreClass(tx *gorm.DB, classID uint64) error {
+	classModel := model.NewClassModel(tx)
+	if err := classModel.Restore(classID).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RestoreSession restores a deleted session
+func RestoreSession(tx *gorm.DB, sessionID uuid.UUID) error {
+	sessionModel := model.NewSessionModel(tx)
+	s := model.Session{ID: sessionID}
+	if err := tx.Unscoped().Select("class_id").Where("id = ?", sessionID).First(&s).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := ensureClassExists(tx, s.ClassID); err != nil {
+		return err
+	}
+
+	if err := sessionModel.Restore(&s).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RestoreClassSessions restores the sessions of a class removed by the last deletion
+func RestoreClassSessions(tx *gorm.DB, classID uint64) error {
+	if err := ensureClassExists(tx, classID); err != nil {
+		return err
+	}
+
+	sessionModel := model.NewSessionModel(tx)
+	res := sessionModel.RestoreLastDeleted(classID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("no deleted session found for class '%d'", classID))
+	}
+
+	return nil
+}
+
+// RestoreStudent restores a deleted student
+func RestoreStudent(tx *gorm.DB, studentID uint64) error {
+	studentModel := model.NewStudentModel(tx)
+	st := model.Student{ID: studentID}
+	if err := tx.Unscoped().Select("class_id").Where("id = ?", studentID).First(&st).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if err := ensureClassExists(tx, st.ClassID); err != nil {
+		return err
+	}
+
+	if err := studentModel.Restore(&st).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// RestoreUser restores a deleted user and its account
+func RestoreUser(tx *gorm.DB, userID uint64) error {
+	userModel := model.NewUserModel(tx)
+	if err := userModel.Restore(&model.User{ID: userID}).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// Purge permanently deletes every item soft deleted before the given date. The rows removed by the purge
+// are recorded as deleted, and a purge entry records how many items of each kind were purged.
+func Purge(tx *gorm.DB, before time.Time) error {
+	tx = audit.WithAction(tx, "purge")
+	purges := []struct {
+		entity string
+		purge  func(time.Time) (int64, error)
+	}{
+		{"sessions", model.NewSessionModel(tx).Purge},
+		{"students", model.NewStudentModel(tx).Purge},
+		{"timetable_slots", model.NewTimetableModel(tx).Purge},
+		{"classes", model.NewClassModel(tx).Purge},
+		{"users_t", model.NewUserModel(tx).Purge},
+	}
+
+	purged := model.AuditChanges{"before": before}
+	total := int64(0)
+	for _, p := range purges {
+		n, err := p.purge(before)
+		if err != nil {
+			return err
+		}
+		purged[p.entity] = n
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+
+	return audit.Record(tx, "purge", "trash", "", purged)
+}
@@ -0,0 +1,62 @@
+package trash
+
+import (
+	"fmt"
+	"gin-template/pkg/model"
+	"gin-template/utils/audit"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+	"time"
+)
+
+// TestPurge tests that a purge records the purged rows and a purge entry counting them
+func TestPurge(t *testing.T) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=Europe/Paris",
+		"localhost", "postgres", "postgres", "postgres", 5432,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Migrator().DropTable(&model.AuditLog{}, &model.Session{}, "guardian_students", &model.Student{},
+		&model.TimetableSlot{}, "class_teachers", &model.Class{}, &model.User{}, &model.Account{})
+	if err := db.AutoMigrate(&model.Account{}, &model.User{}, &model.Class{}, &model.TimetableSlot{},
+		&model.Student{}, &model.Session{}, &model.AuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.RegisterCallbacks(db, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	class := model.Class{Name: "purged", Year: "2022"}
+	db.Create(&class)
+	kept := model.Student{Email: "student.kept@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID}
+	db.Create(&kept)
+	db.Delete(&kept)
+	db.Delete(&class)
+	db.Where("1 = 1").Delete(&model.AuditLog{})
+
+	if err := Purge(db, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	var deletes, purges []model.AuditLog
+	db.Where("action = ?", "purge").Where("entity <> ?", "trash").Find(&deletes)
+	if len(deletes) != 2 {
+		t.Errorf("The purged class and student should be recorded, got %d entries", len(deletes))
+	}
+	db.Where("action = ? AND entity = ?", "purge", "trash").Find(&purges)
+	if len(purges) != 1 || fmt.Sprint(purges[0].After["classes"]) != "1" || fmt.Sprint(purges[0].After["students"]) != "1" {
+		t.Errorf("A purge entry should count the purged items, got %+v", purges)
+	}
+
+	if err := Purge(db, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if db.Where("action = ? AND entity = ?", "purge", "trash").Find(&purges); len(purges) != 1 {
+		t.Error("An empty purge should not be recorded")
+	}
+}
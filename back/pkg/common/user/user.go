@@ -1,10 +1,15 @@
 package user
 
 import (
+	"errors"
+	"fmt"
+	"gin-template/pkg/common/role"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
 	"gorm.io/gorm"
+	"strings"
 )
 
 // GetUser gets a user from the database by id
@@ -47,50 +52,129 @@ func GetUserList(db *gorm.DB, req dto.UserQueryParams) ([]dto.User, error) {
 	return userList, nil
 }
 
-// SuperAdminUpdateUser updates a user in the database by a super admin
-func SuperAdminUpdateUser(db *gorm.DB, req dto.UpdateUser) error {
+// rolePermissions gets the permissions of a role, none if the role does not exist anymore
+func rolePermissions(db *gorm.DB, name enum.Role) (model.Permissions, error) {
+	r, err := model.NewRoleModel(db).GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Permissions{}, nil
+	}
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+	return r.Permissions, nil
+}
+
+// updateAccount updates the email and the username of the account of a user,
+// it returns true if the email changed and must be verified again
+func updateAccount(db *gorm.DB, u *model.User, req dto.UpdateUser) (bool, error) {
+	accountModel := model.AccountModel{Tx: db}
+	a := model.Account{ID: u.AccountID, Email: req.Email, Username: req.Username}
+	if err := accountModel.Update(&a); err != nil {
+		return false, error2.FromDatabaseError(err)
+	}
+	return req.Email != "" && u.Account != nil && !strings.EqualFold(req.Email, u.Account.Email), nil
+}
+
+// SuperAdminUpdateUser updates a user in the database by a user manager, it returns true if the email changed.
+// The updater must hold every permission of the role of the user and of the role it gives.
+func SuperAdminUpdateUser(db *gorm.DB, req dto.UpdateUser, updaterPermissions model.Permissions) (bool, error) {
 	userModel := model.UserModel{Tx: db}
 	// update user in database
 	u := model.User{}
 	if err := userModel.FindByUserID(req.Id, &u).Error; err != nil {
-		return error2.FromDatabaseError(err)
+		return false, error2.FromDatabaseError(err)
+	}
+
+	permissions, err := rolePermissions(db, u.Role)
+	if err != nil {
+		return false, err
+	}
+	if !updaterPermissions.Grants(permissions) {
+		return false, error2.ForbiddenError(fmt.Sprintf("you cannot update a user of the role '%s'", u.Role))
+	}
+
+	if req.Role != "" {
+		if err := role.EnsureRole(db, req.Role); err != nil {
+			return false, err
+		}
+		if permissions, err = rolePermissions(db, req.Role); err != nil {
+			return false, err
+		}
+		if !updaterPermissions.Grants(permissions) {
+			return false, error2.ForbiddenError(fmt.Sprintf("you cannot grant the role '%s'", req.Role))
+		}
 	}
 
 	// update account in database
-	accountModel := model.AccountModel{Tx: db}
-	a := model.Account{ID: u.AccountID, Email: req.Email, Username: req.Username}
-	if err := accountModel.Update(&a); err != nil {
-		return error2.FromDatabaseError(err)
+	emailChanged, err := updateAccount(db, &u, req)
+	if err != nil {
+		return false, err
+	}
+
+	// the role is carried by the tokens, the sessions are revoked when it changes
+	if req.Role != "" && req.Role != u.Role {
+		if err := model.NewTokenModel(db).RevokeUserFamilies(req.Id, "").Error; err != nil {
+			return false, error2.FromDatabaseError(err)
+		}
 	}
 
 	// update user in database
 	u = model.User{ID: req.Id, FirstName: req.FirstName, LastName: req.LastName, Role: req.Role}
 	if err := userModel.Update(&u); err != nil {
-		return error2.FromDatabaseError(err)
+		return false, error2.FromDatabaseError(err)
 	}
 
-	return nil
+	return emailChanged, nil
 }
 
-// UpdateUser updates a user in the database
-func UpdateUser(db *gorm.DB, req dto.UpdateUser) error {
+// UpdateUser updates a user in the database, it returns true if the email changed
+func UpdateUser(db *gorm.DB, req dto.UpdateUser) (bool, error) {
 	userModel := model.UserModel{Tx: db}
 	// update user in database
 	u := model.User{}
 	if err := userModel.FindByUserID(req.Id, &u).Error; err != nil {
-		return error2.FromDatabaseError(err)
+		return false, error2.FromDatabaseError(err)
 	}
 
 	// update account in database
-	accountModel := model.AccountModel{Tx: db}
-	a := model.Account{ID: u.AccountID, Email: req.Email, Username: req.Username}
-	if err := accountModel.Update(&a); err != nil {
-		return error2.FromDatabaseError(err)
+	emailChanged, err := updateAccount(db, &u, req)
+	if err != nil {
+		return false, err
 	}
 
 	// update user in database
 	u = model.User{ID: req.Id, FirstName: req.FirstName, LastName: req.LastName}
 	if err := userModel.Update(&u); err != nil {
+		return false, error2.FromDatabaseError(err)
+	}
+	return emailChanged, nil
+}
+
+// DeleteUser soft deletes a user and its account and logs out its sessions, the user stays in the trash until purged,
+// the deleter must hold every permission of the role of the user
+func DeleteUser(db *gorm.DB, userId, deleterId uint64, deleterPermissions model.Permissions) error {
+	if userId == deleterId {
+		return error2.ForbiddenError("you cannot delete your own account")
+	}
+
+	userModel := model.NewUserModel(db)
+	u := model.User{}
+	if err := userModel.FindByUserID(userId, &u).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	permissions, err := rolePermissions(db, u.Role)
+	if err != nil {
+		return err
+	}
+	if !deleterPermissions.Grants(permissions) {
+		return error2.ForbiddenError(fmt.Sprintf("you cannot delete a user of the role '%s'", u.Role))
+	}
+
+	if err := model.NewTokenModel(db).RevokeUserFamilies(userId, "").Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+	if err := userModel.Delete(u); err != nil {
 		return error2.FromDatabaseError(err)
 	}
 	return nil
@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"gin-template/utils/webhook"
+	"gorm.io/gorm"
+	"time"
+)
+
+// dueBatchSize is the maximum number of deliveries attempted in one run
+const dueBatchSize = 100
+
+// toWebhook converts a webhook model to a dto.Webhook
+func toWebhook(w model.Webhook) dto.Webhook {
+	return dto.Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// GetWebhooks gets all webhooks
+func GetWebhooks(tx *gorm.DB) (*dto.WebhookList, error) {
+	webhookModel := model.NewWebhookModel(tx)
+	webhooks, err := webhookModel.FindAll()
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	webhookDtos := make([]dto.Webhook, 0)
+	for _, w := range webhooks {
+		webhookDtos = append(webhookDtos, toWebhook(w))
+	}
+
+	return &dto.WebhookList{Webhooks: webhookDtos}, nil
+}
+
+// GetWebhookByID gets a webhook by ID
+func GetWebhookByID(tx *gorm.DB, webhookID uint64) (*dto.Webhook, error) {
+	webhookModel := model.NewWebhookModel(tx)
+	w, err := webhookModel.GetByID(webhookID)
+	if err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	webhook := toWebhook(*w)
+	return &webhook, nil
+}
+
+// checkURL checks that the URL of a webhook targets a public address
+func checkURL(url string) error {
+	if err := webhook.CheckURL(url); err != nil {
+		return error2.BadRequestError("invalid webhook URL", map[string]string{"URL": err.Error()})
+	}
+	return nil
+}
+
+// CreateWebhook creates a new webhook subscription, its URL must target a public address
+func CreateWebhook(tx *gorm.DB, req dto.CreateWebhook) (*dto.Webhook, error) {
+	if err := checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	webhookModel := model.NewWebhookModel(tx)
+	w := model.Webhook{
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+		Active: true,
+	}
+	if err := webhookModel.Create(&w); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	webhook := toWebhook(w)
+	return &webhook, nil
+}
+
+// UpdateWebhook updates a webhook subscription
+func UpdateWebhook(tx *gorm.DB, req dto.UpdateWebhook) error {
+	webhookModel := model.NewWebhookModel(tx)
+	w, err := webhookModel.GetByID(req.Id)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if req.URL != "" {
+		if err := checkURL(req.URL); err != nil {
+			return err
+		}
+		w.URL = req.URL
+	}
+	if len(req.Events) > 0 {
+		w.Events = req.Events
+	}
+	if req.Secret != "" {
+		w.Secret = req.Secret
+	}
+	if req.Active != nil {
+		w.Active = *req.Active
+	}
+
+	if err := webhookModel.Update(w).Error; err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook subscription
+func DeleteWebhook(tx *gorm.DB, webhookID uint64) error {
+	webhookModel := model.NewWebhookModel(tx)
+	res := webhookModel.Delete(webhookID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("webhook '%d' not found", webhookID))
+	}
+
+	return nil
+}
+
+// GetDeliveries gets the deliveries of a webhook
+func GetDeliveries(tx *gorm.DB, webhookID uint64, req dto.WebhookDeliveryQueryParams) (*dto.WebhookDeliveryList, error) {
+	if _, err := model.NewWebhookModel(tx).GetByID(webhookID); err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	deliveryModel := model.NewWebhookDeliveryModel(tx)
+	var deliveries []model.WebhookDelivery
+	if err := deliveryModel.FindAll(&deliveries, webhookID, req).Error; err != nil {
+		return nil, error2.FromDatabaseError(err)
+	}
+
+	deliveryDtos := make([]dto.WebhookDelivery, 0)
+	for _, d := range deliveries {
+		deliveryDtos = append(deliveryDtos, dto.WebhookDelivery{
+			ID:            d.ID,
+			Event:         d.Event,
+			Payload:       d.Payload,
+			Status:        d.Status,
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt,
+			ResponseCode:  d.ResponseCode,
+			LastError:     d.LastError,
+			DeliveredAt:   d.DeliveredAt,
+			CreatedAt:     d.CreatedAt,
+		})
+	}
+
+	return &dto.WebhookDeliveryList{Deliveries: deliveryDtos}, nil
+}
+
+// ReplayDelivery schedules a new attempt of a delivery, whatever its status
+func ReplayDelivery(tx *gorm.DB, webhookID, deliveryID uint64) error {
+	deliveryModel := model.NewWebhookDeliveryModel(tx)
+	res := deliveryModel.Replay(webhookID, deliveryID)
+	if res.Error != nil {
+		return error2.FromDatabaseError(res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return error2.NotFoundError(fmt.Sprintf("delivery '%d' not found for webhook '%d'", deliveryID, webhookID))
+	}
+
+	return nil
+}
+
+// Dispatch queues a delivery of the event to every active webhook subscribed to it
+func Dispatch(tx *gorm.DB, event enum.WebhookEvent, data interface{}) error {
+	webhookModel := model.NewWebhookModel(tx)
+	webhooks, err := webhookModel.FindActiveByEvent(event)
+	if err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	deliveries := make([]model.WebhookDelivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     w.ID,
+			Event:         event,
+			Status:        enum.DELIVERY_PENDING,
+			NextAttemptAt: now,
+		})
+	}
+
+	deliveryModel := model.NewWebhookDeliveryModel(tx)
+	if err := deliveryModel.Create(deliveries); err != nil {
+		return error2.FromDatabaseError(err)
+	}
+
+	// The payload carries the delivery id so the receivers can deduplicate the retries
+	for i := range deliveries {
+		payload, err := json.Marshal(dto.WebhookEvent{
+			ID:        deliveries[i].ID,
+			Event:     event,
+			CreatedAt: now,
+			Data:      data,
+		})
+		if err != nil {
+			return error2.InternalServerError("", err)
+		}
+		deliveries[i].Payload = string(payload)
+		if err := deliveryModel.Save(&deliveries[i]); err != nil {
+			return error2.FromDatabaseError(err)
+		}
+	}
+
+	return nil
+}
+
+// claimDeliveries locks the due deliveries and postpones them for the time needed to send them all,
+// an attempt interrupted by a crash is retried once the claim expires
+func claimDeliveries(db *gorm.DB, conf config.WebhookConfig) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		deliveryModel := model.NewWebhookDeliveryModel(tx)
+		now := time.Now()
+		var err error
+		if deliveries, err = deliveryModel.FindDue(now, dueBatchSize); err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, 0, len(deliveries))
+		for _, d := range deliveries {
+			ids = append(ids, d.ID)
+		}
+		lease := time.Duration(len(deliveries)*conf.Timeout)*time.Second + time.Minute
+		return deliveryModel.Claim(ids, now.Add(lease))
+	})
+	return deliveries, err
+}
+
+// ProcessDeliveries attempts the due deliveries, a failed delivery is retried with an exponential backoff
+// until it reaches the maximum number of attempts and is dead.
+// The deliveries are claimed in a short transaction and sent out of it, the result of each one is then
+// recorded in its own transaction so that a failure never sends again the deliveries already made.
+func ProcessDeliveries(db *gorm.DB, sender *webhook.Sender, conf config.WebhookConfig) error {
+	deliveries, err := claimDeliveries(db, conf)
+	if err != nil {
+		return err
+	}
+
+	for i := range deliveries {
+		d := &deliveries[i]
+		d.Attempts++
+
+		if d.Webhook == nil {
+			d.Status, d.LastError = enum.DELIVERY_DEAD, "webhook deleted"
+		} else {
+			d.ResponseCode, err = sender.Send(d.Webhook.URL, d.Webhook.Secret, d.Event.String(), d.ID, []byte(d.Payload))
+			now := time.Now()
+			switch {
+			case err == nil:
+				d.Status, d.LastError, d.DeliveredAt = enum.DELIVERY_DELIVERED, "", &now
+			case d.Attempts >= conf.MaxAttempts || errors.Is(err, webhook.ErrPrivateAddress):
+				d.Status, d.LastError = enum.DELIVERY_DEAD, err.Error()
+			default:
+				d.LastError = err.Error()
+				d.NextAttemptAt = now.Add(webhook.Backoff(
+					d.Attempts,
+					time.Duration(conf.Backoff)*time.Second,
+					time.Duration(conf.MaxBackoff)*time.Second,
+				))
+			}
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return model.NewWebhookDeliveryModel(tx).Save(d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	"time"
+)
+
+type AlertRule struct {
+	// ID is the id of the rule
+	ID uint64 `json:"id"`
+	// ClassID is the class the rule applies to, null for every class of the institution
+	ClassID *uint64 `json:"class_id"`
+	// Kind is the kind of rule
+	Kind enum.AlertRuleKind `json:"kind"`
+	// Threshold is the number of absences or the attendance rate in percent triggering the alert
+	Threshold float64 `json:"threshold"`
+	// WindowDays is the number of days the attendance is evaluated on, 0 for the current term
+	WindowDays int `json:"window_days"`
+	// Notify is true if the alerts are sent to the teachers of the class
+	Notify bool `json:"notify"`
+	// Active is false if the rule is not evaluated
+	Active bool `json:"active"`
+}
+
+type AlertRuleList struct {
+	// Rules is the list of rules
+	Rules []AlertRule `json:"rules"`
+}
+
+type AlertRuleQueryParams struct {
+	// ClassID filters the rules applying to a class
+	ClassID uint64 `json:"class_id" form:"class_id" binding:"omitempty"`
+}
+
+type CreateAlertRule struct {
+	// ClassID is the class the rule applies to, omitted for every class of the institution
+	ClassID *uint64 `json:"class_id" binding:"omitempty"`
+	// Kind is the kind of rule
+	Kind enum.AlertRuleKind `json:"kind" binding:"required,oneof=absence_count attendance_rate"`
+	// Threshold is the number of absences or the attendance rate in percent triggering the alert
+	Threshold float64 `json:"threshold" binding:"required,gt=0"`
+	// WindowDays is the number of days the attendance is evaluated on, 0 for the current term
+	WindowDays int `json:"window_days" binding:"omitempty,min=0,max=366"`
+	// Notify is true if the alerts must be sent to the teachers of the class
+	Notify bool `json:"notify"`
+}
+
+type UpdateAlertRule struct {
+	// ID is the id of the rule
+	Id uint64 `json:"-" uri:"rule_id" path:"rule_id"`
+	// Threshold is the number of absences or the attendance rate in percent triggering the alert
+	Threshold float64 `json:"threshold" binding:"omitempty,gt=0"`
+	// WindowDays is the number of days the attendance is evaluated on, 0 for the current term
+	WindowDays *int `json:"window_days" binding:"omitempty,min=0,max=366"`
+	// Notify is true if the alerts must be sent to the teachers of the class
+	Notify *bool `json:"notify" binding:"omitempty"`
+	// Active is false if the rule must not be evaluated
+	Active *bool `json:"active" binding:"omitempty"`
+}
+
+type Alert struct {
+	// ID is the id of the alert
+	ID uint64 `json:"id"`
+	// Rule is the rule that raised the alert
+	Rule AlertRule `json:"rule"`
+	// Student is the student at risk
+	Student Student `json:"student"`
+	// Value is the number of absences or the attendance rate that raised the alert
+	Value float64 `json:"value"`
+	// CreatedAt is the date the alert was raised
+	CreatedAt time.Time `json:"created_at"`
+	// AcknowledgedAt is the date a teacher acknowledged the alert
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	// AcknowledgedBy is the id of the user who acknowledged the alert
+	AcknowledgedBy *uint64 `json:"acknowledged_by,omitempty"`
+}
+
+type AlertList struct {
+	// Alerts is the list of alerts
+	Alerts []Alert `json:"alerts"`
+}
+
+type AlertQueryParams struct {
+	// Open filters the alerts not acknowledged yet
+	Open bool `json:"open" form:"open" binding:"omitempty"`
+}
+
+type StudentAtRiskEvent struct {
+	// AlertID is the id of the alert
+	AlertID uint64 `json:"alert_id"`
+	// ClassID is the id of the class of the student
+	ClassID uint64 `json:"class_id"`
+	// Rule is the rule that raised the alert
+	Rule AlertRule `json:"rule"`
+	// Student is the student at risk
+	Student Student `json:"student"`
+	// Value is the number of absences or the attendance rate that raised the alert
+	Value float64 `json:"value"`
+}
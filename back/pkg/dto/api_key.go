@@ -0,0 +1,84 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	"time"
+)
+
+type ServiceAccount struct {
+	// ID is the id of the user of the service account
+	ID uint64 `json:"id"`
+	// Username is the username of the service account
+	Username string `json:"username"`
+	// Name describes the service account
+	Name string `json:"name"`
+	// Role is the role of the service account, its keys are restricted to its permissions
+	Role enum.Role `json:"role"`
+	// CreatedAt is the date the service account was created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ServiceAccountList struct {
+	// ServiceAccounts is the list of the service accounts
+	ServiceAccounts []ServiceAccount `json:"service_accounts"`
+}
+
+type CreateServiceAccount struct {
+	// Username is the username of the service account
+	Username string `json:"username" binding:"required,min=3,max=20,alphanum"`
+	// Name describes the service account
+	Name string `json:"name" binding:"required,min=2,max=120"`
+	// Role is the role of the service account
+	Role enum.Role `json:"role" binding:"required,max=40"`
+}
+
+type APIKey struct {
+	// ID is the id of the key
+	ID uint64 `json:"id"`
+	// Name describes the integration using the key
+	Name string `json:"name"`
+	// Prefix is the public part of the key identifying it
+	Prefix string `json:"prefix"`
+	// Permissions is the set of permissions the key is restricted to
+	Permissions []enum.Permission `json:"permissions"`
+	// ClassID is the class the key is restricted to, null for every class
+	ClassID *uint64 `json:"class_id"`
+	// Tenant is the tenant the key is restricted to, empty for every tenant
+	Tenant string `json:"tenant,omitempty"`
+	// ExpiresAt is the date the key stops being accepted, null if it never expires
+	ExpiresAt *time.Time `json:"expires_at"`
+	// LastUsedAt is the date the key was last used, to the minute
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// LastUsedIP is the address of the client which last used the key
+	LastUsedIP string `json:"last_used_ip,omitempty"`
+	// CreatedAt is the date the key was created
+	CreatedAt time.Time `json:"created_at"`
+	// CreatedBy is the id of the user who created the key
+	CreatedBy uint64 `json:"created_by"`
+}
+
+type APIKeyList struct {
+	// Keys is the list of the keys of a service account
+	Keys []APIKey `json:"keys"`
+}
+
+type CreateAPIKey struct {
+	// UserID is the id of the service account
+	UserID uint64 `json:"-" uri:"user_id" path:"user_id"`
+	// Name describes the integration using the key
+	Name string `json:"name" binding:"required,min=2,max=80"`
+	// Permissions is the set of permissions the key is restricted to, the creator must hold them
+	Permissions []enum.Permission `json:"permissions" binding:"required,min=1"`
+	// ClassID restricts the key to the routes of a class
+	ClassID *uint64 `json:"class_id" binding:"omitempty"`
+	// Tenant restricts the key to a tenant
+	Tenant string `json:"tenant" binding:"omitempty,max=80"`
+	// ExpiresAt is the date the key stops being accepted, the key never expires when omitted
+	ExpiresAt *time.Time `json:"expires_at" binding:"omitempty"`
+}
+
+type CreatedAPIKey struct {
+	APIKey
+	// Key is the key to send in the X-API-Key header, it is only returned at its creation
+	Key string `json:"key"`
+}
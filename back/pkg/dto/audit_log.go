@@ -0,0 +1,52 @@
+package dto
+
+import "time"
+
+type AuditLog struct {
+	// ID is the id of the audit entry
+	ID uint64 `json:"id"`
+	// CreatedAt is the date of the action
+	CreatedAt time.Time `json:"created_at"`
+	// ActorID is the id of the user who made the action
+	ActorID *uint64 `json:"actor_id"`
+	// Tenant is the tenant the action was made on
+	Tenant string `json:"tenant"`
+	// Action is the kind of action
+	Action string `json:"action"`
+	// Entity is the table of the target entity
+	Entity string `json:"entity"`
+	// EntityID is the primary key of the target entity
+	EntityID string `json:"entity_id"`
+	// Before is the value of the changed columns before the action
+	Before map[string]interface{} `json:"before,omitempty"`
+	// After is the value of the changed columns after the action
+	After map[string]interface{} `json:"after,omitempty"`
+	// RequestID is the id of the HTTP request that made the action
+	RequestID string `json:"request_id"`
+}
+
+type AuditLogList struct {
+	// Entries is the list of audit entries
+	Entries []AuditLog `json:"entries"`
+}
+
+type AuditLogQueryParams struct {
+	// Page is the page number
+	Page int `json:"page" form:"page,default=1" binding:"omitempty,min=1"`
+	// PageSize is the page size
+	PageSize int `json:"page_size" form:"page_size,default=50" binding:"omitempty,min=1,max=500"`
+	// ActorID filters by the user who made the action
+	ActorID uint64 `json:"actor_id" form:"actor_id" binding:"omitempty"`
+	// Action filters by kind of action
+	Action string `json:"action" form:"action" binding:"omitempty,max=40"`
+	// Entity filters by table of the target entity
+	Entity string `json:"entity" form:"entity" binding:"omitempty,max=80"`
+	// EntityID filters by primary key of the target entity
+	EntityID string `json:"entity_id" form:"entity_id" binding:"omitempty,max=120"`
+	// RequestID filters by HTTP request
+	RequestID string `json:"request_id" form:"request_id" binding:"omitempty,max=64"`
+	// From filters the actions made after this date
+	From time.Time `json:"from" form:"from" time_format:"2006-01-02T15:04:05Z07:00" binding:"omitempty"`
+	// To filters the actions made before this date
+	To time.Time `json:"to" form:"to" time_format:"2006-01-02T15:04:05Z07:00" binding:"omitempty"`
+}
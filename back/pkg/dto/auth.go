@@ -1,6 +1,9 @@
 package dto
 
-import "time"
+import (
+	"gin-template/pkg/model/enum"
+	"time"
+)
 
 type Login struct {
 	// Username is the username of the user
@@ -16,8 +19,8 @@ type Register struct {
 	Username string `json:"username" binding:"required,min=3,max=20,alphanum"`
 	// Email is the email of the user
 	Email string `json:"email" binding:"required,email"`
-	// Password is the password of the user
-	Password string `json:"password" binding:"required,min=6"`
+	// Password is the password of the user, following the password policy
+	Password string `json:"password" binding:"required"`
 	// FirstName is the first name of the user
 	FirstName string `json:"first_name" binding:"required,min=2"`
 	// LastName is the last name of the user
@@ -29,8 +32,25 @@ type ChangePassword struct {
 	UserId uint64 `json:"-"`
 	// OldPassword is the old password of the user
 	OldPassword string `json:"old_password" binding:"required"`
-	// NewPassword is the new password of the user
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	// NewPassword is the new password of the user, following the password policy
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type ForgotPassword struct {
+	// Email is the email of the account whose password is forgotten
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPassword struct {
+	// Token is the reset token received by email
+	Token string `json:"token" binding:"required,len=64,hexadecimal"`
+	// NewPassword is the new password of the user, following the password policy
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type VerifyEmail struct {
+	// Token is the token of the verification link received by email
+	Token string `json:"token" binding:"required"`
 }
 
 type AuthResponse struct {
@@ -40,4 +60,126 @@ type AuthResponse struct {
 	RefreshToken string `json:"refresh_token"`
 	// ExpiresIn is the expiration time of the access token
 	ExpiresIn time.Time `json:"expires_in"`
+	// RefreshExpiresIn is the expiration time of the refresh token
+	RefreshExpiresIn time.Time `json:"refresh_expires_in"`
+}
+
+type LoginResponse struct {
+	// AuthResponse holds the tokens, absent while the second factor is required
+	*AuthResponse
+	// MFARequired is true if the login must be confirmed with a second factor at /auth/mfa/verify
+	MFARequired bool `json:"mfa_required"`
+	// MFAToken is the token of the login waiting for the second factor
+	MFAToken string `json:"mfa_token,omitempty"`
+	// MFAExpiresIn is the expiration time of the token waiting for the second factor
+	MFAExpiresIn *time.Time `json:"mfa_expires_in,omitempty"`
+	// MFAEnrollmentRequired is true if the role of the user requires a second factor which is not enrolled yet,
+	// only the account routes are allowed until it is
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
+}
+
+type VerifyMFA struct {
+	// MFAToken is the token of the login waiting for the second factor
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a code of the authenticator app or a recovery code
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type MFACode struct {
+	// Code is a code of the authenticator app or, except to confirm the enrollment, a recovery code
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type MFAEnrollment struct {
+	// Secret is the base32 encoded secret to enter in the authenticator app
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth URI to display as QR code
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodes struct {
+	// Codes are the single-use codes replacing a code of the authenticator app, they are only shown once
+	Codes []string `json:"codes"`
+}
+
+type OIDCAuthorization struct {
+	// AuthorizationURL is the URL of the provider the user is redirected to
+	AuthorizationURL string `json:"authorization_url"`
+	// FlowToken is kept by the client and sent back with the code to /auth/oidc/callback
+	FlowToken string `json:"flow_token"`
+	// ExpiresIn is the expiration time of the flow token
+	ExpiresIn time.Time `json:"expires_in"`
+}
+
+type OIDCCallback struct {
+	// Code is the authorization code returned by the provider
+	Code string `json:"code" binding:"required,max=2048"`
+	// State is the state returned by the provider
+	State string `json:"state" binding:"required,max=255"`
+	// FlowToken is the token returned with the authorization URL
+	FlowToken string `json:"flow_token" binding:"required"`
+}
+
+type RefreshToken struct {
+	// RefreshToken is the refresh token to exchange, defaults to the bearer token
+	RefreshToken string `json:"refresh_token"`
+}
+
+type Client struct {
+	// UserAgent is the user agent of the client
+	UserAgent string
+	// IP is the address of the client
+	IP string
+}
+
+type LoginSession struct {
+	// ID is the id of the session
+	ID string `json:"id"`
+	// UserAgent is the user agent of the client of the session
+	UserAgent string `json:"user_agent"`
+	// IP is the address of the client of the session
+	IP string `json:"ip"`
+	// LoggedInAt is the date of the login
+	LoggedInAt time.Time `json:"logged_in_at"`
+	// LastUsedAt is the date the session was last refreshed
+	LastUsedAt time.Time `json:"last_used_at"`
+	// ExpiresAt is the date the session ends if it is not refreshed
+	ExpiresAt time.Time `json:"expires_at"`
+	// Current is true for the session of the request
+	Current bool `json:"current"`
+}
+
+type LoginSessionList struct {
+	// Sessions is the list of the active sessions
+	Sessions []LoginSession `json:"sessions"`
+}
+
+type LoginAttempt struct {
+	// CreatedAt is the date of the attempt
+	CreatedAt time.Time `json:"created_at"`
+	// Method is how the user authenticated: password, mfa or oidc
+	Method string `json:"method"`
+	// IP is the address of the client
+	IP string `json:"ip"`
+	// UserAgent is the user agent of the client
+	UserAgent string `json:"user_agent"`
+	// Success is false if the credentials were wrong
+	Success bool `json:"success"`
+	// NewClient is true if the login succeeded from a client never seen for the account
+	NewClient bool `json:"new_client"`
+}
+
+type LoginNewClientEvent struct {
+	// UserID is the id of the user who logged in
+	UserID uint64 `json:"user_id"`
+	// Username is the username of the user
+	Username string `json:"username"`
+	// Role is the role of the user
+	Role enum.Role `json:"role"`
+	// Method is how the user authenticated: password, mfa or oidc
+	Method string `json:"method"`
+	// IP is the address of the client
+	IP string `json:"ip"`
+	// UserAgent is the user agent of the client
+	UserAgent string `json:"user_agent"`
 }
@@ -7,13 +7,17 @@ type TinyClass struct {
 	Name string `json:"name"`
 	// Year is the year of the class
 	Year string `json:"year"`
+	// TermID is the id of the term of the class
+	TermID *uint64 `json:"term_id,omitempty"`
 }
 
 type CreateClass struct {
 	// Name is the name of the class
 	Name string `json:"name" binding:"required,min=3,max=20,alphanum"`
-	// Year is the year of the class
-	Year string `json:"year" binding:"required"`
+	// Year is the year of the class, defaults to the term name when a term is given
+	Year string `json:"year" binding:"required_without=TermID"`
+	// TermID is the id of the term of the class
+	TermID *uint64 `json:"term_id" binding:"omitempty"`
 }
 
 type UpdateClass struct {
@@ -23,12 +27,20 @@ type UpdateClass struct {
 	Name string `json:"name" binding:"omitempty,min=3,max=20,alphanum"`
 	// Year is the year of the class
 	Year string `json:"year" binding:"omitempty"`
+	// TermID is the id of the term of the class
+	TermID *uint64 `json:"term_id" binding:"omitempty"`
 }
 
 type Class struct {
 	TinyClass
+	// NotifyAbsences is true if the absent students are notified by email
+	NotifyAbsences bool `json:"notify_absences"`
 	// Students is the list of students in the class
 	Students []Student `json:"students"`
+	// Teachers is the list of teachers of the class
+	Teachers []User `json:"teachers"`
+	// Timetable is the list of weekly slots of the class
+	Timetable []TimetableSlot `json:"timetable"`
 }
 
 type ClassList struct {
@@ -53,3 +65,48 @@ type AddStudentToClass struct {
 	// Student is the student to add to the class
 	Student Student `json:"student" binding:"required"`
 }
+
+type TimetableSlot struct {
+	// ID is the id of the slot
+	ID uint64 `json:"id,omitempty"`
+	// Weekday is the day of the week of the slot (0 is sunday)
+	Weekday int `json:"weekday" binding:"min=0,max=6"`
+	// StartTime is the start time of the slot (HH:MM)
+	StartTime string `json:"start_time" binding:"required,datetime=15:04"`
+	// EndTime is the end time of the slot (HH:MM)
+	EndTime string `json:"end_time" binding:"required,datetime=15:04"`
+	// Room is the room where the slot takes place
+	Room string `json:"room" binding:"omitempty,max=80"`
+}
+
+type SetClassTimetable struct {
+	// ClassId is the id of the class
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// Slots is the new list of weekly slots of the class
+	Slots []TimetableSlot `json:"slots" binding:"dive"`
+}
+
+type ClassTeacher struct {
+	// ClassId is the id of the class
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// UserId is the id of the teacher
+	UserId uint64 `json:"user_id" uri:"user_id" binding:"required"`
+}
+
+type RolloverClass struct {
+	// ClassId is the id of the class to clone
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// TermID is the id of the term the class is cloned into
+	TermID uint64 `json:"term_id" binding:"required"`
+	// Name is the name of the new class, defaults to the name of the cloned class
+	Name string `json:"name" binding:"omitempty,min=3,max=20,alphanum"`
+	// StudentIDs is the list of students promoted to the new class
+	StudentIDs []uint64 `json:"student_ids"`
+}
+
+type SetClassNotifications struct {
+	// ClassId is the id of the class
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// Enabled is true if the absent students must be notified by email
+	Enabled *bool `json:"enabled" binding:"required"`
+}
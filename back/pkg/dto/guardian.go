@@ -0,0 +1,113 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+type StudentGuardian struct {
+	// ClassId is the id of the class of the student
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// StudentId is the id of the student
+	StudentId uint64 `json:"-" uri:"student_id" path:"student_id"`
+	// UserId is the id of the guardian
+	UserId uint64 `json:"user_id" uri:"user_id" binding:"required"`
+}
+
+type CreateGuardianInvitation struct {
+	// ClassId is the id of the class of the student
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// StudentId is the id of the student
+	StudentId uint64 `json:"-" uri:"student_id" path:"student_id"`
+	// Email is the email of the invited guardian
+	Email string `json:"email" binding:"required,email"`
+}
+
+type GuardianInvitation struct {
+	// Code is the code the guardian accepts the invitation with, only returned at creation
+	Code string `json:"code"`
+	// Email is the email of the invited guardian
+	Email string `json:"email"`
+	// ExpiresAt is the date after which the invitation cannot be accepted
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type AcceptGuardianInvitation struct {
+	// Code is the invitation code
+	Code string `json:"code" binding:"required,len=32,hexadecimal"`
+}
+
+type Child struct {
+	Student
+	// Class is the class of the student
+	Class TinyClass `json:"class"`
+}
+
+type ChildList struct {
+	// Children is the list of students the user is guardian of
+	Children []Child `json:"children"`
+}
+
+type ChildAttendance struct {
+	// SessionID is the id of the session
+	SessionID uuid.UUID `json:"session_id"`
+	// SessionDate is the creation date of the session
+	SessionDate time.Time `json:"session_date"`
+	// Status is the attendance status of the student
+	Status enum.AttendanceStatus `json:"status"`
+	// Justified is true if the absence was justified
+	Justified bool `json:"justified"`
+}
+
+type ChildAttendanceList struct {
+	// Attendances is the attendance of the student, most recent first
+	Attendances []ChildAttendance `json:"attendances"`
+}
+
+type Justification struct {
+	// ID is the id of the justification
+	ID uint64 `json:"id"`
+	// Student is the absent student, omitted in the views of a single student
+	Student *Student `json:"student,omitempty"`
+	// SessionID is the id of the session missed
+	SessionID uuid.UUID `json:"session_id"`
+	// SessionDate is the creation date of the session missed
+	SessionDate time.Time `json:"session_date"`
+	// Reason is the reason of the absence
+	Reason string `json:"reason"`
+	// Status is the review status of the justification
+	Status enum.JustificationStatus `json:"status"`
+	// CreatedAt is the date the justification was submitted
+	CreatedAt time.Time `json:"created_at"`
+	// ReviewedAt is the date of the review
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+type JustificationList struct {
+	// Justifications is the list of justifications
+	Justifications []Justification `json:"justifications"`
+}
+
+type JustificationQueryParams struct {
+	// Status filters by review status
+	Status enum.JustificationStatus `json:"status" form:"status" binding:"omitempty,oneof=pending accepted rejected"`
+}
+
+type CreateJustification struct {
+	// StudentId is the id of the absent student
+	StudentId uint64 `json:"-" uri:"student_id" path:"student_id"`
+	// SessionID is the id of the session missed
+	SessionID string `json:"session_id" binding:"required,uuid"`
+	// Reason is the reason of the absence
+	Reason string `json:"reason" binding:"required,min=3,max=2000"`
+}
+
+type ReviewJustification struct {
+	// ClassId is the id of the class
+	ClassId uint64 `json:"-" uri:"class_id" path:"class_id"`
+	// JustificationId is the id of the justification
+	JustificationId uint64 `json:"-" uri:"justification_id" path:"justification_id"`
+	// Status is the review decision
+	Status enum.JustificationStatus `json:"status" binding:"required,oneof=accepted rejected"`
+}
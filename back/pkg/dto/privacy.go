@@ -0,0 +1,85 @@
+package dto
+
+import "time"
+
+type PersonalDataQueryParams struct {
+	// Format is the format of the export:
+	// * json: a single JSON document
+	// * zip: a ZIP archive with a JSON file per section
+	Format string `json:"format" form:"format,default=json" binding:"omitempty,oneof=json zip"`
+}
+
+type ExportedIdentity struct {
+	// Issuer is the URL of the identity provider
+	Issuer string `json:"issuer"`
+	// Subject is the id of the user at the provider
+	Subject string `json:"subject"`
+	// CreatedAt is the date the identity was linked
+	CreatedAt time.Time `json:"created_at"`
+	// LastLoginAt is the date of the last login with the identity
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+type ExportedAccount struct {
+	User
+	// Service is true for a service account
+	Service bool `json:"service"`
+	// CreatedAt is the date the account was created
+	CreatedAt time.Time `json:"created_at"`
+	// VerifiedAt is the date the email was verified
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	// DeletedAt is the date the account was deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ErasedAt is the date the personal data of the account was erased
+	ErasedAt *time.Time `json:"erased_at,omitempty"`
+	// Identities are the identities of the single sign-on linked to the account
+	Identities []ExportedIdentity `json:"identities"`
+	// Sessions are the active login sessions
+	Sessions []LoginSession `json:"sessions"`
+	// Classes are the classes the user teaches
+	Classes []TinyClass `json:"classes"`
+	// Children are the students the user is guardian of
+	Children []Student `json:"children"`
+}
+
+type ExportedStudent struct {
+	Student
+	// Class is the class of the student
+	Class TinyClass `json:"class"`
+	// CreatedAt is the date the student was added to the roster
+	CreatedAt time.Time `json:"created_at"`
+	// DeletedAt is the date the student was deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ErasedAt is the date the personal data of the student was erased
+	ErasedAt *time.Time `json:"erased_at,omitempty"`
+	// Guardians are the users who are guardians of the student
+	Guardians []User `json:"guardians"`
+	// Attendances is the attendance of the student, most recent first
+	Attendances []ChildAttendance `json:"attendances"`
+	// Justifications are the justifications of the absences of the student, most recent first
+	Justifications []Justification `json:"justifications"`
+}
+
+type PersonalData struct {
+	// ExportedAt is the date of the export
+	ExportedAt time.Time `json:"exported_at"`
+	// Account is the account of the user, omitted for a student without account
+	Account *ExportedAccount `json:"account,omitempty"`
+	// LoginHistory is the list of the logins of the account, the newest first
+	LoginHistory []LoginAttempt `json:"login_history"`
+	// Students are the roster entries of the person, the ones having the email of the account for a user
+	Students []ExportedStudent `json:"students"`
+	// SubmittedJustifications are the justifications the user submitted as guardian, most recent first
+	SubmittedJustifications []Justification `json:"submitted_justifications"`
+	// AuditLogs are the audit entries about the person and the actions it made, oldest first
+	AuditLogs []AuditLog `json:"audit_logs"`
+}
+
+type Erasure struct {
+	// ErasedAt is the date of the erasure
+	ErasedAt time.Time `json:"erased_at"`
+	// Users is the number of user accounts erased
+	Users int `json:"users"`
+	// Students is the number of roster entries erased
+	Students int `json:"students"`
+}
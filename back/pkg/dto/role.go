@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	"time"
+)
+
+type Role struct {
+	// Name is the name of the role
+	Name enum.Role `json:"name"`
+	// Description is a human readable description of the role
+	Description string `json:"description"`
+	// Permissions is the set of permissions granted to the role
+	Permissions []enum.Permission `json:"permissions"`
+	// RequireMFA is true if the users of the role must confirm their logins with a second factor
+	RequireMFA bool `json:"require_mfa"`
+	// Builtin is true if the role is created with the database and can not be deleted
+	Builtin bool `json:"builtin"`
+	// UpdatedAt is the date the role was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RoleList struct {
+	// Roles is the list of roles
+	Roles []Role `json:"roles"`
+}
+
+type PermissionList struct {
+	// Permissions is the list of the permissions a role can be granted
+	Permissions []enum.Permission `json:"permissions"`
+}
+
+type CreateRole struct {
+	// Name is the name of the role
+	Name enum.Role `json:"name" binding:"required,min=3,max=40,lowercase,alphanum"`
+	// Description is a human readable description of the role
+	Description string `json:"description" binding:"omitempty,max=255"`
+	// Permissions is the set of permissions granted to the role
+	Permissions []enum.Permission `json:"permissions" binding:"required,min=1"`
+	// RequireMFA is true if the users of the role must confirm their logins with a second factor
+	RequireMFA bool `json:"require_mfa"`
+}
+
+type UpdateRole struct {
+	// Name is the name of the role
+	Name enum.Role `json:"-" uri:"role_name" path:"role_name"`
+	// Description is a human readable description of the role
+	Description string `json:"description" binding:"omitempty,max=255"`
+	// Permissions is the new set of permissions granted to the role
+	Permissions []enum.Permission `json:"permissions" binding:"omitempty,min=1"`
+	// RequireMFA changes whether the users of the role must confirm their logins with a second factor
+	RequireMFA *bool `json:"require_mfa"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type RoutePolicy struct {
+	// Method is the HTTP method of the route
+	Method string `json:"method"`
+	// Path is the full path of the route
+	Path string `json:"path"`
+	// Policy is the permission required by the route, or public
+	Policy string `json:"policy"`
+}
+
+type RouteList struct {
+	// Routes is the list of routes with their authorization policy
+	Routes []RoutePolicy `json:"routes"`
+}
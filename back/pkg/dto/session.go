@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"gin-template/pkg/model/enum"
 	uuid "github.com/satori/go.uuid"
 	"time"
 )
@@ -37,3 +38,24 @@ type JoinSession struct {
 	// Password is the password of the session
 	Password string `json:"password" binding:"required,min=8,max=255" path:"password" form:"password" query:"password"`
 }
+
+type CheckIn struct {
+	// Password is the password of the session
+	Password string `json:"password" binding:"required,min=8,max=255"`
+}
+
+type Attendance struct {
+	// Student is the student whose attendance is recorded
+	Student Student `json:"student"`
+	// Status is the attendance status of the student
+	Status enum.AttendanceStatus `json:"status"`
+	// Manual is true if the attendance was set by a staff member
+	Manual bool `json:"manual"`
+	// UpdatedAt is the date of the last attendance change
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type AttendanceList struct {
+	// Attendances is the list of attendances of the session
+	Attendances []Attendance `json:"attendances"`
+}
@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	"time"
+)
+
+type StaffInvitation struct {
+	// ID is the id of the invitation
+	ID uint64 `json:"id"`
+	// Email is the email the invitation is sent to
+	Email string `json:"email"`
+	// Username is the username of the account created by the invitation
+	Username string `json:"username"`
+	// FirstName is the first name of the invitee
+	FirstName string `json:"first_name"`
+	// LastName is the last name of the invitee
+	LastName string `json:"last_name"`
+	// Role is the role of the account created by the invitation
+	Role enum.Role `json:"role"`
+	// CreatedBy is the id of the user who sent the invitation
+	CreatedBy uint64 `json:"created_by"`
+	// CreatedAt is the date the invitation was sent
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is the date after which the link cannot be used, an expired invitation can be resent
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type StaffInvitationList struct {
+	// Invitations is the list of the pending invitations
+	Invitations []StaffInvitation `json:"invitations"`
+}
+
+type AcceptStaffInvitation struct {
+	// Token is the token of the invitation link
+	Token string `json:"token" binding:"required"`
+	// Password is the password of the new account, following the password policy
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,67 @@
+package dto
+
+import "time"
+
+type TinyTerm struct {
+	// ID is the id of the term
+	ID uint64 `json:"id"`
+	// Name is the name of the term
+	Name string `json:"name"`
+	// StartDate is the first day of the term
+	StartDate time.Time `json:"start_date"`
+	// EndDate is the last day of the term
+	EndDate time.Time `json:"end_date"`
+	// Archived is true if the term is read-only
+	Archived bool `json:"archived"`
+}
+
+type Term struct {
+	TinyTerm
+	// Holidays is the list of holidays of the term
+	Holidays []Holiday `json:"holidays"`
+}
+
+type TermList struct {
+	// Terms is the list of terms
+	Terms []TinyTerm `json:"terms"`
+}
+
+type Holiday struct {
+	// ID is the id of the holiday
+	ID uint64 `json:"id,omitempty"`
+	// Name is the name of the holiday
+	Name string `json:"name" binding:"required,min=2,max=120"`
+	// StartDate is the first day of the holiday
+	StartDate time.Time `json:"start_date" binding:"required"`
+	// EndDate is the last day of the holiday
+	EndDate time.Time `json:"end_date" binding:"required,gtefield=StartDate"`
+}
+
+type CreateTerm struct {
+	// Name is the name of the term
+	Name string `json:"name" binding:"required,min=3,max=80"`
+	// StartDate is the first day of the term
+	StartDate time.Time `json:"start_date" binding:"required"`
+	// EndDate is the last day of the term
+	EndDate time.Time `json:"end_date" binding:"required,gtfield=StartDate"`
+	// Holidays is the list of holidays of the term
+	Holidays []Holiday `json:"holidays" binding:"omitempty,dive"`
+}
+
+type UpdateTerm struct {
+	// ID is the id of the term
+	Id uint64 `json:"-" uri:"term_id" path:"term_id"`
+	// Name is the name of the term
+	Name string `json:"name" binding:"omitempty,min=3,max=80"`
+	// StartDate is the first day of the term
+	StartDate time.Time `json:"start_date" binding:"omitempty"`
+	// EndDate is the last day of the term
+	EndDate time.Time `json:"end_date" binding:"omitempty"`
+}
+
+type AddHolidayToTerm struct {
+	// TermId is the id of the term
+	TermId uint64 `json:"-" uri:"term_id" path:"term_id"`
+	// Holiday is the holiday to add to the term
+	Holiday Holiday `json:"holiday" binding:"required"`
+}
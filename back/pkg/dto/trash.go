@@ -0,0 +1,60 @@
+package dto
+
+import "time"
+
+type TrashQueryParams struct {
+	// Page is the page number
+	Page int `json:"page" form:"page,default=1" binding:"omitempty,min=1"`
+	// PageSize is the page size
+	PageSize int `json:"page_size" form:"page_size,default=10" binding:"omitempty,min=1,max=100"`
+	// ClassID filters sessions and students by class
+	ClassID uint64 `json:"class_id" form:"class_id" binding:"omitempty"`
+}
+
+type DeletedClass struct {
+	TinyClass
+	// DeletedAt is the deletion date of the class
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type DeletedSession struct {
+	TinySession
+	// ClassID is the id of the class of the session
+	ClassID uint64 `json:"class_id"`
+	// DeletedAt is the deletion date of the session
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type DeletedStudent struct {
+	Student
+	// ClassID is the id of the class of the student
+	ClassID uint64 `json:"class_id"`
+	// DeletedAt is the deletion date of the student
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type DeletedUser struct {
+	User
+	// DeletedAt is the deletion date of the user
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type DeletedClassList struct {
+	// Classes is the list of deleted classes
+	Classes []DeletedClass `json:"classes"`
+}
+
+type DeletedSessionList struct {
+	// Sessions is the list of deleted sessions
+	Sessions []DeletedSession `json:"sessions"`
+}
+
+type DeletedStudentList struct {
+	// Students is the list of deleted students
+	Students []DeletedStudent `json:"students"`
+}
+
+type DeletedUserList struct {
+	// Users is the list of deleted users
+	Users []DeletedUser `json:"users"`
+}
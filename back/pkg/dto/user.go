@@ -15,6 +15,12 @@ type User struct {
 	LastName string `json:"last_name"`
 	// Role is the role of the user
 	Role enum.Role `json:"role"`
+	// Verified is true if the email of the user is verified
+	Verified bool `json:"verified,omitempty"`
+	// MFAEnabled is true if the logins of the user are confirmed with a second factor
+	MFAEnabled bool `json:"mfa_enabled,omitempty"`
+	// LoginHistory is the list of the latest logins of the user, the newest first, only on its own account
+	LoginHistory []LoginAttempt `json:"login_history,omitempty"`
 }
 
 type CreateUser struct {
@@ -27,7 +33,7 @@ type CreateUser struct {
 	// LastName is the last name of the user
 	LastName string `json:"last_name" binding:"required,min=2"`
 	// Role is the role of the user
-	Role string `json:"role" binding:"required,oneof=admin student"`
+	Role enum.Role `json:"role" binding:"required,max=40"`
 }
 
 type UpdateUser struct {
@@ -41,7 +47,7 @@ type UpdateUser struct {
 	// LastName is the last name of the user
 	LastName string `json:"last_name" binding:"omitempty,min=2"`
 	// Role is the role of the user
-	Role enum.Role `json:"role" binding:"omitempty,oneof=superadmin admin student"`
+	Role enum.Role `json:"role" binding:"omitempty,max=40"`
 }
 
 type UserQueryParams struct {
@@ -0,0 +1,115 @@
+package dto
+
+import (
+	"gin-template/pkg/model/enum"
+	uuid "github.com/satori/go.uuid"
+	"time"
+)
+
+type Webhook struct {
+	// ID is the id of the webhook
+	ID uint64 `json:"id"`
+	// URL is the address the events are posted to
+	URL string `json:"url"`
+	// Events is the list of events the webhook is subscribed to
+	Events []enum.WebhookEvent `json:"events"`
+	// Active is false if the webhook does not receive new events
+	Active bool `json:"active"`
+	// CreatedAt is the creation date of the webhook
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookList struct {
+	// Webhooks is the list of webhooks
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type CreateWebhook struct {
+	// URL is the address the events are posted to
+	URL string `json:"url" binding:"required,url,max=2048"`
+	// Events is the list of events the webhook is subscribed to
+	Events []enum.WebhookEvent `json:"events" binding:"required,min=1,dive,oneof=session.closed student.absent student.at_risk login.new_client"`
+	// Secret is the key used to sign the deliveries
+	Secret string `json:"secret" binding:"required,min=16,max=255"`
+}
+
+type UpdateWebhook struct {
+	// ID is the id of the webhook
+	Id uint64 `json:"-" uri:"webhook_id" path:"webhook_id"`
+	// URL is the address the events are posted to
+	URL string `json:"url" binding:"omitempty,url,max=2048"`
+	// Events is the list of events the webhook is subscribed to
+	Events []enum.WebhookEvent `json:"events" binding:"omitempty,min=1,dive,oneof=session.closed student.absent student.at_risk login.new_client"`
+	// Secret is the key used to sign the deliveries
+	Secret string `json:"secret" binding:"omitempty,min=16,max=255"`
+	// Active is false if the webhook must not receive new events
+	Active *bool `json:"active" binding:"omitempty"`
+}
+
+type WebhookDelivery struct {
+	// ID is the id of the delivery
+	ID uint64 `json:"id"`
+	// Event is the event delivered
+	Event enum.WebhookEvent `json:"event"`
+	// Payload is the JSON body posted to the webhook
+	Payload string `json:"payload"`
+	// Status is the delivery status
+	Status enum.DeliveryStatus `json:"status"`
+	// Attempts is the number of attempts made
+	Attempts int `json:"attempts"`
+	// NextAttemptAt is the date of the next attempt of a pending delivery
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// ResponseCode is the HTTP status code of the last attempt
+	ResponseCode int `json:"response_code,omitempty"`
+	// LastError is the error of the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+	// DeliveredAt is the date of the successful attempt
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	// CreatedAt is the date the event was emitted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDeliveryList struct {
+	// Deliveries is the list of deliveries
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
+
+type WebhookDeliveryQueryParams struct {
+	// Page is the page number
+	Page int `json:"page" form:"page,default=1" binding:"omitempty,min=1"`
+	// PageSize is the page size
+	PageSize int `json:"page_size" form:"page_size,default=20" binding:"omitempty,min=1,max=100"`
+	// Status filters by delivery status
+	Status enum.DeliveryStatus `json:"status" form:"status" binding:"omitempty,oneof=pending delivered dead"`
+}
+
+type WebhookEvent struct {
+	// ID is the id of the delivery, the same across retries
+	ID uint64 `json:"id"`
+	// Event is the type of event
+	Event enum.WebhookEvent `json:"event"`
+	// CreatedAt is the date the event was emitted
+	CreatedAt time.Time `json:"created_at"`
+	// Data is the content of the event
+	Data interface{} `json:"data"`
+}
+
+type SessionClosedEvent struct {
+	// SessionID is the id of the closed session
+	SessionID uuid.UUID `json:"session_id"`
+	// ClassID is the id of the class of the session
+	ClassID uint64 `json:"class_id"`
+	// Present is the number of students present
+	Present int `json:"present"`
+	// Absent is the number of students absent
+	Absent int `json:"absent"`
+}
+
+type StudentAbsentEvent struct {
+	// SessionID is the id of the session
+	SessionID uuid.UUID `json:"session_id"`
+	// ClassID is the id of the class of the session
+	ClassID uint64 `json:"class_id"`
+	// Student is the absent student
+	Student Student `json:"student"`
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"context"
+	"gin-template/logging"
+	"gorm.io/gorm"
+	"time"
+)
+
+type Job struct {
+	// Name is the name of the job, used in logs
+	Name string
+	// Interval is the duration between two runs of the job
+	Interval time.Duration
+	// Run is the function executed at each run, inside a transaction
+	Run func(tx *gorm.DB) error
+	// OwnTransactions is true if Run opens its own transactions, it then gets the connection instead of a transaction
+	OwnTransactions bool
+}
+
+// Start runs the job in background every interval until the context is done
+func (j Job) Start(ctx context.Context, db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(j.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var err error
+				if j.OwnTransactions {
+					err = j.Run(db.WithContext(ctx))
+				} else {
+					err = db.WithContext(ctx).Transaction(j.Run)
+				}
+				if err != nil {
+					logging.Error.Printf("job %s failed: %v", j.Name, err)
+				}
+			}
+		}
+	}()
+}
@@ -2,16 +2,22 @@ package middleware
 
 import (
 	"errors"
+	"fmt"
 	"gin-template/config"
+	"gin-template/logging"
 	token2 "gin-template/pkg/model"
 	"gin-template/pkg/model/enum"
+	"gin-template/utils/apikey"
+	"gin-template/utils/audit"
 	error2 "gin-template/utils/error"
 	jwt2 "gin-template/utils/jwt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"gorm.io/gorm"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Claims struct {
@@ -20,20 +26,44 @@ type Claims struct {
 	Role   enum.Role `json:"role"`
 }
 
+// APIKeyHeader is the header holding the API key of a service account, an alternative to a Bearer token
+const APIKeyHeader = "X-API-Key"
+
 type JwtMiddleware struct {
 	Conf config.JwtConfig
-	Jwt  jwt2.JwtManager
+	// Keys verifies the access tokens
+	Keys *jwt2.KeySet
+	// Tenant is the tenant served by the application, the API keys restricted to another one are refused
+	Tenant string
+	// DB records the use of the API keys out of the transactions of the requests, nil to not record it
+	DB *gorm.DB
 }
 
-func NewJwtMiddleware(conf config.JwtConfig) JwtMiddleware {
+func NewJwtMiddleware(conf config.Config, db *gorm.DB, keys *jwt2.KeySet) JwtMiddleware {
 	return JwtMiddleware{
-		Conf: conf,
-		Jwt:  jwt2.NewJwtManager(conf.Secret, conf.Expiration),
+		Conf:   conf.Jwt,
+		Keys:   keys,
+		Tenant: conf.Tenant,
+		DB:     db,
 	}
 }
 
-func (j *JwtMiddleware) MiddlewareFunc(accessRoles map[string][]enum.Role) gin.HandlerFunc {
+// Authorize authenticates the request and checks that the role of the user grants
+// the permission of the policy, a route without policy is always denied.
+// When the role requires a second factor, a login without it only reaches the account routes
+// so that the user can enroll. A request with an API key is authorized by authorizeAPIKey.
+func (j *JwtMiddleware) Authorize(policy Policy) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if policy.IsZero() {
+			c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("access denied"))
+			return
+		}
+
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			j.authorizeAPIKey(c, policy, key)
+			return
+		}
+
 		db := c.MustGet("DB").(*gorm.DB)
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -48,44 +78,145 @@ func (j *JwtMiddleware) MiddlewareFunc(accessRoles map[string][]enum.Role) gin.H
 		token = strings.TrimPrefix(token, "Bearer ")
 		c.Set("token", token)
 
-		rClaims, err := jwt2.ParseToken(token, j.Conf.Secret)
+		rClaims, err := jwt2.ParseTypedToken(token, j.Keys, jwt2.AccessToken)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, error2.UnauthorizedError(err.Error()))
 			return
 		}
-		c.Set("claims", rClaims)
 
-		tokenModel := token2.TokenModel{Tx: db}
-		err = tokenModel.FindToken(&token2.Token{TokenID: rClaims.ID}).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, error2.UnauthorizedError("token is expired"))
+		// check the token family has not been revoked
+		active, err := token2.NewTokenModel(db).IsFamilyActive(rClaims.Family, time.Now())
+		if err != nil {
+			error2.FromDatabaseError(err).FillHTTPContextError(c)
 			return
 		}
-
-		// Get last segment of handler path
-		// e.g. gin-template/pkg/service/v1.Login-fm => Login
-		// e.g. gin-template/pkg/common/AuthInterface.Login => Login
-		handler := c.HandlerName()
-		if strings.HasSuffix(handler, "-fm") {
-			handler = handler[:len(handler)-3]
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, error2.UnauthorizedError("token is revoked"))
+			return
 		}
-		handler = handler[strings.LastIndex(handler, ".")+1:]
+		c.Set("claims", rClaims)
+		audit.SetActor(db, rClaims.UserId)
 
-		// check if user has access to this route
-		access, ok := accessRoles[handler]
-		if !ok {
+		// check if the role of the user grants the permission
+		role, err := token2.NewRoleModel(db).GetByName(rClaims.Role)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("access denied"))
 			return
 		}
+		if err != nil {
+			error2.FromDatabaseError(err).FillHTTPContextError(c)
+			return
+		}
+		permissions := loginPermissions(role, rClaims.MFA)
+		c.Set("permissions", permissions)
 
-		// check if role is allowed to access this route
-		for _, role := range access {
-			if rClaims.Role.HasPermission(role) {
-				c.Next()
+		if !permissions.Has(policy.Permission) {
+			if role.Permissions.Has(policy.Permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("two-factor authentication is required for your role"))
 				return
 			}
+			c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("access denied"))
+			return
 		}
 
+		c.Next()
+	}
+}
+
+// loginPermissions returns the permissions granted to a login, only the account ones when the role
+// requires a second factor the login did not provide, so that the handlers checking further permissions refuse them too
+func loginPermissions(role *token2.Role, mfa bool) token2.Permissions {
+	if role.RequireMFA && !mfa {
+		return role.Permissions.Intersect(token2.Permissions{enum.ACCOUNT_SELF})
+	}
+	return role.Permissions
+}
+
+// authorizeAPIKey authenticates a request with the API key of a service account and checks that both
+// the role of the service account and the key grant the permission of the policy.
+// A key restricted to a class only reaches the routes of the class.
+func (j *JwtMiddleware) authorizeAPIKey(c *gin.Context, policy Policy, key string) {
+	invalid := error2.UnauthorizedError("API key is invalid")
+	prefix, secret, ok := apikey.Parse(key)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, invalid)
+		return
+	}
+
+	db := c.MustGet("DB").(*gorm.DB)
+	k, err := token2.NewAPIKeyModel(db).FindByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, invalid)
+		return
+	}
+	if err != nil {
+		error2.FromDatabaseError(err).FillHTTPContextError(c)
+		return
+	}
+	if !apikey.Verify(secret, k.Hash) || k.User == nil || k.User.Account == nil || !k.User.Account.Service {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, invalid)
+		return
+	}
+
+	now := time.Now()
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, error2.UnauthorizedError("API key is expired"))
+		return
+	}
+	if k.Tenant != "" && k.Tenant != j.Tenant {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, error2.UnauthorizedError("API key is restricted to another tenant"))
+		return
+	}
+
+	if j.DB != nil {
+		if err := token2.NewAPIKeyModel(j.DB).Touch(k.ID, c.ClientIP(), now); err != nil {
+			logging.Error.Printf("api key %d: %v", k.ID, err)
+		}
+	}
+
+	c.Set("claims", &jwt2.Claims{UserId: k.User.ID, Role: k.User.Role, Type: jwt2.APIKeyToken})
+	audit.SetActor(db, k.User.ID)
+
+	role, err := token2.NewRoleModel(db).GetByName(k.User.Role)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("access denied"))
+		return
+	}
+	if err != nil {
+		error2.FromDatabaseError(err).FillHTTPContextError(c)
+		return
+	}
+	permissions := role.Permissions.Intersect(k.Permissions)
+	c.Set("permissions", permissions)
+
+	if !permissions.Has(policy.Permission) {
+		c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError("access denied"))
+		return
+	}
+	if k.ClassID != nil && c.Param("class_id") != strconv.FormatUint(*k.ClassID, 10) {
+		c.AbortWithStatusJSON(http.StatusForbidden, error2.ForbiddenError(
+			fmt.Sprintf("API key is restricted to the class %d", *k.ClassID),
+		))
+		return
+	}
+
+	c.Next()
+}
+
+// HasPermission returns true if the role of the authenticated user grants the permission
+func HasPermission(c *gin.Context, permission enum.Permission) bool {
+	permissions, ok := c.Get("permissions")
+	if !ok {
+		return false
+	}
+	return permissions.(token2.Permissions).Has(permission)
+}
+
+// GrantedPermissions returns the permissions of the authenticated user, restricted to its API key if any
+func GrantedPermissions(c *gin.Context) token2.Permissions {
+	permissions, ok := c.Get("permissions")
+	if !ok {
+		return token2.Permissions{}
 	}
+	return permissions.(token2.Permissions)
 }
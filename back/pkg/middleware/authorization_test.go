@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestLoginPermissions_MFARequired tests that a login without the second factor required by its role
+// only holds the account permission, whatever the role grants
+func TestLoginPermissions_MFARequired(t *testing.T) {
+	roles := []model.Role{
+		{Name: enum.SUPERADMIN, Permissions: model.Permissions{enum.ALL_PERMISSIONS}, RequireMFA: true},
+		{Name: enum.ADMIN, Permissions: model.Permissions{enum.ACCOUNT_SELF, enum.USER_READ, enum.USER_WRITE, enum.PRIVACY_MANAGE}, RequireMFA: true},
+	}
+
+	for _, role := range roles {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set("permissions", loginPermissions(&role, false))
+
+		if !HasPermission(c, enum.ACCOUNT_SELF) {
+			t.Errorf("Login of %s without MFA should reach its account", role.Name)
+		}
+		for _, permission := range []enum.Permission{enum.USER_READ, enum.USER_WRITE, enum.ROLE_MANAGE, enum.PRIVACY_MANAGE} {
+			if HasPermission(c, permission) {
+				t.Errorf("Login of %s without MFA should not hold %s", role.Name, permission)
+			}
+		}
+
+		if !loginPermissions(&role, true).Has(enum.USER_WRITE) {
+			t.Errorf("Login of %s with MFA should hold the permissions of its role", role.Name)
+		}
+	}
+}
+
+// TestLoginPermissions_MFANotRequired tests that a role without second factor keeps its permissions
+func TestLoginPermissions_MFANotRequired(t *testing.T) {
+	role := model.Role{Name: enum.STUDENT, Permissions: model.Permissions{enum.ACCOUNT_SELF, enum.USER_READ}}
+
+	if permissions := loginPermissions(&role, false); !permissions.Has(enum.USER_READ) {
+		t.Errorf("Role without MFA should keep its permissions, got %v", permissions)
+	}
+
+	guardian := model.Role{Name: enum.GUARDIAN, Permissions: model.Permissions{enum.GUARDIAN_ACCESS}, RequireMFA: true}
+	if permissions := loginPermissions(&guardian, false); len(permissions) != 0 {
+		t.Errorf("Login without MFA should not gain the account permission its role lacks, got %v", permissions)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"gin-template/utils/audit"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"time"
@@ -22,8 +23,10 @@ func (dm *DatabaseMiddleware) SetDBMiddleware() gin.HandlerFunc {
 		var db *gorm.DB
 		timeoutContext, cancel := context.WithTimeout(context.Background(), time.Hour)
 		defer cancel()
+		// The audit information is filled along the request, e.g. with the user once authenticated
+		auditContext := audit.WithInfo(timeoutContext, &audit.Info{RequestID: c.GetString("request_id")})
 		// We use the timeout context to avoid the database connection to be blocked
-		db = dm.DB.WithContext(timeoutContext).Begin()
+		db = dm.DB.WithContext(auditContext).Begin()
 		if db.Error != nil {
 			c.AbortWithStatusJSON(500, gin.H{"error": "database error"})
 			return
@@ -27,12 +27,18 @@ func CustomLogger(param gin.LogFormatterParams) string {
 		user = fmt.Sprintf("%d", claims.UserId)
 	}
 
-	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %20s | %15s |%s %-7s %s %#v\n%s",
+	requestId := NoId
+	if id, ok := param.Keys["request_id"]; ok {
+		requestId = id.(string)
+	}
+
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %20s | %15s | %36s |%s %-7s %s %#v\n%s",
 		param.TimeStamp.Format("01/02/2006 - 15:04:05"),
 		statusColor, param.StatusCode, resetColor,
 		param.Latency,
 		param.ClientIP,
 		user,
+		requestId,
 		methodColor, param.Method, resetColor,
 		param.Path,
 		param.ErrorMessage,
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-template/pkg/model/enum"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Policy is the authorization policy of a route
+type Policy struct {
+	// Permission is the permission the role of the user must grant
+	Permission enum.Permission
+	// Public is true if the route is reachable without authentication
+	Public bool
+}
+
+// Public is the policy of the routes reachable without authentication
+var Public = Policy{Public: true}
+
+// Require returns the policy of the routes requiring a permission
+func Require(permission enum.Permission) Policy {
+	return Policy{Permission: permission}
+}
+
+// IsZero returns true if no policy is declared
+func (p Policy) IsZero() bool {
+	return !p.Public && p.Permission == ""
+}
+
+func (p Policy) String() string {
+	switch {
+	case p.Public:
+		return "public"
+	case p.IsZero():
+		return "none"
+	default:
+		return p.Permission.String()
+	}
+}
+
+// RoutePolicy is the policy declared for a route
+type RoutePolicy struct {
+	// Method is the HTTP method of the route
+	Method string
+	// Path is the full path of the route
+	Path string
+	// Policy is the authorization policy of the route
+	Policy Policy
+}
+
+// PolicyTable is the table of the policies declared for the routes of a router
+type PolicyTable struct {
+	basePath string
+	routes   []RoutePolicy
+}
+
+// Routes returns the declared route policies sorted by path and method
+func (t *PolicyTable) Routes() []RoutePolicy {
+	routes := make([]RoutePolicy, len(t.routes))
+	copy(routes, t.routes)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// Check returns an error if a route served under the base path of the router
+// was registered without policy, or directly on the gin engine
+func (t *PolicyTable) Check(routes gin.RoutesInfo) error {
+	declared := make(map[string]Policy, len(t.routes))
+	for _, r := range t.routes {
+		declared[r.Method+" "+r.Path] = r.Policy
+	}
+
+	missing := make([]string, 0)
+	for _, r := range routes {
+		if r.Path != t.basePath && !strings.HasPrefix(r.Path, strings.TrimSuffix(t.basePath, "/")+"/") {
+			continue
+		}
+		if policy, ok := declared[r.Method+" "+r.Path]; !ok || policy.IsZero() {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("routes without authorization policy: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Router registers routes along with their authorization policy,
+// the routes use the policy of their router which is inherited by its groups
+type Router struct {
+	group   *gin.RouterGroup
+	jwt     JwtMiddleware
+	policy  Policy
+	table   *PolicyTable
+	limiter *RateLimiter
+	// limits are the rate limits applied to the routes once authorized
+	limits []gin.HandlerFunc
+}
+
+// NewRouter creates a router on a gin group authorizing its routes with the middleware,
+// its routes have no policy until one is declared
+func NewRouter(group *gin.RouterGroup, jwt JwtMiddleware, limiter *RateLimiter) *Router {
+	return &Router{
+		group:   group,
+		jwt:     jwt,
+		table:   &PolicyTable{basePath: group.BasePath()},
+		limiter: limiter,
+	}
+}
+
+// Table returns the policy table shared by the router and its groups
+func (r *Router) Table() *PolicyTable {
+	return r.table
+}
+
+// Group creates a group of routes with the same path prefix and policy
+func (r *Router) Group(relativePath string) *Router {
+	router := *r
+	router.group = r.group.Group(relativePath)
+	return &router
+}
+
+// Require returns the router registering routes which require the permission
+func (r *Router) Require(permission enum.Permission) *Router {
+	return r.withPolicy(Require(permission))
+}
+
+// Public returns the router registering routes reachable without authentication
+func (r *Router) Public() *Router {
+	return r.withPolicy(Public)
+}
+
+func (r *Router) withPolicy(policy Policy) *Router {
+	router := *r
+	router.policy = policy
+	return &router
+}
+
+// Limit returns the router registering routes rate limited with the limits of a route group
+func (r *Router) Limit(group string) *Router {
+	router := *r
+	router.limits = append(append([]gin.HandlerFunc{}, r.limits...), r.limiter.Limit(group))
+	return &router
+}
+
+// Handle registers a route with the policy of the router
+func (r *Router) Handle(method, relativePath string, handlers ...gin.HandlerFunc) {
+	fullPath := path.Join(r.group.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(fullPath, "/") {
+		fullPath += "/"
+	}
+	r.table.routes = append(r.table.routes, RoutePolicy{Method: method, Path: fullPath, Policy: r.policy})
+
+	handlers = append(append([]gin.HandlerFunc{}, r.limits...), handlers...)
+	if !r.policy.Public {
+		handlers = append([]gin.HandlerFunc{r.jwt.Authorize(r.policy)}, handlers...)
+	}
+	r.group.Handle(method, relativePath, handlers...)
+}
+
+// GET registers a GET route
+func (r *Router) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+// POST registers a POST route
+func (r *Router) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+// PUT registers a PUT route
+func (r *Router) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+// DELETE registers a DELETE route
+func (r *Router) DELETE(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, relativePath, handlers...)
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gin-template/config"
+	"gin-template/logging"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"gin-template/utils/ratelimit"
+	"github.com/gin-gonic/gin"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// maxPeekedBody is the maximum size of a body read to find the account of a login
+const maxPeekedBody = 1 << 20
+
+// RateLimiter limits the requests of the route groups with the token buckets of a store
+type RateLimiter struct {
+	store  ratelimit.Store
+	groups map[string]config.RateLimitGroupConfig
+}
+
+// NewRateLimiter creates a rate limiter with the limits of the route groups
+func NewRateLimiter(store ratelimit.Store, conf config.RateLimitConfig) *RateLimiter {
+	return &RateLimiter{store: store, groups: conf.Groups}
+}
+
+// limitOf converts a configured rule to a limit
+func limitOf(rule config.RateLimitRule) ratelimit.Limit {
+	return ratelimit.Limit{Burst: rule.Burst, Period: time.Duration(rule.Period) * time.Second}
+}
+
+// accountKey returns the authenticated user of a request, or else the account of the login in its body
+func accountKey(c *gin.Context) string {
+	if claims, ok := c.Get("claims"); ok {
+		return fmt.Sprintf("user:%d", claims.(*jwt2.Claims).UserId)
+	}
+	if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekedBody))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+	var login struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}
+	if json.Unmarshal(body, &login) != nil {
+		return ""
+	}
+	if login.Username != "" {
+		return "login:" + strings.ToLower(login.Username)
+	}
+	if login.Email != "" {
+		return "login:" + strings.ToLower(login.Email)
+	}
+	return ""
+}
+
+// Limit returns the middleware limiting the requests of a route group by client address,
+// by account and by session, a refused request is answered 429 with a Retry-After header.
+// The requests are allowed when the store fails so that it never locks the users out
+func (l *RateLimiter) Limit(group string) gin.HandlerFunc {
+	conf := l.groups[group]
+	return func(c *gin.Context) {
+		now := time.Now()
+		buckets := map[string]config.RateLimitRule{
+			"ip:" + c.ClientIP(): conf.IP,
+		}
+		if conf.Account.Burst > 0 {
+			if key := accountKey(c); key != "" {
+				buckets[key] = conf.Account
+			}
+		}
+		if sessionID := c.Param("session_id"); sessionID != "" {
+			buckets["session:"+sessionID] = conf.Session
+		}
+
+		for key, rule := range buckets {
+			limit := limitOf(rule)
+			if limit.IsZero() {
+				continue
+			}
+
+			wait, allowed, err := l.store.Take(group+":"+key, limit, now)
+			if err != nil {
+				logging.Error.Printf("rate limit: %v", err)
+				continue
+			}
+			if !allowed {
+				c.Header("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				error2.TooManyRequestsError("").FillHTTPContextError(c)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"gin-template/pkg/dto"
 	"gorm.io/gorm"
+	"time"
 )
 
 type Account struct {
@@ -12,7 +13,11 @@ type Account struct {
 	Email    string `json:"email" gorm:"uniqueIndex:unique_idx_email;not null"`
 	Username string `json:"username" gorm:"uniqueIndex:unique_idx_username;not null;size:80"`
 	Password string `json:"password" gorm:"not null"`
-	User     User   `json:"user" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;re"`
+	// VerifiedAt is the date the email was verified, nil while unverified
+	VerifiedAt *time.Time `json:"verified_at"`
+	// Service is true for a service account, which authenticates with API keys and never logs in
+	Service bool `json:"service" gorm:"not null;default:false"`
+	User    User `json:"user" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;re"`
 }
 
 // TableName returns the name of the table
@@ -36,6 +41,18 @@ func (a *AccountModel) FindByEmailOrUsername(model *Account) *gorm.DB {
 	).Or("username = ?", model.Username).Preload("User").First(model)
 }
 
+// FindByEmail finds an account by email, ignoring the case
+func (a *AccountModel) FindByEmail(email string, model *Account) *gorm.DB {
+	return a.Tx.Where("lower(email) = lower(?)", email).Preload("User").First(model)
+}
+
+// UsernameExists returns true if an account, including a deleted one, has the username
+func (a *AccountModel) UsernameExists(username string) (bool, error) {
+	var count int64
+	err := a.Tx.Unscoped().Model(&Account{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, err
+}
+
 // FindAll finds all accounts in the database and returns them
 func (a *AccountModel) FindAll(models *[]Account, params dto.UserQueryParams) *gorm.DB {
 	return a.Tx.Scopes(func(db *gorm.DB) *gorm.DB {
@@ -46,13 +63,31 @@ func (a *AccountModel) FindAll(models *[]Account, params dto.UserQueryParams) *g
 	).Preload("User").Find(&models)
 }
 
+// FindServiceAccounts finds the service accounts along with their user
+func (a *AccountModel) FindServiceAccounts() ([]Account, error) {
+	var accounts []Account
+	err := a.Tx.Where("service").Preload("User").Order("id").Find(&accounts).Error
+	return accounts, err
+}
+
 // Create creates a new account in the database
 func (a *AccountModel) Create(model *Account) error {
 	return a.Tx.Create(model).Error
 }
 
-// Update updates an account in the database
+// Update updates an account in the database, a new email is not verified anymore
 func (a *AccountModel) Update(model *Account) error {
+	if model.Email != "" {
+		res := a.Tx.Model(&Account{}).
+			Where("id = ? AND LOWER(email) <> LOWER(?)", model.ID, model.Email).
+			Update("verified_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			model.VerifiedAt = nil
+		}
+	}
 	return a.Tx.Select("User").Updates(model).Error
 }
 
@@ -60,3 +95,8 @@ func (a *AccountModel) Update(model *Account) error {
 func (a *AccountModel) Delete(model Account) error {
 	return a.Tx.Select("User").Delete(&model).Error
 }
+
+// Verify marks the email of an account as verified
+func (a *AccountModel) Verify(id uint64, now time.Time) *gorm.DB {
+	return a.Tx.Model(&Account{}).Where("id = ? AND verified_at IS NULL", id).Update("verified_at", now)
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 )
 
 // SetupTestDatabase sets up the test database
@@ -30,6 +31,16 @@ func SetupTestDatabase() *gorm.DB {
 	})
 
 	// Truncate all tables
+	db.Migrator().DropTable(&StaffInvitation{})
+	db.Migrator().DropTable(&SigningKey{})
+	db.Migrator().DropTable(&APIKey{})
+	db.Migrator().DropTable(&PasswordHistory{})
+	db.Migrator().DropTable(&LoginAttempt{})
+	db.Migrator().DropTable(&RateLimitBucket{})
+	db.Migrator().DropTable(&Identity{})
+	db.Migrator().DropTable(&RecoveryCode{})
+	db.Migrator().DropTable(&MFA{})
+	db.Migrator().DropTable(&PasswordReset{})
 	db.Migrator().DropTable(&User{})
 	db.Migrator().DropTable(&Account{})
 	db.Migrator().DropTable(&Token{})
@@ -39,6 +50,15 @@ func SetupTestDatabase() *gorm.DB {
 		User{},
 		Token{},
 		Account{},
+		PasswordReset{},
+		MFA{},
+		RecoveryCode{},
+		Identity{},
+		RateLimitBucket{},
+		LoginAttempt{},
+		PasswordHistory{},
+		SigningKey{},
+		StaffInvitation{},
 	)
 
 	return db
@@ -213,6 +233,38 @@ func TestAccountModel_Update(t *testing.T) {
 	}
 }
 
+// TestAccountModel_UpdateEmail tests that a changed email must be verified again
+func TestAccountModel_UpdateEmail(t *testing.T) {
+	db := SetupTestDatabase()
+	accountModel := AccountModel{Tx: db}
+
+	verifiedAt := time.Now()
+	account := Account{
+		Email:      "user.email@gmail.com",
+		Username:   "username.email",
+		Password:   "password",
+		VerifiedAt: &verifiedAt,
+		User:       User{FirstName: "first", LastName: "last"},
+	}
+	db.Create(&account)
+
+	if err := accountModel.Update(&Account{ID: account.ID, Email: "User.Email@gmail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&account, account.ID)
+	if account.VerifiedAt == nil {
+		t.Error("Email differing only by its case should stay verified")
+	}
+
+	if err := accountModel.Update(&Account{ID: account.ID, Email: "classmate@gmail.com"}); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&account, account.ID)
+	if account.Email != "classmate@gmail.com" || account.VerifiedAt != nil {
+		t.Error("Changed email should not be verified anymore")
+	}
+}
+
 func TestAccountModel_Delete(t *testing.T) {
 	// Setup the test database
 	db := SetupTestDatabase()
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type AlertRule struct {
+	gorm.Model
+	// ID is the id of the rule
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// ClassID is the class the rule applies to, null for every class of the institution
+	ClassID *uint64 `json:"class_id" gorm:"index"`
+	// Class is the class the rule applies to
+	Class *Class `json:"class" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Kind is the kind of rule
+	Kind enum.AlertRuleKind `json:"kind" gorm:"type:varchar(40);not null"`
+	// Threshold is the number of absences or the attendance rate in percent triggering the alert
+	Threshold float64 `json:"threshold" gorm:"not null"`
+	// WindowDays is the number of days the attendance is evaluated on, 0 for the current term
+	WindowDays int `json:"window_days" gorm:"not null;default:0"`
+	// Notify is true if the alerts are sent to the teachers of the class
+	Notify bool `json:"notify" gorm:"not null;default:false"`
+	// Active is false if the rule must not be evaluated
+	Active bool `json:"active" gorm:"not null;default:true"`
+}
+
+// TableName overrides the default table name generated by GORM to be `alert_rules`
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+type Alert struct {
+	// ID is the id of the alert
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the alert was raised
+	CreatedAt time.Time `json:"created_at"`
+	// RuleID is the foreign key to the alert rule table
+	RuleID uint64 `json:"rule_id" gorm:"not null;index"`
+	// Rule is the rule that raised the alert
+	Rule *AlertRule `json:"rule" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// ClassID is the foreign key to the class table
+	ClassID uint64 `json:"class_id" gorm:"not null;index"`
+	// Class is the class of the student at risk
+	Class *Class `json:"class" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// StudentID is the foreign key to the student table
+	StudentID uint64 `json:"student_id" gorm:"not null;index"`
+	// Student is the student at risk
+	Student *Student `json:"student" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Value is the number of absences or the attendance rate that raised the alert
+	Value float64 `json:"value"`
+	// AcknowledgedAt is the date a teacher acknowledged the alert, null while open
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	// AcknowledgedBy is the id of the user who acknowledged the alert
+	AcknowledgedBy *uint64 `json:"acknowledged_by"`
+	// NotifiedAt is the date the alert was sent to the teachers
+	NotifiedAt *time.Time `json:"notified_at" gorm:"index"`
+}
+
+// TableName overrides the default table name generated by GORM to be `alerts`
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+type AlertRuleModel struct {
+	Tx *gorm.DB
+}
+
+// NewAlertRuleModel creates a new alert rule model
+func NewAlertRuleModel(tx *gorm.DB) *AlertRuleModel {
+	return &AlertRuleModel{Tx: tx}
+}
+
+// Create creates a new rule
+func (m *AlertRuleModel) Create(rule *AlertRule) error {
+	return m.Tx.Omit(clause.Associations).Create(rule).Error
+}
+
+// GetByID gets a rule by ID
+func (m *AlertRuleModel) GetByID(id uint64) (*AlertRule, error) {
+	var rule AlertRule
+	err := m.Tx.First(&rule, id).Error
+	return &rule, err
+}
+
+// FindAll gets the rules, only the institution rules and those of the class if a class is given
+func (m *AlertRuleModel) FindAll(classID uint64) ([]AlertRule, error) {
+	var rules []AlertRule
+	tx := m.Tx.Order("id")
+	if classID != 0 {
+		tx = tx.Where("class_id = ? OR class_id IS NULL", classID)
+	}
+	err := tx.Find(&rules).Error
+	return rules, err
+}
+
+// FindActiveForClass gets the active rules applying to a class
+func (m *AlertRuleModel) FindActiveForClass(classID uint64) ([]AlertRule, error) {
+	var rules []AlertRule
+	err := m.Tx.Where("active = ? AND (class_id = ? OR class_id IS NULL)", true, classID).Find(&rules).Error
+	return rules, err
+}
+
+// Update updates the threshold, window, notification and state of a rule
+func (m *AlertRuleModel) Update(rule *AlertRule) *gorm.DB {
+	return m.Tx.Model(rule).Select("threshold", "window_days", "notify", "active").Updates(rule)
+}
+
+// Delete deletes a rule
+func (m *AlertRuleModel) Delete(id uint64) *gorm.DB {
+	return m.Tx.Delete(&AlertRule{}, id)
+}
+
+type AlertModel struct {
+	Tx *gorm.DB
+}
+
+// NewAlertModel creates a new alert model
+func NewAlertModel(tx *gorm.DB) *AlertModel {
+	return &AlertModel{Tx: tx}
+}
+
+// Create raises an alert
+func (m *AlertModel) Create(alert *Alert) error {
+	return m.Tx.Omit(clause.Associations).Create(alert).Error
+}
+
+// HasOpen returns true if a rule already raised an alert not acknowledged yet for a student
+func (m *AlertModel) HasOpen(ruleID, studentID uint64) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&Alert{}).
+		Where("rule_id = ? AND student_id = ? AND acknowledged_at IS NULL", ruleID, studentID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindByClass gets the alerts of a class with the rules and students, most recent first
+func (m *AlertModel) FindByClass(classID uint64, openOnly bool) ([]Alert, error) {
+	var alerts []Alert
+	tx := m.Tx.Where("class_id = ?", classID)
+	if openOnly {
+		tx = tx.Where("acknowledged_at IS NULL")
+	}
+	err := tx.Preload("Rule", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Student").Order("created_at desc, id desc").Find(&alerts).Error
+	return alerts, err
+}
+
+// Acknowledge marks an open alert of a class as acknowledged by a user
+func (m *AlertModel) Acknowledge(classID, id, userID uint64) *gorm.DB {
+	return m.Tx.Model(&Alert{}).
+		Where("id = ? AND class_id = ? AND acknowledged_at IS NULL", id, classID).
+		Updates(map[string]interface{}{"acknowledged_at": time.Now(), "acknowledged_by": userID})
+}
+
+// FindUnnotified gets the ids of the alerts of the notifying rules not sent yet
+func (m *AlertModel) FindUnnotified() ([]uint64, error) {
+	var ids []uint64
+	err := m.Tx.Model(&Alert{}).
+		Joins("JOIN alert_rules ON alert_rules.id = alerts.rule_id AND alert_rules.notify").
+		Where("alerts.notified_at IS NULL").
+		Order("alerts.class_id, alerts.id").
+		Pluck("alerts.id", &ids).Error
+	return ids, err
+}
+
+// LockUnnotified locks and gets an alert not sent yet, with its rule, its student and its class with the teachers.
+// An alert locked by another replica or already sent is not found so that each alert is sent once.
+func (m *AlertModel) LockUnnotified(id uint64) (*Alert, error) {
+	var alert Alert
+	err := m.Tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "alerts"}, Options: "SKIP LOCKED"}).
+		Where("alerts.id = ? AND alerts.notified_at IS NULL", id).
+		Preload("Rule").
+		Preload("Student").
+		Preload("Class.Teachers.Account").
+		First(&alert).Error
+	return &alert, err
+}
+
+// MarkNotified marks alerts as sent
+func (m *AlertModel) MarkNotified(ids []uint64, notifiedAt time.Time) error {
+	return m.Tx.Model(&Alert{}).Where("id IN ?", ids).Update("notified_at", notifiedAt).Error
+}
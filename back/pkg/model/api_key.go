@@ -0,0 +1,93 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// usageResolution is the precision of the last use of the API keys, a key is not updated more often
+const usageResolution = time.Minute
+
+// APIKey is a named key of a service account authenticating the requests of an integration
+type APIKey struct {
+	// ID is the id of the key
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the key was created
+	CreatedAt time.Time `json:"created_at"`
+	// Name describes the integration using the key
+	Name string `json:"name" gorm:"size:80;not null"`
+	// UserID is the foreign key to the user table, the service account of the key
+	UserID uint64 `json:"user_id" gorm:"not null;index"`
+	// User is the service account of the key
+	User *User `json:"user" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Prefix is the public part of the key identifying it
+	Prefix string `json:"prefix" gorm:"size:16;not null;uniqueIndex"`
+	// Hash is the SHA-256 hash of the secret part of the key
+	Hash string `json:"-" gorm:"size:64;not null"`
+	// Permissions is the set of permissions the key is restricted to, within the ones of the role of the service account
+	Permissions Permissions `json:"permissions" gorm:"type:text;not null"`
+	// ClassID restricts the key to the routes of a class when set
+	ClassID *uint64 `json:"class_id" gorm:"index"`
+	// Class is the class the key is restricted to
+	Class *Class `json:"class" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Tenant restricts the key to a tenant when set
+	Tenant string `json:"tenant" gorm:"size:80"`
+	// ExpiresAt is the date the key stops being accepted, null if it never expires
+	ExpiresAt *time.Time `json:"expires_at"`
+	// LastUsedAt is the date the key was last used, to the minute
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// LastUsedIP is the address of the client which last used the key
+	LastUsedIP string `json:"last_used_ip" gorm:"size:45"`
+	// CreatedBy is the id of the user who created the key
+	CreatedBy uint64 `json:"created_by"`
+}
+
+// TableName overrides the default table name generated by GORM to be `api_keys`
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+type APIKeyModel struct {
+	Tx *gorm.DB
+}
+
+// NewAPIKeyModel creates a new API key model
+func NewAPIKeyModel(tx *gorm.DB) *APIKeyModel {
+	return &APIKeyModel{Tx: tx}
+}
+
+// Create creates a key
+func (m *APIKeyModel) Create(key *APIKey) error {
+	return m.Tx.Omit(clause.Associations).Create(key).Error
+}
+
+// FindByPrefix finds a key by its prefix along with its service account,
+// the user is nil when the service account is deleted
+func (m *APIKeyModel) FindByPrefix(prefix string) (*APIKey, error) {
+	var key APIKey
+	err := m.Tx.Preload("User.Account").Where("prefix = ?", prefix).First(&key).Error
+	return &key, err
+}
+
+// FindByUser gets the keys of a service account
+func (m *APIKeyModel) FindByUser(userID uint64) ([]APIKey, error) {
+	var keys []APIKey
+	err := m.Tx.Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// Delete deletes a key of a service account, it returns false if the service account has no such key
+func (m *APIKeyModel) Delete(userID, keyID uint64) (bool, error) {
+	result := m.Tx.Where("user_id = ?", userID).Delete(&APIKey{}, keyID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Touch records the use of a key at most once per minute. The statement is raw so that the usage
+// is not recorded in the audit log, and must run out of the transaction of a request which may not be committed
+func (m *APIKeyModel) Touch(keyID uint64, ip string, now time.Time) error {
+	return m.Tx.Exec(
+		"UPDATE api_keys SET last_used_at = ?, last_used_ip = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now, ip, keyID, now.Add(-usageResolution),
+	).Error
+}
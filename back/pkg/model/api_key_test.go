@@ -0,0 +1,79 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// setupAPIKeyTestDatabase sets up the test database with the API keys, which may be restricted to a class
+func setupAPIKeyTestDatabase() *gorm.DB {
+	db := SetupClassTestDatabase().Tx
+	db.AutoMigrate(APIKey{})
+	return db
+}
+
+// TestAPIKeyModel_FindByPrefix tests that a key is found with its service account until the account is deleted
+func TestAPIKeyModel_FindByPrefix(t *testing.T) {
+	db := setupAPIKeyTestDatabase()
+	keyModel := NewAPIKeyModel(db)
+
+	account := Account{Email: "sync@service.invalid", Username: "sync", Service: true, User: User{FirstName: "Sync", Role: enum.ADMIN}}
+	db.Create(&account)
+
+	key := APIKey{Name: "nightly sync", UserID: account.User.ID, Prefix: "0123456789abcdef", Hash: "hash", Permissions: Permissions{enum.USER_READ}}
+	if err := keyModel.Create(&key); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := keyModel.FindByPrefix("0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.User == nil || found.User.Account == nil || !found.User.Account.Service {
+		t.Error("Key should be found with its service account")
+	}
+	if !found.Permissions.Has(enum.USER_READ) || found.Permissions.Has(enum.USER_WRITE) {
+		t.Error("Key should keep its permissions")
+	}
+
+	db.Delete(&account.User)
+	found, err = keyModel.FindByPrefix("0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.User != nil {
+		t.Error("Key of a deleted service account should have no user")
+	}
+}
+
+// TestAPIKeyModel_Touch tests that the use of a key is recorded at most once per minute
+func TestAPIKeyModel_Touch(t *testing.T) {
+	db := setupAPIKeyTestDatabase()
+	keyModel := NewAPIKeyModel(db)
+
+	account := Account{Email: "touch@service.invalid", Username: "touch", Service: true, User: User{FirstName: "Touch", Role: enum.ADMIN}}
+	db.Create(&account)
+	key := APIKey{Name: "touch", UserID: account.User.ID, Prefix: "fedcba9876543210", Hash: "hash"}
+	keyModel.Create(&key)
+
+	now := time.Now().Truncate(time.Second)
+	if err := keyModel.Touch(key.ID, "1.2.3.4", now); err != nil {
+		t.Fatal(err)
+	}
+	if err := keyModel.Touch(key.ID, "5.6.7.8", now.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	found, _ := keyModel.FindByPrefix("fedcba9876543210")
+	if found.LastUsedAt == nil || !found.LastUsedAt.Equal(now) || found.LastUsedIP != "1.2.3.4" {
+		t.Error("Use within the minute should not be recorded again")
+	}
+
+	keyModel.Touch(key.ID, "5.6.7.8", now.Add(2*time.Minute))
+	found, _ = keyModel.FindByPrefix("fedcba9876543210")
+	if found.LastUsedIP != "5.6.7.8" {
+		t.Error("Use after the minute should be recorded")
+	}
+}
@@ -0,0 +1,170 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Attendance struct {
+	// ID is the id of the attendance record
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the attendance was first recorded
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the date of the last attendance change
+	UpdatedAt time.Time `json:"updated_at"`
+	// SessionID is the foreign key to the session table
+	SessionID uuid.UUID `json:"session_id" gorm:"type:uuid;not null;uniqueIndex:unique_idx_attendance"`
+	// Session is the session the attendance is recorded for
+	Session *Session `json:"session" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// StudentID is the foreign key to the student table
+	StudentID uint64 `json:"student_id" gorm:"not null;uniqueIndex:unique_idx_attendance;index"`
+	// Student is the student whose attendance is recorded
+	Student *Student `json:"student" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Status is the attendance status of the student
+	Status enum.AttendanceStatus `json:"status" gorm:"type:varchar(20);not null"`
+	// Manual is true if the attendance was set by a staff member rather than by the student check-in
+	Manual bool `json:"manual" gorm:"not null;default:false"`
+	// Justified is true if the absence was justified
+	Justified bool `json:"justified" gorm:"not null;default:false"`
+}
+
+// AttendanceStats is the attendance summary of a student
+type AttendanceStats struct {
+	// StudentID is the id of the student
+	StudentID uint64
+	// Total is the number of sessions with an attendance record
+	Total int64
+	// Present is the number of sessions the student attended
+	Present int64
+	// UnjustifiedAbsences is the number of sessions the student missed without justification
+	UnjustifiedAbsences int64
+}
+
+// Rate returns the attendance rate in percent, 100 if there is no session
+func (s AttendanceStats) Rate() float64 {
+	if s.Total == 0 {
+		return 100
+	}
+	return float64(s.Present) * 100 / float64(s.Total)
+}
+
+// TableName overrides the default table name generated by GORM to be `attendances`
+func (Attendance) TableName() string {
+	return "attendances"
+}
+
+type AttendanceModel struct {
+	Tx *gorm.DB
+}
+
+// NewAttendanceModel creates a new attendance model
+func NewAttendanceModel(tx *gorm.DB) *AttendanceModel {
+	return &AttendanceModel{Tx: tx}
+}
+
+// FindBySession gets the attendance of a session with the students
+func (m *AttendanceModel) FindBySession(sessionID uuid.UUID) ([]Attendance, error) {
+	var attendances []Attendance
+	err := m.Tx.Where("session_id = ?", sessionID).Preload("Student").Find(&attendances).Error
+	return attendances, err
+}
+
+// Upsert creates the attendance of a student or updates its status if it already exists
+func (m *AttendanceModel) Upsert(attendance *Attendance) error {
+	return m.Tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"status", "manual", "updated_at"}),
+	}).Create(attendance).Error
+}
+
+// Delete deletes the attendance of a student to a session
+func (m *AttendanceModel) Delete(sessionID uuid.UUID, studentID uint64) *gorm.DB {
+	return m.Tx.Where("session_id = ? AND student_id = ?", sessionID, studentID).Delete(&Attendance{})
+}
+
+// MarkAbsent marks as absent the students of the class with no attendance to the session,
+// it returns the created attendances with the students
+func (m *AttendanceModel) MarkAbsent(sessionID uuid.UUID, classID uint64) ([]Attendance, error) {
+	var students []Student
+	err := m.Tx.Where(
+		"class_id = ? AND id NOT IN (?)",
+		classID,
+		m.Tx.Model(&Attendance{}).Select("student_id").Where("session_id = ?", sessionID),
+	).Find(&students).Error
+	if err != nil || len(students) == 0 {
+		return nil, err
+	}
+
+	attendances := make([]Attendance, 0, len(students))
+	for i := range students {
+		attendances = append(attendances, Attendance{
+			SessionID: sessionID,
+			StudentID: students[i].ID,
+			Student:   &students[i],
+			Status:    enum.ABSENT,
+		})
+	}
+	return attendances, m.Tx.Omit(clause.Associations).Create(&attendances).Error
+}
+
+// CountByStatus counts the attendances of a session with the given status
+func (m *AttendanceModel) CountByStatus(sessionID uuid.UUID, status enum.AttendanceStatus) (int64, error) {
+	var count int64
+	err := m.Tx.Model(&Attendance{}).Where("session_id = ? AND status = ?", sessionID, status).Count(&count).Error
+	return count, err
+}
+
+// StatsByStudent summarizes the attendance of the students of a class to the sessions created since the given date
+func (m *AttendanceModel) StatsByStudent(classID uint64, since time.Time) ([]AttendanceStats, error) {
+	var stats []AttendanceStats
+	err := m.Tx.Model(&Attendance{}).
+		Select(
+			"attendances.student_id, COUNT(*) AS total, "+
+				"COUNT(*) FILTER (WHERE attendances.status = ?) AS present, "+
+				"COUNT(*) FILTER (WHERE attendances.status = ? AND NOT attendances.justified) AS unjustified_absences",
+			enum.PRESENT, enum.ABSENT,
+		).
+		Joins("JOIN sessions ON sessions.id = attendances.session_id AND sessions.deleted_at IS NULL").
+		Joins("JOIN students ON students.id = attendances.student_id AND students.deleted_at IS NULL").
+		Where("sessions.class_id = ? AND students.class_id = ? AND sessions.created_at >= ?", classID, classID, since).
+		Group("attendances.student_id").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// FindByStudent gets the attendance of a student with the sessions, most recent first
+func (m *AttendanceModel) FindByStudent(studentID uint64) ([]Attendance, error) {
+	var attendances []Attendance
+	err := m.Tx.Joins("Session").
+		Where("attendances.student_id = ?", studentID).
+		Order("\"Session\".created_at desc").
+		Find(&attendances).Error
+	return attendances, err
+}
+
+// GetAbsence gets the absence of a student to a session with the session
+func (m *AttendanceModel) GetAbsence(sessionID uuid.UUID, studentID uint64) (*Attendance, error) {
+	var attendance Attendance
+	err := m.Tx.Preload("Session").Where("session_id = ? AND student_id = ? AND status = ?", sessionID, studentID, enum.ABSENT).
+		First(&attendance).Error
+	return &attendance, err
+}
+
+// SetJustified sets whether an absence is justified
+func (m *AttendanceModel) SetJustified(id uint64, justified bool) error {
+	return m.Tx.Model(&Attendance{ID: id}).Update("justified", justified).Error
+}
+
+// FindByClass gets the attendance of the students of a class to the sessions created since the given date,
+// with the sessions and the students, in the order of the sessions
+func (m *AttendanceModel) FindByClass(classID uint64, since time.Time) ([]Attendance, error) {
+	var attendances []Attendance
+	err := m.Tx.Joins("Session").Joins("Student").
+		Where("\"Session\".class_id = ? AND \"Session\".created_at >= ?", classID, since).
+		Order("\"Session\".created_at, \"Student\".last_name, \"Student\".first_name").
+		Find(&attendances).Error
+	return attendances, err
+}
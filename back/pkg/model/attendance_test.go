@@ -0,0 +1,14 @@
+package model
+
+import "testing"
+
+// TestAttendanceStats_Rate tests the attendance rate of a student
+func TestAttendanceStats_Rate(t *testing.T) {
+	if rate := (AttendanceStats{}).Rate(); rate != 100 {
+		t.Errorf("Rate without session is %f, expected 100", rate)
+	}
+
+	if rate := (AttendanceStats{Total: 5, Present: 4}).Rate(); rate != 80 {
+		t.Errorf("Rate is %f, expected 80", rate)
+	}
+}
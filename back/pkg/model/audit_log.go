@@ -0,0 +1,153 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AuditChanges is a set of column values stored as jsonb
+type AuditChanges map[string]interface{}
+
+// Scan implements the sql.Scanner interface
+func (c *AuditChanges) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("failed to scan audit changes: ", value))
+	}
+	return json.Unmarshal(data, c)
+}
+
+// Value implements the driver.Valuer interface
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+type AuditLog struct {
+	// ID is the id of the audit entry
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date of the action
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	// ActorID is the id of the user who made the action, null for anonymous or system actions
+	ActorID *uint64 `json:"actor_id" gorm:"index"`
+	// Tenant is the tenant the action was made on
+	Tenant string `json:"tenant" gorm:"size:80"`
+	// Action is the kind of action (create, update, delete, restore, attendance...)
+	Action string `json:"action" gorm:"not null;size:40;index"`
+	// Entity is the table of the target entity
+	Entity string `json:"entity" gorm:"not null;size:80;index:idx_audit_entity"`
+	// EntityID is the primary key of the target entity
+	EntityID string `json:"entity_id" gorm:"size:120;index:idx_audit_entity"`
+	// Before is the value of the changed columns before the action
+	Before AuditChanges `json:"before" gorm:"type:jsonb"`
+	// After is the value of the changed columns after the action
+	After AuditChanges `json:"after" gorm:"type:jsonb"`
+	// RequestID is the id of the HTTP request that made the action
+	RequestID string `json:"request_id" gorm:"size:64;index"`
+}
+
+// TableName overrides the default table name generated by GORM to be `audit_logs`
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// EntityRefs are the primary keys of entities by table, as recorded in the audit log
+type EntityRefs map[string][]string
+
+// Add adds the primary keys of entities of a table
+func (r EntityRefs) Add(entity string, ids ...uint64) {
+	for _, id := range ids {
+		r[entity] = append(r[entity], fmt.Sprint(id))
+	}
+}
+
+// Merge adds the entities of other
+func (r EntityRefs) Merge(other EntityRefs) {
+	for entity, ids := range other {
+		r[entity] = append(r[entity], ids...)
+	}
+}
+
+// AuditLogModel gives an append-only access to the audit log, only the erasure of personal data changes entries
+type AuditLogModel struct {
+	Tx *gorm.DB
+}
+
+// NewAuditLogModel creates a new audit log model
+func NewAuditLogModel(tx *gorm.DB) *AuditLogModel {
+	return &AuditLogModel{Tx: tx}
+}
+
+// Create appends entries to the audit log
+func (m *AuditLogModel) Create(entries []AuditLog) error {
+	return m.Tx.Create(&entries).Error
+}
+
+// FindAll gets the audit entries matching the filters paginated, most recent first
+func (m *AuditLogModel) FindAll(entries *[]AuditLog, params dto.AuditLogQueryParams) *gorm.DB {
+	tx := m.Tx.Scopes(paginate(params.Page, params.PageSize))
+	if params.ActorID != 0 {
+		tx = tx.Where("actor_id = ?", params.ActorID)
+	}
+	if params.Action != "" {
+		tx = tx.Where("action = ?", params.Action)
+	}
+	if params.Entity != "" {
+		tx = tx.Where("entity = ?", params.Entity)
+	}
+	if params.EntityID != "" {
+		tx = tx.Where("entity_id = ?", params.EntityID)
+	}
+	if params.RequestID != "" {
+		tx = tx.Where("request_id = ?", params.RequestID)
+	}
+	if !params.From.IsZero() {
+		tx = tx.Where("created_at >= ?", params.From)
+	}
+	if !params.To.IsZero() {
+		tx = tx.Where("created_at <= ?", params.To)
+	}
+	return tx.Order("created_at desc, id desc").Find(entries)
+}
+
+// FindBySubject gets the entries about the entities and the ones of the actions made by the actor, if any, oldest first
+func (m *AuditLogModel) FindBySubject(refs EntityRefs, actorID *uint64) ([]AuditLog, error) {
+	entries := make([]AuditLog, 0)
+	tx := m.Tx.Where("1 = 0")
+	for entity, ids := range refs {
+		tx = tx.Or("entity = ? AND entity_id IN ?", entity, ids)
+	}
+	if actorID != nil {
+		tx = tx.Or("actor_id = ?", *actorID)
+	}
+	err := tx.Order("created_at, id").Find(&entries).Error
+	return entries, err
+}
+
+// Scrub clears the values recorded by the entries about the entities, the entries themselves are kept
+func (m *AuditLogModel) Scrub(refs EntityRefs) error {
+	for entity, ids := range refs {
+		err := m.Tx.Model(&AuditLog{}).Where("entity = ? AND entity_id IN ?", entity, ids).
+			Updates(map[string]interface{}{"before": nil, "after": nil}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 type Class struct {
 	gorm.Model
@@ -10,6 +13,16 @@ type Class struct {
 	Name string `json:"name" gorm:"not null"`
 	// Year is the year of the class
 	Year string `json:"year" gorm:"not null"`
+	// TermID is the foreign key to the term table
+	TermID *uint64 `json:"term_id"`
+	// Term is the academic term of the class
+	Term *Term `json:"term" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	// NotifyAbsences is false if the absent students of the class must not be notified by email
+	NotifyAbsences bool `json:"notify_absences" gorm:"not null;default:true"`
+	// Teachers is the list of teachers of the class
+	Teachers []*User `json:"teachers" gorm:"many2many:class_teachers;"`
+	// Timetable is the list of weekly slots of the class
+	Timetable []TimetableSlot `json:"timetable" gorm:"foreignKey:ClassID"`
 	// Students is the list of students in the class
 	Students []Student `json:"students" gorm:"foreignKey:ClassID"`
 	// Sessions is the list of sessions in the class
@@ -37,10 +50,19 @@ func (m *ClassModel) Create(class *Class) error {
 // GetByID gets a class by ID
 func (m *ClassModel) GetByID(id uint64) (*Class, error) {
 	var class Class
-	err := m.Tx.Where("id = ?", id).Preload("Students").First(&class).Error
+	err := m.Tx.Where("id = ?", id).Preload("Students").Preload("Teachers").Preload("Timetable").First(&class).Error
 	return &class, err
 }
 
+// IsArchived returns true if the class belongs to an archived term
+func (m *ClassModel) IsArchived(id uint64) (bool, error) {
+	var class Class
+	if err := m.Tx.Where("id = ?", id).Preload("Term").First(&class).Error; err != nil {
+		return false, err
+	}
+	return class.Term != nil && class.Term.Archived, nil
+}
+
 // FindAll gets all classes
 func (m *ClassModel) FindAll() ([]Class, error) {
 	var classes []Class
@@ -53,12 +75,100 @@ func (m *ClassModel) Update(class *Class) error {
 	return m.Tx.Model(class).Updates(class).Error
 }
 
-// Delete deletes a class
+// GetWithTerm gets a class by ID with its term
+func (m *ClassModel) GetWithTerm(id uint64) (*Class, error) {
+	var class Class
+	err := m.Tx.Preload("Term").First(&class, id).Error
+	return &class, err
+}
+
+// NotifiesAbsences returns true if the absent students of a class are notified
+func (m *ClassModel) NotifiesAbsences(id uint64) (bool, error) {
+	var class Class
+	err := m.Tx.Select("id", "notify_absences").First(&class, id).Error
+	return class.NotifyAbsences, err
+}
+
+// SetNotifyAbsences enables or disables the absence notifications of a class
+func (m *ClassModel) SetNotifyAbsences(id uint64, enabled bool) *gorm.DB {
+	return m.Tx.Model(&Class{ID: id}).Update("notify_absences", enabled)
+}
+
+// Delete soft deletes a class along with its students, sessions and timetable
+// Every row is stamped with the same deletion date so that they can be restored together
 func (m *ClassModel) Delete(id uint64) error {
-	return m.Tx.Delete(&Class{ID: id}).Error
+	now := time.Now().UTC()
+	for _, child := range []interface{}{&Student{}, &Session{}, &TimetableSlot{}} {
+		if err := m.Tx.Model(child).Where("class_id = ?", id).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return m.Tx.Model(&Class{}).Where("id = ?", id).Update("deleted_at", now).Error
+}
+
+// FindDeleted gets all soft deleted classes paginated
+func (m *ClassModel) FindDeleted(classes *[]Class, page, pageSize int) *gorm.DB {
+	return m.Tx.Scopes(onlyDeleted, paginate(page, pageSize)).Order("deleted_at desc").Find(classes)
+}
+
+// Restore restores a soft deleted class and the students, sessions and timetable deleted with it
+func (m *ClassModel) Restore(id uint64) *gorm.DB {
+	var class Class
+	if tx := m.Tx.Scopes(onlyDeleted).Where("id = ?", id).First(&class); tx.Error != nil {
+		return tx
+	}
+
+	for _, child := range []interface{}{&Student{}, &Session{}, &TimetableSlot{}} {
+		tx := m.Tx.Unscoped().Model(child).Where(
+			"class_id = ? AND deleted_at = ?", id, class.DeletedAt.Time,
+		).Update("deleted_at", nil)
+		if tx.Error != nil {
+			return tx
+		}
+	}
+	return m.Tx.Unscoped().Model(&Class{}).Where("id = ?", id).Update("deleted_at", nil)
+}
+
+// Purge permanently deletes the classes soft deleted before the given date and returns their number
+func (m *ClassModel) Purge(before time.Time) (int64, error) {
+	err := m.Tx.Exec(
+		"DELETE FROM class_teachers WHERE class_id IN (SELECT id FROM classes WHERE deleted_at < ?)", before,
+	).Error
+	if err != nil {
+		return 0, err
+	}
+	res := m.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&Class{})
+	return res.RowsAffected, res.Error
 }
 
 // AddStudent adds a student to a class
 func (m *ClassModel) AddStudent(class *Class, student *Student) error {
 	return m.Tx.Model(class).Association("Students").Append(student)
 }
+
+// AddTeacher adds a teacher to a class
+func (m *ClassModel) AddTeacher(class *Class, teacher *User) error {
+	return m.Tx.Model(class).Association("Teachers").Append(teacher)
+}
+
+// RemoveTeacher removes a teacher from a class
+func (m *ClassModel) RemoveTeacher(class *Class, teacher *User) error {
+	return m.Tx.Model(class).Association("Teachers").Delete(teacher)
+}
+
+// MoveStudents moves the given students of a class to another class
+func (m *ClassModel) MoveStudents(fromID, toID uint64, studentIDs []uint64) *gorm.DB {
+	return m.Tx.Model(&Student{}).Where(
+		"class_id = ? AND id IN ?", fromID, studentIDs,
+	).Update("class_id", toID)
+}
+
+// FindByTeacher gets the classes a user teaches
+func (m *ClassModel) FindByTeacher(userID uint64) ([]Class, error) {
+	var classes []Class
+	err := m.Tx.Joins("JOIN class_teachers ON class_teachers.class_id = classes.id").
+		Where("class_teachers.user_id = ?", userID).
+		Order("classes.id").
+		Find(&classes).Error
+	return classes, err
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+)
+
+// SetupClassTestDatabase sets up the test database with the class related tables
+func SetupClassTestDatabase() *ClassModel {
+	db := SetupTestDatabase()
+
+	// Truncate class tables
+	db.Migrator().DropTable(&Session{}, "guardian_students", &Student{}, &TimetableSlot{}, "class_teachers", &Class{}, &Holiday{}, &Term{})
+
+	// Migrate the schema
+	db.AutoMigrate(
+		Term{},
+		Holiday{},
+		Class{},
+		TimetableSlot{},
+		Student{},
+		Session{},
+	)
+
+	return NewClassModel(db)
+}
+
+// TestClassModel_DeleteRestore tests that restoring a class restores what was deleted with it
+func TestClassModel_DeleteRestore(t *testing.T) {
+	classModel := SetupClassTestDatabase()
+	db := classModel.Tx
+
+	class := Class{Name: "class1", Year: "2022"}
+	if err := classModel.Create(&class); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&Student{Email: "student.restore@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID})
+	db.Create(&Session{Password: "password", ClassID: class.ID})
+	deletedBefore := Session{Password: "password", ClassID: class.ID}
+	db.Create(&deletedBefore)
+	db.Delete(&deletedBefore)
+
+	// Delete the class
+	if err := classModel.Delete(class.ID); err != nil {
+		t.Error(err)
+	}
+
+	var sessions []Session
+	if db.Where("class_id = ?", class.ID).Find(&sessions); len(sessions) != 0 {
+		t.Error("Sessions of the class are not deleted")
+	}
+
+	// Restore the class
+	if err := classModel.Restore(class.ID).Error; err != nil {
+		t.Error(err)
+	}
+
+	restored, err := classModel.GetByID(class.ID)
+	if err != nil {
+		t.Error("Class is not restored")
+	}
+
+	if len(restored.Students) != 1 {
+		t.Error("Student of the class is not restored")
+	}
+
+	if db.Where("class_id = ?", class.ID).Find(&sessions); len(sessions) != 1 {
+		t.Error("Only the sessions deleted with the class should be restored")
+	}
+}
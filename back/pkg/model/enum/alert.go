@@ -0,0 +1,34 @@
+package enum
+
+import "database/sql/driver"
+
+type AlertRuleKind string
+
+const (
+	// ABSENCE_COUNT triggers when a student has more unjustified absences than the threshold
+	ABSENCE_COUNT AlertRuleKind = "absence_count"
+	// ATTENDANCE_RATE triggers when the attendance rate of a student in percent is below the threshold
+	ATTENDANCE_RATE AlertRuleKind = "attendance_rate"
+)
+
+func (k *AlertRuleKind) Scan(value interface{}) error {
+	*k = AlertRuleKind(value.(string))
+	return nil
+}
+
+func (k AlertRuleKind) Value() (driver.Value, error) {
+	return string(k), nil
+}
+
+func (k AlertRuleKind) String() string {
+	return string(k)
+}
+
+func (k AlertRuleKind) IsValid() bool {
+	switch k {
+	case ABSENCE_COUNT, ATTENDANCE_RATE:
+		return true
+	default:
+		return false
+	}
+}
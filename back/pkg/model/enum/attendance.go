@@ -0,0 +1,32 @@
+package enum
+
+import "database/sql/driver"
+
+type AttendanceStatus string
+
+const (
+	PRESENT AttendanceStatus = "present"
+	ABSENT  AttendanceStatus = "absent"
+)
+
+func (s *AttendanceStatus) Scan(value interface{}) error {
+	*s = AttendanceStatus(value.(string))
+	return nil
+}
+
+func (s AttendanceStatus) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+func (s AttendanceStatus) String() string {
+	return string(s)
+}
+
+func (s AttendanceStatus) IsValid() bool {
+	switch s {
+	case PRESENT, ABSENT:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package enum
+
+import "database/sql/driver"
+
+type JustificationStatus string
+
+const (
+	JUSTIFICATION_PENDING  JustificationStatus = "pending"
+	JUSTIFICATION_ACCEPTED JustificationStatus = "accepted"
+	JUSTIFICATION_REJECTED JustificationStatus = "rejected"
+)
+
+func (s *JustificationStatus) Scan(value interface{}) error {
+	*s = JustificationStatus(value.(string))
+	return nil
+}
+
+func (s JustificationStatus) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+func (s JustificationStatus) String() string {
+	return string(s)
+}
+
+func (s JustificationStatus) IsValid() bool {
+	switch s {
+	case JUSTIFICATION_PENDING, JUSTIFICATION_ACCEPTED, JUSTIFICATION_REJECTED:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package enum
+
+import "database/sql/driver"
+
+type Permission string
+
+const (
+	// ALL_PERMISSIONS grants every permission, including the ones added later
+	ALL_PERMISSIONS Permission = "*"
+
+	ACCOUNT_SELF         Permission = "account:self"
+	USER_READ            Permission = "user:read"
+	USER_WRITE           Permission = "user:write"
+	ROLE_MANAGE          Permission = "role:manage"
+	CLASS_READ           Permission = "class:read"
+	CLASS_WRITE          Permission = "class:write"
+	CLASS_TEACH          Permission = "class:teach"
+	STUDENT_WRITE        Permission = "student:write"
+	TERM_READ            Permission = "term:read"
+	TERM_WRITE           Permission = "term:write"
+	SESSION_READ         Permission = "session:read"
+	SESSION_WRITE        Permission = "session:write"
+	SESSION_CLOSE        Permission = "session:close"
+	ATTENDANCE_READ      Permission = "attendance:read"
+	ATTENDANCE_OVERRIDE  Permission = "attendance:override"
+	JUSTIFICATION_REVIEW Permission = "justification:review"
+	GUARDIAN_ACCESS      Permission = "guardian:access"
+	ALERT_READ           Permission = "alert:read"
+	ALERT_WRITE          Permission = "alert:write"
+	WEBHOOK_MANAGE       Permission = "webhook:manage"
+	TRASH_MANAGE         Permission = "trash:manage"
+	AUDIT_READ           Permission = "audit:read"
+	MFA_RESET            Permission = "mfa:reset"
+	API_KEY_MANAGE       Permission = "api_key:manage"
+	INVITATION_MANAGE    Permission = "invitation:manage"
+	PRIVACY_MANAGE       Permission = "privacy:manage"
+)
+
+// Permissions returns the list of the named permissions
+func Permissions() []Permission {
+	return []Permission{
+		ACCOUNT_SELF,
+		USER_READ,
+		USER_WRITE,
+		ROLE_MANAGE,
+		CLASS_READ,
+		CLASS_WRITE,
+		CLASS_TEACH,
+		STUDENT_WRITE,
+		TERM_READ,
+		TERM_WRITE,
+		SESSION_READ,
+		SESSION_WRITE,
+		SESSION_CLOSE,
+		ATTENDANCE_READ,
+		ATTENDANCE_OVERRIDE,
+		JUSTIFICATION_REVIEW,
+		GUARDIAN_ACCESS,
+		ALERT_READ,
+		ALERT_WRITE,
+		WEBHOOK_MANAGE,
+		TRASH_MANAGE,
+		AUDIT_READ,
+		MFA_RESET,
+		API_KEY_MANAGE,
+		INVITATION_MANAGE,
+		PRIVACY_MANAGE,
+	}
+}
+
+func (p *Permission) Scan(value interface{}) error {
+	*p = Permission(value.(string))
+	return nil
+}
+
+func (p Permission) Value() (driver.Value, error) {
+	return string(p), nil
+}
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+func (p Permission) IsValid() bool {
+	if p == ALL_PERMISSIONS {
+		return true
+	}
+	for _, permission := range Permissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -8,6 +8,7 @@ const (
 	SUPERADMIN Role = "superadmin"
 	ADMIN      Role = "admin"
 	STUDENT    Role = "student"
+	GUARDIAN   Role = "guardian"
 )
 
 func (r *Role) Scan(value interface{}) error {
@@ -23,27 +24,13 @@ func (r Role) String() string {
 	return string(r)
 }
 
-func (r Role) IsValid() bool {
+// IsBuiltin returns true if the role is one of the roles created with the database,
+// other roles are defined at runtime by the superadmins
+func (r Role) IsBuiltin() bool {
 	switch r {
-	case SUPERADMIN, ADMIN, STUDENT:
+	case SUPERADMIN, ADMIN, STUDENT, GUARDIAN:
 		return true
 	default:
 		return false
 	}
 }
-
-// HasPermission checks if the role has the permission
-func (r Role) HasPermission(permission Role) bool {
-	perm := map[Role][]Role{
-		SUPERADMIN: {SUPERADMIN, ADMIN, STUDENT},
-		ADMIN:      {ADMIN, STUDENT},
-		STUDENT:    {STUDENT},
-	}[r]
-
-	for _, p := range perm {
-		if p == permission {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,64 @@
+package enum
+
+import "database/sql/driver"
+
+type WebhookEvent string
+
+const (
+	SESSION_CLOSED   WebhookEvent = "session.closed"
+	STUDENT_ABSENT   WebhookEvent = "student.absent"
+	STUDENT_AT_RISK  WebhookEvent = "student.at_risk"
+	LOGIN_NEW_CLIENT WebhookEvent = "login.new_client"
+)
+
+func (e *WebhookEvent) Scan(value interface{}) error {
+	*e = WebhookEvent(value.(string))
+	return nil
+}
+
+func (e WebhookEvent) Value() (driver.Value, error) {
+	return string(e), nil
+}
+
+func (e WebhookEvent) String() string {
+	return string(e)
+}
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case SESSION_CLOSED, STUDENT_ABSENT, STUDENT_AT_RISK, LOGIN_NEW_CLIENT:
+		return true
+	default:
+		return false
+	}
+}
+
+type DeliveryStatus string
+
+const (
+	DELIVERY_PENDING   DeliveryStatus = "pending"
+	DELIVERY_DELIVERED DeliveryStatus = "delivered"
+	DELIVERY_DEAD      DeliveryStatus = "dead"
+)
+
+func (s *DeliveryStatus) Scan(value interface{}) error {
+	*s = DeliveryStatus(value.(string))
+	return nil
+}
+
+func (s DeliveryStatus) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+func (s DeliveryStatus) String() string {
+	return string(s)
+}
+
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DELIVERY_PENDING, DELIVERY_DELIVERED, DELIVERY_DEAD:
+		return true
+	default:
+		return false
+	}
+}
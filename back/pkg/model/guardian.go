@@ -0,0 +1,104 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type GuardianInvitation struct {
+	// ID is the id of the invitation
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the invitation was created
+	CreatedAt time.Time `json:"created_at"`
+	// StudentID is the foreign key to the student table
+	StudentID uint64 `json:"student_id" gorm:"not null;index"`
+	// Student is the student the guardian is invited for
+	Student *Student `json:"student" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Email is the email of the invited guardian
+	Email string `json:"email" gorm:"not null"`
+	// CodeHash is the SHA-256 hash of the invitation code
+	CodeHash string `json:"-" gorm:"not null;size:64;uniqueIndex:unique_idx_guardian_invitation_code"`
+	// CreatedBy is the id of the staff member who created the invitation
+	CreatedBy uint64 `json:"created_by" gorm:"not null"`
+	// ExpiresAt is the date after which the invitation cannot be accepted
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	// AcceptedAt is the date the invitation was accepted
+	AcceptedAt *time.Time `json:"accepted_at"`
+	// AcceptedBy is the id of the user who accepted the invitation
+	AcceptedBy *uint64 `json:"accepted_by"`
+}
+
+// TableName overrides the default table name generated by GORM to be `guardian_invitations`
+func (GuardianInvitation) TableName() string {
+	return "guardian_invitations"
+}
+
+type GuardianModel struct {
+	Tx *gorm.DB
+}
+
+// NewGuardianModel creates a new guardian model
+func NewGuardianModel(tx *gorm.DB) *GuardianModel {
+	return &GuardianModel{Tx: tx}
+}
+
+// AddGuardian makes a user guardian of a student
+func (m *GuardianModel) AddGuardian(student *Student, guardian *User) error {
+	return m.Tx.Model(student).Omit("Guardians.*").Association("Guardians").Append(guardian)
+}
+
+// RemoveGuardian removes a user from the guardians of a student
+func (m *GuardianModel) RemoveGuardian(student *Student, guardian *User) error {
+	return m.Tx.Model(student).Association("Guardians").Delete(guardian)
+}
+
+// IsGuardianOf returns true if a user is guardian of a student
+func (m *GuardianModel) IsGuardianOf(userID, studentID uint64) (bool, error) {
+	var count int64
+	err := m.Tx.Table("guardian_students").
+		Where("user_id = ? AND student_id = ?", userID, studentID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindChildren gets the students a user is guardian of, with their class
+func (m *GuardianModel) FindChildren(userID uint64) ([]Student, error) {
+	var students []Student
+	err := m.Tx.Joins("JOIN guardian_students ON guardian_students.student_id = students.id").
+		Where("guardian_students.user_id = ?", userID).
+		Preload("Class").
+		Order("students.last_name, students.first_name").
+		Find(&students).Error
+	return students, err
+}
+
+// CreateInvitation creates a guardian invitation
+func (m *GuardianModel) CreateInvitation(invitation *GuardianInvitation) error {
+	return m.Tx.Omit(clause.Associations).Create(invitation).Error
+}
+
+// GetInvitationByCodeHash gets an invitation by the hash of its code
+func (m *GuardianModel) GetInvitationByCodeHash(codeHash string) (*GuardianInvitation, error) {
+	var invitation GuardianInvitation
+	err := m.Tx.Where("code_hash = ?", codeHash).First(&invitation).Error
+	return &invitation, err
+}
+
+// HasPendingInvitation returns true if an invitation not accepted yet is still valid for an email
+func (m *GuardianModel) HasPendingInvitation(email string, now time.Time) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&GuardianInvitation{}).
+		Where("accepted_at IS NULL AND expires_at > ?", now).
+		Where("LOWER(email) = LOWER(?)", email).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// AcceptInvitation marks an invitation as accepted by a user, if it was not already
+func (m *GuardianModel) AcceptInvitation(invitation *GuardianInvitation, userID uint64) *gorm.DB {
+	return m.Tx.Model(invitation).Where("accepted_at IS NULL").Updates(map[string]interface{}{
+		"accepted_at": time.Now(),
+		"accepted_by": userID,
+	})
+}
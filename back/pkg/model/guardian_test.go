@@ -0,0 +1,88 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"testing"
+	"time"
+)
+
+// TestGuardianModel_IsGuardianOf tests the guardian to student relation
+func TestGuardianModel_IsGuardianOf(t *testing.T) {
+	classModel := SetupClassTestDatabase()
+	db := classModel.Tx
+	guardianModel := NewGuardianModel(db)
+
+	class := Class{Name: "class1", Year: "2022"}
+	if err := classModel.Create(&class); err != nil {
+		t.Fatal(err)
+	}
+	student := Student{Email: "student.guardian@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID}
+	db.Create(&student)
+	account := Account{
+		Email:    "guardian@gmail.com",
+		Username: "guardian",
+		Password: "password",
+		User:     User{FirstName: "first", LastName: "last", Role: enum.GUARDIAN},
+	}
+	db.Create(&account)
+
+	if err := guardianModel.AddGuardian(&student, &account.User); err != nil {
+		t.Error(err)
+	}
+
+	if ok, _ := guardianModel.IsGuardianOf(account.User.ID, student.ID); !ok {
+		t.Error("User should be guardian of the student")
+	}
+
+	if children, _ := guardianModel.FindChildren(account.User.ID); len(children) != 1 {
+		t.Error("Student is not in the children of the guardian")
+	}
+
+	if err := guardianModel.RemoveGuardian(&student, &account.User); err != nil {
+		t.Error(err)
+	}
+
+	if ok, _ := guardianModel.IsGuardianOf(account.User.ID, student.ID); ok {
+		t.Error("User should not be guardian of the student anymore")
+	}
+}
+
+// TestGuardianModel_HasPendingInvitation tests that only the invitations neither accepted nor expired are pending
+func TestGuardianModel_HasPendingInvitation(t *testing.T) {
+	classModel := SetupClassTestDatabase()
+	db := classModel.Tx
+	db.Migrator().DropTable(&GuardianInvitation{})
+	db.AutoMigrate(&GuardianInvitation{})
+	guardianModel := NewGuardianModel(db)
+
+	class := Class{Name: "class1", Year: "2022"}
+	if err := classModel.Create(&class); err != nil {
+		t.Fatal(err)
+	}
+	student := Student{Email: "student.invited@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID}
+	db.Create(&student)
+
+	now := time.Now()
+	invitation := GuardianInvitation{
+		StudentID: student.ID,
+		Email:     "Parent@Gmail.com",
+		CodeHash:  "pending",
+		CreatedBy: 1,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := guardianModel.CreateInvitation(&invitation); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := guardianModel.HasPendingInvitation("parent@gmail.com", now); err != nil || !ok {
+		t.Errorf("Invitation should be pending whatever the case of the email: %v", err)
+	}
+	if ok, _ := guardianModel.HasPendingInvitation("parent@gmail.com", now.Add(2*time.Hour)); ok {
+		t.Error("Expired invitation should not be pending")
+	}
+
+	guardianModel.AcceptInvitation(&invitation, 1)
+	if ok, _ := guardianModel.HasPendingInvitation("parent@gmail.com", now); ok {
+		t.Error("Accepted invitation should not be pending")
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Identity links the identity of a user at an OpenID Connect provider to an account
+type Identity struct {
+	// ID is the id of the identity
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the identity was linked
+	CreatedAt time.Time `json:"created_at"`
+	// AccountID is the foreign key to the account table
+	AccountID uint64 `json:"account_id" gorm:"not null;index"`
+	// Account is the account the identity logs in
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Issuer is the URL of the provider
+	Issuer string `json:"issuer" gorm:"not null;size:255;uniqueIndex:unique_idx_identity"`
+	// Subject is the id of the user at the provider
+	Subject string `json:"subject" gorm:"not null;size:255;uniqueIndex:unique_idx_identity"`
+	// LastLoginAt is the date of the last login with the identity
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `identities`
+func (Identity) TableName() string {
+	return "identities"
+}
+
+type IdentityModel struct {
+	Tx *gorm.DB
+}
+
+// NewIdentityModel creates a new identity model
+func NewIdentityModel(tx *gorm.DB) *IdentityModel {
+	return &IdentityModel{Tx: tx}
+}
+
+// FindBySubject finds the identity of a user at a provider along with its account
+func (m *IdentityModel) FindBySubject(issuer, subject string) (*Identity, error) {
+	var identity Identity
+	err := m.Tx.Preload("Account.User").Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	return &identity, err
+}
+
+// Create links an identity to an account
+func (m *IdentityModel) Create(identity *Identity) error {
+	return m.Tx.Create(identity).Error
+}
+
+// Touch records a login with an identity
+func (m *IdentityModel) Touch(id uint64, now time.Time) error {
+	return m.Tx.Model(&Identity{}).Where("id = ?", id).Update("last_login_at", now).Error
+}
+
+// FindByAccount gets the identities linked to an account
+func (m *IdentityModel) FindByAccount(accountID uint64) ([]Identity, error) {
+	var identities []Identity
+	err := m.Tx.Where("account_id = ?", accountID).Order("id").Find(&identities).Error
+	return identities, err
+}
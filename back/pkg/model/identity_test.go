@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIdentityModel_FindBySubject tests that an identity is found by its provider and subject
+func TestIdentityModel_FindBySubject(t *testing.T) {
+	db := SetupTestDatabase()
+	identityModel := NewIdentityModel(db)
+
+	account := Account{Email: "sso@gmail.com", Username: "sso", Password: "password", User: User{FirstName: "Jules", LastName: "Doe"}}
+	db.Create(&account)
+
+	identity := Identity{AccountID: account.ID, Issuer: "https://sso.university.fr", Subject: "42", LastLoginAt: time.Now()}
+	if err := identityModel.Create(&identity); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := identityModel.FindBySubject("https://sso.university.fr", "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Account == nil || found.Account.User.FirstName != "Jules" {
+		t.Error("Identity should be found with its account and user")
+	}
+
+	if _, err := identityModel.FindBySubject("https://other.fr", "42"); err == nil {
+		t.Error("Identity of another provider should not be found")
+	}
+
+	if err := identityModel.Create(&Identity{AccountID: account.ID, Issuer: "https://sso.university.fr", Subject: "42"}); err == nil {
+		t.Error("Identity should be linked once")
+	}
+}
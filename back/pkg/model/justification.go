@@ -0,0 +1,124 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Justification struct {
+	// ID is the id of the justification
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the justification was submitted
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the date of the last change of the justification
+	UpdatedAt time.Time `json:"updated_at"`
+	// AttendanceID is the foreign key to the attendance table
+	AttendanceID uint64 `json:"attendance_id" gorm:"not null;index"`
+	// Attendance is the absence justified
+	Attendance *Attendance `json:"attendance" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Reason is the reason of the absence
+	Reason string `json:"reason" gorm:"type:text;not null"`
+	// SubmittedBy is the id of the user who submitted the justification
+	SubmittedBy uint64 `json:"submitted_by" gorm:"not null"`
+	// Status is the review status of the justification
+	Status enum.JustificationStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	// ReviewedBy is the id of the staff member who reviewed the justification
+	ReviewedBy *uint64 `json:"reviewed_by"`
+	// ReviewedAt is the date of the review
+	ReviewedAt *time.Time `json:"reviewed_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `justifications`
+func (Justification) TableName() string {
+	return "justifications"
+}
+
+type JustificationModel struct {
+	Tx *gorm.DB
+}
+
+// NewJustificationModel creates a new justification model
+func NewJustificationModel(tx *gorm.DB) *JustificationModel {
+	return &JustificationModel{Tx: tx}
+}
+
+// Create submits a justification
+func (m *JustificationModel) Create(justification *Justification) error {
+	return m.Tx.Omit(clause.Associations).Create(justification).Error
+}
+
+// HasActive returns true if an absence has a pending or accepted justification
+func (m *JustificationModel) HasActive(attendanceID uint64) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&Justification{}).
+		Where("attendance_id = ? AND status IN ?", attendanceID, []enum.JustificationStatus{
+			enum.JUSTIFICATION_PENDING, enum.JUSTIFICATION_ACCEPTED,
+		}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindByStudent gets the justifications of the absences of a student with the sessions, most recent first
+func (m *JustificationModel) FindByStudent(studentID uint64) ([]Justification, error) {
+	var justifications []Justification
+	err := m.Tx.Joins("JOIN attendances ON attendances.id = justifications.attendance_id").
+		Where("attendances.student_id = ?", studentID).
+		Preload("Attendance.Session").
+		Order("justifications.created_at desc").
+		Find(&justifications).Error
+	return justifications, err
+}
+
+// FindByClass gets the justifications of the absences to the sessions of a class
+// with the students and sessions, optionally filtered by status, most recent first
+func (m *JustificationModel) FindByClass(classID uint64, status enum.JustificationStatus) ([]Justification, error) {
+	var justifications []Justification
+	tx := m.Tx.Joins("JOIN attendances ON attendances.id = justifications.attendance_id").
+		Joins("JOIN sessions ON sessions.id = attendances.session_id").
+		Where("sessions.class_id = ?", classID)
+	if status != "" {
+		tx = tx.Where("justifications.status = ?", status)
+	}
+	err := tx.Preload("Attendance.Student").
+		Preload("Attendance.Session").
+		Order("justifications.created_at desc").
+		Find(&justifications).Error
+	return justifications, err
+}
+
+// GetByClassID gets a justification of an absence to a session of a class
+func (m *JustificationModel) GetByClassID(classID, id uint64) (*Justification, error) {
+	var justification Justification
+	err := m.Tx.Joins("JOIN attendances ON attendances.id = justifications.attendance_id").
+		Joins("JOIN sessions ON sessions.id = attendances.session_id").
+		Where("justifications.id = ? AND sessions.class_id = ?", id, classID).
+		First(&justification).Error
+	return &justification, err
+}
+
+// Review sets the status of a pending justification
+func (m *JustificationModel) Review(justification *Justification, status enum.JustificationStatus, reviewerID uint64) *gorm.DB {
+	now := time.Now()
+	return m.Tx.Model(justification).Where("status = ?", enum.JUSTIFICATION_PENDING).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewed_by": reviewerID,
+		"reviewed_at": now,
+	})
+}
+
+// FindBySubmitter gets the justifications submitted by a user with the students and sessions, most recent first
+func (m *JustificationModel) FindBySubmitter(userID uint64) ([]Justification, error) {
+	var justifications []Justification
+	err := m.Tx.Where("submitted_by = ?", userID).
+		Preload("Attendance.Student", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Attendance.Session", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Order("created_at desc").
+		Find(&justifications).Error
+	return justifications, err
+}
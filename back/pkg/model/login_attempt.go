@@ -0,0 +1,92 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type LoginAttempt struct {
+	// ID is the id of the attempt
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date of the attempt
+	CreatedAt time.Time `json:"created_at" gorm:"index:idx_login_attempts_account,priority:2;index"`
+	// AccountID is the foreign key to the account table
+	AccountID uint64 `json:"account_id" gorm:"not null;index:idx_login_attempts_account,priority:1"`
+	// Account is the account the login targeted
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Method is how the user authenticated: password, mfa or oidc
+	Method string `json:"method" gorm:"type:varchar(20);not null"`
+	// IP is the address of the client
+	IP string `json:"ip" gorm:"size:45"`
+	// UserAgent is the user agent of the client
+	UserAgent string `json:"user_agent" gorm:"size:255"`
+	// Success is false if the credentials were wrong
+	Success bool `json:"success" gorm:"not null"`
+	// NewClient is true if the login succeeded from a client never seen for the account
+	NewClient bool `json:"new_client" gorm:"not null;default:false"`
+}
+
+// TableName overrides the default table name generated by GORM to be `login_attempts`
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+type LoginAttemptModel struct {
+	Tx *gorm.DB
+}
+
+// NewLoginAttemptModel creates a new login attempt model
+func NewLoginAttemptModel(tx *gorm.DB) *LoginAttemptModel {
+	return &LoginAttemptModel{Tx: tx}
+}
+
+// Create records an attempt
+func (m *LoginAttemptModel) Create(attempt *LoginAttempt) error {
+	return m.Tx.Omit(clause.Associations).Create(attempt).Error
+}
+
+// FailuresSinceSuccess returns the number of failed attempts of an account since its last successful login,
+// and the date of the last of them
+func (m *LoginAttemptModel) FailuresSinceSuccess(accountID uint64) (int, *time.Time, error) {
+	var result struct {
+		Count int
+		Last  *time.Time
+	}
+	err := m.Tx.Model(&LoginAttempt{}).
+		Select("count(*) AS count, max(created_at) AS last").
+		Where("account_id = ? AND NOT success", accountID).
+		Where("created_at > (?)", m.Tx.Model(&LoginAttempt{}).
+			Select("coalesce(max(created_at), '-infinity')").
+			Where("account_id = ? AND success", accountID)).
+		Scan(&result).Error
+	return result.Count, result.Last, err
+}
+
+// HasSucceeded returns true if an account already logged in successfully
+func (m *LoginAttemptModel) HasSucceeded(accountID uint64) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&LoginAttempt{}).Where("account_id = ? AND success", accountID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// HasSucceededFrom returns true if an account already logged in successfully from the client
+func (m *LoginAttemptModel) HasSucceededFrom(accountID uint64, ip, userAgent string) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&LoginAttempt{}).
+		Where("account_id = ? AND success AND ip = ? AND user_agent = ?", accountID, ip, userAgent).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// FindByAccount gets the latest attempts of an account, the newest first
+func (m *LoginAttemptModel) FindByAccount(accountID uint64, limit int) ([]LoginAttempt, error) {
+	var attempts []LoginAttempt
+	err := m.Tx.Where("account_id = ?", accountID).Order("created_at DESC, id DESC").Limit(limit).Find(&attempts).Error
+	return attempts, err
+}
+
+// Purge deletes the attempts made before the given date
+func (m *LoginAttemptModel) Purge(before time.Time) error {
+	return m.Tx.Where("created_at < ?", before).Delete(&LoginAttempt{}).Error
+}
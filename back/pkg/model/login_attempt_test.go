@@ -0,0 +1,69 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLoginAttemptModel_FailuresSinceSuccess tests that the failures are counted from the last successful login
+func TestLoginAttemptModel_FailuresSinceSuccess(t *testing.T) {
+	db := SetupTestDatabase()
+	attemptModel := NewLoginAttemptModel(db)
+
+	account := Account{Email: "lockout@gmail.com", Username: "lockout", Password: "password", User: User{FirstName: "Jules", LastName: "Doe"}}
+	db.Create(&account)
+
+	now := time.Now().Truncate(time.Second)
+	attempts := []LoginAttempt{
+		{AccountID: account.ID, CreatedAt: now.Add(-4 * time.Minute), Method: "password"},
+		{AccountID: account.ID, CreatedAt: now.Add(-3 * time.Minute), Method: "password", Success: true},
+		{AccountID: account.ID, CreatedAt: now.Add(-2 * time.Minute), Method: "password"},
+		{AccountID: account.ID, CreatedAt: now.Add(-time.Minute), Method: "password"},
+	}
+	for i := range attempts {
+		if err := attemptModel.Create(&attempts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, last, err := attemptModel.FailuresSinceSuccess(account.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 failures since the success, got %d", count)
+	}
+	if last == nil || !last.Equal(attempts[3].CreatedAt) {
+		t.Error("Last failure should be the latest attempt")
+	}
+
+	history, err := attemptModel.FindByAccount(account.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].ID != attempts[3].ID {
+		t.Error("History should hold the latest attempts, the newest first")
+	}
+}
+
+// TestLoginAttemptModel_HasSucceededFrom tests that a client is known once a login succeeded from it
+func TestLoginAttemptModel_HasSucceededFrom(t *testing.T) {
+	db := SetupTestDatabase()
+	attemptModel := NewLoginAttemptModel(db)
+
+	account := Account{Email: "client@gmail.com", Username: "client", Password: "password", User: User{FirstName: "Jules", LastName: "Doe"}}
+	db.Create(&account)
+
+	attemptModel.Create(&LoginAttempt{AccountID: account.ID, Method: "password", IP: "1.2.3.4", UserAgent: "curl"})
+	if seen, _ := attemptModel.HasSucceededFrom(account.ID, "1.2.3.4", "curl"); seen {
+		t.Error("Client of a failed login should not be known")
+	}
+
+	attemptModel.Create(&LoginAttempt{AccountID: account.ID, Method: "password", IP: "1.2.3.4", UserAgent: "curl", Success: true})
+	if seen, _ := attemptModel.HasSucceededFrom(account.ID, "1.2.3.4", "curl"); !seen {
+		t.Error("Client of a successful login should be known")
+	}
+	if seen, _ := attemptModel.HasSucceededFrom(account.ID, "5.6.7.8", "curl"); seen {
+		t.Error("Client from another address should not be known")
+	}
+}
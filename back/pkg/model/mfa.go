@@ -0,0 +1,128 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// MFA is the TOTP second factor of an account, it is pending until a first code is confirmed
+type MFA struct {
+	// AccountID is the id of the account protected by the second factor
+	AccountID uint64 `json:"account_id" gorm:"primaryKey"`
+	// Account is the account protected by the second factor
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// CreatedAt is the date the enrollment started
+	CreatedAt time.Time `json:"created_at"`
+	// Secret is the base32 encoded TOTP secret
+	Secret string `json:"-" gorm:"not null;size:64"`
+	// EnabledAt is the date the first code was confirmed, nil while pending
+	EnabledAt *time.Time `json:"enabled_at"`
+	// LastUsedStep is the time step of the last accepted code, a code can not be replayed
+	LastUsedStep int64 `json:"-" gorm:"not null;default:0"`
+}
+
+// TableName overrides the default table name generated by GORM to be `mfa`
+func (MFA) TableName() string {
+	return "mfa"
+}
+
+// RecoveryCode is a single-use code replacing a TOTP code when the authenticator is lost
+type RecoveryCode struct {
+	// ID is the id of the recovery code
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// AccountID is the foreign key to the account table
+	AccountID uint64 `json:"account_id" gorm:"not null;index"`
+	// Account is the account the code recovers
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// CodeHash is the SHA-256 hash of the code
+	CodeHash string `json:"-" gorm:"not null;size:64"`
+	// UsedAt is the date the code was used
+	UsedAt *time.Time `json:"used_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `recovery_codes`
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+type MFAModel struct {
+	Tx *gorm.DB
+}
+
+// NewMFAModel creates a new MFA model
+func NewMFAModel(tx *gorm.DB) *MFAModel {
+	return &MFAModel{Tx: tx}
+}
+
+// Get gets the second factor of an account
+func (m *MFAModel) Get(accountID uint64) (*MFA, error) {
+	var mfa MFA
+	err := m.Tx.Where("account_id = ?", accountID).First(&mfa).Error
+	return &mfa, err
+}
+
+// IsEnabled returns true if the account has a confirmed second factor
+func (m *MFAModel) IsEnabled(accountID uint64) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&MFA{}).Where("account_id = ? AND enabled_at IS NOT NULL", accountID).Count(&count).Error
+	return count > 0, err
+}
+
+// Start starts an enrollment, replacing a pending one
+func (m *MFAModel) Start(mfa *MFA) error {
+	return m.Tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"created_at", "secret", "enabled_at", "last_used_step"}),
+	}).Create(mfa).Error
+}
+
+// Enable confirms the second factor of an account
+func (m *MFAModel) Enable(accountID uint64, now time.Time) *gorm.DB {
+	return m.Tx.Model(&MFA{}).Where("account_id = ? AND enabled_at IS NULL", accountID).Update("enabled_at", now)
+}
+
+// UseStep records the time step of an accepted code, it returns false if a code
+// of this step or a later one was already accepted
+func (m *MFAModel) UseStep(accountID uint64, step int64) (bool, error) {
+	res := m.Tx.Model(&MFA{}).
+		Where("account_id = ? AND last_used_step < ?", accountID, step).
+		Update("last_used_step", step)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Delete removes the second factor and the recovery codes of an account
+func (m *MFAModel) Delete(accountID uint64) error {
+	if err := m.Tx.Where("account_id = ?", accountID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return m.Tx.Where("account_id = ?", accountID).Delete(&MFA{}).Error
+}
+
+// ReplaceRecoveryCodes replaces the recovery codes of an account
+func (m *MFAModel) ReplaceRecoveryCodes(accountID uint64, hashes []string) error {
+	if err := m.Tx.Where("account_id = ?", accountID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]RecoveryCode, 0, len(hashes))
+	for _, hash := range hashes {
+		codes = append(codes, RecoveryCode{AccountID: accountID, CodeHash: hash})
+	}
+	return m.Tx.Create(&codes).Error
+}
+
+// UseRecoveryCode marks an unused recovery code of an account as used, it returns false if there is none
+func (m *MFAModel) UseRecoveryCode(accountID uint64, hash string, now time.Time) (bool, error) {
+	res := m.Tx.Model(&RecoveryCode{}).
+		Where("account_id = ? AND code_hash = ? AND used_at IS NULL", accountID, hash).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountRecoveryCodes counts the unused recovery codes of an account
+func (m *MFAModel) CountRecoveryCodes(accountID uint64) (int64, error) {
+	var count int64
+	err := m.Tx.Model(&RecoveryCode{}).Where("account_id = ? AND used_at IS NULL", accountID).Count(&count).Error
+	return count, err
+}
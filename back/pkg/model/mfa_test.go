@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMFAModel_UseStep tests that a TOTP code can not be replayed
+func TestMFAModel_UseStep(t *testing.T) {
+	db := SetupTestDatabase()
+	mfaModel := NewMFAModel(db)
+
+	account := Account{Email: "mfa@gmail.com", Username: "mfa", Password: "password"}
+	db.Create(&account)
+
+	if err := mfaModel.Start(&MFA{AccountID: account.ID, Secret: "GEZDGNBVGY3TQOJQ"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if enabled, _ := mfaModel.IsEnabled(account.ID); enabled {
+		t.Error("Second factor should be pending until confirmed")
+	}
+
+	mfaModel.Enable(account.ID, time.Now())
+	if enabled, _ := mfaModel.IsEnabled(account.ID); !enabled {
+		t.Error("Second factor should be enabled")
+	}
+
+	if used, err := mfaModel.UseStep(account.ID, 10); err != nil || !used {
+		t.Error("Step should be accepted once", err)
+	}
+
+	if used, _ := mfaModel.UseStep(account.ID, 10); used {
+		t.Error("Step should not be accepted twice")
+	}
+
+	if used, _ := mfaModel.UseStep(account.ID, 9); used {
+		t.Error("Earlier step should not be accepted")
+	}
+}
+
+// TestMFAModel_UseRecoveryCode tests that a recovery code can be used once
+func TestMFAModel_UseRecoveryCode(t *testing.T) {
+	db := SetupTestDatabase()
+	mfaModel := NewMFAModel(db)
+
+	account := Account{Email: "recovery@gmail.com", Username: "recovery", Password: "password"}
+	db.Create(&account)
+
+	if err := mfaModel.ReplaceRecoveryCodes(account.ID, []string{"first", "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if used, err := mfaModel.UseRecoveryCode(account.ID, "first", time.Now()); err != nil || !used {
+		t.Error("Recovery code should be usable once", err)
+	}
+
+	if used, _ := mfaModel.UseRecoveryCode(account.ID, "first", time.Now()); used {
+		t.Error("Recovery code should not be usable twice")
+	}
+
+	if count, _ := mfaModel.CountRecoveryCodes(account.ID); count != 1 {
+		t.Errorf("One recovery code should be left, got %d", count)
+	}
+}
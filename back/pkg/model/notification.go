@@ -0,0 +1,74 @@
+package model
+
+import (
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type AbsenceNotification struct {
+	// ID is the id of the notification
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the absence was recorded
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// StudentID is the foreign key to the student table
+	StudentID uint64 `json:"student_id" gorm:"not null;index"`
+	// Student is the absent student
+	Student *Student `json:"student" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// SessionID is the foreign key to the session table
+	SessionID uuid.UUID `json:"session_id" gorm:"type:uuid;not null"`
+	// Session is the session the student missed
+	Session *Session `json:"session" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// SentAt is the date the notification was sent in a digest, null while pending
+	SentAt *time.Time `json:"sent_at" gorm:"index"`
+}
+
+// TableName overrides the default table name generated by GORM to be `absence_notifications`
+func (AbsenceNotification) TableName() string {
+	return "absence_notifications"
+}
+
+type AbsenceNotificationModel struct {
+	Tx *gorm.DB
+}
+
+// NewAbsenceNotificationModel creates a new absence notification model
+func NewAbsenceNotificationModel(tx *gorm.DB) *AbsenceNotificationModel {
+	return &AbsenceNotificationModel{Tx: tx}
+}
+
+// Create queues notifications
+func (m *AbsenceNotificationModel) Create(notifications []AbsenceNotification) error {
+	return m.Tx.Omit(clause.Associations).Create(&notifications).Error
+}
+
+// FindPendingStudents gets the ids of the students having notifications not sent yet and recorded before the given date
+func (m *AbsenceNotificationModel) FindPendingStudents(before time.Time) ([]uint64, error) {
+	var ids []uint64
+	err := m.Tx.Model(&AbsenceNotification{}).
+		Where("sent_at IS NULL AND created_at < ?", before).
+		Distinct("student_id").
+		Order("student_id").
+		Pluck("student_id", &ids).Error
+	return ids, err
+}
+
+// FindPending locks and gets the notifications of a student not sent yet and recorded before the given date,
+// with the student and its guardians and the sessions with their class, oldest first.
+// The notifications locked by another replica are skipped so that each digest is sent once.
+func (m *AbsenceNotificationModel) FindPending(studentID uint64, before time.Time) ([]AbsenceNotification, error) {
+	var notifications []AbsenceNotification
+	err := m.Tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "absence_notifications"}, Options: "SKIP LOCKED"}).
+		Where("student_id = ? AND sent_at IS NULL AND created_at < ?", studentID, before).
+		Preload("Student.Guardians.Account").
+		Preload("Session.Class").
+		Order("created_at").
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkSent marks notifications as sent
+func (m *AbsenceNotificationModel) MarkSent(ids []uint64, sentAt time.Time) error {
+	return m.Tx.Model(&AbsenceNotification{}).Where("id IN ?", ids).Update("sent_at", sentAt).Error
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type PasswordHistory struct {
+	// ID is the id of the entry
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the password was set
+	CreatedAt time.Time `json:"created_at"`
+	// AccountID is the foreign key to the account table
+	AccountID uint64 `json:"account_id" gorm:"not null;index"`
+	// Account is the account whose password was set
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Hash is the bcrypt hash of the password
+	Hash string `json:"-" gorm:"not null"`
+}
+
+// TableName overrides the default table name generated by GORM to be `password_history`
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
+
+type PasswordHistoryModel struct {
+	Tx *gorm.DB
+}
+
+// NewPasswordHistoryModel creates a new password history model
+func NewPasswordHistoryModel(tx *gorm.DB) *PasswordHistoryModel {
+	return &PasswordHistoryModel{Tx: tx}
+}
+
+// FindLatest gets the hashes of the latest passwords of an account, the newest first
+func (m *PasswordHistoryModel) FindLatest(accountID uint64, limit int) ([]PasswordHistory, error) {
+	var history []PasswordHistory
+	err := m.Tx.Where("account_id = ?", accountID).Order("created_at DESC, id DESC").Limit(limit).Find(&history).Error
+	return history, err
+}
+
+// Add records a new password of an account and forgets the ones older than the kept number of passwords
+func (m *PasswordHistoryModel) Add(accountID uint64, hash string, keep int) error {
+	if err := m.Tx.Omit(clause.Associations).Create(&PasswordHistory{AccountID: accountID, Hash: hash}).Error; err != nil {
+		return err
+	}
+
+	kept := m.Tx.Model(&PasswordHistory{}).Select("id").
+		Where("account_id = ?", accountID).
+		Order("created_at DESC, id DESC").
+		Limit(keep)
+	return m.Tx.Where("account_id = ? AND id NOT IN (?)", accountID, kept).Delete(&PasswordHistory{}).Error
+}
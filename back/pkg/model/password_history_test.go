@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+// TestPasswordHistoryModel_Add tests that only the latest passwords of an account are kept
+func TestPasswordHistoryModel_Add(t *testing.T) {
+	db := SetupTestDatabase()
+	historyModel := NewPasswordHistoryModel(db)
+
+	account := Account{Email: "history@gmail.com", Username: "history", Password: "password", User: User{FirstName: "Jules", LastName: "Doe"}}
+	db.Create(&account)
+
+	for _, hash := range []string{"first", "second", "third"} {
+		if err := historyModel.Add(account.ID, hash, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := historyModel.FindLatest(account.ID, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Hash != "third" || history[1].Hash != "second" {
+		t.Error("History should keep the 2 latest passwords, the newest first")
+	}
+}
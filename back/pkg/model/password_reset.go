@@ -0,0 +1,62 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type PasswordReset struct {
+	// ID is the id of the reset request
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the reset was requested
+	CreatedAt time.Time `json:"created_at"`
+	// AccountID is the foreign key to the account table
+	AccountID uint64 `json:"account_id" gorm:"not null;index"`
+	// Account is the account whose password is reset
+	Account *Account `json:"account" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// TokenHash is the SHA-256 hash of the reset token sent by email
+	TokenHash string `json:"-" gorm:"not null;size:64;uniqueIndex:unique_idx_password_reset_token"`
+	// ExpiresAt is the date after which the token cannot be used
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	// UsedAt is the date the token was used, or replaced by a newer one
+	UsedAt *time.Time `json:"used_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `password_resets`
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
+
+type PasswordResetModel struct {
+	Tx *gorm.DB
+}
+
+// NewPasswordResetModel creates a new password reset model
+func NewPasswordResetModel(tx *gorm.DB) *PasswordResetModel {
+	return &PasswordResetModel{Tx: tx}
+}
+
+// Create creates a new password reset, the pending resets of the account are discarded
+func (m *PasswordResetModel) Create(reset *PasswordReset) error {
+	if err := m.Tx.Model(&PasswordReset{}).
+		Where("account_id = ? AND used_at IS NULL", reset.AccountID).
+		Update("used_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return m.Tx.Create(reset).Error
+}
+
+// GetByTokenHash gets a password reset by the hash of its token
+func (m *PasswordResetModel) GetByTokenHash(hash string) (*PasswordReset, error) {
+	var reset PasswordReset
+	err := m.Tx.Where("token_hash = ?", hash).First(&reset).Error
+	return &reset, err
+}
+
+// Use marks a password reset as used, it returns false if it was already used
+func (m *PasswordResetModel) Use(reset *PasswordReset, now time.Time) (bool, error) {
+	res := m.Tx.Model(&PasswordReset{}).
+		Where("id = ? AND used_at IS NULL", reset.ID).
+		Update("used_at", now)
+	return res.RowsAffected > 0, res.Error
+}
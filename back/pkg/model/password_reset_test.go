@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPasswordResetModel_Use tests that a password reset can be used once
+func TestPasswordResetModel_Use(t *testing.T) {
+	db := SetupTestDatabase()
+	resetModel := NewPasswordResetModel(db)
+
+	account := Account{Email: "reset@gmail.com", Username: "reset", Password: "password"}
+	db.Create(&account)
+
+	first := PasswordReset{AccountID: account.ID, TokenHash: "first", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := resetModel.Create(&first); err != nil {
+		t.Fatal(err)
+	}
+	second := PasswordReset{AccountID: account.ID, TokenHash: "second", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := resetModel.Create(&second); err != nil {
+		t.Fatal(err)
+	}
+
+	if reset, _ := resetModel.GetByTokenHash("first"); reset.UsedAt == nil {
+		t.Error("A newer reset should discard the pending one")
+	}
+
+	if used, err := resetModel.Use(&second, time.Now()); err != nil || !used {
+		t.Error("Reset should be usable once", err)
+	}
+
+	if used, _ := resetModel.Use(&second, time.Now()); used {
+		t.Error("Reset should not be usable twice")
+	}
+}
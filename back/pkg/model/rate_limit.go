@@ -0,0 +1,59 @@
+package model
+
+import (
+	"gin-template/utils/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RateLimitBucket is the token bucket of a rate limited key, shared by the replicas
+type RateLimitBucket struct {
+	// Key identifies the limited client, account or session
+	Key string `json:"key" gorm:"primaryKey;size:255"`
+	// Tokens is the number of tokens left at the last refill
+	Tokens float64 `json:"tokens" gorm:"not null"`
+	// RefilledAt is the date of the last refill
+	RefilledAt time.Time `json:"refilled_at" gorm:"not null;index"`
+}
+
+// TableName overrides the default table name generated by GORM to be `rate_limit_buckets`
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// RateLimitModel is a rate limit store holding the buckets in the database,
+// its transactions are independent from the one of the request so that a refused request still spends its token
+type RateLimitModel struct {
+	DB *gorm.DB
+}
+
+// NewRateLimitModel creates a new rate limit model on the database, not on the transaction of a request
+func NewRateLimitModel(db *gorm.DB) *RateLimitModel {
+	return &RateLimitModel{DB: db}
+}
+
+// Take takes a token from the bucket of a key, the bucket is locked while it is refilled
+func (m *RateLimitModel) Take(key string, limit ratelimit.Limit, now time.Time) (time.Duration, bool, error) {
+	var wait time.Duration
+	var allowed bool
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		b := RateLimitBucket{Key: key, Tokens: float64(limit.Burst), RefilledAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&b).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&b).Error; err != nil {
+			return err
+		}
+
+		b.Tokens, wait, allowed = limit.Take(b.Tokens, b.RefilledAt, now)
+		b.RefilledAt = now
+		return tx.Model(&b).Select("tokens", "refilled_at").Updates(&b).Error
+	})
+	return wait, allowed, err
+}
+
+// Purge deletes the buckets not refilled since the given date
+func (m *RateLimitModel) Purge(before time.Time) error {
+	return m.DB.Where("refilled_at < ?", before).Delete(&RateLimitBucket{}).Error
+}
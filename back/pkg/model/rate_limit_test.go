@@ -0,0 +1,36 @@
+package model
+
+import (
+	"gin-template/utils/ratelimit"
+	"testing"
+	"time"
+)
+
+// TestRateLimitModel_Take tests that the buckets held in the database allow their burst only
+func TestRateLimitModel_Take(t *testing.T) {
+	db := SetupTestDatabase()
+	rateLimitModel := NewRateLimitModel(db)
+	limit := ratelimit.Limit{Burst: 2, Period: time.Minute}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, allowed, err := rateLimitModel.Take("login:ip:1.2.3.4", limit, now); err != nil || !allowed {
+			t.Fatalf("Request %d should be allowed by the burst: %v", i+1, err)
+		}
+	}
+
+	wait, allowed, err := rateLimitModel.Take("login:ip:1.2.3.4", limit, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || wait != 30*time.Second {
+		t.Errorf("Request should be refused for 30s, got %s", wait)
+	}
+
+	if err := rateLimitModel.Purge(now.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, allowed, _ := rateLimitModel.Take("login:ip:1.2.3.4", limit, now); !allowed {
+		t.Error("Purged bucket should be full again")
+	}
+}
@@ -0,0 +1,209 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// Permissions is a list of permissions stored as a comma separated string
+type Permissions []enum.Permission
+
+// Scan implements the sql.Scanner interface
+func (p *Permissions) Scan(value interface{}) error {
+	var data string
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		return errors.New(fmt.Sprint("failed to scan permissions: ", value))
+	}
+
+	permissions := make(Permissions, 0)
+	for _, permission := range strings.Split(data, ",") {
+		if permission != "" {
+			permissions = append(permissions, enum.Permission(permission))
+		}
+	}
+	*p = permissions
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (p Permissions) Value() (driver.Value, error) {
+	permissions := make([]string, 0, len(p))
+	for _, permission := range p {
+		permissions = append(permissions, permission.String())
+	}
+	return strings.Join(permissions, ","), nil
+}
+
+// Has returns true if the list grants the permission
+func (p Permissions) Has(permission enum.Permission) bool {
+	for _, perm := range p {
+		if perm == permission || perm == enum.ALL_PERMISSIONS {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants returns true if the list grants every permission of the other one, so that a user holding it
+// can hand the other one out, all the permissions are only granted by themselves
+func (p Permissions) Grants(other Permissions) bool {
+	for _, permission := range other {
+		if !p.Has(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect returns the permissions granted by both lists
+func (p Permissions) Intersect(other Permissions) Permissions {
+	if p.Has(enum.ALL_PERMISSIONS) {
+		return append(Permissions{}, other...)
+	}
+	permissions := make(Permissions, 0, len(p))
+	for _, permission := range p {
+		if other.Has(permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+	return permissions
+}
+
+type Role struct {
+	// Name is the name of the role, referenced by the users
+	Name enum.Role `json:"name" gorm:"primaryKey;type:varchar(40)"`
+	// CreatedAt is the date the role was created
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the date the role was last updated
+	UpdatedAt time.Time `json:"updated_at"`
+	// Description is a human readable description of the role
+	Description string `json:"description" gorm:"size:255"`
+	// Permissions is the set of permissions granted to the role
+	Permissions Permissions `json:"permissions" gorm:"type:text;not null"`
+	// RequireMFA is true if the users of the role must confirm their logins with a second factor
+	RequireMFA bool `json:"require_mfa" gorm:"not null;default:false"`
+}
+
+// TableName overrides the default table name generated by GORM to be `roles`
+func (Role) TableName() string {
+	return "roles"
+}
+
+// DefaultRoles are the builtin roles created with the database
+var DefaultRoles = []Role{
+	{
+		Name:        enum.SUPERADMIN,
+		Description: "Manages the whole institution",
+		Permissions: Permissions{enum.ALL_PERMISSIONS},
+	},
+	{
+		Name:        enum.ADMIN,
+		Description: "Teaches classes and manages their attendance",
+		Permissions: Permissions{
+			enum.ACCOUNT_SELF,
+			enum.CLASS_READ,
+			enum.CLASS_WRITE,
+			enum.CLASS_TEACH,
+			enum.STUDENT_WRITE,
+			enum.TERM_READ,
+			enum.TERM_WRITE,
+			enum.SESSION_READ,
+			enum.SESSION_WRITE,
+			enum.SESSION_CLOSE,
+			enum.ATTENDANCE_READ,
+			enum.ATTENDANCE_OVERRIDE,
+			enum.JUSTIFICATION_REVIEW,
+			enum.ALERT_READ,
+			enum.ALERT_WRITE,
+			enum.WEBHOOK_MANAGE,
+		},
+	},
+	{
+		Name:        enum.STUDENT,
+		Description: "Attends classes",
+		Permissions: Permissions{enum.ACCOUNT_SELF},
+	},
+	{
+		Name:        enum.GUARDIAN,
+		Description: "Follows the attendance of their children",
+		Permissions: Permissions{enum.ACCOUNT_SELF, enum.GUARDIAN_ACCESS},
+	},
+}
+
+type RoleModel struct {
+	Tx *gorm.DB
+}
+
+// NewRoleModel creates a new role model
+func NewRoleModel(tx *gorm.DB) *RoleModel {
+	return &RoleModel{Tx: tx}
+}
+
+// Seed creates the builtin roles which do not exist yet, existing roles are left untouched
+func (m *RoleModel) Seed() error {
+	roles := make([]Role, len(DefaultRoles))
+	copy(roles, DefaultRoles)
+	return m.Tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&roles).Error
+}
+
+// Create creates a new role
+func (m *RoleModel) Create(role *Role) error {
+	return m.Tx.Create(role).Error
+}
+
+// GetByName gets a role by name
+func (m *RoleModel) GetByName(name enum.Role) (*Role, error) {
+	var role Role
+	err := m.Tx.Where("name = ?", name).First(&role).Error
+	return &role, err
+}
+
+// FindAll gets all roles
+func (m *RoleModel) FindAll() ([]Role, error) {
+	var roles []Role
+	err := m.Tx.Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// Update updates the description, the permissions and the second factor requirement of a role
+func (m *RoleModel) Update(role *Role) *gorm.DB {
+	return m.Tx.Model(role).Select("description", "permissions", "require_mfa").Updates(role)
+}
+
+// Delete deletes a role
+func (m *RoleModel) Delete(name enum.Role) *gorm.DB {
+	return m.Tx.Where("name = ?", name).Delete(&Role{})
+}
+
+// CountUsers counts the users, including the deleted ones, having a role
+func (m *RoleModel) CountUsers(name enum.Role) (int64, error) {
+	var count int64
+	err := m.Tx.Unscoped().Model(&User{}).Where("role = ?", name).Count(&count).Error
+	return count, err
+}
+
+// HasPermission returns true if the role grants the permission, an unknown role grants nothing
+func (m *RoleModel) HasPermission(name enum.Role, permission enum.Permission) (bool, error) {
+	role, err := m.GetByName(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return role.Permissions.Has(permission), nil
+}
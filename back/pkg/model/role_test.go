@@ -0,0 +1,75 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"testing"
+)
+
+// TestPermissions_Has tests the permissions granted by a set of permissions
+func TestPermissions_Has(t *testing.T) {
+	p := Permissions{enum.ACCOUNT_SELF, enum.CLASS_READ}
+	if !p.Has(enum.CLASS_READ) {
+		t.Errorf("Permissions should grant %s", enum.CLASS_READ)
+	}
+	if p.Has(enum.CLASS_WRITE) {
+		t.Errorf("Permissions should not grant %s", enum.CLASS_WRITE)
+	}
+
+	if !(Permissions{enum.ALL_PERMISSIONS}).Has(enum.AUDIT_READ) {
+		t.Errorf("Wildcard should grant %s", enum.AUDIT_READ)
+	}
+}
+
+// TestPermissions_Grants tests that a set of permissions can only hand out the permissions it holds
+func TestPermissions_Grants(t *testing.T) {
+	admin := Permissions{enum.ACCOUNT_SELF, enum.USER_READ, enum.USER_WRITE}
+	if !admin.Grants(Permissions{enum.ACCOUNT_SELF, enum.USER_READ}) {
+		t.Error("Permissions should grant a subset of themselves")
+	}
+	if admin.Grants(Permissions{enum.ACCOUNT_SELF, enum.ROLE_MANAGE}) {
+		t.Errorf("Permissions should not grant %s", enum.ROLE_MANAGE)
+	}
+	if admin.Grants(Permissions{enum.ALL_PERMISSIONS}) {
+		t.Error("Named permissions should not grant the wildcard")
+	}
+	if !(Permissions{enum.ALL_PERMISSIONS}).Grants(Permissions{enum.ALL_PERMISSIONS, enum.AUDIT_READ}) {
+		t.Error("Wildcard should grant every permission")
+	}
+}
+
+// TestPermissions_Intersect tests the permissions granted by two sets of permissions
+func TestPermissions_Intersect(t *testing.T) {
+	role := Permissions{enum.ACCOUNT_SELF, enum.CLASS_READ, enum.SESSION_READ}
+	key := Permissions{enum.CLASS_READ, enum.CLASS_WRITE}
+
+	p := role.Intersect(key)
+	if len(p) != 1 || !p.Has(enum.CLASS_READ) {
+		t.Errorf("Intersection should only grant %s, got %v", enum.CLASS_READ, p)
+	}
+
+	p = Permissions{enum.ALL_PERMISSIONS}.Intersect(key)
+	if p.Has(enum.AUDIT_READ) || !p.Has(enum.CLASS_WRITE) {
+		t.Errorf("Intersection with the wildcard should grant the other set, got %v", p)
+	}
+
+	p = key.Intersect(Permissions{enum.ALL_PERMISSIONS})
+	if p.Has(enum.AUDIT_READ) || !p.Has(enum.CLASS_WRITE) {
+		t.Errorf("Intersection with the wildcard should grant the other set, got %v", p)
+	}
+}
+
+// TestPermissions_Scan tests the scan of a comma separated list of permissions
+func TestPermissions_Scan(t *testing.T) {
+	var p Permissions
+	if err := p.Scan("account:self,,class:read"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 2 || p[0] != enum.ACCOUNT_SELF || p[1] != enum.CLASS_READ {
+		t.Errorf("Scanned permissions are %v", p)
+	}
+
+	v, _ := p.Value()
+	if v != "account:self,class:read" {
+		t.Errorf("Value is %v", v)
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "gorm.io/gorm"
+
+// paginate returns a scope limiting a query to the given page
+func paginate(page, pageSize int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		offset := (page - 1) * pageSize
+		return db.Offset(offset).Limit(pageSize)
+	}
+}
+
+// onlyDeleted returns a scope selecting soft deleted rows only
+func onlyDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
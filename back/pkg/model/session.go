@@ -3,6 +3,7 @@ package model
 import (
 	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
+	"time"
 )
 
 type Session struct {
@@ -44,6 +45,11 @@ func (m *SessionModel) GetByID(session *Session) *gorm.DB {
 	return m.Tx.Preload("Class").First(&session)
 }
 
+// GetByClassID gets a session of a class
+func (m *SessionModel) GetByClassID(classID uint64, session *Session) *gorm.DB {
+	return m.Tx.Where("id = ? AND class_id = ?", session.ID, classID).First(session)
+}
+
 // FindAll gets all sessions of a class
 func (m *SessionModel) FindAll(classID uint64) ([]Session, error) {
 	var sessions []Session
@@ -78,3 +84,41 @@ func (m *SessionModel) Delete(session *Session) error {
 func (m *SessionModel) DeleteAll(classID uint64) error {
 	return m.Tx.Where("class_id = ?", classID).Delete(&Session{}).Error
 }
+
+// FindDeleted gets all soft deleted sessions paginated, optionally filtered by class
+func (m *SessionModel) FindDeleted(sessions *[]Session, classID uint64, page, pageSize int) *gorm.DB {
+	tx := m.Tx.Scopes(onlyDeleted, paginate(page, pageSize))
+	if classID != 0 {
+		tx = tx.Where("class_id = ?", classID)
+	}
+	return tx.Order("deleted_at desc").Find(sessions)
+}
+
+// Restore restores a soft deleted session
+func (m *SessionModel) Restore(session *Session) *gorm.DB {
+	if tx := m.Tx.Scopes(onlyDeleted).Where("id = ?", session.ID).First(session); tx.Error != nil {
+		return tx
+	}
+	return m.Tx.Unscoped().Model(&Session{}).Where("id = ?", session.ID).Update("deleted_at", nil)
+}
+
+// RestoreLastDeleted restores the sessions of a class removed by the last deletion
+func (m *SessionModel) RestoreLastDeleted(classID uint64) *gorm.DB {
+	return m.Tx.Unscoped().Model(&Session{}).Where(
+		"class_id = ? AND deleted_at = (?)",
+		classID,
+		m.Tx.Unscoped().Model(&Session{}).Select("MAX(deleted_at)").Where("class_id = ?", classID),
+	).Update("deleted_at", nil)
+}
+
+// Purge permanently deletes the sessions soft deleted before the given date and returns their number
+func (m *SessionModel) Purge(before time.Time) (int64, error) {
+	err := m.Tx.Exec(
+		"DELETE FROM session_students WHERE session_id IN (SELECT id FROM sessions WHERE deleted_at < ?)", before,
+	).Error
+	if err != nil {
+		return 0, err
+	}
+	res := m.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&Session{})
+	return res.RowsAffected, res.Error
+}
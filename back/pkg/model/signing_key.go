@@ -0,0 +1,54 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type SigningKey struct {
+	// ID is the kid identifying the key in the header of the tokens
+	ID string `json:"id" gorm:"primaryKey;size:36"`
+	// CreatedAt is the date the key was generated
+	CreatedAt time.Time `json:"created_at"`
+	// Algorithm is the algorithm of the key, RS256 or EdDSA
+	Algorithm string `json:"algorithm" gorm:"not null;size:16"`
+	// PrivateKey is the PEM encoded private key
+	PrivateKey string `json:"-" gorm:"not null"`
+	// ActiveAt is the date the key starts signing the tokens, it is published before
+	ActiveAt time.Time `json:"active_at" gorm:"not null;index"`
+	// RetiresAt is the date the key stops signing the tokens
+	RetiresAt time.Time `json:"retires_at" gorm:"not null"`
+	// ExpiresAt is the date the key stops verifying the tokens, once the tokens it signed have expired
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+}
+
+type SigningKeyModel struct {
+	Tx *gorm.DB
+}
+
+// NewSigningKeyModel creates a new signing key model
+func NewSigningKeyModel(tx *gorm.DB) *SigningKeyModel {
+	return &SigningKeyModel{Tx: tx}
+}
+
+// Create stores a new signing key
+func (m *SigningKeyModel) Create(k *SigningKey) error {
+	return m.Tx.Create(k).Error
+}
+
+// FindValid gets the keys of an algorithm still verifying the tokens at a date, the latest to sign first
+func (m *SigningKeyModel) FindValid(algorithm string, now time.Time) ([]SigningKey, error) {
+	var keys []SigningKey
+	err := m.Tx.Where("algorithm = ? AND expires_at > ?", algorithm, now).Order("active_at DESC, created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// DeleteExpired deletes the keys which no longer verify any token at a date
+func (m *SigningKeyModel) DeleteExpired(now time.Time) error {
+	return m.Tx.Where("expires_at <= ?", now).Delete(&SigningKey{}).Error
+}
+
+// Retire makes the keys of an algorithm stop signing the tokens at a date, they keep verifying them until they expire
+func (m *SigningKeyModel) Retire(algorithm string, at time.Time) error {
+	return m.Tx.Model(&SigningKey{}).Where("algorithm = ? AND retires_at > ?", algorithm, at).Update("retires_at", at).Error
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSigningKeyModel_FindValid tests that only the keys of the algorithm still verifying tokens are found
+func TestSigningKeyModel_FindValid(t *testing.T) {
+	db := SetupTestDatabase()
+	keyModel := NewSigningKeyModel(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, k := range []SigningKey{
+		{ID: "expired", Algorithm: "EdDSA", PrivateKey: "key", ActiveAt: now.Add(-3 * time.Hour), RetiresAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+		{ID: "retired", Algorithm: "EdDSA", PrivateKey: "key", ActiveAt: now.Add(-2 * time.Hour), RetiresAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: "current", Algorithm: "EdDSA", PrivateKey: "key", ActiveAt: now, RetiresAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)},
+		{ID: "other", Algorithm: "RS256", PrivateKey: "key", ActiveAt: now, RetiresAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)},
+	} {
+		if err := keyModel.Create(&k); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := keyModel.FindValid("EdDSA", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "current" || keys[1].ID != "retired" {
+		t.Error("Valid keys should be the current and the retired ones, the latest first")
+	}
+
+	if err := keyModel.DeleteExpired(now); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&SigningKey{}).Where("id = ?", "expired").Count(&count)
+	if count != 0 {
+		t.Error("Expired key should be deleted")
+	}
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"time"
+)
+
+type StaffInvitation struct {
+	// ID is the id of the invitation
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the invitation was sent
+	CreatedAt time.Time `json:"created_at"`
+	// Email is the email the invitation is sent to
+	Email string `json:"email" gorm:"not null;index"`
+	// Username is the username of the account created by the invitation
+	Username string `json:"username" gorm:"not null"`
+	// FirstName is the first name of the invitee
+	FirstName string `json:"first_name" gorm:"not null"`
+	// LastName is the last name of the invitee
+	LastName string `json:"last_name" gorm:"not null"`
+	// Role is the role of the account created by the invitation
+	Role enum.Role `json:"role" gorm:"not null;type:varchar(40)"`
+	// TokenHash is the SHA-256 hash of the token of the link sent by email, replaced when the invitation is resent
+	TokenHash string `json:"-" gorm:"not null;size:64;uniqueIndex:unique_idx_staff_invitation_token"`
+	// ExpiresAt is the date after which the link cannot be used
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	// CreatedBy is the id of the user who sent the invitation
+	CreatedBy uint64 `json:"created_by" gorm:"not null"`
+	// AcceptedAt is the date the invitee set their password
+	AcceptedAt *time.Time `json:"accepted_at"`
+	// RevokedAt is the date the invitation was revoked
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `staff_invitations`
+func (StaffInvitation) TableName() string {
+	return "staff_invitations"
+}
+
+type StaffInvitationModel struct {
+	Tx *gorm.DB
+}
+
+// NewStaffInvitationModel creates a new staff invitation model
+func NewStaffInvitationModel(tx *gorm.DB) *StaffInvitationModel {
+	return &StaffInvitationModel{Tx: tx}
+}
+
+// Create creates a new invitation
+func (m *StaffInvitationModel) Create(i *StaffInvitation) error {
+	return m.Tx.Create(i).Error
+}
+
+// FindPending gets the invitations neither accepted nor revoked, the newest first, the expired ones included
+func (m *StaffInvitationModel) FindPending() ([]StaffInvitation, error) {
+	var invitations []StaffInvitation
+	err := m.Tx.Where("accepted_at IS NULL AND revoked_at IS NULL").Order("created_at DESC, id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// HasPending returns true if an invitation neither accepted nor revoked is still valid for an email or a username
+func (m *StaffInvitationModel) HasPending(email, username string, now time.Time) (bool, error) {
+	var count int64
+	err := m.Tx.Model(&StaffInvitation{}).
+		Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now).
+		Where("LOWER(email) = LOWER(?) OR username = ?", email, username).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetPending gets an invitation neither accepted nor revoked by its id
+func (m *StaffInvitationModel) GetPending(id uint64) (*StaffInvitation, error) {
+	var i StaffInvitation
+	err := m.Tx.Where("accepted_at IS NULL AND revoked_at IS NULL").First(&i, id).Error
+	return &i, err
+}
+
+// GetByTokenHash gets an invitation by the hash of the token of its link
+func (m *StaffInvitationModel) GetByTokenHash(hash string) (*StaffInvitation, error) {
+	var i StaffInvitation
+	err := m.Tx.Where("token_hash = ?", hash).First(&i).Error
+	return &i, err
+}
+
+// Renew replaces the token of a pending invitation, the previous link cannot be used anymore
+func (m *StaffInvitationModel) Renew(i *StaffInvitation, tokenHash string, expiresAt time.Time) error {
+	i.TokenHash = tokenHash
+	i.ExpiresAt = expiresAt
+	return m.Tx.Model(i).Select("token_hash", "expires_at").Updates(i).Error
+}
+
+// Accept marks an invitation as accepted, it returns false if it was already accepted or revoked
+func (m *StaffInvitationModel) Accept(i *StaffInvitation, now time.Time) (bool, error) {
+	res := m.Tx.Model(&StaffInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", i.ID).
+		Update("accepted_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Revoke marks a pending invitation as revoked, it returns false if it was already accepted or revoked
+func (m *StaffInvitationModel) Revoke(id uint64, now time.Time) (bool, error) {
+	res := m.Tx.Model(&StaffInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	return res.RowsAffected > 0, res.Error
+}
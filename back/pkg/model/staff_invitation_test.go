@@ -0,0 +1,66 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"testing"
+	"time"
+)
+
+// TestStaffInvitationModel_Accept tests that an invitation is accepted once and no longer pending
+func TestStaffInvitationModel_Accept(t *testing.T) {
+	db := SetupTestDatabase()
+	invitationModel := NewStaffInvitationModel(db)
+
+	now := time.Now()
+	invitation := StaffInvitation{Email: "staff@gmail.com", Username: "staff", FirstName: "Jane", LastName: "Doe", Role: enum.ADMIN, TokenHash: "hash", ExpiresAt: now.Add(time.Hour), CreatedBy: 1}
+	if err := invitationModel.Create(&invitation); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := invitationModel.HasPending("STAFF@gmail.com", "other", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pending {
+		t.Error("Invitation should be pending for its email")
+	}
+
+	accepted, err := invitationModel.Accept(&invitation, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !accepted {
+		t.Error("Invitation should be accepted")
+	}
+	if accepted, _ = invitationModel.Accept(&invitation, now); accepted {
+		t.Error("Invitation should not be accepted twice")
+	}
+	if revoked, _ := invitationModel.Revoke(invitation.ID, now); revoked {
+		t.Error("Accepted invitation should not be revoked")
+	}
+	if pending, _ = invitationModel.HasPending("staff@gmail.com", "staff", now); pending {
+		t.Error("Accepted invitation should not be pending")
+	}
+}
+
+// TestStaffInvitationModel_Renew tests that a resent invitation is only found by its new token
+func TestStaffInvitationModel_Renew(t *testing.T) {
+	db := SetupTestDatabase()
+	invitationModel := NewStaffInvitationModel(db)
+
+	invitation := StaffInvitation{Email: "renew@gmail.com", Username: "renew", FirstName: "Jane", LastName: "Doe", Role: enum.ADMIN, TokenHash: "first", ExpiresAt: time.Now(), CreatedBy: 1}
+	if err := invitationModel.Create(&invitation); err != nil {
+		t.Fatal(err)
+	}
+	if err := invitationModel.Renew(&invitation, "second", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := invitationModel.GetByTokenHash("first"); err == nil {
+		t.Error("Previous token should not find the invitation")
+	}
+	found, err := invitationModel.GetByTokenHash("second")
+	if err != nil || found.ID != invitation.ID {
+		t.Error("New token should find the invitation")
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
 
 type Student struct {
 	gorm.Model
@@ -16,6 +20,10 @@ type Student struct {
 	ClassID uint64 `json:"class_id"`
 	// Class is the class of the student
 	Class *Class `json:"class" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Guardians is the list of users who are guardians of the student
+	Guardians []*User `json:"guardians" gorm:"many2many:guardian_students;"`
+	// ErasedAt is the date the personal data of the student was erased, nil if it was not
+	ErasedAt *time.Time `json:"erased_at"`
 }
 
 // TableName returns the name of the table
@@ -32,11 +40,21 @@ func NewStudentModel(tx *gorm.DB) *StudentModel {
 	return &StudentModel{Tx: tx}
 }
 
+// GetByClassID gets a student of a class
+func (s *StudentModel) GetByClassID(classId uint64, student *Student) *gorm.DB {
+	return s.Tx.Where("id = ? AND class_id = ?", student.ID, classId).First(student)
+}
+
 // GetStudentsClass returns the class of the student
 func (s *StudentModel) GetStudentsClass(classId uint64, student []Student) *gorm.DB {
 	return s.Tx.Model(student).Where("class_id = ?", classId).Preload("Class").Find(student)
 }
 
+// FindByEmail finds a student by email, ignoring the case
+func (s *StudentModel) FindByEmail(email string, student *Student) *gorm.DB {
+	return s.Tx.Where("lower(email) = lower(?)", email).First(student)
+}
+
 // AddStudentToClass adds a student to a class
 func (s *StudentModel) AddStudentToClass(student *Student) error {
 	return s.Tx.Association("Class").Append(student)
@@ -46,3 +64,98 @@ func (s *StudentModel) AddStudentToClass(student *Student) error {
 func (s *StudentModel) RemoveStudentFromClass(student *Student) error {
 	return s.Tx.Association("Class").Delete(student)
 }
+
+// FindDeleted gets all soft deleted students paginated, optionally filtered by class
+func (s *StudentModel) FindDeleted(students *[]Student, classID uint64, page, pageSize int) *gorm.DB {
+	tx := s.Tx.Scopes(onlyDeleted, paginate(page, pageSize))
+	if classID != 0 {
+		tx = tx.Where("class_id = ?", classID)
+	}
+	return tx.Order("deleted_at desc").Find(students)
+}
+
+// Restore restores a soft deleted student
+func (s *StudentModel) Restore(student *Student) *gorm.DB {
+	if tx := s.Tx.Scopes(onlyDeleted).Where("id = ?", student.ID).First(student); tx.Error != nil {
+		return tx
+	}
+	return s.Tx.Unscoped().Model(&Student{}).Where("id = ?", student.ID).Update("deleted_at", nil)
+}
+
+// Purge permanently deletes the students soft deleted before the given date and returns their number
+func (s *StudentModel) Purge(before time.Time) (int64, error) {
+	err := s.Tx.Exec(
+		"DELETE FROM guardian_students WHERE student_id IN (SELECT id FROM students WHERE deleted_at < ?)", before,
+	).Error
+	if err != nil {
+		return 0, err
+	}
+	res := s.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&Student{})
+	return res.RowsAffected, res.Error
+}
+
+// GetWithDeleted gets a student, including a soft deleted one, with its class and guardians
+func (s *StudentModel) GetWithDeleted(id uint64) (*Student, error) {
+	var student Student
+	err := s.Tx.Unscoped().Preload("Class", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Guardians").Where("id = ?", id).First(&student).Error
+	return &student, err
+}
+
+// FindAllByEmail finds the students, including the soft deleted ones, having an email, ignoring the case
+func (s *StudentModel) FindAllByEmail(email string) ([]Student, error) {
+	var students []Student
+	err := s.Tx.Unscoped().Preload("Class", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Preload("Guardians").Where("lower(email) = lower(?)", email).Order("id").Find(&students).Error
+	return students, err
+}
+
+// Erase anonymizes a student, removes its guardians, the reasons of its justifications and its pending absence
+// notifications, and closes its alerts so that nothing is sent about it anymore, its attendance is kept so that the statistics of its class do not change,
+// it returns the entities whose audit entries hold the erased data
+func (s *StudentModel) Erase(id uint64, now time.Time) (EntityRefs, error) {
+	refs := EntityRefs{}
+	refs.Add("students", id)
+
+	var justificationIDs, invitationIDs []uint64
+	err := s.Tx.Model(&Justification{}).
+		Where("attendance_id IN (?)", s.Tx.Model(&Attendance{}).Select("id").Where("student_id = ?", id)).
+		Pluck("id", &justificationIDs).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Tx.Model(&GuardianInvitation{}).Where("student_id = ?", id).Pluck("id", &invitationIDs).Error; err != nil {
+		return nil, err
+	}
+	refs.Add("justifications", justificationIDs...)
+	refs.Add("guardian_invitations", invitationIDs...)
+
+	for _, query := range []string{
+		"DELETE FROM guardian_students WHERE student_id = ?",
+		"DELETE FROM guardian_invitations WHERE student_id = ?",
+		"UPDATE justifications SET reason = '' WHERE attendance_id IN (SELECT id FROM attendances WHERE student_id = ?)",
+		"DELETE FROM absence_notifications WHERE student_id = ? AND sent_at IS NULL",
+	} {
+		if err := s.Tx.Exec(query, id).Error; err != nil {
+			return nil, err
+		}
+	}
+	err = s.Tx.Exec(
+		"UPDATE alerts SET notified_at = COALESCE(notified_at, ?), acknowledged_at = COALESCE(acknowledged_at, ?) "+
+			"WHERE student_id = ? AND (notified_at IS NULL OR acknowledged_at IS NULL)",
+		now, now, id,
+	).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Tx.Unscoped().Model(&Student{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":      nil,
+		"first_name": "Erased",
+		"last_name":  fmt.Sprintf("student %d", id),
+		"erased_at":  now,
+	}).Error
+	return refs, err
+}
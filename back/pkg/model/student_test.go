@@ -0,0 +1,87 @@
+package model
+
+import (
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// setupErasureTestDatabase sets up the test database with the attendance of the students and their guardians
+func setupErasureTestDatabase() *gorm.DB {
+	db := setupAPIKeyTestDatabase()
+	db.Migrator().DropTable(&Alert{}, &AlertRule{}, &AbsenceNotification{}, &Justification{}, &Attendance{}, &GuardianInvitation{})
+	db.AutoMigrate(Attendance{}, Justification{}, GuardianInvitation{}, AbsenceNotification{}, AlertRule{}, Alert{})
+	return db
+}
+
+// TestStudentModel_Erase tests that an erased student is anonymized while its attendance statistics are kept
+func TestStudentModel_Erase(t *testing.T) {
+	db := setupErasureTestDatabase()
+
+	class := Class{Name: "erasure", Year: "2022"}
+	db.Create(&class)
+	student := Student{Email: "student.erase@gmail.com", FirstName: "first", LastName: "last", ClassID: class.ID}
+	db.Create(&student)
+	guardian := Account{Email: "guardian.erase@gmail.com", Username: "guardian.erase", Password: "password", User: User{FirstName: "guardian", Role: enum.GUARDIAN}}
+	db.Create(&guardian)
+	NewGuardianModel(db).AddGuardian(&student, &guardian.User)
+	db.Create(&GuardianInvitation{StudentID: student.ID, Email: "other.guardian@gmail.com", CodeHash: "hash", CreatedBy: guardian.User.ID, ExpiresAt: time.Now()})
+
+	session := Session{Password: "password", ClassID: class.ID}
+	db.Create(&session)
+	absence := Attendance{SessionID: session.ID, StudentID: student.ID, Status: enum.ABSENT}
+	db.Create(&absence)
+	justification := Justification{AttendanceID: absence.ID, Reason: "medical appointment", SubmittedBy: guardian.User.ID, Status: enum.JUSTIFICATION_PENDING}
+	db.Create(&justification)
+	NewAbsenceNotificationModel(db).Create([]AbsenceNotification{{StudentID: student.ID, SessionID: session.ID}})
+	rule := AlertRule{Kind: enum.ABSENCE_COUNT, Threshold: 1, Notify: true, Active: true}
+	db.Create(&rule)
+	alert := Alert{RuleID: rule.ID, ClassID: class.ID, StudentID: student.ID, Value: 1}
+	db.Create(&alert)
+
+	attendanceModel := NewAttendanceModel(db)
+	before, _ := attendanceModel.StatsByStudent(class.ID, time.Time{})
+
+	refs, err := NewStudentModel(db).Erase(student.ID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs["students"]) != 1 || len(refs["justifications"]) != 1 || len(refs["guardian_invitations"]) != 1 {
+		t.Errorf("Erased entities should be returned, got %v", refs)
+	}
+
+	erased, err := NewStudentModel(db).GetWithDeleted(student.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased.Email != "" || erased.FirstName == "first" || erased.LastName == "last" || erased.ErasedAt == nil {
+		t.Error("Student should be anonymized")
+	}
+	if len(erased.Guardians) != 0 {
+		t.Error("Guardians of the student should be removed")
+	}
+	var invitations int64
+	if db.Model(&GuardianInvitation{}).Where("student_id = ?", student.ID).Count(&invitations); invitations != 0 {
+		t.Error("Guardian invitations of the student should be deleted")
+	}
+	db.First(&justification, justification.ID)
+	if justification.Reason != "" {
+		t.Error("Reason of the justification should be erased")
+	}
+	if pending, _ := NewAbsenceNotificationModel(db).FindPendingStudents(time.Now().Add(time.Hour)); len(pending) != 0 {
+		t.Error("Pending absence notifications of the student should be deleted")
+	}
+	if unnotified, _ := NewAlertModel(db).FindUnnotified(); len(unnotified) != 0 {
+		t.Error("Alerts of the student should not be sent anymore")
+	}
+	db.First(&alert, alert.ID)
+	if alert.AcknowledgedAt == nil {
+		t.Error("Open alerts of the student should be closed")
+	}
+
+	after, _ := attendanceModel.StatsByStudent(class.ID, time.Time{})
+	if len(after) != 1 || len(before) != 1 || after[0] != before[0] {
+		t.Errorf("Attendance statistics should be kept, got %v instead of %v", after, before)
+	}
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type Term struct {
+	gorm.Model
+	// ID overrides the default ID field from gorm.Model to be uint64. Represents the id of the term
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// Name is the name of the term (e.g. 2022-2023)
+	Name string `json:"name" gorm:"uniqueIndex:unique_idx_term_name;not null;size:80"`
+	// StartDate is the first day of the term
+	StartDate time.Time `json:"start_date" gorm:"not null"`
+	// EndDate is the last day of the term
+	EndDate time.Time `json:"end_date" gorm:"not null"`
+	// Archived is true if the term is over and its classes are read-only
+	Archived bool `json:"archived" gorm:"not null;default:false"`
+	// Holidays is the list of holidays of the term
+	Holidays []Holiday `json:"holidays" gorm:"foreignKey:TermID"`
+	// Classes is the list of classes attached to the term
+	Classes []Class `json:"classes" gorm:"foreignKey:TermID"`
+}
+
+// TableName overrides the default table name generated by GORM to be `terms`
+func (Term) TableName() string {
+	return "terms"
+}
+
+type Holiday struct {
+	gorm.Model
+	// ID overrides the default ID field from gorm.Model to be uint64. Represents the id of the holiday
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// Name is the name of the holiday
+	Name string `json:"name" gorm:"not null;size:120"`
+	// StartDate is the first day of the holiday
+	StartDate time.Time `json:"start_date" gorm:"not null"`
+	// EndDate is the last day of the holiday
+	EndDate time.Time `json:"end_date" gorm:"not null"`
+	// TermID is the foreign key to the term table
+	TermID uint64 `json:"term_id" gorm:"not null"`
+	// Term is the term of the holiday
+	Term *Term `json:"term" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName overrides the default table name generated by GORM to be `holidays`
+func (Holiday) TableName() string {
+	return "holidays"
+}
+
+type TermModel struct {
+	Tx *gorm.DB
+}
+
+// NewTermModel creates a new term model
+func NewTermModel(tx *gorm.DB) *TermModel {
+	return &TermModel{Tx: tx}
+}
+
+// Create creates a new term with its holidays
+func (m *TermModel) Create(term *Term) error {
+	return m.Tx.Create(term).Error
+}
+
+// GetByID gets a term by ID with its holidays
+func (m *TermModel) GetByID(id uint64) (*Term, error) {
+	var term Term
+	err := m.Tx.Where("id = ?", id).Preload("Holidays").First(&term).Error
+	return &term, err
+}
+
+// FindAll gets all terms ordered by start date
+func (m *TermModel) FindAll() ([]Term, error) {
+	var terms []Term
+	err := m.Tx.Order("start_date desc").Find(&terms).Error
+	return terms, err
+}
+
+// Update updates a term
+func (m *TermModel) Update(term *Term) error {
+	return m.Tx.Model(term).Updates(term).Error
+}
+
+// Archive marks a term as archived
+func (m *TermModel) Archive(term *Term) *gorm.DB {
+	return m.Tx.Model(term).Where("id = ?", term.ID).Update("archived", true)
+}
+
+// AddHoliday adds a holiday to a term
+func (m *TermModel) AddHoliday(term *Term, holiday *Holiday) error {
+	return m.Tx.Model(term).Association("Holidays").Append(holiday)
+}
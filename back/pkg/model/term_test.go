@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTermModel_Archive tests that archiving a term makes its classes archived
+func TestTermModel_Archive(t *testing.T) {
+	classModel := SetupClassTestDatabase()
+	termModel := NewTermModel(classModel.Tx)
+
+	term := Term{
+		Name:      "2022-2023",
+		StartDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := termModel.Create(&term); err != nil {
+		t.Fatal(err)
+	}
+	class := Class{Name: "archived", Year: term.Name, TermID: &term.ID}
+	if err := classModel.Create(&class); err != nil {
+		t.Fatal(err)
+	}
+	unattached := Class{Name: "unattached", Year: "2022"}
+	if err := classModel.Create(&unattached); err != nil {
+		t.Fatal(err)
+	}
+
+	if archived, err := classModel.IsArchived(class.ID); err != nil || archived {
+		t.Error("Class of a current term should not be archived", err)
+	}
+
+	if res := termModel.Archive(&term); res.Error != nil || res.RowsAffected != 1 {
+		t.Fatal("Term is not archived", res.Error)
+	}
+
+	if archived, err := classModel.IsArchived(class.ID); err != nil || !archived {
+		t.Error("Class of an archived term should be archived", err)
+	}
+	if archived, err := classModel.IsArchived(unattached.ID); err != nil || archived {
+		t.Error("Class without term should not be archived", err)
+	}
+}
+
+// TestClassModel_MoveStudents tests that only the given students of the class are moved
+func TestClassModel_MoveStudents(t *testing.T) {
+	classModel := SetupClassTestDatabase()
+	db := classModel.Tx
+
+	from := Class{Name: "from", Year: "2022"}
+	to := Class{Name: "to", Year: "2023"}
+	other := Class{Name: "other", Year: "2022"}
+	for _, class := range []*Class{&from, &to, &other} {
+		if err := classModel.Create(class); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	promoted := Student{Email: "student.promoted@gmail.com", FirstName: "first", LastName: "last", ClassID: from.ID}
+	kept := Student{Email: "student.kept@gmail.com", FirstName: "first", LastName: "last", ClassID: from.ID}
+	outsider := Student{Email: "student.outsider@gmail.com", FirstName: "first", LastName: "last", ClassID: other.ID}
+	for _, student := range []*Student{&promoted, &kept, &outsider} {
+		db.Create(student)
+	}
+
+	res := classModel.MoveStudents(from.ID, to.ID, []uint64{promoted.ID, outsider.ID})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if res.RowsAffected != 1 {
+		t.Errorf("Only the student of the class should be moved, got %d", res.RowsAffected)
+	}
+
+	for _, check := range []struct {
+		student Student
+		classID uint64
+	}{{promoted, to.ID}, {kept, from.ID}, {outsider, other.ID}} {
+		var student Student
+		db.First(&student, check.student.ID)
+		if student.ClassID != check.classID {
+			t.Errorf("Student %s should be in class %d, got %d", check.student.Email, check.classID, student.ClassID)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type TimetableSlot struct {
+	gorm.Model
+	// ID overrides the default ID field from gorm.Model to be uint64. Represents the id of the slot
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// Weekday is the day of the week of the slot (0 is sunday)
+	Weekday time.Weekday `json:"weekday" gorm:"not null"`
+	// StartTime is the start time of the slot (HH:MM)
+	StartTime string `json:"start_time" gorm:"not null;size:5"`
+	// EndTime is the end time of the slot (HH:MM)
+	EndTime string `json:"end_time" gorm:"not null;size:5"`
+	// Room is the room where the slot takes place
+	Room string `json:"room" gorm:"size:80"`
+	// ClassID is the foreign key to the class table
+	ClassID uint64 `json:"class_id" gorm:"not null"`
+	// Class is the class of the slot
+	Class *Class `json:"class" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName overrides the default table name generated by GORM to be `timetable_slots`
+func (TimetableSlot) TableName() string {
+	return "timetable_slots"
+}
+
+type TimetableModel struct {
+	Tx *gorm.DB
+}
+
+// NewTimetableModel creates a new timetable model
+func NewTimetableModel(tx *gorm.DB) *TimetableModel {
+	return &TimetableModel{Tx: tx}
+}
+
+// FindAll gets all slots of a class
+func (m *TimetableModel) FindAll(classID uint64) ([]TimetableSlot, error) {
+	var slots []TimetableSlot
+	err := m.Tx.Where("class_id = ?", classID).Order("weekday, start_time").Find(&slots).Error
+	return slots, err
+}
+
+// Replace replaces all slots of a class by the given ones
+func (m *TimetableModel) Replace(classID uint64, slots []TimetableSlot) error {
+	if err := m.Tx.Where("class_id = ?", classID).Delete(&TimetableSlot{}).Error; err != nil {
+		return err
+	}
+
+	if len(slots) == 0 {
+		return nil
+	}
+
+	for i := range slots {
+		slots[i].ClassID = classID
+	}
+	return m.Tx.Create(&slots).Error
+}
+
+// Purge permanently deletes the slots soft deleted before the given date and returns their number
+func (m *TimetableModel) Purge(before time.Time) (int64, error) {
+	res := m.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&TimetableSlot{})
+	return res.RowsAffected, res.Error
+}
@@ -1,10 +1,34 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
+// Token is a refresh token issued to a user, the tokens rotated from the same login share a family
 type Token struct {
-	ID        int64          `json:"id" gorm:"primaryKey"`
-	TokenID   string         `json:"token_id" gorm:"uniqueIndex:unique_idx_token;not null"`
+	ID int64 `json:"id" gorm:"primaryKey"`
+	// TokenID is the id of the refresh token
+	TokenID string `json:"token_id" gorm:"uniqueIndex:unique_idx_token;not null"`
+	// CreatedAt is the date the refresh token was issued
+	CreatedAt time.Time `json:"created_at"`
+	// Family is the id shared by the tokens rotated from the same login
+	Family string `json:"family" gorm:"size:36;not null;default:'';index"`
+	// UserID is the id of the user the token is issued to
+	UserID uint64 `json:"user_id" gorm:"not null;default:0;index"`
+	// UserAgent is the user agent of the client the token is issued to
+	UserAgent string `json:"user_agent" gorm:"size:255"`
+	// IP is the address of the client the token is issued to
+	IP string `json:"ip" gorm:"size:45"`
+	// LoggedInAt is the date of the login which started the token family
+	LoggedInAt time.Time `json:"logged_in_at"`
+	// LastUsedAt is the date the token family was last refreshed
+	LastUsedAt time.Time `json:"last_used_at"`
+	// ExpiresAt is the expiration date of the refresh token
+	ExpiresAt time.Time `json:"expires_at"`
+	// RotatedAt is the date the refresh token was exchanged for a new one
+	RotatedAt *time.Time `json:"rotated_at"`
+	// DeletedAt is the date the token family was revoked
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
@@ -17,9 +41,43 @@ type TokenModel struct {
 	Tx *gorm.DB
 }
 
-// FindToken finds a token by id
-func (t *TokenModel) FindToken(model *Token) *gorm.DB {
-	return t.Tx.Where("deleted_at is NULL").First(model)
+// NewTokenModel creates a new token model
+func NewTokenModel(tx *gorm.DB) *TokenModel {
+	return &TokenModel{Tx: tx}
+}
+
+// FindByTokenID finds a refresh token by its id, including the revoked ones
+func (t *TokenModel) FindByTokenID(tokenID string) (*Token, error) {
+	var token Token
+	err := t.Tx.Unscoped().Where("token_id = ?", tokenID).First(&token).Error
+	return &token, err
+}
+
+// IsFamilyActive returns true if a token family has a refresh token neither revoked nor expired
+func (t *TokenModel) IsFamilyActive(family string, now time.Time) (bool, error) {
+	var count int64
+	err := t.Tx.Model(&Token{}).
+		Where("family = ? AND family <> '' AND expires_at > ?", family, now).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindActiveByUser finds the current refresh token of each active token family of a user,
+// most recently used first
+func (t *TokenModel) FindActiveByUser(userID uint64, now time.Time) ([]Token, error) {
+	var tokens []Token
+	err := t.Tx.Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at desc").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// GetActiveFamily gets the current refresh token of an active token family of a user
+func (t *TokenModel) GetActiveFamily(userID uint64, family string, now time.Time) (*Token, error) {
+	var token Token
+	err := t.Tx.Where("user_id = ? AND family = ? AND rotated_at IS NULL AND expires_at > ?", userID, family, now).
+		First(&token).Error
+	return &token, err
 }
 
 // CreateToken creates a new token in the database
@@ -27,7 +85,26 @@ func (t *TokenModel) CreateToken(model *Token) error {
 	return t.Tx.Create(model).Error
 }
 
-// DeleteToken deletes a token from the database
-func (t *TokenModel) DeleteToken(model Token) error {
-	return t.Tx.Where("token_id = ?", model.TokenID).Delete(&model).Error
+// Rotate marks a refresh token as exchanged, it returns false if it was already exchanged
+func (t *TokenModel) Rotate(token *Token, now time.Time) (bool, error) {
+	res := t.Tx.Model(&Token{}).
+		Where("id = ? AND rotated_at IS NULL", token.ID).
+		Update("rotated_at", now)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeFamily revokes all the tokens of a token family
+func (t *TokenModel) RevokeFamily(family string) error {
+	return t.Tx.Where("family = ?", family).Delete(&Token{}).Error
+}
+
+// RevokeUserFamilies revokes all the token families of a user but the kept one
+func (t *TokenModel) RevokeUserFamilies(userID uint64, keptFamily string) *gorm.DB {
+	return t.Tx.Where("user_id = ? AND family <> ?", userID, keptFamily).Delete(&Token{})
+}
+
+// Purge deletes the refresh tokens expired before the given date, revoked ones included
+func (t *TokenModel) Purge(before time.Time) (int64, error) {
+	res := t.Tx.Unscoped().Where("expires_at < ?", before).Delete(&Token{})
+	return res.RowsAffected, res.Error
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTokenModel_Purge tests that only the expired refresh tokens are purged, revoked ones included
+func TestTokenModel_Purge(t *testing.T) {
+	db := SetupTestDatabase()
+	tokenModel := NewTokenModel(db)
+
+	now := time.Now()
+	expired := Token{TokenID: "expired", Family: "purge", UserID: 1, ExpiresAt: now.Add(-time.Hour)}
+	revoked := Token{TokenID: "revoked", Family: "revoked", UserID: 1, ExpiresAt: now.Add(-time.Hour)}
+	active := Token{TokenID: "active", Family: "purge", UserID: 1, ExpiresAt: now.Add(time.Hour)}
+	for _, token := range []*Token{&expired, &revoked, &active} {
+		if err := tokenModel.CreateToken(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tokenModel.RevokeFamily("revoked"); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := tokenModel.Purge(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Errorf("2 tokens should be purged, got %d", purged)
+	}
+	if _, err := tokenModel.FindByTokenID("active"); err != nil {
+		t.Error("Active token should be kept")
+	}
+}
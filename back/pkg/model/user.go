@@ -5,6 +5,7 @@ import (
 	"gin-template/pkg/dto"
 	"gin-template/pkg/model/enum"
 	"gorm.io/gorm"
+	"time"
 )
 
 type User struct {
@@ -19,8 +20,10 @@ type User struct {
 	FirstName string `json:"first_name;not null;size:120"`
 	// LastName is the last name of the user
 	LastName string `json:"last_name;not null;size:120"`
-	// Role is the role of the user
-	Role enum.Role `json:"role" gorm:"type:role;default:student"`
+	// Role is the name of the role of the user
+	Role enum.Role `json:"role" gorm:"type:varchar(40);not null;default:student;index"`
+	// ErasedAt is the date the personal data of the user was erased, nil if it was not
+	ErasedAt *time.Time `json:"erased_at"`
 }
 
 // TableName returns the name of the table
@@ -57,6 +60,11 @@ func (u *UserModel) FindAll(models []User, params dto.UserQueryParams) *gorm.DB
 	).Find(&models)
 }
 
+// SetRole sets the role of a user
+func (u *UserModel) SetRole(userId uint64, role enum.Role) error {
+	return u.Tx.Model(&User{ID: userId}).Update("role", role).Error
+}
+
 // Update updates a user in the database
 // and returns an error if there is one
 func (u *UserModel) Update(model *User) error {
@@ -88,3 +96,124 @@ func (u *UserModel) RemoveUserFromSession(user *User, session *Session) error {
 func (u *UserModel) Create(model *User) error {
 	return u.Tx.Create(model).Error
 }
+
+// FindDeleted gets all soft deleted users with their account paginated
+func (u *UserModel) FindDeleted(models *[]User, page, pageSize int) *gorm.DB {
+	return u.Tx.Scopes(onlyDeleted, paginate(page, pageSize)).Preload("Account", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Order("deleted_at desc").Find(models)
+}
+
+// Restore restores a soft deleted user and its account, an erased user cannot be restored
+func (u *UserModel) Restore(model *User) *gorm.DB {
+	if tx := u.Tx.Scopes(onlyDeleted).Where("id = ? AND erased_at IS NULL", model.ID).First(model); tx.Error != nil {
+		return tx
+	}
+
+	tx := u.Tx.Unscoped().Model(&Account{}).Where("id = ?", model.AccountID).Update("deleted_at", nil)
+	if tx.Error != nil {
+		return tx
+	}
+	return u.Tx.Unscoped().Model(&User{}).Where("id = ?", model.ID).Update("deleted_at", nil)
+}
+
+// Purge permanently deletes the users and accounts soft deleted before the given date and returns the number of users
+func (u *UserModel) Purge(before time.Time) (int64, error) {
+	for _, query := range []string{
+		"DELETE FROM session_students WHERE user_id IN (SELECT id FROM users_t WHERE deleted_at < ?)",
+		"DELETE FROM class_teachers WHERE user_id IN (SELECT id FROM users_t WHERE deleted_at < ?)",
+		"DELETE FROM guardian_students WHERE user_id IN (SELECT id FROM users_t WHERE deleted_at < ?)",
+	} {
+		if err := u.Tx.Exec(query, before).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	res := u.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&User{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	return res.RowsAffected, u.Tx.Unscoped().Where("deleted_at < ?", before).Delete(&Account{}).Error
+}
+
+// GetWithDeleted gets a user, including a soft deleted one, with its account
+func (u *UserModel) GetWithDeleted(userId uint64) (*User, error) {
+	var user User
+	err := u.Tx.Unscoped().Preload("Account", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id = ?", userId).First(&user).Error
+	return &user, err
+}
+
+// Erase anonymizes a user and its account, deletes their credentials, logins and links to classes and students,
+// and soft deletes them, it returns the entities whose audit entries hold the erased data
+func (u *UserModel) Erase(user *User, now time.Time) (EntityRefs, error) {
+	refs := EntityRefs{}
+	refs.Add("users_t", user.ID)
+	refs.Add("accounts", user.AccountID)
+
+	var identityIDs []uint64
+	if err := u.Tx.Model(&Identity{}).Where("account_id = ?", user.AccountID).Pluck("id", &identityIDs).Error; err != nil {
+		return nil, err
+	}
+	refs.Add("identities", identityIDs...)
+
+	// The invitations sent to the email of the user hold its email and names
+	if user.Account != nil {
+		for table, invitation := range map[string]interface{}{
+			"staff_invitations":    &StaffInvitation{},
+			"guardian_invitations": &GuardianInvitation{},
+		} {
+			var ids []uint64
+			err := u.Tx.Model(invitation).Where("lower(email) = lower(?)", user.Account.Email).Pluck("id", &ids).Error
+			if err != nil {
+				return nil, err
+			}
+			refs.Add(table, ids...)
+			if err := u.Tx.Exec("DELETE FROM "+table+" WHERE lower(email) = lower(?)", user.Account.Email).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, query := range []string{
+		"DELETE FROM tokens WHERE user_id = ?",
+		"DELETE FROM api_keys WHERE user_id = ?",
+		"DELETE FROM class_teachers WHERE user_id = ?",
+		"DELETE FROM guardian_students WHERE user_id = ?",
+	} {
+		if err := u.Tx.Exec(query, user.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, query := range []string{
+		"DELETE FROM identities WHERE account_id = ?",
+		"DELETE FROM recovery_codes WHERE account_id = ?",
+		"DELETE FROM mfa WHERE account_id = ?",
+		"DELETE FROM password_resets WHERE account_id = ?",
+		"DELETE FROM password_history WHERE account_id = ?",
+		"DELETE FROM login_attempts WHERE account_id = ?",
+	} {
+		if err := u.Tx.Exec(query, user.AccountID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err := u.Tx.Unscoped().Model(&Account{}).Where("id = ?", user.AccountID).Updates(map[string]interface{}{
+		"email":       fmt.Sprintf("erased-%d@erased.invalid", user.AccountID),
+		"username":    fmt.Sprintf("erased-%d", user.AccountID),
+		"password":    "",
+		"verified_at": nil,
+		"deleted_at":  now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	err = u.Tx.Unscoped().Model(&User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"first_name": "Erased",
+		"last_name":  fmt.Sprintf("user %d", user.ID),
+		"erased_at":  now,
+		"deleted_at": now,
+	}).Error
+	return refs, err
+}
@@ -3,7 +3,9 @@ package model
 import (
 	"fmt"
 	"gin-template/pkg/dto"
+	"gin-template/pkg/model/enum"
 	"testing"
+	"time"
 )
 
 // TestUserModel_Find finds a user by id
@@ -115,3 +117,50 @@ func TestUserModel_Delete(t *testing.T) {
 		t.Error("User is not deleted")
 	}
 }
+
+// TestUserModel_Erase tests that an erased user is anonymized, deleted and cannot log in nor be restored
+func TestUserModel_Erase(t *testing.T) {
+	db := setupErasureTestDatabase()
+
+	account := Account{Email: "user.erase@gmail.com", Username: "username.erase", Password: "password", User: User{FirstName: "first", LastName: "last", Role: enum.GUARDIAN}}
+	db.Create(&account)
+	db.Create(&Identity{AccountID: account.ID, Issuer: "https://idp.example.com", Subject: "erase"})
+	db.Create(&LoginAttempt{AccountID: account.ID, Method: "password", IP: "1.2.3.4", Success: true})
+	db.Create(&Token{TokenID: "erase", UserID: account.User.ID})
+	db.Create(&StaffInvitation{Email: "USER.erase@gmail.com", Username: "erase", FirstName: "first", LastName: "last", Role: enum.ADMIN, TokenHash: "hash", CreatedBy: 1, ExpiresAt: time.Now()})
+
+	userModel := NewUserModel(db)
+	u, err := userModel.GetWithDeleted(account.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := userModel.Erase(u, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs["accounts"]) != 1 || len(refs["users_t"]) != 1 || len(refs["identities"]) != 1 || len(refs["staff_invitations"]) != 1 {
+		t.Errorf("Erased entities should be returned, got %v", refs)
+	}
+
+	erased, err := userModel.GetWithDeleted(account.User.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erased.FirstName == "first" || erased.ErasedAt == nil || !erased.DeletedAt.Valid {
+		t.Error("User should be anonymized and deleted")
+	}
+	if erased.Account.Email == account.Email || erased.Account.Username == account.Username || erased.Account.Password != "" {
+		t.Error("Account should be anonymized")
+	}
+
+	for _, table := range []interface{}{&Identity{}, &LoginAttempt{}, &Token{}, &StaffInvitation{}} {
+		var count int64
+		if db.Model(table).Count(&count); count != 0 {
+			t.Errorf("%T of the user should be deleted", table)
+		}
+	}
+
+	if err := userModel.Restore(&User{ID: account.User.ID}).Error; err == nil {
+		t.Error("Erased user should not be restored")
+	}
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model/enum"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+)
+
+// WebhookEvents is a list of webhook events stored as a comma separated string
+type WebhookEvents []enum.WebhookEvent
+
+// Scan implements the sql.Scanner interface
+func (e *WebhookEvents) Scan(value interface{}) error {
+	var data string
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		return errors.New(fmt.Sprint("failed to scan webhook events: ", value))
+	}
+
+	events := make(WebhookEvents, 0)
+	for _, event := range strings.Split(data, ",") {
+		if event != "" {
+			events = append(events, enum.WebhookEvent(event))
+		}
+	}
+	*e = events
+	return nil
+}
+
+// Value implements the driver.Valuer interface
+func (e WebhookEvents) Value() (driver.Value, error) {
+	events := make([]string, 0, len(e))
+	for _, event := range e {
+		events = append(events, event.String())
+	}
+	return strings.Join(events, ","), nil
+}
+
+// Contains returns true if the list contains the event
+func (e WebhookEvents) Contains(event enum.WebhookEvent) bool {
+	for _, ev := range e {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+type Webhook struct {
+	gorm.Model
+	// ID is the id of the webhook
+	ID uint64 `json:"id" gorm:"primarykey"`
+	// URL is the address the events are posted to
+	URL string `json:"url" gorm:"not null;size:2048"`
+	// Events is the list of events the webhook is subscribed to
+	Events WebhookEvents `json:"events" gorm:"type:text;not null"`
+	// Secret is the key used to sign the deliveries
+	Secret string `json:"-" gorm:"not null;size:255"`
+	// Active is false if the webhook must not receive new events
+	Active bool `json:"active" gorm:"not null;default:true"`
+}
+
+// TableName overrides the default table name generated by GORM to be `webhooks`
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+type WebhookDelivery struct {
+	// ID is the id of the delivery
+	ID uint64 `json:"id" gorm:"primaryKey"`
+	// CreatedAt is the date the event was emitted
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the date of the last attempt
+	UpdatedAt time.Time `json:"updated_at"`
+	// WebhookID is the foreign key to the webhook table
+	WebhookID uint64 `json:"webhook_id" gorm:"not null;index"`
+	// Webhook is the webhook the event is delivered to
+	Webhook *Webhook `json:"webhook" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// Event is the event delivered
+	Event enum.WebhookEvent `json:"event" gorm:"type:varchar(40);not null"`
+	// Payload is the JSON body posted to the webhook
+	Payload string `json:"payload" gorm:"type:text;not null"`
+	// Status is the delivery status
+	Status enum.DeliveryStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	// Attempts is the number of attempts made
+	Attempts int `json:"attempts" gorm:"not null;default:0"`
+	// NextAttemptAt is the date of the next attempt of a pending delivery
+	NextAttemptAt time.Time `json:"next_attempt_at" gorm:"not null;index"`
+	// ResponseCode is the HTTP status code of the last attempt
+	ResponseCode int `json:"response_code"`
+	// LastError is the error of the last failed attempt
+	LastError string `json:"last_error" gorm:"type:text"`
+	// DeliveredAt is the date of the successful attempt
+	DeliveredAt *time.Time `json:"delivered_at"`
+}
+
+// TableName overrides the default table name generated by GORM to be `webhook_deliveries`
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+type WebhookModel struct {
+	Tx *gorm.DB
+}
+
+// NewWebhookModel creates a new webhook model
+func NewWebhookModel(tx *gorm.DB) *WebhookModel {
+	return &WebhookModel{Tx: tx}
+}
+
+// Create creates a new webhook
+func (m *WebhookModel) Create(webhook *Webhook) error {
+	return m.Tx.Create(webhook).Error
+}
+
+// GetByID gets a webhook by ID
+func (m *WebhookModel) GetByID(id uint64) (*Webhook, error) {
+	var webhook Webhook
+	err := m.Tx.First(&webhook, id).Error
+	return &webhook, err
+}
+
+// FindAll gets all webhooks
+func (m *WebhookModel) FindAll() ([]Webhook, error) {
+	var webhooks []Webhook
+	err := m.Tx.Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// FindActiveByEvent gets the active webhooks subscribed to an event
+func (m *WebhookModel) FindActiveByEvent(event enum.WebhookEvent) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := m.Tx.Where("active = ?", true).Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	subscribed := make([]Webhook, 0)
+	for _, w := range webhooks {
+		if w.Events.Contains(event) {
+			subscribed = append(subscribed, w)
+		}
+	}
+	return subscribed, nil
+}
+
+// Update updates a webhook
+func (m *WebhookModel) Update(webhook *Webhook) *gorm.DB {
+	return m.Tx.Model(webhook).Select("url", "events", "secret", "active").Updates(webhook)
+}
+
+// Delete deletes a webhook
+func (m *WebhookModel) Delete(id uint64) *gorm.DB {
+	return m.Tx.Delete(&Webhook{}, id)
+}
+
+type WebhookDeliveryModel struct {
+	Tx *gorm.DB
+}
+
+// NewWebhookDeliveryModel creates a new webhook delivery model
+func NewWebhookDeliveryModel(tx *gorm.DB) *WebhookDeliveryModel {
+	return &WebhookDeliveryModel{Tx: tx}
+}
+
+// Create creates deliveries
+func (m *WebhookDeliveryModel) Create(deliveries []WebhookDelivery) error {
+	return m.Tx.Omit(clause.Associations).Create(&deliveries).Error
+}
+
+// FindAll gets the deliveries of a webhook paginated, most recent first
+func (m *WebhookDeliveryModel) FindAll(deliveries *[]WebhookDelivery, webhookID uint64, params dto.WebhookDeliveryQueryParams) *gorm.DB {
+	tx := m.Tx.Scopes(paginate(params.Page, params.PageSize)).Where("webhook_id = ?", webhookID)
+	if params.Status != "" {
+		tx = tx.Where("status = ?", params.Status)
+	}
+	return tx.Order("created_at desc, id desc").Find(deliveries)
+}
+
+// FindDue locks and gets the pending deliveries whose next attempt is due, with their webhook
+func (m *WebhookDeliveryModel) FindDue(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := m.Tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", enum.DELIVERY_PENDING, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Preload("Webhook").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Claim postpones the next attempt of deliveries until the given date,
+// so that the other replicas leave them alone while they are sent out of the transaction locking them
+func (m *WebhookDeliveryModel) Claim(ids []uint64, until time.Time) error {
+	return m.Tx.Model(&WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", until).Error
+}
+
+// Save saves the result of a delivery attempt
+func (m *WebhookDeliveryModel) Save(delivery *WebhookDelivery) error {
+	return m.Tx.Omit(clause.Associations).Save(delivery).Error
+}
+
+// Replay sets a delivery of a webhook back to pending to be attempted again
+func (m *WebhookDeliveryModel) Replay(webhookID, id uint64) *gorm.DB {
+	return m.Tx.Model(&WebhookDelivery{}).Where("id = ? AND webhook_id = ?", id, webhookID).Updates(map[string]interface{}{
+		"status":          enum.DELIVERY_PENDING,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+		"last_error":      "",
+	})
+}
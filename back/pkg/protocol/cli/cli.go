@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"gin-template/config"
+	"gin-template/database"
+	"gin-template/pkg/common/auth"
+	"gin-template/pkg/common/class"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/model"
+	"gin-template/pkg/model/enum"
+	"gin-template/pkg/protocol/http"
+	"gin-template/utils/audit"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Command is a subcommand of the server binary
+type Command struct {
+	// Usage describes the arguments of the command
+	Usage string
+	// Run runs the command with its arguments
+	Run func(env Env, args []string) error
+}
+
+// Env is what the commands share
+type Env struct {
+	Conf   config.Config
+	Stdin  io.Reader
+	Stdout io.Writer
+}
+
+// Commands are the subcommands of the server binary, the server is started without subcommand
+var Commands = map[string]Command{
+	"serve":             {Usage: "start the HTTP server", Run: serve},
+	"routes":            {Usage: "print the routes with their authorization policy", Run: routes},
+	"migrate":           {Usage: "[status|up|down [-steps N]], show, apply or revert the migrations of the schema, up also creates the builtin roles", Run: migrate},
+	"create-superadmin": {Usage: "-email EMAIL -username USERNAME -first-name NAME -last-name NAME, the password is read from SUPERADMIN_PASSWORD or the standard input", Run: createSuperadmin},
+	"import-roster":     {Usage: "-class ID [-file FILE], import the students of a class from a CSV with the columns email,first_name,last_name", Run: importRoster},
+	"export":            {Usage: "-class ID [-since YYYY-MM-DD] [-file FILE], export the attendance of a class as CSV", Run: export},
+	"rotate-keys":       {Usage: "publish a new key signing the tokens and retire the current one", Run: rotateKeys},
+	"purge-tokens":      {Usage: "[-older-than DAYS], delete the refresh tokens expired for the given number of days", Run: purgeTokens},
+}
+
+// Run runs the subcommand named by the first argument, the server is started when there is none
+func Run(env Env, args []string) error {
+	if len(args) == 0 {
+		return serve(env, nil)
+	}
+	if args[0] == "help" {
+		fmt.Fprint(env.Stdout, usage())
+		return nil
+	}
+
+	cmd, ok := Commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command '%s'\n%s", args[0], usage())
+	}
+	return cmd.Run(env, args[1:])
+}
+
+// usage lists the commands with their usage
+func usage() string {
+	names := make([]string, 0, len(Commands))
+	for name := range Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("commands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-18s %s\n", name, Commands[name].Usage)
+	}
+	return b.String()
+}
+
+// openDatabase opens the database with the changes of the commands recorded in the audit log
+func openDatabase(conf config.Config) (*gorm.DB, error) {
+	db := database.NewDatabase(conf.Db)
+	if err := audit.RegisterCallbacks(db, conf.Tenant); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openOutput returns the file to write to, the standard output when the path is empty
+func openOutput(env Env, path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{env.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// openInput returns the file to read from, the standard input when the path is empty
+func openInput(env Env, path string) (io.ReadCloser, error) {
+	if path == "" {
+		return io.NopCloser(env.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func serve(env Env, _ []string) error {
+	return http.RunServer(env.Conf)
+}
+
+func routes(env Env, _ []string) error {
+	return http.PrintRoutes(env.Conf, env.Stdout)
+}
+
+func migrate(env Env, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	db, err := database.Open(env.Conf.Db)
+	if err != nil {
+		return err
+	}
+	m, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "status":
+		states, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range states {
+			status := "pending"
+			if s.AppliedAt != nil {
+				status = "applied at " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(env.Stdout, "%-40s %s\n", s.Migration, status)
+		}
+		return nil
+	case "up":
+		applied, err := database.MigrateUp(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range applied {
+			fmt.Fprintf(env.Stdout, "applied %s\n", migration)
+		}
+		fmt.Fprintf(env.Stdout, "database is migrated to version %d\n", m.Latest())
+		return nil
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		reverted, err := m.Down(*steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			fmt.Fprintf(env.Stdout, "reverted %s\n", migration)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate action '%s', expected status, up or down", action)
+}
+
+func createSuperadmin(env Env, args []string) error {
+	fs := flag.NewFlagSet("create-superadmin", flag.ContinueOnError)
+	req := dto.CreateUser{Role: enum.SUPERADMIN}
+	fs.StringVar(&req.Email, "email", "", "email of the superadmin")
+	fs.StringVar(&req.Username, "username", "", "username of the superadmin")
+	fs.StringVar(&req.FirstName, "first-name", "", "first name of the superadmin")
+	fs.StringVar(&req.LastName, "last-name", "", "last name of the superadmin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if req.Email == "" || req.Username == "" || req.FirstName == "" || req.LastName == "" {
+		return errors.New("-email, -username, -first-name and -last-name are required")
+	}
+
+	password := os.Getenv("SUPERADMIN_PASSWORD")
+	if password == "" {
+		line, err := bufio.NewReader(env.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return errors.New("the password must be given in SUPERADMIN_PASSWORD or on the standard input")
+	}
+
+	db, err := openDatabase(env.Conf)
+	if err != nil {
+		return err
+	}
+	var a *model.Account
+	err = db.Transaction(func(tx *gorm.DB) error {
+		a, err = auth.CreateStaffAccount(tx, env.Conf.Password, req, password)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "superadmin %s created with the user id %d\n", a.Username, a.User.ID)
+	return nil
+}
+
+func importRoster(env Env, args []string) error {
+	fs := flag.NewFlagSet("import-roster", flag.ContinueOnError)
+	classID := fs.Uint64("class", 0, "id of the class")
+	path := fs.String("file", "", "CSV file, the standard input when omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *classID == 0 {
+		return errors.New("-class is required")
+	}
+
+	in, err := openInput(env, *path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	records, err := csv.NewReader(in).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "email") {
+		records = records[1:]
+	}
+
+	db, err := openDatabase(env.Conf)
+	if err != nil {
+		return err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i, record := range records {
+			if len(record) < 3 {
+				return fmt.Errorf("line %d: email, first name and last name are required", i+1)
+			}
+			_, err := class.AddStudentToClass(tx, dto.AddStudentToClass{
+				ClassId: *classID,
+				Student: dto.Student{
+					Email:     strings.TrimSpace(record[0]),
+					FirstName: strings.TrimSpace(record[1]),
+					LastName:  strings.TrimSpace(record[2]),
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "%d students imported\n", len(records))
+	return nil
+}
+
+func export(env Env, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	classID := fs.Uint64("class", 0, "id of the class")
+	sinceFlag := fs.String("since", "", "date of the first session, every session when omitted")
+	path := fs.String("file", "", "CSV file, the standard output when omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *classID == 0 {
+		return errors.New("-class is required")
+	}
+	var since time.Time
+	if *sinceFlag != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", *sinceFlag); err != nil {
+			return fmt.Errorf("-since: %w", err)
+		}
+	}
+
+	db := database.NewDatabase(env.Conf.Db)
+	attendances, err := model.NewAttendanceModel(db).FindByClass(*classID, since)
+	if err != nil {
+		return err
+	}
+
+	out, err := openOutput(env, *path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := csv.NewWriter(out)
+	w.Write([]string{"session_id", "session_date", "email", "first_name", "last_name", "status", "manual", "justified"})
+	for _, a := range attendances {
+		w.Write([]string{
+			a.SessionID.String(),
+			a.Session.CreatedAt.Format(time.RFC3339),
+			a.Student.Email,
+			a.Student.FirstName,
+			a.Student.LastName,
+			string(a.Status),
+			fmt.Sprint(a.Manual),
+			fmt.Sprint(a.Justified),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func rotateKeys(env Env, _ []string) error {
+	db := database.NewDatabase(env.Conf.Db)
+	var activeAt time.Time
+	err := db.Transaction(func(tx *gorm.DB) (err error) {
+		activeAt, err = auth.ForceKeyRotation(tx, env.Conf.Jwt, time.Now())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "a new key signs the tokens from %s\n", activeAt.Format(time.RFC3339))
+	return nil
+}
+
+func purgeTokens(env Env, args []string) error {
+	fs := flag.NewFlagSet("purge-tokens", flag.ContinueOnError)
+	days := fs.Int("older-than", 0, "number of days the tokens have been expired")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db := database.NewDatabase(env.Conf.Db)
+	purged, err := model.NewTokenModel(db).Purge(time.Now().AddDate(0, 0, -*days))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "%d refresh tokens purged\n", purged)
+	return nil
+}
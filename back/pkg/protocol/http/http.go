@@ -1,39 +1,138 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"gin-template/config"
 	"gin-template/database"
+	"gin-template/logging"
+	"gin-template/pkg/common/auth"
+	"gin-template/pkg/common/notification"
+	"gin-template/pkg/common/trash"
+	"gin-template/pkg/common/webhook"
+	"gin-template/pkg/job"
 	"gin-template/pkg/middleware"
+	"gin-template/pkg/model"
 	v1 "gin-template/pkg/service/v1"
+	"gin-template/utils/audit"
+	"gin-template/utils/jwt"
+	"gin-template/utils/mail"
+	"gin-template/utils/ratelimit"
+	webhookSender "gin-template/utils/webhook"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
+	"io"
+	"text/tabwriter"
+	"time"
 )
 
 func RunServer(conf config.Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
 	db := database.NewDatabase(conf.Db)
+	if err := audit.RegisterCallbacks(db, conf.Tenant); err != nil {
+		return err
+	}
+	keys, err := auth.LoadKeys(db, conf.Jwt)
+	if err != nil {
+		return err
+	}
+
+	// Start the background jobs.
+	if conf.Jwt.Algorithm == config.RS256 || conf.Jwt.Algorithm == config.EdDSA {
+		job.Job{
+			Name:     "jwt-key-rotation",
+			Interval: auth.KeyRefreshInterval,
+			Run: func(tx *gorm.DB) error {
+				return auth.RotateKeys(tx, conf.Jwt, keys, time.Now())
+			},
+		}.Start(context.Background(), db)
+	}
+	if conf.Trash.Retention > 0 && conf.Trash.PurgeInterval > 0 {
+		job.Job{
+			Name:     "trash-purge",
+			Interval: time.Duration(conf.Trash.PurgeInterval) * time.Hour,
+			Run: func(tx *gorm.DB) error {
+				return trash.Purge(tx, time.Now().AddDate(0, 0, -conf.Trash.Retention))
+			},
+		}.Start(context.Background(), db)
+	}
+	if conf.Webhook.Interval > 0 {
+		sender := webhookSender.NewSender(time.Duration(conf.Webhook.Timeout) * time.Second)
+		job.Job{
+			Name:     "webhook-delivery",
+			Interval: time.Duration(conf.Webhook.Interval) * time.Second,
+			Run: func(db *gorm.DB) error {
+				return webhook.ProcessDeliveries(db, sender, conf.Webhook)
+			},
+			OwnTransactions: true,
+		}.Start(context.Background(), db)
+	}
+	if conf.Login.HistoryRetention > 0 {
+		job.Job{
+			Name:     "login-history-purge",
+			Interval: 24 * time.Hour,
+			Run: func(tx *gorm.DB) error {
+				return model.NewLoginAttemptModel(tx).Purge(time.Now().AddDate(0, 0, -conf.Login.HistoryRetention))
+			},
+		}.Start(context.Background(), db)
+	}
+	if conf.Mail.DigestHour >= 0 {
+		sender := mail.NewSender(conf.Mail)
+		job.Job{
+			Name:     "absence-digests",
+			Interval: 10 * time.Minute,
+			Run: func(db *gorm.DB) error {
+				return notification.SendDigests(db, sender, conf.Mail, time.Now())
+			},
+			OwnTransactions: true,
+		}.Start(context.Background(), db)
+		job.Job{
+			Name:     "alert-notifications",
+			Interval: 10 * time.Minute,
+			Run: func(db *gorm.DB) error {
+				return notification.SendAlerts(db, sender, conf.Mail, time.Now())
+			},
+			OwnTransactions: true,
+		}.Start(context.Background(), db)
+	}
 
 	r := gin.New()
 	dbM := middleware.DatabaseMiddleware{DB: db}
-	r.Use(gin.LoggerWithFormatter(middleware.CustomLogger), gin.Recovery(), middleware.CORSMiddleware(), dbM.SetDBMiddleware())
+	r.Use(
+		middleware.RequestIDMiddleware(),
+		gin.LoggerWithFormatter(middleware.CustomLogger),
+		gin.Recovery(),
+		middleware.CORSMiddleware(),
+		dbM.SetDBMiddleware(),
+	)
 
 	if conf.Env == config.Production {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Setup the routes.
-	rg := r.Group("/api/v1")
-	// Setup the routes for the auth service.
-	v1.SetAuthService(rg.Group("/auth"), conf.Jwt)
-	// Setup the routes for the user service.
-	v1.SetUserRoutes(rg.Group("/users"), conf.Jwt)
-	// Setup the routes for the class service.
-	v1.SetClassRoutes(rg.Group("/classes"), conf.Jwt)
-	// Setup the routes for the session service.
-	v1.SetSessionRoutes(rg.Group("/sessions"), conf.Jwt)
-	// Setup the routes for the websocket service.
-	v1.SetWebsocketRoutes(rg.Group("/ws"))
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if conf.RateLimit.Store == "postgres" {
+		rateLimitModel := model.NewRateLimitModel(db)
+		store = rateLimitModel
+		job.Job{
+			Name:     "rate-limit-purge",
+			Interval: time.Hour,
+			Run: func(tx *gorm.DB) error {
+				return model.NewRateLimitModel(tx).Purge(time.Now().Add(-24 * time.Hour))
+			},
+		}.Start(context.Background(), db)
+	}
+
+	table, err := SetRoutes(r, db, conf, keys, store)
+	if err != nil {
+		return err
+	}
+	logging.Info.Printf("%d routes registered", len(table.Routes()))
 
 	if conf.Env == config.Development {
 		r.GET("/doc/*any", ginSwagger.WrapHandler(
@@ -43,3 +142,73 @@ func RunServer(conf config.Config) error {
 	}
 	return r.Run(fmt.Sprintf("%s:%d", conf.Server.Host, conf.Server.Port))
 }
+
+// SetRoutes registers the routes of the API with their authorization policy and their rate limits
+// held by the store, it fails if a route is served without policy.
+// The use of the API keys is recorded in the database, which is nil when the routes are not served.
+// The keys sign and verify the tokens, their public part is served out of the API.
+func SetRoutes(r *gin.Engine, db *gorm.DB, conf config.Config, keys *jwt.KeySet, store ratelimit.Store) (*middleware.PolicyTable, error) {
+	r.GET(v1.JWKSPath, v1.JWKS(keys))
+
+	rg := middleware.NewRouter(
+		r.Group("/api/v1"),
+		middleware.NewJwtMiddleware(conf, db, keys),
+		middleware.NewRateLimiter(store, conf.RateLimit),
+	)
+	// Setup the routes for the auth service.
+	v1.SetAuthService(rg.Group("/auth"), conf, keys, mail.NewSender(conf.Mail))
+	// Setup the routes for the single sign-on.
+	v1.SetOIDCRoutes(rg.Group("/auth/oidc"), conf, keys)
+	// Setup the routes for the user service.
+	v1.SetUserRoutes(rg.Group("/users"), conf, keys, mail.NewSender(conf.Mail))
+	// Setup the routes for the class service.
+	v1.SetClassRoutes(rg.Group("/classes"))
+	// Setup the routes for the term service.
+	v1.SetTermRoutes(rg.Group("/terms"))
+	// Setup the routes for the session service.
+	v1.SetSessionRoutes(rg.Group("/sessions"))
+	// Setup the routes for the trash service.
+	v1.SetTrashRoutes(rg.Group("/trash"))
+	// Setup the routes for the audit service.
+	v1.SetAuditRoutes(rg.Group("/audit-logs"))
+	// Setup the routes for the webhook service.
+	v1.SetWebhookRoutes(rg.Group("/webhooks"))
+	// Setup the routes for the alert rule service.
+	v1.SetAlertRuleRoutes(rg.Group("/alert-rules"))
+	// Setup the routes for the guardian service.
+	v1.SetGuardianRoutes(rg.Group("/guardian"))
+	// Setup the routes for the staff invitation service.
+	v1.SetStaffInvitationRoutes(rg.Group("/invitations"), conf, mail.NewSender(conf.Mail))
+	// Setup the routes for the service account service.
+	v1.SetServiceAccountRoutes(rg.Group("/service-accounts"))
+	// Setup the routes for the privacy service.
+	v1.SetPrivacyRoutes(rg.Group("/privacy"))
+	// Setup the routes for the role service.
+	v1.SetRoleRoutes(rg.Group("/roles"))
+	// Setup the routes for the route service.
+	v1.SetRouteRoutes(rg.Group("/routes"))
+	// Setup the routes for the websocket service.
+	v1.SetWebsocketRoutes(rg.Group("/ws"))
+
+	table := rg.Table()
+	if err := table.Check(r.Routes()); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// PrintRoutes writes the table of the routes with their authorization policy
+func PrintRoutes(conf config.Config, w io.Writer) error {
+	gin.SetMode(gin.ReleaseMode)
+	table, err := SetRoutes(gin.New(), nil, conf, jwt.NewKeySet(), ratelimit.NewMemoryStore())
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tPOLICY")
+	for _, route := range table.Routes() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", route.Method, route.Path, route.Policy)
+	}
+	return tw.Flush()
+}
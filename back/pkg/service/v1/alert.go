@@ -0,0 +1,197 @@
+package v1
+
+import (
+	"gin-template/pkg/common/alert"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AlertRuleList returns the alert rules
+// @Summary Get the alert rules
+// @Description Get the alert rules, only the institution rules and those of the class if a class is given
+// @Tags alert
+// @Produce json
+// @Param params query dto.AlertRuleQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.AlertRuleList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /alert-rules [get]
+func AlertRuleList(c *gin.Context) {
+	var req dto.AlertRuleQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	rules, err := alert.GetAlertRules(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, rules)
+}
+
+// CreateAlertRule creates an alert rule
+// @Summary Create an alert rule
+// @Description Create an absence threshold rule for a class or, without class, for the whole institution
+// @Tags alert
+// @Accept json
+// @Produce json
+// @Param rule body dto.CreateAlertRule true "Rule"
+// @Security Bearer
+// @Success 201 {object} dto.AlertRule
+// @Failure 400,404,500 {object} error.MyError
+// @Router /alert-rules [post]
+func CreateAlertRule(c *gin.Context) {
+	var req dto.CreateAlertRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	r, err := alert.CreateAlertRule(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, r)
+}
+
+// UpdateAlertRule updates an alert rule
+// @Summary Update an alert rule
+// @Description Update the threshold, window, notification or state of an alert rule
+// @Tags alert
+// @Accept json
+// @Produce json
+// @Param rule_id path int true "Rule ID"
+// @Param rule body dto.UpdateAlertRule true "Rule"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /alert-rules/{rule_id} [put]
+func UpdateAlertRule(c *gin.Context) {
+	var req dto.UpdateAlertRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := alert.UpdateAlertRule(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Alert rule updated"})
+}
+
+// DeleteAlertRule deletes an alert rule
+// @Summary Delete an alert rule
+// @Description Delete an alert rule
+// @Tags alert
+// @Produce json
+// @Param rule_id path int true "Rule ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /alert-rules/{rule_id} [delete]
+func DeleteAlertRule(c *gin.Context) {
+	var req struct {
+		RuleID uint64 `uri:"rule_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := alert.DeleteAlertRule(c.MustGet("DB").(*gorm.DB), req.RuleID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// ClassAlertList returns the alerts of a class
+// @Summary Get the alerts of a class
+// @Description Get the alerts raised for the students of a class, most recent first
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param params query dto.AlertQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.AlertList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/alerts [get]
+func ClassAlertList(c *gin.Context) {
+	var uri struct {
+		ClassID uint64 `uri:"class_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	var req dto.AlertQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	alerts, err := alert.GetClassAlerts(c.MustGet("DB").(*gorm.DB), uri.ClassID, req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, alerts)
+}
+
+// AcknowledgeClassAlert acknowledges an alert of a class
+// @Summary Acknowledge an alert
+// @Description Mark an open alert of a class as handled, the rule can raise a new one for the student afterwards
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param alert_id path int true "Alert ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/alerts/{alert_id}/acknowledge [put]
+func AcknowledgeClassAlert(c *gin.Context) {
+	var req struct {
+		ClassID uint64 `uri:"class_id" binding:"required"`
+		AlertID uint64 `uri:"alert_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := alert.AcknowledgeAlert(c.MustGet("DB").(*gorm.DB), req.ClassID, req.AlertID, claims.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Alert acknowledged"})
+}
+
+// SetAlertRuleRoutes sets up the alert rule routes
+func SetAlertRuleRoutes(r *middleware.Router) {
+	r.Require(enum.ALERT_READ).GET("", AlertRuleList)
+	r.Require(enum.ALERT_WRITE).POST("", CreateAlertRule)
+	r.Require(enum.ALERT_WRITE).PUT("/:rule_id", UpdateAlertRule)
+	r.Require(enum.ALERT_WRITE).DELETE("/:rule_id", DeleteAlertRule)
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"gin-template/pkg/common/audit"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuditLogList returns the audit log
+// @Summary Get the audit log
+// @Description Get the audit entries of every mutating action, most recent first, filtered by the query parameters
+// @Tags audit
+// @Produce json
+// @Param params query dto.AuditLogQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.AuditLogList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /audit-logs [get]
+func AuditLogList(c *gin.Context) {
+	var req dto.AuditLogQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	entries, err := audit.GetAuditLogs(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, entries)
+}
+
+// SetAuditRoutes sets up the audit log routes
+func SetAuditRoutes(r *middleware.Router) {
+	r = r.Require(enum.AUDIT_READ)
+	r.GET("", AuditLogList)
+}
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"errors"
 	"gin-template/config"
 	"gin-template/pkg/common/auth"
 	"gin-template/pkg/dto"
@@ -9,23 +10,63 @@ import (
 	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
 	jwt2 "gin-template/utils/jwt"
+	"gin-template/utils/mail"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"strconv"
+	"strings"
 )
 
 type AuthService struct {
-	jwt config.JwtConfig
+	conf config.Config
+	// keys signs and verifies the tokens
+	keys *jwt2.KeySet
+	// sender sends the password reset and verification emails
+	sender mail.Sender
+	// authenticator checks the credentials of the logins
+	authenticator auth.Authenticator
+}
+
+// clientOf returns the client of the request recorded with its session
+func clientOf(c *gin.Context) dto.Client {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	return dto.Client{UserAgent: userAgent, IP: c.ClientIP()}
+}
+
+// fillLoginError fills the HTTP context with the error of a login, a recorded failure is committed
+// to count towards the lockout and a locked account tells when to retry
+func fillLoginError(c *gin.Context, err error) {
+	var loginErr *auth.LoginError
+	if !errors.As(err, &loginErr) {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	if loginErr.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(loginErr.RetryAfter.Seconds())))
+	}
+	if loginErr.Recorded {
+		c.JSON(loginErr.Code, loginErr.MyError)
+		return
+	}
+	loginErr.FillHTTPContextError(c)
 }
 
 // Login login a user
 // @Summary Login
-// @Description Logs in a user
+// @Description Logs in a user with the password of the directory when configured, or else of the local account.
+// @Description When the second factor of the user is enabled, the response holds
+// @Description a token waiting for the second factor instead of the tokens, to exchange at /auth/mfa/verify.
+// @Description The account is locked for a growing delay after too many consecutive wrong passwords.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param login body dto.Login true "Login request"
-// @Success 202 {object} dto.AuthResponse
-// @Failure 400,404,500 {object} error.MyError
+// @Success 202 {object} dto.LoginResponse
+// @Failure 400,404,429,500 {object} error.MyError
 // @Router /auth/login [post]
 func (a *AuthService) Login(c *gin.Context) {
 	var req dto.Login
@@ -35,9 +76,9 @@ func (a *AuthService) Login(c *gin.Context) {
 	}
 
 	db := c.MustGet("DB").(*gorm.DB)
-	token, err := auth.Login(db, req, a.jwt)
+	token, err := auth.Login(db, req, clientOf(c), a.authenticator, a.conf, a.keys)
 	if err != nil {
-		error2.FromError(err).FillHTTPContextError(c)
+		fillLoginError(c, err)
 		return
 	}
 
@@ -56,7 +97,7 @@ func (*AuthService) Logout(c *gin.Context) {
 	claims := c.MustGet("claims").(*jwt2.Claims)
 
 	db := c.MustGet("DB").(*gorm.DB)
-	err := auth.Logout(db, claims.ID)
+	err := auth.Logout(db, claims.Family)
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
@@ -66,13 +107,13 @@ func (*AuthService) Logout(c *gin.Context) {
 
 // Register register a user
 // @Summary Register
-// @Description Registers a user
+// @Description Registers a user, the password must follow the password policy
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param register body dto.Register true "Register request"
 // @Success 201 {object} dto.AuthResponse
-// @Failure 400,404,500 {object} error.MyError
+// @Failure 400,404,429,500 {object} error.MyError
 // @Router /auth/register [post]
 func (a *AuthService) Register(c *gin.Context) {
 	var req dto.Register
@@ -82,7 +123,7 @@ func (a *AuthService) Register(c *gin.Context) {
 	}
 
 	db := c.MustGet("DB").(*gorm.DB)
-	token, err := auth.Register(db, req, a.jwt)
+	token, err := auth.Register(db, req, clientOf(c), a.conf, a.keys, a.sender)
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
@@ -93,7 +134,8 @@ func (a *AuthService) Register(c *gin.Context) {
 
 // ChangePassword change a user's password
 // @Summary Change password
-// @Description Changes a user's password
+// @Description Changes a user's password, the new password must follow the password policy
+// @Description and differ from the previous passwords of the user.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -102,7 +144,7 @@ func (a *AuthService) Register(c *gin.Context) {
 // @Failure 400,404,500 {object} error.MyError
 // @Security Bearer
 // @Router /auth/change-password [put]
-func (*AuthService) ChangePassword(c *gin.Context) {
+func (a *AuthService) ChangePassword(c *gin.Context) {
 	claims := c.MustGet("claims").(*jwt2.Claims)
 	var req dto.ChangePassword
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -112,7 +154,7 @@ func (*AuthService) ChangePassword(c *gin.Context) {
 
 	req.UserId = claims.UserId
 	db := c.MustGet("DB").(*gorm.DB)
-	err := auth.ChangePassword(db, req)
+	err := auth.ChangePassword(db, a.conf.Password, req)
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
@@ -126,19 +168,38 @@ func (*AuthService) ChangePassword(c *gin.Context) {
 
 // RefreshToken refresh a user's token
 // @Summary Refresh token
-// @Description Refreshes a user's token
+// @Description Exchanges a refresh token, given in the body or as bearer token, for a new pair of tokens.
+// @Description A refresh token can be exchanged once, replaying it revokes the session.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Param refresh_token body dto.RefreshToken false "Refresh token"
 // @Success 202 {object} dto.AuthResponse
-// @Failure 400,404,500 {object} error.MyError
-// @Security Bearer
+// @Failure 400,401,404,500 {object} error.MyError
 // @Router /auth/refresh-token [post]
 func (a *AuthService) RefreshToken(c *gin.Context) {
-	db := c.MustGet("DB").(*gorm.DB)
-	refreshToken := c.MustGet("token").(string)
+	var req dto.RefreshToken
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, error2.FromBindError(err))
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	if req.RefreshToken == "" {
+		error2.UnauthorizedError("refresh token is required").FillHTTPContextError(c)
+		return
+	}
 
-	token, err := auth.RefreshToken(db, a.jwt, refreshToken)
+	db := c.MustGet("DB").(*gorm.DB)
+	token, err := auth.RefreshToken(db, a.conf.Jwt, a.keys, req.RefreshToken, clientOf(c))
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		// The request is not aborted so that the revocation of the session is committed
+		c.JSON(auth.ErrRefreshTokenReused.Code, auth.ErrRefreshTokenReused)
+		return
+	}
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
@@ -149,46 +210,342 @@ func (a *AuthService) RefreshToken(c *gin.Context) {
 
 // GetAccount get a user's account
 // @Summary Get account
-// @Description Gets a user's account information from the token
+// @Description Gets a user's account information from the token, with the history of its latest logins
 // @Tags auth
 // @Produce json
 // @Success 200 {object} dto.User
 // @Failure 400,404,500 {object} error.MyError
 // @Security Bearer
 // @Router /auth/account [get]
-func (*AuthService) GetAccount(c *gin.Context) {
+func (a *AuthService) GetAccount(c *gin.Context) {
 	claims := c.MustGet("claims").(*jwt2.Claims)
 
 	am := model.AccountModel{Tx: c.MustGet("DB").(*gorm.DB)}
-	a, err := auth.GetAccount(am.Tx, claims.UserId)
+	account, err := auth.GetAccount(am.Tx, claims.UserId, a.conf.Login.HistorySize)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// ForgotPassword sends a password reset link
+// @Summary Forgot password
+// @Description Sends a link to reset the password to the email of the account, valid for one hour.
+// @Description The response is the same whether the email is registered or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param forgot_password body dto.ForgotPassword true "Forgot password request"
+// @Success 202
+// @Failure 400,429,500 {object} error.MyError
+// @Router /auth/forgot-password [post]
+func (a *AuthService) ForgotPassword(c *gin.Context) {
+	var req dto.ForgotPassword
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.ForgotPassword(c.MustGet("DB").(*gorm.DB), a.sender, a.conf.Mail, req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword resets a password
+// @Summary Reset password
+// @Description Sets a new password following the password policy with the token of a reset link,
+// @Description the token can be used once and all the sessions of the user are logged out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset_password body dto.ResetPassword true "Reset password request"
+// @Success 202
+// @Failure 400,429,500 {object} error.MyError
+// @Router /auth/reset-password [post]
+func (a *AuthService) ResetPassword(c *gin.Context) {
+	var req dto.ResetPassword
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.ResetPassword(c.MustGet("DB").(*gorm.DB), a.conf.Password, req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Password reset"})
+}
+
+// VerifyEmail verifies an email
+// @Summary Verify email
+// @Description Verifies the email of an account with the token of the link sent by email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verify_email body dto.VerifyEmail true "Verify email request"
+// @Success 202
+// @Failure 400,429,500 {object} error.MyError
+// @Router /auth/verify-email [post]
+func (a *AuthService) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmail
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.VerifyEmail(c.MustGet("DB").(*gorm.DB), a.keys, req.Token); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification sends a new verification link
+// @Summary Resend the verification email
+// @Description Sends a new email verification link to the user
+// @Tags auth
+// @Produce json
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/verify-email/resend [post]
+func (a *AuthService) ResendVerification(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt2.Claims)
+
+	if err := auth.ResendVerification(c.MustGet("DB").(*gorm.DB), a.sender, a.conf, a.keys, claims.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Verification email sent"})
+}
+
+// LoginSessionList returns the user's active sessions
+// @Summary Get the active sessions
+// @Description Gets the sessions the user is logged in, the session of the request is flagged as current
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.LoginSessionList
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/sessions [get]
+func (*AuthService) LoginSessionList(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt2.Claims)
+
+	sessions, err := auth.GetLoginSessions(c.MustGet("DB").(*gorm.DB), claims.UserId, claims.Family)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, sessions)
+}
+
+// RevokeLoginSession revokes one of the user's sessions
+// @Summary Revoke a session
+// @Description Logs out one of the sessions of the user
+// @Tags auth
+// @Param session_id path string true "Session ID"
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/sessions/{session_id} [delete]
+func (*AuthService) RevokeLoginSession(c *gin.Context) {
+	var req struct {
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := auth.RevokeLoginSession(c.MustGet("DB").(*gorm.DB), claims.UserId, req.SessionID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// RevokeOtherLoginSessions revokes the user's other sessions
+// @Summary Log out everywhere else
+// @Description Logs out all the sessions of the user but the session of the request
+// @Tags auth
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/sessions [delete]
+func (*AuthService) RevokeOtherLoginSessions(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := auth.RevokeLoginSessions(c.MustGet("DB").(*gorm.DB), claims.UserId, claims.Family); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// VerifyMFA confirms a login with the second factor
+// @Summary Verify the second factor
+// @Description Exchanges the token of a login waiting for the second factor and a code of the authenticator app,
+// @Description or a recovery code, for a pair of tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verify_mfa body dto.VerifyMFA true "Verify MFA request"
+// @Success 202 {object} dto.AuthResponse
+// @Failure 400,401,404,429,500 {object} error.MyError
+// @Router /auth/mfa/verify [post]
+func (a *AuthService) VerifyMFA(c *gin.Context) {
+	var req dto.VerifyMFA
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	token, err := auth.VerifyMFA(c.MustGet("DB").(*gorm.DB), a.conf, a.keys, req, clientOf(c))
+	if err != nil {
+		fillLoginError(c, err)
+		return
+	}
+
+	c.JSON(202, token)
+}
+
+// EnrollMFA starts the enrollment of a second factor
+// @Summary Enroll a second factor
+// @Description Generates the secret of a second factor to add to an authenticator app,
+// @Description it is enabled once a first code is confirmed at /auth/mfa/confirm
+// @Tags auth
+// @Produce json
+// @Success 201 {object} dto.MFAEnrollment
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/mfa/enroll [post]
+func (a *AuthService) EnrollMFA(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt2.Claims)
+
+	enrollment, err := auth.EnrollMFA(c.MustGet("DB").(*gorm.DB), a.conf.MFA, claims.UserId)
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
 	}
 
-	c.JSON(200, a)
+	c.JSON(201, enrollment)
+}
+
+// ConfirmMFA enables the enrolled second factor
+// @Summary Confirm the second factor
+// @Description Enables the enrolled second factor with a first code of the authenticator app
+// @Description and returns the recovery codes, the next logins require the second factor
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param mfa_code body dto.MFACode true "Code of the authenticator app"
+// @Success 202 {object} dto.RecoveryCodes
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/mfa/confirm [post]
+func (a *AuthService) ConfirmMFA(c *gin.Context) {
+	var req dto.MFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	codes, err := auth.ConfirmMFA(c.MustGet("DB").(*gorm.DB), a.conf.MFA, claims.UserId, req.Code)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, codes)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes
+// @Summary Regenerate the recovery codes
+// @Description Replaces the recovery codes of the user, the previous ones can no longer be used
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param mfa_code body dto.MFACode true "Code of the authenticator app or recovery code"
+// @Success 202 {object} dto.RecoveryCodes
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/mfa/recovery-codes [post]
+func (a *AuthService) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	codes, err := auth.RegenerateRecoveryCodes(c.MustGet("DB").(*gorm.DB), a.conf.MFA, claims.UserId, req.Code)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, codes)
+}
+
+// DisableMFA disables the second factor
+// @Summary Disable the second factor
+// @Description Removes the second factor of the user, refused when the role of the user requires it
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param mfa_code body dto.MFACode true "Code of the authenticator app or recovery code"
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /auth/mfa/disable [post]
+func (a *AuthService) DisableMFA(c *gin.Context) {
+	var req dto.MFACode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := auth.DisableMFA(c.MustGet("DB").(*gorm.DB), a.conf.MFA, claims.UserId, req.Code); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Two-factor authentication disabled"})
 }
 
 // SetAuthService Add auth service to gin engine
-func SetAuthService(r *gin.RouterGroup, jwt config.JwtConfig) {
-	as := AuthService{jwt: jwt}
-	// Set middleware
-	mddl := middleware.NewJwtMiddleware(jwt)
-
-	// Set routes
-	// Routes without privileges required
-	r.POST("/login", as.Login)
-	r.POST("/register", as.Register)
-	// Add privileges on routes
-	r.Use(mddl.MiddlewareFunc(map[string][]enum.Role{
-		"Logout":         {enum.STUDENT},
-		"ChangePassword": {enum.STUDENT},
-		"RefreshToken":   {enum.STUDENT},
-		"GetAccount":     {enum.STUDENT},
-	}))
-	// Routes with privileges required
-	r.DELETE("/logout", as.Logout)
-	r.PUT("/change-password", as.ChangePassword)
-	r.POST("/refresh-token", as.RefreshToken)
-	r.GET("/account", as.GetAccount)
+func SetAuthService(r *middleware.Router, conf config.Config, keys *jwt2.KeySet, sender mail.Sender) {
+	as := AuthService{conf: conf, keys: keys, sender: sender, authenticator: auth.NewChain(conf)}
+
+	r.Public().Limit("login").POST("/login", as.Login)
+	r.Public().Limit("register").POST("/register", as.Register)
+	r.Public().POST("/refresh-token", as.RefreshToken)
+	r.Public().Limit("register").POST("/forgot-password", as.ForgotPassword)
+	r.Public().Limit("register").POST("/reset-password", as.ResetPassword)
+	r.Public().Limit("register").POST("/verify-email", as.VerifyEmail)
+	r.Public().Limit("login").POST("/mfa/verify", as.VerifyMFA)
+	r.Require(enum.ACCOUNT_SELF).DELETE("/logout", as.Logout)
+	r.Require(enum.ACCOUNT_SELF).PUT("/change-password", as.ChangePassword)
+	r.Require(enum.ACCOUNT_SELF).GET("/account", as.GetAccount)
+	r.Require(enum.ACCOUNT_SELF).POST("/verify-email/resend", as.ResendVerification)
+	r.Require(enum.ACCOUNT_SELF).GET("/sessions", as.LoginSessionList)
+	r.Require(enum.ACCOUNT_SELF).DELETE("/sessions", as.RevokeOtherLoginSessions)
+	r.Require(enum.ACCOUNT_SELF).DELETE("/sessions/:session_id", as.RevokeLoginSession)
+	r.Require(enum.ACCOUNT_SELF).POST("/mfa/enroll", as.EnrollMFA)
+	r.Require(enum.ACCOUNT_SELF).POST("/mfa/confirm", as.ConfirmMFA)
+	r.Require(enum.ACCOUNT_SELF).POST("/mfa/recovery-codes", as.RegenerateRecoveryCodes)
+	r.Require(enum.ACCOUNT_SELF).POST("/mfa/disable", as.DisableMFA)
 }
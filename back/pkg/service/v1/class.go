@@ -1,13 +1,15 @@
 package v1
 
 import (
-	"gin-template/config"
 	"gin-template/pkg/common/class"
+	"gin-template/pkg/common/guardian"
+	"gin-template/pkg/common/justification"
 	"gin-template/pkg/common/session"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/middleware"
 	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
@@ -129,7 +131,7 @@ func UpdateClass(c *gin.Context) {
 // @Router /classes/{class_id} [delete]
 func DeleteClass(c *gin.Context) {
 	var req struct {
-		ClassID uint64 `uri:"class_id" binding"required"`
+		ClassID uint64 `uri:"class_id" binding:"required"`
 	}
 	if err := c.ShouldBindUri(&req); err != nil {
 		c.JSON(400, error2.FromBindError(err))
@@ -265,6 +267,144 @@ func DeleteClassSession(c *gin.Context) {
 	c.JSON(204, nil)
 }
 
+// SetClassNotifications enables or disables the absence notifications of a class
+// @Summary Enable or disable the absence notifications
+// @Description Enable or disable the emails sent to the absent students of a class
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param notifications body dto.SetClassNotifications true "Notifications"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/notifications [put]
+func SetClassNotifications(c *gin.Context) {
+	var req dto.SetClassNotifications
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := class.SetClassNotifications(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Notifications updated"})
+}
+
+// SessionAttendanceList returns the attendance of a class session
+// @Summary Get the attendance of a class session
+// @Description Get the attendance of the students of a class session
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param session_id path string true "Session ID"
+// @Security Bearer
+// @Success 200 {object} dto.AttendanceList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/sessions/{session_id}/students [get]
+func SessionAttendanceList(c *gin.Context) {
+	var req struct {
+		ClassID   uint64 `uri:"class_id" binding:"required"`
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	a, err := session.GetSessionAttendance(
+		c.MustGet("DB").(*gorm.DB),
+		req.ClassID,
+		uuid.Must(uuid.FromString(req.SessionID)),
+	)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, a)
+}
+
+// AddStudentToSession marks a student as present to a class session
+// @Summary Mark a student as present
+// @Description Manually mark a student of the class as present to a class session
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param session_id path string true "Session ID"
+// @Param student_id path int true "Student ID"
+// @Security Bearer
+// @Success 201
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/sessions/{session_id}/students/{student_id} [post]
+func AddStudentToSession(c *gin.Context) {
+	var req struct {
+		ClassID   uint64 `uri:"class_id" binding:"required"`
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+		StudentID uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := session.AddStudentToSession(
+		c.MustGet("DB").(*gorm.DB),
+		uuid.Must(uuid.FromString(req.SessionID)),
+		req.StudentID,
+		req.ClassID,
+	); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Student marked as present"})
+}
+
+// RemoveStudentFromSession removes the attendance of a student to a class session
+// @Summary Remove the attendance of a student
+// @Description Manually remove the attendance of a student to a class session
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param session_id path string true "Session ID"
+// @Param student_id path int true "Student ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/sessions/{session_id}/students/{student_id} [delete]
+func RemoveStudentFromSession(c *gin.Context) {
+	var req struct {
+		ClassID   uint64 `uri:"class_id" binding:"required"`
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+		StudentID uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := session.RemoveStudentFromSession(
+		c.MustGet("DB").(*gorm.DB),
+		uuid.Must(uuid.FromString(req.SessionID)),
+		req.StudentID,
+		req.ClassID,
+	); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
 // AddStudentToClass adds a student to a class
 // @Summary Add a student to a class
 // @Description Add a student to a class
@@ -297,29 +437,318 @@ func AddStudentToClass(c *gin.Context) {
 	c.JSON(201, st)
 }
 
+// AddTeacherToClass adds a teacher to a class
+// @Summary Add a teacher to a class
+// @Description Add a staff member as teacher of a class
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param teacher body dto.ClassTeacher true "Teacher"
+// @Security Bearer
+// @Success 201
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/teachers [post]
+func AddTeacherToClass(c *gin.Context) {
+	var req dto.ClassTeacher
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := class.AddTeacherToClass(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Teacher added"})
+}
+
+// RemoveTeacherFromClass removes a teacher from a class
+// @Summary Remove a teacher from a class
+// @Description Remove a teacher from a class
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param user_id path int true "User ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/teachers/{user_id} [delete]
+func RemoveTeacherFromClass(c *gin.Context) {
+	var req dto.ClassTeacher
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := class.RemoveTeacherFromClass(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// SetClassTimetable sets the timetable of a class
+// @Summary Set the timetable of a class
+// @Description Replace the weekly timetable of a class
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param timetable body dto.SetClassTimetable true "Timetable"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/timetable [put]
+func SetClassTimetable(c *gin.Context) {
+	var req dto.SetClassTimetable
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := class.SetClassTimetable(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Timetable updated"})
+}
+
+// RolloverClass clones a class into another term
+// @Summary Rollover a class
+// @Description Clone a class structure (name, teachers, timetable) into the next term and promote the selected students
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param rollover body dto.RolloverClass true "Rollover"
+// @Security Bearer
+// @Success 201 {object} dto.TinyClass
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/rollover [post]
+func RolloverClass(c *gin.Context) {
+	var req dto.RolloverClass
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	cl, err := class.RolloverClass(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, cl)
+}
+
+// AddGuardianToStudent adds a guardian to a student
+// @Summary Add a guardian to a student
+// @Description Make a guardian user guardian of a student of the class
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param student_id path int true "Student ID"
+// @Param guardian body dto.StudentGuardian true "Guardian"
+// @Security Bearer
+// @Success 201
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/students/{student_id}/guardians [post]
+func AddGuardianToStudent(c *gin.Context) {
+	var req dto.StudentGuardian
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := guardian.AddGuardianToStudent(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, gin.H{"message": "Guardian added"})
+}
+
+// RemoveGuardianFromStudent removes a guardian from a student
+// @Summary Remove a guardian from a student
+// @Description Remove a user from the guardians of a student of the class
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param student_id path int true "Student ID"
+// @Param user_id path int true "User ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/students/{student_id}/guardians/{user_id} [delete]
+func RemoveGuardianFromStudent(c *gin.Context) {
+	var req dto.StudentGuardian
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := guardian.RemoveGuardianFromStudent(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// CreateGuardianInvitation invites a guardian for a student
+// @Summary Invite a guardian
+// @Description Create an invitation code to become guardian of a student of the class, valid for 7 days
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param student_id path int true "Student ID"
+// @Param invitation body dto.CreateGuardianInvitation true "Invitation"
+// @Security Bearer
+// @Success 201 {object} dto.GuardianInvitation
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/students/{student_id}/guardian-invitations [post]
+func CreateGuardianInvitation(c *gin.Context) {
+	var req dto.CreateGuardianInvitation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	invitation, err := guardian.CreateGuardianInvitation(c.MustGet("DB").(*gorm.DB), req, claims.UserId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, invitation)
+}
+
+// ClassJustificationList returns the justifications of a class
+// @Summary Get the justifications of a class
+// @Description Get the justifications of the absences to the sessions of a class, most recent first
+// @Tags class
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param params query dto.JustificationQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.JustificationList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /classes/{class_id}/justifications [get]
+func ClassJustificationList(c *gin.Context) {
+	var uri struct {
+		ClassID uint64 `uri:"class_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	var req dto.JustificationQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	justifications, err := justification.GetClassJustifications(c.MustGet("DB").(*gorm.DB), uri.ClassID, req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, justifications)
+}
+
+// ReviewJustification reviews a justification
+// @Summary Review a justification
+// @Description Accept or reject a pending justification, an accepted one justifies the absence
+// @Tags class
+// @Accept json
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Param justification_id path int true "Justification ID"
+// @Param review body dto.ReviewJustification true "Review"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /classes/{class_id}/justifications/{justification_id} [put]
+func ReviewJustification(c *gin.Context) {
+	var req dto.ReviewJustification
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := justification.ReviewJustification(c.MustGet("DB").(*gorm.DB), req, claims.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Justification reviewed"})
+}
+
 // SetClassRoutes sets up the class routes
-func SetClassRoutes(r *gin.RouterGroup, jwtConfig config.JwtConfig) {
-	mdl := middleware.NewJwtMiddleware(jwtConfig)
-	r.Use(mdl.MiddlewareFunc(map[string][]enum.Role{
-		"GetClass":           {enum.ADMIN},
-		"ClassList":          {enum.ADMIN},
-		"CreateClass":        {enum.ADMIN},
-		"UpdateClass":        {enum.ADMIN},
-		"DeleteClass":        {enum.ADMIN},
-		"ClassSessionList":   {enum.ADMIN},
-		"CreateClassSession": {enum.ADMIN},
-		"CloseClassSession":  {enum.ADMIN},
-		"DeleteClassSession": {enum.ADMIN},
-		"AddStudentToClass":  {enum.ADMIN},
-	}))
-	r.GET("", ClassList)
-	r.GET("/:class_id", GetClass)
-	r.POST("", CreateClass)
-	r.PUT("/:class_id", UpdateClass)
-	r.DELETE("/:class_id", DeleteClass)
-	r.GET("/:class_id/sessions", ClassSessionList)
-	r.POST("/:class_id/sessions", CreateClassSession)
-	r.PUT("/:class_id/sessions/:session_id", CloseClassSession)
-	r.DELETE("/:class_id/sessions", DeleteClassSession)
-	r.POST("/:class_id/students", AddStudentToClass)
+func SetClassRoutes(r *middleware.Router) {
+	r.Require(enum.CLASS_READ).GET("", ClassList)
+	r.Require(enum.CLASS_READ).GET("/:class_id", GetClass)
+	r.Require(enum.CLASS_WRITE).POST("", CreateClass)
+	r.Require(enum.CLASS_WRITE).PUT("/:class_id", UpdateClass)
+	r.Require(enum.CLASS_WRITE).DELETE("/:class_id", DeleteClass)
+	r.Require(enum.SESSION_READ).GET("/:class_id/sessions", ClassSessionList)
+	r.Require(enum.SESSION_WRITE).POST("/:class_id/sessions", CreateClassSession)
+	r.Require(enum.SESSION_CLOSE).PUT("/:class_id/sessions/:session_id", CloseClassSession)
+	r.Require(enum.SESSION_WRITE).DELETE("/:class_id/sessions", DeleteClassSession)
+	r.Require(enum.CLASS_WRITE).PUT("/:class_id/notifications", SetClassNotifications)
+	r.Require(enum.ALERT_READ).GET("/:class_id/alerts", ClassAlertList)
+	r.Require(enum.ALERT_WRITE).PUT("/:class_id/alerts/:alert_id/acknowledge", AcknowledgeClassAlert)
+	r.Require(enum.ATTENDANCE_READ).GET("/:class_id/sessions/:session_id/students", SessionAttendanceList)
+	r.Require(enum.ATTENDANCE_OVERRIDE).POST("/:class_id/sessions/:session_id/students/:student_id", AddStudentToSession)
+	r.Require(enum.ATTENDANCE_OVERRIDE).DELETE("/:class_id/sessions/:session_id/students/:student_id", RemoveStudentFromSession)
+	r.Require(enum.STUDENT_WRITE).POST("/:class_id/students", AddStudentToClass)
+	r.Require(enum.STUDENT_WRITE).POST("/:class_id/students/:student_id/guardians", AddGuardianToStudent)
+	r.Require(enum.STUDENT_WRITE).DELETE("/:class_id/students/:student_id/guardians/:user_id", RemoveGuardianFromStudent)
+	r.Require(enum.STUDENT_WRITE).POST("/:class_id/students/:student_id/guardian-invitations", CreateGuardianInvitation)
+	r.Require(enum.ATTENDANCE_READ).GET("/:class_id/justifications", ClassJustificationList)
+	r.Require(enum.JUSTIFICATION_REVIEW).PUT("/:class_id/justifications/:justification_id", ReviewJustification)
+	r.Require(enum.CLASS_WRITE).POST("/:class_id/teachers", AddTeacherToClass)
+	r.Require(enum.CLASS_WRITE).DELETE("/:class_id/teachers/:user_id", RemoveTeacherFromClass)
+	r.Require(enum.CLASS_WRITE).PUT("/:class_id/timetable", SetClassTimetable)
+	r.Require(enum.CLASS_WRITE).POST("/:class_id/rollover", RolloverClass)
 }
@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"gin-template/pkg/common/guardian"
+	"gin-template/pkg/common/justification"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChildList returns the children of the guardian
+// @Summary Get my children
+// @Description Get the students the authenticated user is guardian of
+// @Tags guardian
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.ChildList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /guardian/children [get]
+func ChildList(c *gin.Context) {
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	children, err := guardian.GetChildren(c.MustGet("DB").(*gorm.DB), claims.UserId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, children)
+}
+
+// ChildAttendanceList returns the attendance of a child
+// @Summary Get the attendance of a child
+// @Description Get the attendance of a student the authenticated user is guardian of, most recent first
+// @Tags guardian
+// @Produce json
+// @Param student_id path int true "Student ID"
+// @Security Bearer
+// @Success 200 {object} dto.ChildAttendanceList
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /guardian/children/{student_id}/attendances [get]
+func ChildAttendanceList(c *gin.Context) {
+	var req struct {
+		StudentID uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	attendances, err := guardian.GetChildAttendance(c.MustGet("DB").(*gorm.DB), claims.UserId, req.StudentID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, attendances)
+}
+
+// ChildJustificationList returns the justifications of a child
+// @Summary Get the justifications of a child
+// @Description Get the justifications of the absences of a student the authenticated user is guardian of
+// @Tags guardian
+// @Produce json
+// @Param student_id path int true "Student ID"
+// @Security Bearer
+// @Success 200 {object} dto.JustificationList
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /guardian/children/{student_id}/justifications [get]
+func ChildJustificationList(c *gin.Context) {
+	var req struct {
+		StudentID uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	db := c.MustGet("DB").(*gorm.DB)
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := guardian.EnsureGuardianOf(db, claims.UserId, req.StudentID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	justifications, err := justification.GetStudentJustifications(db, req.StudentID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, justifications)
+}
+
+// CreateChildJustification submits a justification for an absence of a child
+// @Summary Justify an absence of a child
+// @Description Submit a justification for an absence of a student the authenticated user is guardian of
+// @Tags guardian
+// @Accept json
+// @Produce json
+// @Param student_id path int true "Student ID"
+// @Param justification body dto.CreateJustification true "Justification"
+// @Security Bearer
+// @Success 201 {object} dto.Justification
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /guardian/children/{student_id}/justifications [post]
+func CreateChildJustification(c *gin.Context) {
+	var req dto.CreateJustification
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	db := c.MustGet("DB").(*gorm.DB)
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := guardian.EnsureGuardianOf(db, claims.UserId, req.StudentId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	j, err := justification.SubmitJustification(db, req, claims.UserId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, j)
+}
+
+// AcceptGuardianInvitation accepts a guardian invitation
+// @Summary Accept a guardian invitation
+// @Description Become guardian of the invited student, a student account becomes a guardian account and must sign in again
+// @Tags guardian
+// @Accept json
+// @Produce json
+// @Param invitation body dto.AcceptGuardianInvitation true "Invitation"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /guardian/invitations/accept [post]
+func AcceptGuardianInvitation(c *gin.Context) {
+	var req dto.AcceptGuardianInvitation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if err := guardian.AcceptGuardianInvitation(c.MustGet("DB").(*gorm.DB), req, claims.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Invitation accepted"})
+}
+
+// SetGuardianRoutes sets up the guardian routes
+func SetGuardianRoutes(r *middleware.Router) {
+	r.Require(enum.GUARDIAN_ACCESS).GET("/children", ChildList)
+	r.Require(enum.GUARDIAN_ACCESS).GET("/children/:student_id/attendances", ChildAttendanceList)
+	r.Require(enum.GUARDIAN_ACCESS).GET("/children/:student_id/justifications", ChildJustificationList)
+	r.Require(enum.GUARDIAN_ACCESS).POST("/children/:student_id/justifications", CreateChildJustification)
+	r.Require(enum.ACCOUNT_SELF).POST("/invitations/accept", AcceptGuardianInvitation)
+}
@@ -0,0 +1,19 @@
+package v1
+
+import (
+	jwt2 "gin-template/utils/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSPath is the path of the public keys verifying the tokens, served at the root of the server
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWKS returns the handler of the public keys verifying the tokens, in the JSON Web Key Set format.
+// The other services verify the tokens with them, a key is published before it signs any token
+// and until the tokens it signed have expired. The set is empty when the tokens are signed with HS256.
+func JWKS(keys *jwt2.KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(200, keys.JWKS())
+	}
+}
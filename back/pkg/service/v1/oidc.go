@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"gin-template/config"
+	"gin-template/pkg/common/auth"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"gin-template/utils/oidc"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+type OIDCService struct {
+	conf config.Config
+	// keys signs and verifies the flow tokens and the tokens of the logins
+	keys *jwt2.KeySet
+	// provider is the OpenID Connect provider of the single sign-on
+	provider *oidc.Provider
+}
+
+// Authorize starts a single sign-on
+// @Summary Start a single sign-on
+// @Description Returns the URL of the identity provider the user is redirected to, along with a flow token
+// @Description the client keeps and sends back with the code the provider returns to the redirect URL.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} dto.OIDCAuthorization
+// @Failure 404,500,503 {object} error.MyError
+// @Router /auth/oidc/authorize [get]
+func (o *OIDCService) Authorize(c *gin.Context) {
+	authorization, err := auth.OIDCAuthorize(c.Request.Context(), o.provider, o.conf, o.keys)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, authorization)
+}
+
+// Callback completes a single sign-on
+// @Summary Complete a single sign-on
+// @Description Exchanges the code returned by the identity provider for the tokens of the matching account.
+// @Description An unknown identity is linked to the account having its email, or provisioned when enabled.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param callback body dto.OIDCCallback true "Callback request"
+// @Success 202 {object} dto.LoginResponse
+// @Failure 400,401,403,404,429,500 {object} error.MyError
+// @Router /auth/oidc/callback [post]
+func (o *OIDCService) Callback(c *gin.Context) {
+	var req dto.OIDCCallback
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	db := c.MustGet("DB").(*gorm.DB)
+	token, err := auth.OIDCCallback(c.Request.Context(), db, o.provider, o.conf, o.keys, req, clientOf(c))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, token)
+}
+
+// SetOIDCRoutes sets the routes of the OpenID Connect single sign-on
+func SetOIDCRoutes(r *middleware.Router, conf config.Config, keys *jwt2.KeySet) {
+	o := OIDCService{
+		conf:     conf,
+		keys:     keys,
+		provider: oidc.NewProvider(conf.OIDC, &http.Client{Timeout: 10 * time.Second}),
+	}
+
+	r.Public().GET("/authorize", o.Authorize)
+	r.Public().Limit("login").POST("/callback", o.Callback)
+}
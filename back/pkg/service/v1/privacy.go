@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"gin-template/pkg/common/privacy"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sendPersonalData sends an export as JSON or as a ZIP archive to download
+func sendPersonalData(c *gin.Context, data *dto.PersonalData, format, name string) {
+	if format != "zip" {
+		c.JSON(200, data)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := privacy.WriteZip(&buf, data); err != nil {
+		error2.InternalServerError("", err).FillHTTPContextError(c)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", name))
+	c.Data(200, "application/zip", buf.Bytes())
+}
+
+// ExportUserData exports the personal data of a user
+// @Summary Export the personal data of a user
+// @Description Returns everything stored about a user, including a deleted one: its account, logins, the roster entries
+// @Description having its email with their attendance and justifications, and the audit entries about it or its actions.
+// @Description Users can export their own data.
+// @Tags privacy
+// @Produce json,application/zip
+// @Param user_id path int true "User ID"
+// @Param params query dto.PersonalDataQueryParams false "..."
+// @Success 200 {object} dto.PersonalData
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /privacy/users/{user_id}/export [get]
+func ExportUserData(c *gin.Context) {
+	var req struct {
+		UserId uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+	var params dto.PersonalDataQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	if req.UserId != claims.UserId && !middleware.HasPermission(c, enum.PRIVACY_MANAGE) {
+		error2.ForbiddenError("You can only export your own data").FillHTTPContextError(c)
+		return
+	}
+
+	data, err := privacy.ExportUser(c.MustGet("DB").(*gorm.DB), req.UserId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	sendPersonalData(c, data, params.Format, fmt.Sprintf("user-%d", req.UserId))
+}
+
+// EraseUserData erases the personal data of a user
+// @Summary Erase the personal data of a user
+// @Description Anonymizes a user and the roster entries having its email, deletes its credentials and logins and deletes its account.
+// @Description The attendance is kept so that the statistics do not change, the audit entries are kept without the values they recorded.
+// @Tags privacy
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Success 200 {object} dto.Erasure
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /privacy/users/{user_id}/erase [post]
+func EraseUserData(c *gin.Context) {
+	var req struct {
+		UserId uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	erasure, err := privacy.EraseUser(c.MustGet("DB").(*gorm.DB), req.UserId, claims.UserId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, erasure)
+}
+
+// ExportStudentData exports the personal data of a student
+// @Summary Export the personal data of a student
+// @Description Returns everything stored about a roster entry, including a deleted one: the student, its guardians,
+// @Description attendance and justifications, and the audit entries about them.
+// @Tags privacy
+// @Produce json,application/zip
+// @Param student_id path int true "Student ID"
+// @Param params query dto.PersonalDataQueryParams false "..."
+// @Success 200 {object} dto.PersonalData
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /privacy/students/{student_id}/export [get]
+func ExportStudentData(c *gin.Context) {
+	var req struct {
+		StudentId uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+	var params dto.PersonalDataQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	data, err := privacy.ExportStudent(c.MustGet("DB").(*gorm.DB), req.StudentId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	sendPersonalData(c, data, params.Format, fmt.Sprintf("student-%d", req.StudentId))
+}
+
+// EraseStudentData erases the personal data of a student
+// @Summary Erase the personal data of a student
+// @Description Anonymizes a roster entry, removes its guardians, the reasons of its justifications and its pending absence notifications, and closes its alerts.
+// @Description The attendance is kept so that the statistics of the class do not change, the audit entries are kept without the values they recorded.
+// @Tags privacy
+// @Produce json
+// @Param student_id path int true "Student ID"
+// @Success 200 {object} dto.Erasure
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /privacy/students/{student_id}/erase [post]
+func EraseStudentData(c *gin.Context) {
+	var req struct {
+		StudentId uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	erasure, err := privacy.EraseStudent(c.MustGet("DB").(*gorm.DB), req.StudentId)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, erasure)
+}
+
+// SetPrivacyRoutes sets the routes of the export and the erasure of personal data
+func SetPrivacyRoutes(r *middleware.Router) {
+	r.Require(enum.ACCOUNT_SELF).GET("/users/:user_id/export", ExportUserData)
+	r.Require(enum.PRIVACY_MANAGE).POST("/users/:user_id/erase", EraseUserData)
+	r.Require(enum.PRIVACY_MANAGE).GET("/students/:student_id/export", ExportStudentData)
+	r.Require(enum.PRIVACY_MANAGE).POST("/students/:student_id/erase", EraseStudentData)
+}
@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"gin-template/pkg/common/role"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RoleList returns the roles
+// @Summary Get the roles
+// @Description Get the roles with the permissions they grant
+// @Tags role
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.RoleList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /roles [get]
+func RoleList(c *gin.Context) {
+	roles, err := role.GetRoles(c.MustGet("DB").(*gorm.DB))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, roles)
+}
+
+// PermissionList returns the permissions
+// @Summary Get the permissions
+// @Description Get the permissions a role can be granted, '*' grants every permission
+// @Tags role
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.PermissionList
+// @Router /roles/permissions [get]
+func PermissionList(c *gin.Context) {
+	c.JSON(200, role.GetPermissions())
+}
+
+// CreateRole creates a role
+// @Summary Create a role
+// @Description Create a role granting a set of permissions, only the permissions held by the creator can be granted
+// @Tags role
+// @Accept json
+// @Produce json
+// @Param role body dto.CreateRole true "Role"
+// @Security Bearer
+// @Success 201 {object} dto.Role
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /roles [post]
+func CreateRole(c *gin.Context) {
+	var req dto.CreateRole
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	r, err := role.CreateRole(c.MustGet("DB").(*gorm.DB), req, middleware.GrantedPermissions(c))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, r)
+}
+
+// UpdateRole updates a role
+// @Summary Update a role
+// @Description Update the description, the permissions or the two-factor requirement of a role, the change applies to the next requests of its users.
+// @Description Only the two-factor requirement of the superadmin role can be edited, the updater must hold every permission of the role and of the new ones.
+// @Tags role
+// @Accept json
+// @Produce json
+// @Param role_name path string true "Role name"
+// @Param role body dto.UpdateRole true "Role"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /roles/{role_name} [put]
+func UpdateRole(c *gin.Context) {
+	var req dto.UpdateRole
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := role.UpdateRole(c.MustGet("DB").(*gorm.DB), req, middleware.GrantedPermissions(c)); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Role updated"})
+}
+
+// DeleteRole deletes a role
+// @Summary Delete a role
+// @Description Delete a role which is neither builtin nor given to a user
+// @Tags role
+// @Produce json
+// @Param role_name path string true "Role name"
+// @Security Bearer
+// @Success 204
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /roles/{role_name} [delete]
+func DeleteRole(c *gin.Context) {
+	var req struct {
+		Name enum.Role `uri:"role_name" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := role.DeleteRole(c.MustGet("DB").(*gorm.DB), req.Name); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func SetRoleRoutes(r *middleware.Router) {
+	r = r.Require(enum.ROLE_MANAGE)
+	r.GET("", RoleList)
+	r.GET("/permissions", PermissionList)
+	r.POST("", CreateRole)
+	r.PUT("/:role_name", UpdateRole)
+	r.DELETE("/:role_name", DeleteRole)
+}
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	"github.com/gin-gonic/gin"
+)
+
+type RouteService struct {
+	table *middleware.PolicyTable
+}
+
+// RouteList returns the routes with their authorization policy
+// @Summary Get the routes
+// @Description Get the effective table of the routes with the permission they require
+// @Tags route
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.RouteList
+// @Failure 401,403 {object} error.MyError
+// @Router /routes [get]
+func (rs RouteService) RouteList(c *gin.Context) {
+	routes := make([]dto.RoutePolicy, 0)
+	for _, r := range rs.table.Routes() {
+		routes = append(routes, dto.RoutePolicy{
+			Method: r.Method,
+			Path:   r.Path,
+			Policy: r.Policy.String(),
+		})
+	}
+
+	c.JSON(200, dto.RouteList{Routes: routes})
+}
+
+func SetRouteRoutes(r *middleware.Router) {
+	rs := RouteService{table: r.Table()}
+	r.Require(enum.ROLE_MANAGE).GET("", rs.RouteList)
+}
@@ -0,0 +1,186 @@
+package v1
+
+import (
+	"gin-template/pkg/common/serviceaccount"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ServiceAccountList returns the service accounts
+// @Summary Get the service accounts
+// @Description Get the service accounts used by the integrations
+// @Tags service account
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.ServiceAccountList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /service-accounts [get]
+func ServiceAccountList(c *gin.Context) {
+	accounts, err := serviceaccount.GetServiceAccounts(c.MustGet("DB").(*gorm.DB))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, accounts)
+}
+
+// CreateServiceAccount creates a service account
+// @Summary Create a service account
+// @Description Create a service account with a role, it has no password and authenticates with its API keys
+// @Tags service account
+// @Accept json
+// @Produce json
+// @Param service_account body dto.CreateServiceAccount true "Service account"
+// @Security Bearer
+// @Success 201 {object} dto.ServiceAccount
+// @Failure 400,404,500 {object} error.MyError
+// @Router /service-accounts [post]
+func CreateServiceAccount(c *gin.Context) {
+	var req dto.CreateServiceAccount
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	account, err := serviceaccount.CreateServiceAccount(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, account)
+}
+
+// DeleteServiceAccount deletes a service account
+// @Summary Delete a service account
+// @Description Delete a service account, its API keys are refused from then on
+// @Tags service account
+// @Param user_id path int true "User ID of the service account"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /service-accounts/{user_id} [delete]
+func DeleteServiceAccount(c *gin.Context) {
+	var req struct {
+		UserID uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := serviceaccount.DeleteServiceAccount(c.MustGet("DB").(*gorm.DB), req.UserID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// APIKeyList returns the API keys of a service account
+// @Summary Get the API keys of a service account
+// @Description Get the API keys of a service account with their last use, the keys themselves are never returned
+// @Tags service account
+// @Produce json
+// @Param user_id path int true "User ID of the service account"
+// @Security Bearer
+// @Success 200 {object} dto.APIKeyList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /service-accounts/{user_id}/api-keys [get]
+func APIKeyList(c *gin.Context) {
+	var req struct {
+		UserID uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	keys, err := serviceaccount.GetAPIKeys(c.MustGet("DB").(*gorm.DB), req.UserID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, keys)
+}
+
+// CreateAPIKey creates an API key of a service account
+// @Summary Create an API key
+// @Description Create an API key of a service account, restricted to permissions held by both the creator
+// @Description and the role of the service account, and optionally to a class, a tenant and a period.
+// @Description The key is only returned in this response, it is sent in the X-API-Key header.
+// @Tags service account
+// @Accept json
+// @Produce json
+// @Param user_id path int true "User ID of the service account"
+// @Param api_key body dto.CreateAPIKey true "API key"
+// @Security Bearer
+// @Success 201 {object} dto.CreatedAPIKey
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /service-accounts/{user_id}/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req dto.CreateAPIKey
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	key, err := serviceaccount.CreateAPIKey(c.MustGet("DB").(*gorm.DB), req, claims.UserId, middleware.GrantedPermissions(c))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, key)
+}
+
+// RevokeAPIKey revokes an API key of a service account
+// @Summary Revoke an API key
+// @Description Delete an API key of a service account, it is refused from then on
+// @Tags service account
+// @Param user_id path int true "User ID of the service account"
+// @Param key_id path int true "API key ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /service-accounts/{user_id}/api-keys/{key_id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	var req struct {
+		UserID uint64 `uri:"user_id" binding:"required"`
+		KeyID  uint64 `uri:"key_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := serviceaccount.RevokeAPIKey(c.MustGet("DB").(*gorm.DB), req.UserID, req.KeyID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// SetServiceAccountRoutes sets up the service account routes
+func SetServiceAccountRoutes(r *middleware.Router) {
+	r = r.Require(enum.API_KEY_MANAGE)
+	r.GET("", ServiceAccountList)
+	r.POST("", CreateServiceAccount)
+	r.DELETE("/:user_id", DeleteServiceAccount)
+	r.GET("/:user_id/api-keys", APIKeyList)
+	r.POST("/:user_id/api-keys", CreateAPIKey)
+	r.DELETE("/:user_id/api-keys/:key_id", RevokeAPIKey)
+}
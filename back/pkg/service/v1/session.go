@@ -1,11 +1,12 @@
 package v1
 
 import (
-	"gin-template/config"
 	"gin-template/pkg/common/session"
+	"gin-template/pkg/dto"
 	"gin-template/pkg/middleware"
 	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/satori/go.uuid"
 	"gorm.io/gorm"
@@ -68,14 +69,46 @@ func DeleteSession(c *gin.Context) {
 	c.JSON(204, nil)
 }
 
-// SetSessionRoutes sets the routes for the session service
-func SetSessionRoutes(r *gin.RouterGroup, config config.JwtConfig) {
-	mdl := middleware.NewJwtMiddleware(config)
-	r.Use(mdl.MiddlewareFunc(map[string][]enum.Role{
-		"GetSession":    {enum.ADMIN},
-		"DeleteSession": {enum.ADMIN},
-	}))
+// CheckInSession checks in to a session
+// @Summary Check in to a session
+// @Description Marks the student of the user as present to an open session of its class, the email of the user must be verified
+// @Tags session
+// @Accept json
+// @Produce json
+// @Param session_id path string true "TinySession ID"
+// @Param check_in body dto.CheckIn true "Check in"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,429,500 {object} error.MyError
+// @Router /sessions/{session_id}/check-in [post]
+func CheckInSession(c *gin.Context) {
+	var req dto.CheckIn
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
 
-	r.GET("/:session_id", GetSession)
-	r.DELETE("/:session_id", DeleteSession)
+	var path struct {
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&path); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	db := c.MustGet("DB").(*gorm.DB)
+	if err := session.CheckIn(db, uuid.Must(uuid.FromString(path.SessionID)), req.Password, claims.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Checked in"})
+}
+
+// SetSessionRoutes sets the routes for the session service
+func SetSessionRoutes(r *middleware.Router) {
+	r.Require(enum.SESSION_READ).GET("/:session_id", GetSession)
+	r.Require(enum.SESSION_WRITE).DELETE("/:session_id", DeleteSession)
+	r.Require(enum.ACCOUNT_SELF).Limit("check_in").POST("/:session_id/check-in", CheckInSession)
 }
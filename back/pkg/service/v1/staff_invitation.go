@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"gin-template/config"
+	"gin-template/pkg/common/auth"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	jwt2 "gin-template/utils/jwt"
+	"gin-template/utils/mail"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StaffInvitationService struct {
+	conf config.Config
+	// sender sends the invitation emails
+	sender mail.Sender
+}
+
+// InvitationList returns the pending invitations
+// @Summary Get the pending invitations
+// @Description Get the invitations neither accepted nor revoked, including the expired ones which can be resent
+// @Tags invitation
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.StaffInvitationList
+// @Failure 400,500 {object} error.MyError
+// @Router /invitations [get]
+func (s *StaffInvitationService) InvitationList(c *gin.Context) {
+	invitations, err := auth.GetInvitations(c.MustGet("DB").(*gorm.DB))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, invitations)
+}
+
+// Invite invites a staff member
+// @Summary Invite a staff member
+// @Description Send an invitation link by email, the account is created with the role once the invitee sets their password.
+// @Description The role must only grant permissions held by the inviter.
+// @Tags invitation
+// @Accept json
+// @Produce json
+// @Param invitation body dto.CreateUser true "Invitee"
+// @Security Bearer
+// @Success 201 {object} dto.StaffInvitation
+// @Failure 400,403,500 {object} error.MyError
+// @Router /invitations [post]
+func (s *StaffInvitationService) Invite(c *gin.Context) {
+	var req dto.CreateUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	invitation, err := auth.Invite(c.MustGet("DB").(*gorm.DB), s.sender, s.conf, req, claims.UserId, middleware.GrantedPermissions(c))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, invitation)
+}
+
+// ResendInvitation sends a new link of a pending invitation
+// @Summary Resend an invitation
+// @Description Send a new link of a pending invitation valid for a new period, the previous link cannot be used anymore
+// @Tags invitation
+// @Produce json
+// @Param invitation_id path int true "Invitation ID"
+// @Security Bearer
+// @Success 200 {object} dto.StaffInvitation
+// @Failure 400,404,500 {object} error.MyError
+// @Router /invitations/{invitation_id}/resend [post]
+func (s *StaffInvitationService) ResendInvitation(c *gin.Context) {
+	var req struct {
+		ID uint64 `uri:"invitation_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	invitation, err := auth.ResendInvitation(c.MustGet("DB").(*gorm.DB), s.sender, s.conf, req.ID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, invitation)
+}
+
+// RevokeInvitation revokes a pending invitation
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation, its link cannot be used anymore
+// @Tags invitation
+// @Param invitation_id path int true "Invitation ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /invitations/{invitation_id} [delete]
+func (s *StaffInvitationService) RevokeInvitation(c *gin.Context) {
+	var req struct {
+		ID uint64 `uri:"invitation_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.RevokeInvitation(c.MustGet("DB").(*gorm.DB), req.ID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// AcceptInvitation accepts an invitation
+// @Summary Accept an invitation
+// @Description Create the account of an invitation with the password chosen by the invitee, who can then log in.
+// @Description The link can only be used once, before it expires.
+// @Tags invitation
+// @Accept json
+// @Param invitation body dto.AcceptStaffInvitation true "Invitation"
+// @Success 204
+// @Failure 400,429,500 {object} error.MyError
+// @Router /invitations/accept [post]
+func (s *StaffInvitationService) AcceptInvitation(c *gin.Context) {
+	var req dto.AcceptStaffInvitation
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.AcceptInvitation(c.MustGet("DB").(*gorm.DB), s.conf.Password, req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// SetStaffInvitationRoutes sets up the staff invitation routes
+func SetStaffInvitationRoutes(r *middleware.Router, conf config.Config, sender mail.Sender) {
+	s := StaffInvitationService{conf: conf, sender: sender}
+
+	r.Public().Limit("register").POST("/accept", s.AcceptInvitation)
+	r = r.Require(enum.INVITATION_MANAGE)
+	r.GET("", s.InvitationList)
+	r.POST("", s.Invite)
+	r.POST("/:invitation_id/resend", s.ResendInvitation)
+	r.DELETE("/:invitation_id", s.RevokeInvitation)
+}
@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"gin-template/pkg/common/term"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTerm returns a term
+// @Summary Get a term
+// @Description Get a term by ID with its holidays
+// @Tags term
+// @Produce json
+// @Param term_id path int true "Term ID"
+// @Security Bearer
+// @Success 200 {object} dto.Term
+// @Failure 400,404,500 {object} error.MyError
+// @Router /terms/{term_id} [get]
+func GetTerm(c *gin.Context) {
+	var req struct {
+		TermID uint64 `uri:"term_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	t, err := term.GetTermByID(c.MustGet("DB").(*gorm.DB), req.TermID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, t)
+}
+
+// TermList returns a list of terms
+// @Summary Get a list of terms
+// @Description Get a list of academic terms, most recent first
+// @Tags term
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.TermList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /terms [get]
+func TermList(c *gin.Context) {
+	terms, err := term.GetAllTerms(c.MustGet("DB").(*gorm.DB))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, terms)
+}
+
+// CreateTerm creates a term
+// @Summary Create a term
+// @Description Create an academic term with its holidays
+// @Tags term
+// @Accept json
+// @Produce json
+// @Param term body dto.CreateTerm true "Term"
+// @Security Bearer
+// @Success 201 {object} dto.TinyTerm
+// @Failure 400,404,500 {object} error.MyError
+// @Router /terms [post]
+func CreateTerm(c *gin.Context) {
+	var req dto.CreateTerm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	t, err := term.CreateTerm(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, t)
+}
+
+// UpdateTerm updates a term
+// @Summary Update a term
+// @Description Update a term that is not archived
+// @Tags term
+// @Accept json
+// @Produce json
+// @Param term_id path int true "Term ID"
+// @Param term body dto.UpdateTerm true "Term"
+// @Security Bearer
+// @Success 202
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /terms/{term_id} [put]
+func UpdateTerm(c *gin.Context) {
+	var req dto.UpdateTerm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := term.UpdateTerm(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Term updated"})
+}
+
+// ArchiveTerm archives a term
+// @Summary Archive a term
+// @Description Archive a past term, its classes become read-only
+// @Tags term
+// @Produce json
+// @Param term_id path int true "Term ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /terms/{term_id}/archive [put]
+func ArchiveTerm(c *gin.Context) {
+	var req struct {
+		TermID uint64 `uri:"term_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := term.ArchiveTerm(c.MustGet("DB").(*gorm.DB), req.TermID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Term archived"})
+}
+
+// AddHolidayToTerm adds a holiday to a term
+// @Summary Add a holiday to a term
+// @Description Add a holiday to a term that is not archived
+// @Tags term
+// @Accept json
+// @Produce json
+// @Param term_id path int true "Term ID"
+// @Param holiday body dto.AddHolidayToTerm true "Holiday"
+// @Security Bearer
+// @Success 201 {object} dto.Holiday
+// @Failure 400,403,404,500 {object} error.MyError
+// @Router /terms/{term_id}/holidays [post]
+func AddHolidayToTerm(c *gin.Context) {
+	var req dto.AddHolidayToTerm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	h, err := term.AddHolidayToTerm(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, h)
+}
+
+// SetTermRoutes sets up the term routes
+func SetTermRoutes(r *middleware.Router) {
+	r.Require(enum.TERM_READ).GET("", TermList)
+	r.Require(enum.TERM_READ).GET("/:term_id", GetTerm)
+	r.Require(enum.TERM_WRITE).POST("", CreateTerm)
+	r.Require(enum.TERM_WRITE).PUT("/:term_id", UpdateTerm)
+	r.Require(enum.TERM_WRITE).PUT("/:term_id/archive", ArchiveTerm)
+	r.Require(enum.TERM_WRITE).POST("/:term_id/holidays", AddHolidayToTerm)
+}
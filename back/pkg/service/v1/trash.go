@@ -0,0 +1,268 @@
+package v1
+
+import (
+	"gin-template/pkg/common/trash"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+// DeletedClassList returns the deleted classes
+// @Summary Get the deleted classes
+// @Description Get the soft deleted classes, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param params query dto.TrashQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.DeletedClassList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/classes [get]
+func DeletedClassList(c *gin.Context) {
+	var req dto.TrashQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	classes, err := trash.GetDeletedClasses(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, classes)
+}
+
+// DeletedSessionList returns the deleted sessions
+// @Summary Get the deleted sessions
+// @Description Get the soft deleted sessions, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param params query dto.TrashQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.DeletedSessionList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/sessions [get]
+func DeletedSessionList(c *gin.Context) {
+	var req dto.TrashQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	sessions, err := trash.GetDeletedSessions(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, sessions)
+}
+
+// DeletedStudentList returns the deleted students
+// @Summary Get the deleted students
+// @Description Get the soft deleted students, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param params query dto.TrashQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.DeletedStudentList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/students [get]
+func DeletedStudentList(c *gin.Context) {
+	var req dto.TrashQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	students, err := trash.GetDeletedStudents(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, students)
+}
+
+// DeletedUserList returns the deleted users
+// @Summary Get the deleted users
+// @Description Get the soft deleted users, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Param params query dto.TrashQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.DeletedUserList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/users [get]
+func DeletedUserList(c *gin.Context) {
+	var req dto.TrashQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	users, err := trash.GetDeletedUsers(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, users)
+}
+
+// RestoreClass restores a deleted class
+// @Summary Restore a class
+// @Description Restore a deleted class with the students, sessions and timetable deleted with it
+// @Tags trash
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/classes/{class_id}/restore [put]
+func RestoreClass(c *gin.Context) {
+	var req struct {
+		ClassID uint64 `uri:"class_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := trash.RestoreClass(c.MustGet("DB").(*gorm.DB), req.ClassID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Class restored"})
+}
+
+// RestoreClassSessions restores the last deleted sessions of a class
+// @Summary Restore the sessions of a class
+// @Description Restore the sessions of a class removed by the last deletion
+// @Tags trash
+// @Produce json
+// @Param class_id path int true "Class ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/classes/{class_id}/sessions/restore [put]
+func RestoreClassSessions(c *gin.Context) {
+	var req struct {
+		ClassID uint64 `uri:"class_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := trash.RestoreClassSessions(c.MustGet("DB").(*gorm.DB), req.ClassID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Sessions restored"})
+}
+
+// RestoreSession restores a deleted session
+// @Summary Restore a session
+// @Description Restore a deleted session
+// @Tags trash
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/sessions/{session_id}/restore [put]
+func RestoreSession(c *gin.Context) {
+	var req struct {
+		SessionID string `uri:"session_id" binding:"required,uuid"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := trash.RestoreSession(
+		c.MustGet("DB").(*gorm.DB),
+		uuid.Must(uuid.FromString(req.SessionID)),
+	); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Session restored"})
+}
+
+// RestoreStudent restores a deleted student
+// @Summary Restore a student
+// @Description Restore a deleted student
+// @Tags trash
+// @Produce json
+// @Param student_id path int true "Student ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/students/{student_id}/restore [put]
+func RestoreStudent(c *gin.Context) {
+	var req struct {
+		StudentID uint64 `uri:"student_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := trash.RestoreStudent(c.MustGet("DB").(*gorm.DB), req.StudentID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Student restored"})
+}
+
+// RestoreUser restores a deleted user
+// @Summary Restore a user
+// @Description Restore a deleted user and its account
+// @Tags trash
+// @Produce json
+// @Param user_id path int true "User ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /trash/users/{user_id}/restore [put]
+func RestoreUser(c *gin.Context) {
+	var req struct {
+		UserID uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := trash.RestoreUser(c.MustGet("DB").(*gorm.DB), req.UserID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "User restored"})
+}
+
+// SetTrashRoutes sets up the trash routes
+func SetTrashRoutes(r *middleware.Router) {
+	r = r.Require(enum.TRASH_MANAGE)
+	r.GET("/classes", DeletedClassList)
+	r.GET("/sessions", DeletedSessionList)
+	r.GET("/students", DeletedStudentList)
+	r.GET("/users", DeletedUserList)
+	r.PUT("/classes/:class_id/restore", RestoreClass)
+	r.PUT("/classes/:class_id/sessions/restore", RestoreClassSessions)
+	r.PUT("/sessions/:session_id/restore", RestoreSession)
+	r.PUT("/students/:student_id/restore", RestoreStudent)
+	r.PUT("/users/:user_id/restore", RestoreUser)
+}
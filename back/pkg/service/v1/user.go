@@ -2,16 +2,26 @@ package v1
 
 import (
 	"gin-template/config"
+	"gin-template/pkg/common/auth"
 	user2 "gin-template/pkg/common/user"
 	"gin-template/pkg/dto"
 	"gin-template/pkg/middleware"
 	"gin-template/pkg/model/enum"
 	error2 "gin-template/utils/error"
 	jwt2 "gin-template/utils/jwt"
+	"gin-template/utils/mail"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+type UserService struct {
+	conf config.Config
+	// keys signs the email verification links
+	keys *jwt2.KeySet
+	// sender sends the verification of a changed email
+	sender mail.Sender
+}
+
 // GetUser returns a user
 // @Summary Get a user
 // @Description Get a user
@@ -34,7 +44,7 @@ func GetUser(c *gin.Context) {
 	}
 
 	claims := c.MustGet("claims").(*jwt2.Claims)
-	if req.UserId != claims.UserId && claims.Role != enum.SUPERADMIN {
+	if req.UserId != claims.UserId && !middleware.HasPermission(c, enum.USER_READ) {
 		error2.ForbiddenError("You can only access your own account").FillHTTPContextError(c)
 		return
 	}
@@ -85,7 +95,7 @@ func UserList(c *gin.Context) {
 
 // UpdateUser updates a user
 // @Summary Update a user
-// @Description Update a user by id
+// @Description Update a user by id, a changed email must be verified again. A user manager must hold every permission of the role of the user and of the role it gives
 // @Tags user
 // @Accept json
 // @Produce json
@@ -93,10 +103,10 @@ func UserList(c *gin.Context) {
 // @Param user body dto.UpdateUser true "User"
 // Security Bearer
 // @Success 200 {object} dto.User
-// @Failure 400,404,500 {object} error.MyError
+// @Failure 400,403,404,500 {object} error.MyError
 // @Security Bearer
 // @Router /users/{user_id} [put]
-func UpdateUser(c *gin.Context) {
+func (s *UserService) UpdateUser(c *gin.Context) {
 	var req dto.UpdateUser
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(400, error2.FromBindError(err))
@@ -109,42 +119,136 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	claims := c.MustGet("claims").(*jwt2.Claims)
-	if req.Id != claims.UserId && claims.Role != enum.SUPERADMIN {
+	if req.Id != claims.UserId && !middleware.HasPermission(c, enum.USER_WRITE) {
 		error2.ForbiddenError("You can only update your account").FillHTTPContextError(c)
 		return
 	}
 
+	if req.Role != "" && !middleware.HasPermission(c, enum.ROLE_MANAGE) {
+		error2.ForbiddenError("You can not change the role of a user").FillHTTPContextError(c)
+		return
+	}
+
 	db := c.MustGet("DB").(*gorm.DB)
+	var emailChanged bool
 	var err error
-	switch claims.Role {
-	case enum.SUPERADMIN:
-		// Superadmin can update any user
-		err = user2.SuperAdminUpdateUser(db, req)
-	default:
+	if middleware.HasPermission(c, enum.USER_WRITE) {
+		// User managers can update any user
+		emailChanged, err = user2.SuperAdminUpdateUser(db, req, middleware.GrantedPermissions(c))
+	} else {
 		// User can only update himself
-		err = user2.UpdateUser(db, req)
+		emailChanged, err = user2.UpdateUser(db, req)
 	}
 	if err != nil {
 		error2.FromError(err).FillHTTPContextError(c)
 		return
 	}
 
+	// the new email must be verified before it is trusted, e.g. to check in as the student having it
+	if emailChanged {
+		if err := auth.ResendVerification(db, s.sender, s.conf, s.keys, req.Id); err != nil {
+			error2.FromError(err).FillHTTPContextError(c)
+			return
+		}
+	}
+
 	c.JSON(202, gin.H{"message": "User updated"})
 }
 
-func SetUserRoutes(r *gin.RouterGroup, config config.JwtConfig) {
-	// Setup middleware
-	mdl := middleware.NewJwtMiddleware(config)
-
-	// Add privileges on routes
-	r.Use(mdl.MiddlewareFunc(map[string][]enum.Role{
-		"GetUser":    {enum.SUPERADMIN, enum.STUDENT},
-		"UserList":   {enum.SUPERADMIN},
-		"UpdateUser": {enum.SUPERADMIN, enum.STUDENT},
-	}))
-
-	// Add routes to the router
-	r.GET("/:user_id", GetUser)
-	r.GET("", UserList)
-	r.PUT("/:user_id", UpdateUser)
+// DeleteUser deletes a user
+// @Summary Delete a user
+// @Description Deletes a user and its account and logs out its sessions, the user can be restored from the trash until purged.
+// @Description Deleting a user requires every permission of its role, erasing its personal data is done with the privacy routes.
+// @Tags user
+// @Param user_id path int true "User ID"
+// @Success 204
+// @Failure 400,403,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /users/{user_id} [delete]
+func DeleteUser(c *gin.Context) {
+	var req struct {
+		UserId uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	claims := c.MustGet("claims").(*jwt2.Claims)
+	err := user2.DeleteUser(c.MustGet("DB").(*gorm.DB), req.UserId, claims.UserId, middleware.GrantedPermissions(c))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// RevokeUserSessions revokes all the sessions of a user
+// @Summary Revoke the sessions of a user
+// @Description Logs out all the sessions of a user, e.g. after a change of its permissions
+// @Tags user
+// @Param user_id path int true "User ID"
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /users/{user_id}/sessions [delete]
+func RevokeUserSessions(c *gin.Context) {
+	var req struct {
+		UserId uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	db := c.MustGet("DB").(*gorm.DB)
+	if _, err := user2.GetUser(db, req.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	if err := auth.RevokeLoginSessions(db, req.UserId, ""); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// ResetUserMFA resets the two-factor authentication of a user
+// @Summary Reset the two-factor authentication of a user
+// @Description Removes the second factor of a user who lost it and logs out all its sessions, the user enrolls again after the next login
+// @Tags user
+// @Param user_id path int true "User ID"
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Security Bearer
+// @Router /users/{user_id}/mfa [delete]
+func ResetUserMFA(c *gin.Context) {
+	var req struct {
+		UserId uint64 `uri:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := auth.ResetMFA(c.MustGet("DB").(*gorm.DB), req.UserId); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+func SetUserRoutes(r *middleware.Router, conf config.Config, keys *jwt2.KeySet, sender mail.Sender) {
+	s := UserService{conf: conf, keys: keys, sender: sender}
+
+	r.Require(enum.ACCOUNT_SELF).GET("/:user_id", GetUser)
+	r.Require(enum.USER_READ).GET("", UserList)
+	r.Require(enum.ACCOUNT_SELF).PUT("/:user_id", s.UpdateUser)
+	r.Require(enum.USER_WRITE).DELETE("/:user_id", DeleteUser)
+	r.Require(enum.USER_WRITE).DELETE("/:user_id/sessions", RevokeUserSessions)
+	r.Require(enum.MFA_RESET).DELETE("/:user_id/mfa", ResetUserMFA)
 }
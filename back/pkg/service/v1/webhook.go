@@ -0,0 +1,220 @@
+package v1
+
+import (
+	"gin-template/pkg/common/webhook"
+	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
+	"gin-template/pkg/model/enum"
+	error2 "gin-template/utils/error"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookList returns the webhooks
+// @Summary Get the webhooks
+// @Description Get the webhook subscriptions
+// @Tags webhook
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} dto.WebhookList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks [get]
+func WebhookList(c *gin.Context) {
+	webhooks, err := webhook.GetWebhooks(c.MustGet("DB").(*gorm.DB))
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, webhooks)
+}
+
+// GetWebhook returns a webhook
+// @Summary Get a webhook
+// @Description Get a webhook subscription by ID
+// @Tags webhook
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Security Bearer
+// @Success 200 {object} dto.Webhook
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks/{webhook_id} [get]
+func GetWebhook(c *gin.Context) {
+	var req struct {
+		WebhookID uint64 `uri:"webhook_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	w, err := webhook.GetWebhookByID(c.MustGet("DB").(*gorm.DB), req.WebhookID)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, w)
+}
+
+// CreateWebhook creates a webhook
+// @Summary Create a webhook
+// @Description Subscribe a URL to events, the deliveries are signed with the secret. The URL must not target a private, loopback or link-local address.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhook true "Webhook"
+// @Security Bearer
+// @Success 201 {object} dto.Webhook
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	w, err := webhook.CreateWebhook(c.MustGet("DB").(*gorm.DB), req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(201, w)
+}
+
+// UpdateWebhook updates a webhook
+// @Summary Update a webhook
+// @Description Update the URL, events, secret or state of a webhook subscription
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param webhook body dto.UpdateWebhook true "Webhook"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks/{webhook_id} [put]
+func UpdateWebhook(c *gin.Context) {
+	var req dto.UpdateWebhook
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := webhook.UpdateWebhook(c.MustGet("DB").(*gorm.DB), req); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Webhook updated"})
+}
+
+// DeleteWebhook deletes a webhook
+// @Summary Delete a webhook
+// @Description Delete a webhook subscription, its pending deliveries become dead
+// @Tags webhook
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Security Bearer
+// @Success 204
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks/{webhook_id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	var req struct {
+		WebhookID uint64 `uri:"webhook_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := webhook.DeleteWebhook(c.MustGet("DB").(*gorm.DB), req.WebhookID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(204, nil)
+}
+
+// WebhookDeliveryList returns the deliveries of a webhook
+// @Summary Get the deliveries of a webhook
+// @Description Get the delivery log of a webhook, most recent first
+// @Tags webhook
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param params query dto.WebhookDeliveryQueryParams false "..."
+// @Security Bearer
+// @Success 200 {object} dto.WebhookDeliveryList
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks/{webhook_id}/deliveries [get]
+func WebhookDeliveryList(c *gin.Context) {
+	var uri struct {
+		WebhookID uint64 `uri:"webhook_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	var req dto.WebhookDeliveryQueryParams
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	deliveries, err := webhook.GetDeliveries(c.MustGet("DB").(*gorm.DB), uri.WebhookID, req)
+	if err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(200, deliveries)
+}
+
+// ReplayWebhookDelivery replays a delivery of a webhook
+// @Summary Replay a delivery
+// @Description Schedule a new attempt of a delivery, typically a dead one
+// @Tags webhook
+// @Produce json
+// @Param webhook_id path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Security Bearer
+// @Success 202
+// @Failure 400,404,500 {object} error.MyError
+// @Router /webhooks/{webhook_id}/deliveries/{delivery_id}/replay [put]
+func ReplayWebhookDelivery(c *gin.Context) {
+	var req struct {
+		WebhookID  uint64 `uri:"webhook_id" binding:"required"`
+		DeliveryID uint64 `uri:"delivery_id" binding:"required"`
+	}
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(400, error2.FromBindError(err))
+		return
+	}
+
+	if err := webhook.ReplayDelivery(c.MustGet("DB").(*gorm.DB), req.WebhookID, req.DeliveryID); err != nil {
+		error2.FromError(err).FillHTTPContextError(c)
+		return
+	}
+
+	c.JSON(202, gin.H{"message": "Delivery scheduled"})
+}
+
+// SetWebhookRoutes sets up the webhook routes
+func SetWebhookRoutes(r *middleware.Router) {
+	r = r.Require(enum.WEBHOOK_MANAGE)
+	r.GET("", WebhookList)
+	r.GET("/:webhook_id", GetWebhook)
+	r.POST("", CreateWebhook)
+	r.PUT("/:webhook_id", UpdateWebhook)
+	r.DELETE("/:webhook_id", DeleteWebhook)
+	r.GET("/:webhook_id/deliveries", WebhookDeliveryList)
+	r.PUT("/:webhook_id/deliveries/:delivery_id/replay", ReplayWebhookDelivery)
+}
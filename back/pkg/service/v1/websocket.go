@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gin-template/pkg/common/session"
 	"gin-template/pkg/dto"
+	"gin-template/pkg/middleware"
 	"gin-template/pkg/model"
 	error2 "gin-template/utils/error"
 	"github.com/gin-gonic/gin"
@@ -57,7 +58,7 @@ func (ws *WebSocketSession) JoinSessionHandler(w http.ResponseWriter, r *http.Re
 // @Param session_id path string true "Session ID"
 // @Param join_session query dto.JoinSession true "Join Session"
 // @Success 200
-// @Failure 400,404,500 {object} error.MyError
+// @Failure 400,404,429,500 {object} error.MyError
 // @Router /ws/sessions/{session_id}/join [get]
 func JoinSession(c *gin.Context) {
 	var req dto.JoinSession
@@ -92,6 +93,7 @@ func JoinSession(c *gin.Context) {
 }
 
 // SetWebsocketRoutes sets the websocket router
-func SetWebsocketRoutes(r *gin.RouterGroup) {
-	r.GET("/sessions/:session_id/join", JoinSession)
+func SetWebsocketRoutes(r *middleware.Router) {
+	// The session password authenticates the connection
+	r.Public().Limit("check_in").GET("/sessions/:session_id/join", JoinSession)
 }
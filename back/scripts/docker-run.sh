@@ -2,4 +2,5 @@
 
 # This script is used to run the application in a docker container.
 # It is used by the docker-compose.yml file.
-./wait && ./main
\ No newline at end of file
+# The migrations are applied before the server starts, the replicas wait for each other on the migration lock.
+./wait && ./main migrate up && ./main
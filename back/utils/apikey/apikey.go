@@ -0,0 +1,51 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// Scheme starts every key so that a leaked key is easy to recognize
+	Scheme = "epk"
+	// prefixSize is the size of the public part of a key in bytes
+	prefixSize = 8
+	// secretSize is the size of the secret part of a key in bytes
+	secretSize = 32
+)
+
+// Generate generates a key of the form epk_<prefix>_<secret>, it returns the key,
+// its prefix identifying it and the hash of its secret to store
+func Generate() (key, prefix, hash string, err error) {
+	buf := make([]byte, prefixSize+secretSize)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(buf[:prefixSize])
+	secret := hex.EncodeToString(buf[prefixSize:])
+	return Scheme + "_" + prefix + "_" + secret, prefix, Hash(secret), nil
+}
+
+// Parse splits a key in its prefix and its secret, ok is false if it is malformed
+func Parse(key string) (prefix, secret string, ok bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != Scheme || len(parts[1]) != 2*prefixSize || len(parts[2]) != 2*secretSize {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// Hash returns the hex encoded SHA-256 hash of the secret of a key
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify returns true if the secret matches the stored hash
+func Verify(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(secret)), []byte(hash)) == 1
+}
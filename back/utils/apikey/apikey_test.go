@@ -0,0 +1,49 @@
+package apikey
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerate tests that a generated key is parsed back and verified against its hash
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "epk_"+prefix+"_") {
+		t.Errorf("Key %q should start with the scheme and the prefix", key)
+	}
+
+	parsedPrefix, secret, ok := Parse(key)
+	if !ok || parsedPrefix != prefix {
+		t.Fatalf("Key %q should be parsed", key)
+	}
+	if !Verify(secret, hash) {
+		t.Error("Secret should match the hash")
+	}
+	if Verify(strings.Repeat("0", len(secret)), hash) {
+		t.Error("Another secret should not match the hash")
+	}
+
+	other, _, _, _ := Generate()
+	if other == key {
+		t.Error("Keys should be random")
+	}
+}
+
+// TestParse tests that the malformed keys are refused
+func TestParse(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"epk_0123456789abcdef",
+		"abc_0123456789abcdef_" + strings.Repeat("a", 64),
+		"epk_0123_" + strings.Repeat("a", 64),
+		"epk_0123456789abcdef_" + strings.Repeat("a", 63),
+		"epk_0123456789abcdef_" + strings.Repeat("a", 64) + "_extra",
+	} {
+		if _, _, ok := Parse(key); ok {
+			t.Errorf("Key %q should be refused", key)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"gin-template/pkg/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+type contextKey string
+
+const (
+	infoKey      contextKey = "audit_info"
+	actionKey    contextKey = "audit_action"
+	beforeKey               = "audit:before"
+	truncatedKey            = "audit:truncated"
+	pluginName              = "audit"
+
+	// maxTrackedRows is the maximum number of rows whose previous values are recorded for one statement,
+	// the rows changed beyond it are reported by a single entry
+	maxTrackedRows = 500
+	// redacted replaces the value of sensitive columns
+	redacted = "[REDACTED]"
+)
+
+var (
+	// ignoredTables are the tables whose changes are not recorded
+	ignoredTables = map[string]bool{
+		"absence_notifications": true,
+		"audit_logs":            true,
+		"login_attempts":        true,
+		"password_history":      true,
+		"password_resets":       true,
+		"rate_limit_buckets":    true,
+		"signing_keys":          true,
+		"recovery_codes":        true,
+		"tokens":                true,
+		"webhook_deliveries":    true,
+	}
+	// sensitiveColumns are the columns whose values are never recorded, along with the ones having a sensitive suffix
+	sensitiveColumns = map[string]bool{
+		"hash":     true,
+		"password": true,
+		"secret":   true,
+	}
+	// sensitiveSuffixes are the suffixes of the columns holding hashes or secrets, like code_hash or client_secret
+	sensitiveSuffixes = []string{"_hash", "_secret"}
+)
+
+// Info is the request information attached to the audit entries
+type Info struct {
+	// RequestID is the id of the HTTP request
+	RequestID string
+	// ActorID is the id of the authenticated user, nil when anonymous
+	ActorID *uint64
+}
+
+// WithInfo returns a context carrying the request information
+func WithInfo(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, infoKey, info)
+}
+
+// FromContext returns the request information of a context, nil if there is none
+func FromContext(ctx context.Context) *Info {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(infoKey).(*Info)
+	return info
+}
+
+// SetActor sets the authenticated user of the request the connection belongs to
+func SetActor(tx *gorm.DB, userId uint64) {
+	if info := FromContext(tx.Statement.Context); info != nil {
+		info.ActorID = &userId
+	}
+}
+
+// WithAction returns a connection whose changes are recorded under the given action
+// instead of create, update or delete
+func WithAction(tx *gorm.DB, action string) *gorm.DB {
+	return tx.WithContext(context.WithValue(tx.Statement.Context, actionKey, action))
+}
+
+type recorder struct {
+	tenant string
+}
+
+// RegisterCallbacks registers the GORM callbacks recording every create, update and delete in the audit log
+func RegisterCallbacks(db *gorm.DB, tenant string) error {
+	return db.Use(recorder{tenant: tenant})
+}
+
+// Name returns the name of the recorder as a GORM plugin
+func (r recorder) Name() string {
+	return pluginName
+}
+
+// Initialize registers the callbacks of the recorder
+func (r recorder) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+
+	if err := cb.Create().After("gorm:create").Register("audit:after_create", r.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("audit:before_update", r.captureBefore); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("audit:after_update", r.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", r.captureBefore); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("audit:after_delete", r.afterDelete)
+}
+
+// Record appends an entry to the audit log for an action the callbacks do not see, like the statements
+// run with Exec by a purge or an erasure. Nothing is recorded if the callbacks are not registered.
+func Record(db *gorm.DB, action, entity, entityID string, details model.AuditChanges) error {
+	r, ok := db.Config.Plugins[pluginName].(recorder)
+	if !ok {
+		return nil
+	}
+
+	entry := r.newEntry(db, action, entityID)
+	entry.Action, entry.Entity, entry.After = action, entity, redact(details)
+	return model.NewAuditLogModel(db.Session(&gorm.Session{NewDB: true})).Create([]model.AuditLog{entry})
+}
+
+// tracked returns true if the statement changes must be recorded
+func tracked(db *gorm.DB) bool {
+	return db.Error == nil && !db.DryRun && db.Statement.Table != "" && !ignoredTables[db.Statement.Table]
+}
+
+// newEntry returns an audit entry filled with the request information
+func (r recorder) newEntry(db *gorm.DB, action, entityID string) model.AuditLog {
+	entry := model.AuditLog{
+		Tenant:   r.tenant,
+		Action:   action,
+		Entity:   db.Statement.Table,
+		EntityID: entityID,
+	}
+	if a, ok := db.Statement.Context.Value(actionKey).(string); ok {
+		entry.Action = a
+	}
+	if info := FromContext(db.Statement.Context); info != nil {
+		entry.RequestID = info.RequestID
+		entry.ActorID = info.ActorID
+	}
+	return entry
+}
+
+// save appends the entries to the audit log in the same transaction as the statement
+func (r recorder) save(db *gorm.DB, entries []model.AuditLog) {
+	if len(entries) == 0 {
+		return
+	}
+	auditModel := model.NewAuditLogModel(db.Session(&gorm.Session{NewDB: true}))
+	if err := auditModel.Create(entries); err != nil {
+		_ = db.AddError(fmt.Errorf("audit log: %w", err))
+	}
+}
+
+// primaryKey returns the primary key of a row as a string
+func primaryKey(sch *schema.Schema, row map[string]interface{}) string {
+	if sch == nil {
+		return ""
+	}
+	keys := make([]string, 0, len(sch.PrimaryFieldDBNames))
+	for _, name := range sch.PrimaryFieldDBNames {
+		keys = append(keys, fmt.Sprint(row[name]))
+	}
+	return strings.Join(keys, ":")
+}
+
+// isSensitive returns true if the values of a column are never recorded
+func isSensitive(column string) bool {
+	if sensitiveColumns[column] {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(column, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact hides the values of the sensitive columns
+func redact(values map[string]interface{}) model.AuditChanges {
+	if values == nil {
+		return nil
+	}
+	changes := make(model.AuditChanges, len(values))
+	for k, v := range values {
+		if isSensitive(k) {
+			v = redacted
+		}
+		changes[k] = v
+	}
+	return changes
+}
+
+// rowsOf returns the column values of the statement rows
+func rowsOf(db *gorm.DB) []map[string]interface{} {
+	stmt := db.Statement
+	if stmt.Schema == nil {
+		return nil
+	}
+
+	values := make([]reflect.Value, 0)
+	switch rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() {
+	case reflect.Struct:
+		values = append(values, rv)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, reflect.Indirect(rv.Index(i)))
+		}
+	default:
+		return nil
+	}
+
+	rows := make([]map[string]interface{}, 0, len(values))
+	for _, v := range values {
+		row := make(map[string]interface{})
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName == "" {
+				continue
+			}
+			value, _ := f.ValueOf(stmt.Context, v)
+			row[f.DBName] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// captureBefore stores the rows targeted by an update or a delete before they are changed
+func (r recorder) captureBefore(db *gorm.DB) {
+	if !tracked(db) {
+		return
+	}
+
+	stmt := db.Statement
+	q := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	filtered := false
+	if where, ok := stmt.Clauses["WHERE"]; ok {
+		q = q.Clauses(where.Expression)
+		filtered = true
+	}
+	if stmt.Schema != nil {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			q = q.Clauses(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+			filtered = true
+		}
+		if stmt.Schema.LookUpField("DeletedAt") != nil && !stmt.Unscoped {
+			q = q.Where("deleted_at IS NULL")
+		}
+	}
+	if !filtered {
+		return
+	}
+
+	var rows []map[string]interface{}
+	if err := q.Limit(maxTrackedRows + 1).Find(&rows).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("audit log: %w", err))
+		return
+	}
+	if len(rows) > maxTrackedRows {
+		rows = rows[:maxTrackedRows]
+		db.InstanceSet(truncatedKey, true)
+	}
+	db.InstanceSet(beforeKey, rows)
+}
+
+// untracked returns the entry reporting the rows changed by a statement beyond the recorded ones, if any
+func (r recorder) untracked(db *gorm.DB, action string) []model.AuditLog {
+	if truncated, _ := db.InstanceGet(truncatedKey); truncated != true {
+		return nil
+	}
+	entry := r.newEntry(db, action, "")
+	entry.After = model.AuditChanges{"rows": db.RowsAffected, "recorded": maxTrackedRows}
+	return []model.AuditLog{entry}
+}
+
+// before returns the rows captured before an update or a delete
+func before(db *gorm.DB) []map[string]interface{} {
+	if v, ok := db.InstanceGet(beforeKey); ok {
+		return v.([]map[string]interface{})
+	}
+	return nil
+}
+
+// afterCreate records the created rows
+func (r recorder) afterCreate(db *gorm.DB) {
+	if !tracked(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	entries := make([]model.AuditLog, 0)
+	for _, row := range rowsOf(db) {
+		entry := r.newEntry(db, "create", primaryKey(db.Statement.Schema, row))
+		entry.After = redact(row)
+		entries = append(entries, entry)
+	}
+	r.save(db, entries)
+}
+
+// afterUpdate records the changed columns of the updated rows
+func (r recorder) afterUpdate(db *gorm.DB) {
+	if !tracked(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	set, ok := db.Statement.Clauses["SET"].Expression.(clause.Set)
+	if !ok {
+		return
+	}
+
+	assigned := make(map[string]interface{}, len(set))
+	action := "update"
+	for _, a := range set {
+		if a.Column.Name == "updated_at" {
+			continue
+		}
+		assigned[a.Column.Name] = a.Value
+		if a.Column.Name == "deleted_at" {
+			if a.Value == nil {
+				action = "restore"
+			} else {
+				action = "delete"
+			}
+		}
+	}
+
+	entries := make([]model.AuditLog, 0)
+	for _, row := range before(db) {
+		b, a := make(map[string]interface{}), make(map[string]interface{})
+		for column, value := range assigned {
+			if fmt.Sprint(row[column]) == fmt.Sprint(value) {
+				continue
+			}
+			b[column], a[column] = row[column], value
+		}
+		if len(a) == 0 {
+			continue
+		}
+
+		entry := r.newEntry(db, action, primaryKey(db.Statement.Schema, row))
+		entry.Before, entry.After = redact(b), redact(a)
+		entries = append(entries, entry)
+	}
+	r.save(db, append(entries, r.untracked(db, action)...))
+}
+
+// afterDelete records the deleted rows
+func (r recorder) afterDelete(db *gorm.DB) {
+	if !tracked(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	entries := make([]model.AuditLog, 0)
+	for _, row := range before(db) {
+		entry := r.newEntry(db, "delete", primaryKey(db.Statement.Schema, row))
+		entry.Before = redact(row)
+		entries = append(entries, entry)
+	}
+	r.save(db, append(entries, r.untracked(db, "delete")...))
+}
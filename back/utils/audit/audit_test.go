@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"testing"
+)
+
+// TestFromContext tests the retrieval of the request information from a context
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Error("Context without request information should return nil")
+	}
+
+	ctx := WithInfo(context.Background(), &Info{RequestID: "request"})
+	info := FromContext(ctx)
+	if info == nil || info.RequestID != "request" {
+		t.Error("Request information is not retrieved")
+	}
+}
+
+// TestRedact tests that the sensitive columns are never recorded
+func TestRedact(t *testing.T) {
+	changes := redact(map[string]interface{}{
+		"email":         "user@gmail.com",
+		"password":      "hash",
+		"code_hash":     "code",
+		"token_hash":    "token",
+		"client_secret": "secret",
+		"hashed_by":     "user",
+	})
+
+	if changes["email"] != "user@gmail.com" {
+		t.Error("Email should not be redacted")
+	}
+
+	if changes["password"] != redacted {
+		t.Error("Password should be redacted")
+	}
+
+	for _, column := range []string{"code_hash", "token_hash", "client_secret"} {
+		if changes[column] != redacted {
+			t.Errorf("Column %s should be redacted", column)
+		}
+	}
+
+	if changes["hashed_by"] != "user" {
+		t.Error("Column only containing hash should not be redacted")
+	}
+}
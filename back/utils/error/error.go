@@ -77,6 +77,22 @@ func NotFoundError(message string) *MyError {
 	return NewError(404, "Not Found", message, nil)
 }
 
+// TooManyRequestsError returns a new struct of MyError with code 429
+func TooManyRequestsError(message string) *MyError {
+	if message == "" {
+		message = "Too many requests, please retry later"
+	}
+	return NewError(429, "Too Many Requests", message, nil)
+}
+
+// ServiceUnavailableError returns a new struct of MyError with code 503
+func ServiceUnavailableError(message string) *MyError {
+	if message == "" {
+		message = "Service unavailable"
+	}
+	return NewError(503, "Service Unavailable", message, nil)
+}
+
 // FromBindError returns a new struct of MyError with code 400
 func FromBindError(err error) *MyError {
 	verr, ok := err.(validator.ValidationErrors)
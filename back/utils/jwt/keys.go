@@ -0,0 +1,211 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gin-template/config"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// rsaKeySize is the size in bits of the generated RSA keys
+const rsaKeySize = 2048
+
+// Key is a key signing or verifying the tokens, identified in their header by its ID
+type Key struct {
+	// ID is the kid of the key, empty for the shared secret
+	ID string
+	// Method is the signing method of the key
+	Method jwt.SigningMethod
+	// Private signs the tokens, it is nil for a key only verifying them
+	Private interface{}
+	// Public verifies the tokens, it is the secret itself for HS256
+	Public interface{}
+}
+
+// KeySet holds the keys verifying the tokens and the current one signing them, it is safe for concurrent use
+type KeySet struct {
+	mu      sync.RWMutex
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet returns an empty key set, which neither signs nor verifies tokens until keys are set
+func NewKeySet() *KeySet {
+	return &KeySet{keys: map[string]*Key{}}
+}
+
+// NewSecretKeySet returns a key set signing and verifying HS256 tokens with a shared secret
+func NewSecretKeySet(secret string) *KeySet {
+	ks := NewKeySet()
+	ks.Set(&Key{Method: jwt.SigningMethodHS256, Private: []byte(secret), Public: []byte(secret)}, nil)
+	return ks
+}
+
+// Set replaces the keys of the set, current signs the tokens and is verifying them along with the others
+func (s *KeySet) Set(current *Key, others []*Key) {
+	keys := make(map[string]*Key, len(others)+1)
+	for _, k := range others {
+		keys[k.ID] = k
+	}
+	if current != nil {
+		keys[current.ID] = current
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = current
+	s.keys = keys
+}
+
+// Current returns the key signing the tokens, nil if there is none
+func (s *KeySet) Current() *Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Keys returns the keys verifying the tokens ordered by ID
+func (s *KeySet) Keys() []*Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// sign signs the claims with the current key and records its ID in the header of the token
+func (s *KeySet) sign(claims jwt.Claims) (string, error) {
+	k := s.Current()
+	if k == nil {
+		return "", errors.New("no key to sign the token")
+	}
+
+	token := jwt.NewWithClaims(k.Method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+	return token.SignedString(k.Private)
+}
+
+// keyFunc returns the key verifying a token from its kid, the token must use the method of the key
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	s.mu.RLock()
+	k, ok := s.keys[kid]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key '%s'", kid)
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method '%s'", token.Method.Alg())
+	}
+	return k.Public, nil
+}
+
+// GenerateKey generates a key of an asymmetric algorithm
+func GenerateKey(id, algorithm string) (*Key, error) {
+	switch algorithm {
+	case config.RS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	case config.EdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: public}, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm '%s'", algorithm)
+}
+
+// MarshalKey encodes the private part of an asymmetric key in PEM
+func MarshalKey(k *Key) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// UnmarshalKey decodes an asymmetric key from its private part encoded by MarshalKey
+func UnmarshalKey(id, algorithm, encoded string) (*Key, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("invalid PEM key")
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == config.RS256 {
+			return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+		}
+	case ed25519.PrivateKey:
+		if algorithm == config.EdDSA {
+			return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: private.Public()}, nil
+		}
+	}
+	return nil, fmt.Errorf("key does not match the algorithm '%s'", algorithm)
+}
+
+// JWK is the public part of a key in the JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// N and E are the modulus and the exponent of an RSA key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Crv and X are the curve and the public key of an EdDSA key
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a set of JSON Web Keys
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public parts of the asymmetric keys of the set, the shared secret is never published
+func (s *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0)}
+	for _, k := range s.Keys() {
+		jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+		switch public := k.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,118 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"gin-template/config"
+	"gin-template/pkg/model/enum"
+	"github.com/golang-jwt/jwt/v4"
+	"testing"
+)
+
+// TestKeySet tests that the tokens signed by a rotated key keep being verified by the set
+func TestKeySet(t *testing.T) {
+	jwtConfig := config.JwtConfig{Expiration: 24}
+	for _, algorithm := range []string{config.RS256, config.EdDSA} {
+		old, err := GenerateKey("old", algorithm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := NewKeySet()
+		keys.Set(old, nil)
+
+		tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _ := jwt.ParseWithClaims(tokens.AccessToken, &Claims{}, keys.keyFunc)
+		if parsed == nil || parsed.Header["kid"] != "old" || parsed.Method.Alg() != algorithm {
+			t.Errorf("%s token should be signed by the current key", algorithm)
+		}
+
+		current, _ := GenerateKey("current", algorithm)
+		keys.Set(current, []*Key{old})
+		if _, err = ParseTypedToken(tokens.AccessToken, keys, AccessToken); err != nil {
+			t.Errorf("%s token of a retired key should be verified: %v", algorithm, err)
+		}
+
+		keys.Set(current, nil)
+		if _, err = ParseTypedToken(tokens.AccessToken, keys, AccessToken); err == nil {
+			t.Errorf("%s token of an expired key should be refused", algorithm)
+		}
+	}
+}
+
+// TestKeySet_Method tests that a token is refused when it is not signed with the method of its key
+func TestKeySet_Method(t *testing.T) {
+	key, _ := GenerateKey("key", config.EdDSA)
+	keys := NewKeySet()
+	keys.Set(key, nil)
+
+	// an HS256 token signed with the public key under the kid of the key
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{Type: AccessToken})
+	token.Header["kid"] = "key"
+	forged, err := token.SignedString([]byte(key.Public.(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseToken(forged, keys); err == nil {
+		t.Error("Token signed with another method should be refused")
+	}
+
+	if _, err = ParseToken(forged, NewKeySet()); err == nil {
+		t.Error("Token should be refused by an empty key set")
+	}
+}
+
+// TestMarshalKey tests that a stored key signs tokens verified by the generated one
+func TestMarshalKey(t *testing.T) {
+	for _, algorithm := range []string{config.RS256, config.EdDSA} {
+		key, _ := GenerateKey("key", algorithm)
+		encoded, err := MarshalKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := UnmarshalKey("key", algorithm, encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signing := NewKeySet()
+		signing.Set(loaded, nil)
+		verifying := NewKeySet()
+		verifying.Set(key, nil)
+
+		token, _, _ := GenerateMFAPendingToken(1, enum.ADMIN, signing)
+		if _, err = ParseTypedToken(token, verifying, MFAPendingToken); err != nil {
+			t.Errorf("%s token of the stored key should be verified: %v", algorithm, err)
+		}
+	}
+
+	key, _ := GenerateKey("key", config.EdDSA)
+	encoded, _ := MarshalKey(key)
+	if _, err := UnmarshalKey("key", config.RS256, encoded); err == nil {
+		t.Error("Key should not be loaded with another algorithm")
+	}
+}
+
+// TestKeySet_JWKS tests that only the public parts of the asymmetric keys are published
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, _ := GenerateKey("a", config.RS256)
+	edKey, _ := GenerateKey("b", config.EdDSA)
+	keys := NewKeySet()
+	keys.Set(edKey, []*Key{rsaKey})
+
+	set := keys.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS should hold 2 keys, got %d", len(set.Keys))
+	}
+	if k := set.Keys[0]; k.Kid != "a" || k.Kty != "RSA" || k.Alg != "RS256" || k.N == "" || k.E != "AQAB" {
+		t.Errorf("RSA key is not published: %+v", k)
+	}
+	if k := set.Keys[1]; k.Kid != "b" || k.Kty != "OKP" || k.Crv != "Ed25519" || k.Alg != "EdDSA" || k.X == "" {
+		t.Errorf("EdDSA key is not published: %+v", k)
+	}
+
+	if len(NewSecretKeySet("secret").JWKS().Keys) != 0 {
+		t.Error("Shared secret should not be published")
+	}
+}
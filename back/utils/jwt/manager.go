@@ -5,111 +5,220 @@ import (
 	"gin-template/config"
 	"gin-template/pkg/model/enum"
 	"github.com/golang-jwt/jwt/v4"
+	uuid "github.com/satori/go.uuid"
 	"time"
 )
 
-type JwtManager struct {
-	Secret    string `json:"secret"`
-	ExpiresIn int    `json:"expires_in"`
-}
+// TokenType is the type of a token, an access token authenticates the requests
+// and a refresh token is exchanged once for a new pair of tokens
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+	// VerifyEmailToken is signed in the email verification links, its subject is the email
+	VerifyEmailToken TokenType = "verify_email"
+	// MFAPendingToken is issued by a login waiting for the second factor, it is exchanged for a pair of tokens
+	MFAPendingToken TokenType = "mfa_pending"
+	// OIDCFlowToken holds the state of a single sign-on kept by the client until the callback
+	OIDCFlowToken TokenType = "oidc_flow"
+	// APIKeyToken marks the claims of a request authenticated with an API key, such claims are never signed
+	APIKeyToken TokenType = "api_key"
+)
+
+// defaultAccessExpiration is the duration of the access tokens in minutes when not configured
+const defaultAccessExpiration = 15
+
+// mfaPendingExpiration is the duration of the tokens waiting for the second factor
+const mfaPendingExpiration = 5 * time.Minute
+
+// oidcFlowExpiration is the duration of a single sign-on at the provider
+const oidcFlowExpiration = 10 * time.Minute
 
 type JwtToken struct {
 	AccessToken  string    `json:"access_token"`
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresIn    time.Time `json:"expires_in" format:"unix"`
-	TokenID      string    `json:"-"`
+	// RefreshExpiresIn is the expiration time of the refresh token
+	RefreshExpiresIn time.Time `json:"refresh_expires_in" format:"unix"`
+	// TokenID is the id of the refresh token
+	TokenID string `json:"-"`
+	// Family is the id shared by the tokens rotated from the same login
+	Family string `json:"-"`
 }
 
 type Claims struct {
 	jwt.RegisteredClaims
 	UserId uint64    `json:"user_id"`
 	Role   enum.Role `json:"role"`
+	// Type is the type of the token
+	Type TokenType `json:"typ"`
+	// Family is the id shared by the tokens rotated from the same login
+	Family string `json:"fam"`
+	// MFA is true if the login was confirmed with a second factor
+	MFA bool `json:"mfa,omitempty"`
 }
 
-func NewJwtManager(secret string, expiresIn int) JwtManager {
-	return JwtManager{
-		Secret:    secret,
-		ExpiresIn: expiresIn,
-	}
+// OIDCFlow is the state of a single sign-on, the verifier and the nonce never leave the client but in the callback
+type OIDCFlow struct {
+	// State is the value the provider returns along with the code
+	State string `json:"state"`
+	// Nonce is the value the provider returns in the ID token
+	Nonce string `json:"nonce"`
+	// Verifier is the PKCE code verifier
+	Verifier string `json:"verifier"`
 }
 
-func GenerateTokens(userId uint64, role enum.Role, conf config.JwtConfig) (JwtToken, error) {
-	tokenId := fmt.Sprintf("%d:%d", userId, time.Now().Unix())
-	now := time.Now().UTC().Add(time.Duration(conf.Expiration) * time.Hour)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+type oidcFlowClaims struct {
+	jwt.RegisteredClaims
+	// Type is the type of the token
+	Type TokenType `json:"typ"`
+	OIDCFlow
+}
+
+// GenerateTokens generates the access and refresh tokens of a new token family
+func GenerateTokens(userId uint64, role enum.Role, conf config.JwtConfig, keys *KeySet) (JwtToken, error) {
+	return GenerateFamilyTokens(userId, role, uuid.NewV4().String(), false, conf, keys)
+}
+
+// GenerateFamilyTokens generates the access and refresh tokens rotated in a token family,
+// mfa records whether the login of the family was confirmed with a second factor
+func GenerateFamilyTokens(userId uint64, role enum.Role, family string, mfa bool, conf config.JwtConfig, keys *KeySet) (JwtToken, error) {
+	accessMinutes := conf.AccessExpiration
+	if accessMinutes <= 0 {
+		accessMinutes = defaultAccessExpiration
+	}
+
+	now := time.Now().UTC()
+	accessExpiresAt := now.Add(time.Duration(accessMinutes) * time.Minute)
+	refreshExpiresAt := now.Add(time.Duration(conf.Expiration) * time.Hour)
+	refreshId := uuid.NewV4().String()
+
+	access, err := keys.sign(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now),
-			ID:        tokenId,
+			ExpiresAt: jwt.NewNumericDate(accessExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewV4().String(),
 		},
 		UserId: userId,
 		Role:   role,
+		Type:   AccessToken,
+		Family: family,
+		MFA:    mfa,
 	})
-
-	refresh, err := token.SignedString([]byte(conf.Secret))
 	if err != nil {
 		return JwtToken{}, err
 	}
-	token = jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+
+	refresh, err := keys.sign(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ID: tokenId,
+			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        refreshId,
 		},
 		UserId: userId,
 		Role:   role,
+		Type:   RefreshToken,
+		Family: family,
+		MFA:    mfa,
 	})
-	access, err := token.SignedString([]byte(conf.Secret))
 	if err != nil {
 		return JwtToken{}, err
 	}
 
 	return JwtToken{
-		AccessToken:  access,
-		RefreshToken: refresh,
-		ExpiresIn:    now,
-		TokenID:      tokenId,
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresIn:        accessExpiresAt,
+		RefreshExpiresIn: refreshExpiresAt,
+		TokenID:          refreshId,
+		Family:           family,
 	}, nil
 }
 
-func ParseToken(token, secret string) (*Claims, error) {
-	cl := &Claims{}
-	_, err := jwt.ParseWithClaims(token, cl, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+// GenerateVerificationToken generates the token of an email verification link valid for the given hours
+func GenerateVerificationToken(email string, validity int, keys *KeySet) (string, error) {
+	now := time.Now().UTC()
+	return keys.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   email,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(validity) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewV4().String(),
+		},
+		Type: VerifyEmailToken,
 	})
+}
+
+// GenerateMFAPendingToken generates the short-lived token of a login waiting for the second factor
+func GenerateMFAPendingToken(userId uint64, role enum.Role, keys *KeySet) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(mfaPendingExpiration)
+	token, err := keys.sign(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewV4().String(),
+		},
+		UserId: userId,
+		Role:   role,
+		Type:   MFAPendingToken,
+	})
+	return token, expiresAt, err
+}
+
+// GenerateOIDCFlowToken generates the token holding the state of a single sign-on
+func GenerateOIDCFlowToken(flow OIDCFlow, keys *KeySet) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(oidcFlowExpiration)
+	token, err := keys.sign(oidcFlowClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        uuid.NewV4().String(),
+		},
+		Type:     OIDCFlowToken,
+		OIDCFlow: flow,
+	})
+	return token, expiresAt, err
+}
+
+// ParseOIDCFlowToken parses the token holding the state of a single sign-on
+func ParseOIDCFlowToken(token string, keys *KeySet) (*OIDCFlow, error) {
+	cl := &oidcFlowClaims{}
+	_, err := jwt.ParseWithClaims(token, cl, keys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
 
-	return cl, nil
+	if cl.Type != OIDCFlowToken {
+		return nil, fmt.Errorf("%s token required", OIDCFlowToken)
+	}
+
+	return &cl.OIDCFlow, nil
 }
 
-func (j *JwtToken) RefreshTokenWithToken(refreshToken string, conf config.JwtConfig) error {
-	var cl *Claims
-	var err error
-	if refreshToken == "" {
-		cl = &Claims{
-			Role: enum.SUPERADMIN,
-		}
-	} else {
-		cl, err = ParseToken(refreshToken, conf.Secret)
-	}
+// ParseToken parses a token verified by a key of the set
+func ParseToken(token string, keys *KeySet) (*Claims, error) {
+	cl := &Claims{}
+	_, err := jwt.ParseWithClaims(token, cl, keys.keyFunc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	now := time.Now().UTC().Add(time.Duration(conf.Expiration) * time.Hour)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now),
-		},
-		UserId: cl.UserId,
-		Role:   cl.Role,
-	})
+	return cl, nil
+}
 
-	access, err := token.SignedString([]byte(conf.Secret))
+// ParseTypedToken parses a token and checks its type
+func ParseTypedToken(token string, keys *KeySet, tokenType TokenType) (*Claims, error) {
+	cl, err := ParseToken(token, keys)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	j.AccessToken = access
-	j.ExpiresIn = now
-	return nil
+	if cl.Type != tokenType {
+		return nil, fmt.Errorf("%s token required", tokenType)
+	}
+
+	return cl, nil
 }
@@ -13,7 +13,8 @@ func TestGenerateTokens(t *testing.T) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	if err != nil {
 		t.Error(err)
 	}
@@ -42,8 +43,16 @@ func TestGenerateTokens(t *testing.T) {
 		t.Error("Expires in is in the past")
 	}
 
-	if tokens.ExpiresIn.Before(time.Now().Add(time.Duration(jwtConfig.Expiration)*time.Hour - time.Minute)) {
-		t.Error("Expires in is too far in the future")
+	if tokens.ExpiresIn.After(time.Now().Add(time.Duration(defaultAccessExpiration) * time.Minute)) {
+		t.Error("Access token expires too far in the future")
+	}
+
+	if tokens.RefreshExpiresIn.Before(time.Now().Add(time.Duration(jwtConfig.Expiration)*time.Hour - time.Minute)) {
+		t.Error("Refresh token expires too early")
+	}
+
+	if tokens.Family == "" {
+		t.Error("Family is empty")
 	}
 }
 
@@ -53,12 +62,13 @@ func TestParseToken(t *testing.T) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	if err != nil {
 		t.Error(err)
 	}
 
-	claims, err := ParseToken(tokens.AccessToken, jwtConfig.Secret)
+	claims, err := ParseToken(tokens.AccessToken, keys)
 	if err != nil {
 		t.Error(err)
 	}
@@ -71,9 +81,47 @@ func TestParseToken(t *testing.T) {
 		t.Error("Role is not SUPERADMIN")
 	}
 
-	if claims.ID != tokens.TokenID {
+	if claims.Type != AccessToken {
+		t.Error("Type is not access")
+	}
+
+	if claims.Family != tokens.Family {
+		t.Error("Family is not the same")
+	}
+
+	refreshClaims, err := ParseToken(tokens.RefreshToken, keys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if refreshClaims.ID != tokens.TokenID {
 		t.Error("Token ID is not the same")
 	}
+
+	if refreshClaims.ID == claims.ID {
+		t.Error("Access token and refresh token share their ID")
+	}
+}
+
+// TestParseTypedToken tests the parsing of a token of a given type
+func TestParseTypedToken(t *testing.T) {
+	jwtConfig := config.JwtConfig{
+		Secret:     "this_is_a_secret",
+		Expiration: 24,
+	}
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if _, err = ParseTypedToken(tokens.RefreshToken, keys, RefreshToken); err != nil {
+		t.Error(err)
+	}
+
+	if _, err = ParseTypedToken(tokens.RefreshToken, keys, AccessToken); err == nil {
+		t.Error("Expected error with a refresh token used as access token")
+	}
 }
 
 // TestParseTokenWithInvalidToken tests the parsing of a token with an invalid token
@@ -82,7 +130,8 @@ func TestParseTokenWithInvalidToken(t *testing.T) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	_, err := ParseToken("invalid_token", jwtConfig.Secret)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	_, err := ParseToken("invalid_token", keys)
 	if err == nil {
 		t.Error("Expected error")
 	}
@@ -94,59 +143,143 @@ func TestParseTokenWithInvalidSecret(t *testing.T) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	if err != nil {
 		t.Error(err)
 	}
 
-	_, err = ParseToken(tokens.AccessToken, "invalid_secret")
+	_, err = ParseToken(tokens.AccessToken, NewSecretKeySet("invalid_secret"))
 	if err == nil {
 		t.Error("Expected error")
 	}
 }
 
-// TestRefreshTokenWithToken tests the refresh token with token
-func TestRefreshTokenWithToken(t *testing.T) {
+// TestGenerateVerificationToken tests the generation of an email verification token
+func TestGenerateVerificationToken(t *testing.T) {
 	jwtConfig := config.JwtConfig{
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	token, err := GenerateVerificationToken("user@gmail.com", 48, keys)
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = tokens.RefreshTokenWithToken(tokens.RefreshToken, jwtConfig)
+	claims, err := ParseTypedToken(token, keys, VerifyEmailToken)
 	if err != nil {
 		t.Error(err)
 	}
 
-	if tokens.AccessToken == "" {
-		t.Error("Access token is empty")
+	if claims.Subject != "user@gmail.com" {
+		t.Error("Subject is not the email")
 	}
 
-	if tokens.RefreshToken == "" {
-		t.Error("Refresh token is empty")
+	if _, err = ParseTypedToken(token, keys, AccessToken); err == nil {
+		t.Error("Expected error with a verification token used as access token")
 	}
+}
 
-	if tokens.ExpiresIn.IsZero() {
-		t.Error("Expires in is empty")
+// TestGenerateOIDCFlowToken tests the token holding the state of a single sign-on
+func TestGenerateOIDCFlowToken(t *testing.T) {
+	jwtConfig := config.JwtConfig{
+		Secret:     "this_is_a_secret",
+		Expiration: 24,
+	}
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	flow := OIDCFlow{State: "state", Nonce: "nonce", Verifier: "verifier"}
+	token, _, err := GenerateOIDCFlowToken(flow, keys)
+	if err != nil {
+		t.Error(err)
 	}
 
-	if tokens.TokenID == "" {
-		t.Error("Token ID is empty")
+	parsed, err := ParseOIDCFlowToken(token, keys)
+	if err != nil {
+		t.Error(err)
 	}
 
-	if tokens.AccessToken == tokens.RefreshToken {
-		t.Error("Access token and refresh token are the same")
+	if *parsed != flow {
+		t.Error("Flow is not the same")
 	}
 
-	if time.Now().After(tokens.ExpiresIn) {
-		t.Error("Expires in is in the past")
+	tokens, _ := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
+	if _, err = ParseOIDCFlowToken(tokens.AccessToken, keys); err == nil {
+		t.Error("Expected error with an access token used as flow token")
+	}
+}
+
+// TestGenerateFamilyTokens tests the rotation of tokens in a token family
+func TestGenerateFamilyTokens(t *testing.T) {
+	jwtConfig := config.JwtConfig{
+		Secret:           "this_is_a_secret",
+		Expiration:       24,
+		AccessExpiration: 5,
+	}
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, err := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	rotated, err := GenerateFamilyTokens(1, enum.SUPERADMIN, tokens.Family, true, jwtConfig, keys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if rotated.Family != tokens.Family {
+		t.Error("Family is not the same")
+	}
+
+	if rotated.TokenID == tokens.TokenID {
+		t.Error("Token ID is the same")
+	}
+
+	if rotated.RefreshToken == tokens.RefreshToken {
+		t.Error("Refresh token is the same")
+	}
+
+	if rotated.ExpiresIn.After(time.Now().Add(5 * time.Minute)) {
+		t.Error("Access token expires too far in the future")
+	}
+
+	claims, err := ParseTypedToken(rotated.AccessToken, keys, AccessToken)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !claims.MFA {
+		t.Error("Access token does not record the second factor")
+	}
+}
+
+// TestGenerateMFAPendingToken tests the generation of the token of a login waiting for the second factor
+func TestGenerateMFAPendingToken(t *testing.T) {
+	jwtConfig := config.JwtConfig{
+		Secret:     "this_is_a_secret",
+		Expiration: 24,
+	}
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	token, expiresAt, err := GenerateMFAPendingToken(1, enum.ADMIN, keys)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if expiresAt.After(time.Now().Add(5 * time.Minute)) {
+		t.Error("Pending token expires too far in the future")
+	}
+
+	claims, err := ParseTypedToken(token, keys, MFAPendingToken)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if claims.UserId != 1 || claims.Role != enum.ADMIN {
+		t.Error("Pending token does not identify the user")
 	}
 
-	if tokens.ExpiresIn.Before(time.Now().Add(time.Duration(jwtConfig.Expiration)*time.Hour - time.Minute)) {
-		t.Error("Expires in is too far in the future")
+	if _, err = ParseTypedToken(token, keys, AccessToken); err == nil {
+		t.Error("Expected error with a pending token used as access token")
 	}
 }
 
@@ -156,8 +289,9 @@ func BenchmarkGenerateTokens(b *testing.B) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
+	keys := NewSecretKeySet(jwtConfig.Secret)
 	for i := 0; i < b.N; i++ {
-		GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+		GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	}
 }
 
@@ -167,20 +301,22 @@ func BenchmarkParseToken(b *testing.B) {
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, _ := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, _ := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	for i := 0; i < b.N; i++ {
-		ParseToken(tokens.AccessToken, jwtConfig.Secret)
+		ParseToken(tokens.AccessToken, keys)
 	}
 }
 
-// BenchmarkRefreshTokenWithToken benchmarks the refresh token with token
-func BenchmarkRefreshTokenWithToken(b *testing.B) {
+// BenchmarkGenerateFamilyTokens benchmarks the rotation of tokens in a token family
+func BenchmarkGenerateFamilyTokens(b *testing.B) {
 	jwtConfig := config.JwtConfig{
 		Secret:     "this_is_a_secret",
 		Expiration: 24,
 	}
-	tokens, _ := GenerateTokens(1, enum.SUPERADMIN, jwtConfig)
+	keys := NewSecretKeySet(jwtConfig.Secret)
+	tokens, _ := GenerateTokens(1, enum.SUPERADMIN, jwtConfig, keys)
 	for i := 0; i < b.N; i++ {
-		tokens.RefreshTokenWithToken(tokens.RefreshToken, jwtConfig)
+		GenerateFamilyTokens(1, enum.SUPERADMIN, tokens.Family, true, jwtConfig, keys)
 	}
 }
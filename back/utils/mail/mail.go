@@ -0,0 +1,134 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"gin-template/config"
+	"gin-template/logging"
+	"mime"
+	"net/smtp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+type Message struct {
+	// To is the list of recipients
+	To []string
+	// Subject is the subject of the email
+	Subject string
+	// Body is the plain text content of the email
+	Body string
+}
+
+// Sender sends emails
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSender returns an SMTP sender if a host is configured, otherwise a sender logging the emails
+func NewSender(conf config.MailConfig) Sender {
+	if conf.Host == "" {
+		return LogSender{}
+	}
+	return &SMTPSender{conf: conf}
+}
+
+type SMTPSender struct {
+	conf config.MailConfig
+}
+
+// Send sends the email through the SMTP server
+func (s *SMTPSender) Send(msg Message) error {
+	var auth smtp.Auth
+	if s.conf.Username != "" {
+		auth = smtp.PlainAuth("", s.conf.Username, s.conf.Password, s.conf.Host)
+	}
+	addr := fmt.Sprintf("%s:%d", s.conf.Host, s.conf.Port)
+	return smtp.SendMail(addr, auth, s.conf.From, msg.To, Build(s.conf.From, msg, time.Now()))
+}
+
+// Build returns the RFC 5322 representation of a plain text UTF-8 email
+func Build(from string, msg Message, date time.Time) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// LogSender logs the emails instead of sending them, used when no SMTP server is configured
+type LogSender struct{}
+
+// Send logs the email
+func (LogSender) Send(msg Message) error {
+	logging.Info.Printf("email to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
+
+// FakeSender records the emails, used in tests
+type FakeSender struct {
+	mu sync.Mutex
+	// Sent is the list of emails sent
+	Sent []Message
+	// Err is returned by Send when not nil
+	Err error
+}
+
+// Send records the email
+func (f *FakeSender) Send(msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Sent = append(f.Sent, msg)
+	return nil
+}
+
+// Template is a localized email template, the subject and body are text/template strings
+type Template map[string]struct {
+	Subject string
+	Body    string
+}
+
+// Render renders the template in the given language, falling back to the fallback language
+func (t Template) Render(lang, fallback string, to []string, data interface{}) (Message, error) {
+	tpl, ok := t[lang]
+	if !ok {
+		if tpl, ok = t[fallback]; !ok {
+			return Message{}, fmt.Errorf("no template for language '%s'", lang)
+		}
+	}
+
+	subject, err := execute(tpl.Subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	body, err := execute(tpl.Body, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{To: to, Subject: subject, Body: body}, nil
+}
+
+// execute executes a text/template string
+func execute(text string, data interface{}) (string, error) {
+	tpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
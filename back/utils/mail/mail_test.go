@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTemplate = Template{
+	"fr": {Subject: "Bonjour {{.Name}}", Body: "Vous avez {{.Count}} absences"},
+	"en": {Subject: "Hello {{.Name}}", Body: "You have {{.Count}} absences"},
+}
+
+// TestTemplate_Render tests the rendering of a template in a language
+func TestTemplate_Render(t *testing.T) {
+	data := struct {
+		Name  string
+		Count int
+	}{Name: "Jules", Count: 2}
+
+	msg, err := testTemplate.Render("en", "fr", []string{"jules@gmail.com"}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "Hello Jules" || msg.Body != "You have 2 absences" {
+		t.Error("Template is not rendered in english")
+	}
+
+	msg, err = testTemplate.Render("de", "fr", nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Subject != "Bonjour Jules" {
+		t.Error("Template should fall back to french")
+	}
+
+	if _, err := (Template{}).Render("de", "fr", nil, data); err == nil {
+		t.Error("Rendering a missing language should fail")
+	}
+}
+
+// TestBuild tests that the email headers are encoded
+func TestBuild(t *testing.T) {
+	raw := string(Build("noreply@epicarte.fr", Message{
+		To:      []string{"a@gmail.com", "b@gmail.com"},
+		Subject: "Absences de la journée",
+		Body:    "line1\nline2",
+	}, time.Date(2022, 10, 3, 18, 0, 0, 0, time.UTC)))
+
+	if !strings.Contains(raw, "To: a@gmail.com, b@gmail.com\r\n") {
+		t.Error("Recipients are not set")
+	}
+
+	if !strings.Contains(raw, "Subject: =?utf-8?q?Absences_de_la_journ=C3=A9e?=\r\n") {
+		t.Error("Subject is not encoded")
+	}
+
+	if !strings.HasSuffix(raw, "\r\n\r\nline1\r\nline2") {
+		t.Error("Body is not separated from the headers")
+	}
+}
+
+// TestFakeSender tests that the fake sender records the emails
+func TestFakeSender(t *testing.T) {
+	var sender Sender = &FakeSender{}
+	if err := sender.Send(Message{To: []string{"a@gmail.com"}}); err != nil {
+		t.Error(err)
+	}
+
+	if len(sender.(*FakeSender).Sent) != 1 {
+		t.Error("Email is not recorded")
+	}
+}
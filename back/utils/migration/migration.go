@@ -0,0 +1,267 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockKey is the key of the advisory lock held while migrating, so that replicas starting together migrate once
+const lockKey = 727394061
+
+// fileName matches the files of the migrations, VERSION_NAME.up.sql and VERSION_NAME.down.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the schema along with the SQL reverting it
+type Migration struct {
+	Version uint
+	Name    string
+	// Up applies the change
+	Up string
+	// Down reverts the change
+	Down string
+}
+
+// Load reads the migrations of a directory, ordered by version, every version must have an up and a down file
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected migration file '%s'", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid version of the migration file '%s'", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations '%s' and '%s' have the same version", m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// String returns the version and the name of the migration as in its file names
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Record is a migration applied to the database
+type Record struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName overrides the table name used by Record to `schema_migrations`
+func (Record) TableName() string {
+	return "schema_migrations"
+}
+
+// State is a migration along with the date it was applied, nil while it is pending
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies and reverts the migrations, recording the applied ones in the schema_migrations table
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	// Baseline is called before the first migration is applied to a database having no record,
+	// it returns true if it brought an existing schema to the first migration, which is then recorded without being run
+	Baseline func(tx *gorm.DB) (bool, error)
+}
+
+// New returns a migrator of the migrations ordered by version
+func New(db *gorm.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// Latest returns the version of the last migration, 0 when there is none
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// records returns the applied migrations by version, none when the schema_migrations table does not exist
+func (m *Migrator) records(tx *gorm.DB) (map[uint]Record, error) {
+	records := map[uint]Record{}
+	if !tx.Migrator().HasTable(&Record{}) {
+		return records, nil
+	}
+
+	var found []Record
+	if err := tx.Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range found {
+		records[r.Version] = r
+	}
+	return records, nil
+}
+
+// Status returns the migrations with the date they were applied
+func (m *Migrator) Status() ([]State, error) {
+	records, err := m.records(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, len(m.migrations))
+	for i, migration := range m.migrations {
+		states[i].Migration = migration
+		if r, ok := records[migration.Version]; ok {
+			appliedAt := r.AppliedAt
+			states[i].AppliedAt = &appliedAt
+		}
+	}
+	return states, nil
+}
+
+// Pending returns the migrations not applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	states, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// lock creates the schema_migrations table and takes the advisory lock until the end of the transaction
+func (m *Migrator) lock(tx *gorm.DB) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockKey).Error; err != nil {
+		return err
+	}
+	return tx.AutoMigrate(&Record{})
+}
+
+// Up applies the pending migrations in order, in a single transaction so that a failure leaves the schema untouched
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := m.lock(tx); err != nil {
+			return err
+		}
+		records, err := m.records(tx)
+		if err != nil {
+			return err
+		}
+
+		for i, migration := range m.migrations {
+			if _, ok := records[migration.Version]; ok {
+				continue
+			}
+
+			baselined := false
+			if i == 0 && len(records) == 0 && m.Baseline != nil {
+				if baselined, err = m.Baseline(tx); err != nil {
+					return fmt.Errorf("baseline of %s: %w", migration, err)
+				}
+			}
+			if !baselined {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return fmt.Errorf("migration %s: %w", migration, err)
+				}
+			}
+
+			record := Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Down reverts the last applied migrations, the given number of them, in a single transaction
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, errors.New("the number of migrations to revert must be positive")
+	}
+
+	var reverted []Migration
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := m.lock(tx); err != nil {
+			return err
+		}
+		var records []Record
+		if err := tx.Order("version desc").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+
+		byVersion := make(map[uint]Migration, len(m.migrations))
+		for _, migration := range m.migrations {
+			byVersion[migration.Version] = migration
+		}
+		for _, r := range records {
+			migration, ok := byVersion[r.Version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is unknown to this version, it cannot be reverted", r.Version, r.Name)
+			}
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("migration %s: %w", migration, err)
+			}
+			if err := tx.Delete(&r).Error; err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// TestLoad tests that the migrations are paired and ordered by version
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t (c);")},
+		"migrations/0010_add_index.down.sql": {Data: []byte("DROP INDEX idx;")},
+		"migrations/0002_initial.up.sql":     {Data: []byte("CREATE TABLE t (c int);")},
+		"migrations/0002_initial.down.sql":   {Data: []byte("DROP TABLE t;")},
+	}
+
+	migrations, err := Load(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Errorf("Migrations should be ordered by version, got %d and %d", migrations[0].Version, migrations[1].Version)
+	}
+	if migrations[0].Up != "CREATE TABLE t (c int);" || migrations[0].Down != "DROP TABLE t;" {
+		t.Error("Migration should hold the content of its up and down files")
+	}
+	if migrations[1].String() != "0010_add_index" {
+		t.Errorf("Migration should be named as its files, got %s", migrations[1])
+	}
+	if New(nil, migrations).Latest() != 10 {
+		t.Error("Latest version should be the one of the last migration")
+	}
+}
+
+// TestLoad_Invalid tests that missing, conflicting and misnamed files are refused
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_initial.up.sql": {Data: []byte("CREATE TABLE t (c int);")},
+		},
+		"same version": {
+			"migrations/0001_initial.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+			"migrations/0001_initial.down.sql": {Data: []byte("DROP TABLE t;")},
+			"migrations/0001_other.up.sql":     {Data: []byte("CREATE TABLE u (c int);")},
+			"migrations/0001_other.down.sql":   {Data: []byte("DROP TABLE u;")},
+		},
+		"misnamed": {
+			"migrations/initial.sql": {Data: []byte("CREATE TABLE t (c int);")},
+		},
+		"version zero": {
+			"migrations/0000_initial.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+			"migrations/0000_initial.down.sql": {Data: []byte("DROP TABLE t;")},
+		},
+	}
+
+	for name, fsys := range tests {
+		if _, err := Load(fsys, "migrations"); err == nil {
+			t.Errorf("Migrations with %s should be refused", name)
+		}
+	}
+}
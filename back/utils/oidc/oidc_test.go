@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"gin-template/config"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// mockProvider is a local OpenID Connect provider authorizing a single code
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// challenge and nonce are the ones of the authorization request
+	challenge string
+	nonce     string
+	// audience is the audience of the issued ID tokens
+	audience string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockProvider{key: key, audience: "attendance"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JwksURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != "valid-code" || CodeChallenge(r.PostForm.Get("code_verifier")) != m.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(t)})
+	})
+	m.server = httptest.NewServer(mux)
+	return m
+}
+
+// idToken signs the ID token of the authorized user
+func (m *mockProvider) idToken(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            m.audience,
+		"sub":            "user-42",
+		"email":          "jules@university.fr",
+		"email_verified": true,
+		"groups":         []string{"students", "staff"},
+		"nonce":          m.nonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// authorize plays the role of the browser authorizing the application at the provider
+func (m *mockProvider) authorize(t *testing.T, authorizationURL string) {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("code_challenge_method") != "S256" {
+		t.Error("Authorization request does not use PKCE")
+	}
+	m.challenge = u.Query().Get("code_challenge")
+	m.nonce = u.Query().Get("nonce")
+}
+
+// TestProvider_Flow tests the authorization-code flow with PKCE against a mock provider
+func TestProvider_Flow(t *testing.T) {
+	mock := newMockProvider(t)
+	defer mock.server.Close()
+
+	provider := NewProvider(config.OIDCConfig{
+		Issuer:      mock.server.URL,
+		ClientID:    "attendance",
+		RedirectURL: "http://localhost:3000/oidc/callback",
+	}, mock.server.Client())
+
+	ctx := context.Background()
+	verifier, _ := RandomString()
+	nonce, _ := RandomString()
+	authorizationURL, err := provider.AuthorizationURL(ctx, "state", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.authorize(t, authorizationURL)
+
+	if _, err := provider.Exchange(ctx, "valid-code", "another-verifier"); err == nil {
+		t.Error("Exchange with a wrong verifier should fail")
+	}
+
+	idToken, err := provider.Exchange(ctx, "valid-code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := provider.Verify(ctx, idToken, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.String("email") != "jules@university.fr" || !claims.EmailVerified() {
+		t.Error("ID token does not hold the verified email")
+	}
+
+	if groups := claims.Strings("groups"); len(groups) != 2 || groups[1] != "staff" {
+		t.Error("ID token does not hold the groups", groups)
+	}
+
+	if _, err := provider.Verify(ctx, idToken, "another-nonce"); err == nil {
+		t.Error("ID token with another nonce should be refused")
+	}
+}
+
+// TestProvider_VerifyAudience tests that an ID token issued to another client is refused
+func TestProvider_VerifyAudience(t *testing.T) {
+	mock := newMockProvider(t)
+	defer mock.server.Close()
+	mock.audience = "another-client"
+	mock.nonce = "nonce"
+
+	provider := NewProvider(config.OIDCConfig{Issuer: mock.server.URL, ClientID: "attendance"}, mock.server.Client())
+	if _, err := provider.Verify(context.Background(), mock.idToken(t), "nonce"); err == nil {
+		t.Error("ID token issued to another client should be refused")
+	}
+}
+
+// TestProvider_Metadata tests that a provider announcing another issuer is refused
+func TestProvider_Metadata(t *testing.T) {
+	mock := newMockProvider(t)
+	defer mock.server.Close()
+
+	provider := NewProvider(config.OIDCConfig{Issuer: mock.server.URL + "/other"}, mock.server.Client())
+	if _, err := provider.Metadata(context.Background()); err == nil {
+		t.Error("Provider announcing another issuer should be refused")
+	}
+}
+
+// TestClaims_EmailVerified tests that an email is only verified when the provider states it
+func TestClaims_EmailVerified(t *testing.T) {
+	tests := []struct {
+		claims   Claims
+		verified bool
+	}{
+		{Claims{"email_verified": true}, true},
+		{Claims{"email_verified": "true"}, true},
+		{Claims{"email_verified": false}, false},
+		{Claims{"email_verified": "false"}, false},
+		{Claims{"email_verified": "yes"}, false},
+		{Claims{}, false},
+	}
+
+	for _, test := range tests {
+		if verified := test.claims.EmailVerified(); verified != test.verified {
+			t.Errorf("EmailVerified of %v should be %t, got %t", test.claims, test.verified, verified)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// HardMinLength is the minimum number of characters of a password whatever the policy says
+	HardMinLength = 8
+	// MaxLength is the maximum length of a password in bytes, bcrypt ignores the following ones
+	MaxLength = 72
+	// minPersonalLength is the minimum length of a personal value looked for in a password
+	minPersonalLength = 3
+	// prefixLength is the number of hex characters of the SHA-1 hash naming a file of a breached list
+	prefixLength = 5
+)
+
+// Policy is the set of rules a password must follow
+type Policy struct {
+	// MinLength is the minimum number of characters, HardMinLength when lower
+	MinLength int
+	// Upper is true if an uppercase letter is required
+	Upper bool
+	// Lower is true if a lowercase letter is required
+	Lower bool
+	// Digit is true if a digit is required
+	Digit bool
+	// Symbol is true if a character which is neither a letter nor a digit is required
+	Symbol bool
+}
+
+// Violations returns the rules of the policy broken by a password, none when it follows them.
+// The personal values, like the username or the email, must not be contained in the password.
+func (p Policy) Violations(password string, personal ...string) []string {
+	violations := make([]string, 0)
+	minLength := p.MinLength
+	if minLength < HardMinLength {
+		minLength = HardMinLength
+	}
+	if n := len([]rune(password)); n < minLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", minLength))
+	}
+	if len(password) > MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+	if p.Upper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.Lower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.Digit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.Symbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(value)
+		if len(value) >= minPersonalLength && strings.Contains(lowered, value) {
+			violations = append(violations, "must not contain your username or email")
+			break
+		}
+	}
+
+	return violations
+}
+
+// PersonalValues returns the values of an account a password must not contain:
+// the username, the email and the local part of the email
+func PersonalValues(username, email string) []string {
+	values := []string{username, email}
+	if at := strings.LastIndex(email, "@"); at > 0 {
+		values = append(values, email[:at])
+	}
+	return values
+}
+
+// BreachedList is a local copy of a list of breached passwords split as in the k-anonymity range API:
+// the file <PREFIX>.txt of the directory holds the lines SUFFIX:COUNT of the uppercase hex SHA-1 hashes
+// starting with the 5 characters of the prefix
+type BreachedList struct {
+	// Dir is the directory of the prefix files
+	Dir string
+}
+
+// Contains returns true if the password appears in the list, a missing prefix file holds no password
+func (l BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	file, err := os.Open(filepath.Join(l.Dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineSuffix, count, found := strings.Cut(line, ":")
+		if !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		// the padding lines added to hide the size of a range have a count of 0
+		if found {
+			if n, err := strconv.Atoi(count); err == nil && n == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	return false, scanner.Err()
+}
@@ -0,0 +1,92 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestPolicy_Violations tests that each rule of the policy is reported
+func TestPolicy_Violations(t *testing.T) {
+	policy := Policy{MinLength: 8, Upper: true, Lower: true, Digit: true, Symbol: true}
+
+	if v := policy.Violations("Tr0ub4dor&3"); len(v) != 0 {
+		t.Errorf("Password should follow the policy, got %v", v)
+	}
+
+	cases := map[string]string{
+		"Sh0rt!":                   "must be at least 8 characters long",
+		"lowercase0!":              "must contain an uppercase letter",
+		"UPPERCASE0!":              "must contain a lowercase letter",
+		"NoDigitsHere!":            "must contain a digit",
+		"NoSymbols000":             "must contain a symbol",
+		strings.Repeat("Aa0!", 19): "must be at most 72 bytes long",
+	}
+	for password, expected := range cases {
+		v := policy.Violations(password)
+		if len(v) != 1 || v[0] != expected {
+			t.Errorf("Violations of %q should be [%s], got %v", password, expected, v)
+		}
+	}
+}
+
+// TestPolicy_ViolationsHardMinLength tests that a policy cannot allow passwords shorter than the hard minimum
+func TestPolicy_ViolationsHardMinLength(t *testing.T) {
+	policy := Policy{MinLength: 0}
+
+	v := policy.Violations("a")
+	if len(v) != 1 || v[0] != "must be at least 8 characters long" {
+		t.Errorf("Password shorter than the hard minimum should be refused, got %v", v)
+	}
+	if v := policy.Violations("abcdefgh"); len(v) != 0 {
+		t.Errorf("Password of the hard minimum length should be accepted, got %v", v)
+	}
+}
+
+// TestPolicy_ViolationsPersonal tests that a password containing the username or the email is refused
+func TestPolicy_ViolationsPersonal(t *testing.T) {
+	policy := Policy{MinLength: 8}
+	personal := PersonalValues("jdoe", "john.doe@epitech.eu")
+
+	for _, password := range []string{"JDOE-2023!", "john.doe-rocks", "x-John.Doe@Epitech.eu"} {
+		if v := policy.Violations(password, personal...); len(v) != 1 {
+			t.Errorf("Password %q should be refused as personal, got %v", password, v)
+		}
+	}
+
+	if v := policy.Violations("correct horse battery", personal...); len(v) != 0 {
+		t.Errorf("Password should not be personal, got %v", v)
+	}
+
+	if v := policy.Violations("abcdefgh", "", "ab"); len(v) != 0 {
+		t.Errorf("Short personal values should be ignored, got %v", v)
+	}
+}
+
+// TestBreachedList_Contains tests the lookup of a password in the prefix files
+func TestBreachedList_Contains(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	content := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list := BreachedList{Dir: dir}
+
+	if found, err := list.Contains("password"); err != nil || !found {
+		t.Errorf("Password should be breached: %v", err)
+	}
+
+	if found, err := list.Contains("a password nobody ever used"); err != nil || found {
+		t.Errorf("Password without prefix file should not be breached: %v", err)
+	}
+
+	padded := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:0\r\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(padded), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := list.Contains("password"); found {
+		t.Error("Password of a padding line should not be breached")
+	}
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket holding at most Burst tokens and refilled entirely in Period
+type Limit struct {
+	// Burst is the number of requests allowed at once
+	Burst int
+	// Period is the duration to refill the whole bucket
+	Period time.Duration
+}
+
+// IsZero returns true if the limit does not limit anything
+func (l Limit) IsZero() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+// rate returns the tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Take refills a bucket holding tokens since the last refill and takes a token from it,
+// it returns the tokens left and, when the bucket is empty, the wait before a token is available
+func (l Limit) Take(tokens float64, refilledAt, now time.Time) (float64, time.Duration, bool) {
+	elapsed := now.Sub(refilledAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(l.Burst), tokens+elapsed*l.rate())
+	}
+
+	if tokens >= 1 {
+		return tokens - 1, 0, true
+	}
+	wait := time.Duration(math.Ceil((1 - tokens) / l.rate() * float64(time.Second)))
+	return tokens, wait, false
+}
+
+// Store holds the buckets of the keys
+type Store interface {
+	// Take takes a token from the bucket of a key, a new bucket is full,
+	// it returns the wait before a token is available when the bucket is empty
+	Take(key string, limit Limit, now time.Time) (time.Duration, bool, error)
+}
+
+type bucket struct {
+	tokens     float64
+	refilledAt time.Time
+	// fullAt is the date the bucket is full again and can be forgotten
+	fullAt time.Time
+}
+
+// MemoryStore holds the buckets in memory, the limits apply to a single replica
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// sweepInterval is the interval between two removals of the full buckets
+const sweepInterval = time.Minute
+
+// Take takes a token from the bucket of a key
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.sweptAt) > sweepInterval {
+		for k, b := range s.buckets {
+			if now.After(b.fullAt) {
+				delete(s.buckets, k)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), refilledAt: now}
+		s.buckets[key] = b
+	}
+
+	tokens, wait, allowed := limit.Take(b.tokens, b.refilledAt, now)
+	b.tokens = tokens
+	b.refilledAt = now
+	b.fullAt = now.Add(time.Duration((float64(limit.Burst) - tokens) / limit.rate() * float64(time.Second)))
+	return wait, allowed, nil
+}
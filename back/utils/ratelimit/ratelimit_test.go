@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMemoryStore_Take tests that a bucket allows its burst and is refilled over its period
+func TestMemoryStore_Take(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 3, Period: 30 * time.Second}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if _, allowed, _ := store.Take("ip:1.2.3.4", limit, now); !allowed {
+			t.Fatalf("Request %d should be allowed by the burst", i+1)
+		}
+	}
+
+	wait, allowed, _ := store.Take("ip:1.2.3.4", limit, now)
+	if allowed {
+		t.Fatal("Request should be refused once the burst is spent")
+	}
+	if wait != 10*time.Second {
+		t.Errorf("Token should be available in 10s, got %s", wait)
+	}
+
+	if _, allowed, _ := store.Take("ip:5.6.7.8", limit, now); !allowed {
+		t.Error("Buckets of other keys should not be affected")
+	}
+
+	if _, allowed, _ := store.Take("ip:1.2.3.4", limit, now.Add(10*time.Second)); !allowed {
+		t.Error("Request should be allowed once a token is refilled")
+	}
+
+	if _, allowed, _ := store.Take("ip:1.2.3.4", limit, now.Add(10*time.Second)); allowed {
+		t.Error("Refilled token should be taken once")
+	}
+}
+
+// TestLimit_Take tests that a bucket is never refilled beyond its burst
+func TestLimit_Take(t *testing.T) {
+	limit := Limit{Burst: 2, Period: time.Second}
+	now := time.Now()
+
+	tokens, _, allowed := limit.Take(0, now.Add(-time.Hour), now)
+	if !allowed || tokens != 1 {
+		t.Errorf("Bucket should be refilled up to its burst, %f tokens left", tokens)
+	}
+
+	if !(Limit{}).IsZero() || limit.IsZero() {
+		t.Error("Only a limit without burst should be zero")
+	}
+}
+
+// TestMemoryStore_Sweep tests that the full buckets are forgotten
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Burst: 1, Period: time.Second}
+	now := time.Now()
+
+	store.Take("a", limit, now)
+	store.Take("b", limit, now.Add(2*time.Minute))
+
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("Full bucket should be swept")
+	}
+}
@@ -0,0 +1,82 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 encoding of the SHA-1 secret of the RFC 6238 test vectors
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCode tests the codes against the test vectors of RFC 6238
+func TestCode(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("Code at %d should be %s, got %s", unix, expected, code)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Invalid secret should fail")
+	}
+}
+
+// TestValidate tests that a code is accepted within the skew only
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previous, _ := Code(rfcSecret, Step(now)-1)
+
+	if step, ok := Validate(rfcSecret, previous, now, 1); !ok || step != Step(now)-1 {
+		t.Error("Code of the previous step should be accepted")
+	}
+
+	if _, ok := Validate(rfcSecret, previous, now, 0); ok {
+		t.Error("Code of the previous step should be refused without skew")
+	}
+
+	if _, ok := Validate(rfcSecret, "12345", now, 1); ok {
+		t.Error("Code of the wrong length should be refused")
+	}
+}
+
+// TestGenerateSecret tests that the generated secrets are random and usable
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := GenerateSecret()
+
+	if first == second {
+		t.Error("Secrets should be random")
+	}
+
+	if _, err := Code(first, 1); err != nil {
+		t.Error("Generated secret should be valid base32", err)
+	}
+}
+
+// TestProvisioningURI tests the otpauth URI read by the authenticator apps
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Attendance", "jules@gmail.com", rfcSecret)
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Attendance:jules@gmail.com?") {
+		t.Error("URI should name the issuer and the account", uri)
+	}
+
+	if !strings.Contains(uri, "secret="+rfcSecret) || !strings.Contains(uri, "issuer=Attendance") {
+		t.Error("URI should contain the secret and the issuer", uri)
+	}
+}
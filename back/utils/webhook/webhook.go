@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// EventHeader is the header carrying the event type
+	EventHeader = "X-Webhook-Event"
+	// DeliveryHeader is the header carrying the delivery id, the same across retries
+	DeliveryHeader = "X-Webhook-Delivery"
+	// TimestampHeader is the header carrying the signing date as a unix timestamp
+	TimestampHeader = "X-Webhook-Timestamp"
+	// SignatureHeader is the header carrying the HMAC-SHA256 signature of the delivery
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Sign returns the signature of a body sent at the given timestamp,
+// the hex encoded HMAC-SHA256 of "<timestamp>.<body>" prefixed by "sha256="
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if the signature matches the body sent at the given timestamp
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns the delay before the next attempt after the given number of attempts,
+// doubling from base up to max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// ErrPrivateAddress is returned for a URL or a connection targeting a private, loopback or link-local address
+var ErrPrivateAddress = errors.New("webhook URL must target a public address")
+
+// isPublic returns true if an address can be reached by the deliveries
+func isPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+// CheckURL checks that a URL is an http or https URL whose host is not a private, loopback or link-local address,
+// the addresses a host name resolves to are checked when connecting
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("webhook URL must be an http or https URL")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublic(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// refusePrivate refuses the connections to the addresses which are not public, once the host name is resolved
+func refusePrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+type Sender struct {
+	// Client is the HTTP client used to post the deliveries
+	Client *http.Client
+}
+
+// NewSender returns a sender whose requests time out after the given duration,
+// it refuses to connect to private, loopback and link-local addresses, including through redirections
+func NewSender(timeout time.Duration) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivate}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{Client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// Send posts a signed body to a URL, it returns the response status code
+// and an error if the request failed or the status code is not 2xx
+func (s *Sender) Send(url, secret, event string, deliveryID uint64, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "epicarte-webhook")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(deliveryID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
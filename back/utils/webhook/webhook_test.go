@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestSender_Send tests that the receiver gets a delivery it can verify
+func TestSender_Send(t *testing.T) {
+	secret := "this_is_a_webhook_secret"
+	body := []byte(`{"event":"session.closed"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+
+		if !Verify(secret, timestamp, received, r.Header.Get(SignatureHeader)) {
+			t.Error("Signature is not valid")
+		}
+
+		if r.Header.Get(EventHeader) != "session.closed" {
+			t.Error("Event header is not set")
+		}
+
+		if r.Header.Get(DeliveryHeader) != "42" {
+			t.Error("Delivery header is not set")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	code, err := (&Sender{Client: server.Client()}).Send(server.URL, secret, "session.closed", 42, body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if code != http.StatusNoContent {
+		t.Errorf("Status code is %d, expected %d", code, http.StatusNoContent)
+	}
+}
+
+// TestSender_SendFailure tests that a non 2xx response is an error
+func TestSender_SendFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	code, err := (&Sender{Client: server.Client()}).Send(server.URL, "secret", "student.absent", 1, []byte("{}"))
+	if err == nil {
+		t.Error("A 500 response should be an error")
+	}
+
+	if code != http.StatusInternalServerError {
+		t.Errorf("Status code is %d, expected %d", code, http.StatusInternalServerError)
+	}
+}
+
+// TestNewSender_PrivateAddress tests that the sender refuses to post to a loopback address
+func TestNewSender_PrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request to a loopback address should not be sent")
+	}))
+	defer server.Close()
+
+	if _, err := NewSender(time.Second).Send(server.URL, "secret", "student.absent", 1, []byte("{}")); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Delivery to a loopback address should be refused, got %v", err)
+	}
+}
+
+// TestCheckURL tests that the URLs targeting a private, loopback or link-local address are refused
+func TestCheckURL(t *testing.T) {
+	for _, u := range []string{"https://hooks.example.com/epicarte", "http://93.184.216.34:8080/hook"} {
+		if err := CheckURL(u); err != nil {
+			t.Errorf("URL %s should be accepted, got %v", u, err)
+		}
+	}
+
+	for _, u := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://10.0.0.12/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+		"ftp://hooks.example.com/hook",
+	} {
+		if err := CheckURL(u); err == nil {
+			t.Errorf("URL %s should be refused", u)
+		}
+	}
+}
+
+// TestVerify tests that a tampered body is refused
+func TestVerify(t *testing.T) {
+	signature := Sign("secret", 1, []byte("body"))
+
+	if !Verify("secret", 1, []byte("body"), signature) {
+		t.Error("Signature should be valid")
+	}
+
+	if Verify("secret", 1, []byte("tampered"), signature) {
+		t.Error("Signature of a tampered body should not be valid")
+	}
+
+	if Verify("secret", 2, []byte("body"), signature) {
+		t.Error("Signature of another timestamp should not be valid")
+	}
+}
+
+// TestBackoff tests that the delay doubles up to the maximum
+func TestBackoff(t *testing.T) {
+	base, max := 30*time.Second, 10*time.Minute
+
+	expected := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute}
+	for i, e := range expected {
+		if d := Backoff(i+1, base, max); d != e {
+			t.Errorf("Backoff after %d attempts is %s, expected %s", i+1, d, e)
+		}
+	}
+}